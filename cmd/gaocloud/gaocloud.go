@@ -37,6 +37,11 @@ var (
 )
 
 func main() {
+	if len(os.Args) > 1 && (os.Args[1] == "snapshot" || os.Args[1] == "restore") {
+		runSnapshotCmd(os.Args[1], os.Args[2:])
+		return
+	}
+
 	flag.StringVar(&configFile, "c", "gaocloud.conf", "configure file path")
 	flag.BoolVar(&genConfFile, "gen", false, "generate initial configure file to current directory")
 	flag.BoolVar(&showVersion, "version", false, "show version")
@@ -76,11 +81,28 @@ func runAsMaster(conf *config.GaoCloudConf) {
 	}
 	defer close(stopCh)
 
+	runControlPlane(conf)
+}
+
+//control plane only runs on the db master, slave starts it after
+//it's promoted by failover
+func runAsSlave(conf *config.GaoCloudConf) {
+	stopCh := make(chan struct{})
+	err := db.RunAsSlave(conf, stopCh)
+	if err != nil {
+		log.Fatalf("run slave database failed: %s", err.Error())
+	}
+	defer close(stopCh)
+
+	runControlPlane(conf)
+}
+
+func runControlPlane(conf *config.GaoCloudConf) {
 	if err := globaldns.New(conf.Server.DNSAddr); err != nil {
 		log.Fatalf("create globaldns failed: %v", err.Error())
 	}
 
-	authenticator, err := authentication.New(conf.Server.CasAddr)
+	authenticator, err := authentication.New(conf)
 	if err != nil {
 		log.Fatalf("create authenticator failed:%s", err.Error())
 	}
@@ -89,6 +111,10 @@ func runAsMaster(conf *config.GaoCloudConf) {
 	if err != nil {
 		log.Fatalf("create authorizer failed:%s", err.Error())
 	}
+	authenticator.SetGroupHandler(authorizer.SetExternalGroups)
+	authenticator.JwtAuth.SetLockoutHandler(func(kind, name, message string) {
+		alarm.New().Kind(kind).Name(name).Reason("LoginLockout").Message(message).Publish()
+	})
 
 	server, err := server.NewServer(authenticator.MiddlewareFunc())
 	if err != nil {
@@ -161,8 +187,41 @@ func createSelfSignedTlsCert() error {
 	return ioutil.WriteFile(defaultTlsKeyFile, []byte(cert.Key), 0644)
 }
 
-func runAsSlave(conf *config.GaoCloudConf) {
-	db.RunAsSlave(conf)
+//gaocloud snapshot -f backup.snap [-c gaocloud.conf] [-addr host:port]
+//gaocloud restore -f backup.snap [-c gaocloud.conf] [-addr host:port]
+func runSnapshotCmd(cmd string, args []string) {
+	var snapshotFile, dbAddr string
+	flags := flag.NewFlagSet(cmd, flag.ExitOnError)
+	flags.StringVar(&configFile, "c", "gaocloud.conf", "configure file path")
+	flags.StringVar(&snapshotFile, "f", "", "snapshot file path")
+	flags.StringVar(&dbAddr, "addr", "", "db address, default is the master and slave in configure file")
+	flags.Parse(args)
+
+	log.InitLogger(log.Info)
+
+	if snapshotFile == "" {
+		log.Fatalf("snapshot file should be specified")
+	}
+
+	conf := &config.GaoCloudConf{}
+	if dbAddr == "" {
+		var err error
+		if conf, err = config.LoadConfig(configFile); err != nil {
+			log.Fatalf("load configure file failed:%s", err.Error())
+		}
+	}
+
+	if cmd == "snapshot" {
+		if err := db.TakeSnapshot(conf, dbAddr, snapshotFile); err != nil {
+			log.Fatalf("%s", err.Error())
+		}
+		log.Infof("snapshot is saved to %s", snapshotFile)
+	} else {
+		if err := db.RestoreSnapshot(conf, dbAddr, snapshotFile); err != nil {
+			log.Fatalf("%s", err.Error())
+		}
+		log.Infof("snapshot %s is restored", snapshotFile)
+	}
 }
 
 func genInitConfig() error {
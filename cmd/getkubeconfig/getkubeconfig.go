@@ -99,15 +99,19 @@ func getClusterKubeConfig(addr, token, clusterName string) (string, error) {
 }
 
 func main() {
-	var addr, clusterName, adminPassword string
+	var addr, clusterName, adminPassword, token string
 	flag.StringVar(&addr, "server", "127.0.0.1:443", "gaocloud server listen address")
 	flag.StringVar(&adminPassword, "passwd", "zcloud", "admin password for gaocloud")
+	flag.StringVar(&token, "token", "", "api token, login with admin password if it's empty")
 	flag.StringVar(&clusterName, "cluster", "local", "cluster name")
 	flag.Parse()
 
-	token, err := login(addr, "admin", adminPassword)
-	if err != nil {
-		log.Fatalf("get token failed %s", err.Error())
+	if token == "" {
+		var err error
+		token, err = login(addr, "admin", adminPassword)
+		if err != nil {
+			log.Fatalf("get token failed %s", err.Error())
+		}
 	}
 
 	kubeConfig, err := getClusterKubeConfig(addr, token, clusterName)
@@ -0,0 +1,463 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/gsmlg-opt/GaoCloud/cement/log"
+
+	"github.com/gsmlg-opt/GaoCloud/config"
+	"github.com/gsmlg-opt/GaoCloud/kvzoo"
+	"github.com/gsmlg-opt/GaoCloud/kvzoo/client"
+	"github.com/gsmlg-opt/GaoCloud/pkg/db"
+)
+
+const usage = `kvctl inspects and repairs the gaocloud db through the kvzoo grpc service
+
+usage:
+  kvctl tables   [-c gaocloud.conf] [-addr host:port]
+  kvctl dump     -t table [-c gaocloud.conf] [-addr host:port]
+  kvctl get      -t table -k key [-c gaocloud.conf] [-addr host:port]
+  kvctl put      -t table -k key (-v value | -f file) [-c gaocloud.conf] [-addr host:port]
+  kvctl delete   -t table -k key [-c gaocloud.conf] [-addr host:port]
+  kvctl checksum [-c gaocloud.conf] [-master host:port -slaves host:port,...]
+  kvctl copy     -t table -from host:port -to host:port [-c gaocloud.conf]
+
+without -addr, master and slave in configure file are used, so put and
+delete are replicated to slave. tls and token in configure file are used
+if the file exists.
+`
+
+//returned by checksum if tables on nodes are different
+var errInconsistent = errors.New("tables on nodes are inconsistent")
+
+var (
+	configFile string
+	dbAddr     string
+	tableName  string
+	key        string
+)
+
+var commands = map[string]func(args []string) error{
+	"tables":   runTables,
+	"dump":     runDump,
+	"get":      runGet,
+	"put":      runPut,
+	"delete":   runDelete,
+	"checksum": runChecksum,
+	"copy":     runCopy,
+}
+
+//command returns error instead of exit, so transaction opened on db
+//is rolled back before exit, otherwise it holds the write lock
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	run, ok := commands[os.Args[1]]
+	if ok == false {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	log.InitLogger(log.Warn)
+	if err := run(os.Args[2:]); err == errInconsistent {
+		os.Exit(1)
+	} else if err != nil {
+		log.Fatalf("%s", err.Error())
+	}
+}
+
+func newFlagSet(cmd string, withAddr bool) *flag.FlagSet {
+	flags := flag.NewFlagSet(cmd, flag.ExitOnError)
+	flags.StringVar(&configFile, "c", "gaocloud.conf", "configure file path")
+	if withAddr {
+		flags.StringVar(&dbAddr, "addr", "", "db address, default is the master and slave in configure file")
+	}
+	return flags
+}
+
+//configure file is optional if node address is specified
+func loadConfig(addrSpecified bool) (*config.GaoCloudConf, error) {
+	if _, err := os.Stat(configFile); err != nil && addrSpecified {
+		return &config.GaoCloudConf{}, nil
+	}
+
+	conf, err := config.LoadConfig(configFile)
+	if err != nil {
+		return nil, fmt.Errorf("load configure file failed:%s", err.Error())
+	}
+	return conf, nil
+}
+
+func openDB() (kvzoo.DB, error) {
+	conf, err := loadConfig(dbAddr != "")
+	if err != nil {
+		return nil, err
+	}
+
+	proxy, err := db.NewProxy(conf, dbAddr)
+	if err != nil {
+		return nil, fmt.Errorf("connect db failed:%s", err.Error())
+	}
+	return proxy, nil
+}
+
+func parseTableName() (kvzoo.TableName, error) {
+	if tableName == "" {
+		return "", fmt.Errorf("table should be specified")
+	}
+	return kvzoo.NewTableName(tableName)
+}
+
+func parseTableAndKey() (kvzoo.TableName, string, error) {
+	tn, err := parseTableName()
+	if err != nil {
+		return "", "", err
+	}
+	if key == "" {
+		return "", "", fmt.Errorf("key should be specified")
+	}
+	return tn, key, nil
+}
+
+func runTables(args []string) error {
+	flags := newFlagSet("tables", true)
+	flags.Parse(args)
+
+	kv, err := openDB()
+	if err != nil {
+		return err
+	}
+	defer kv.Close()
+
+	names, err := kv.TableNames()
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(names, func(i, j int) bool {
+		return names[i] < names[j]
+	})
+	for _, name := range names {
+		fmt.Println(name)
+	}
+	return nil
+}
+
+//table isn't created if it doesn't exist
+func beginTransaction(kv kvzoo.DB, tn kvzoo.TableName) (kvzoo.Transaction, error) {
+	names, err := kv.TableNames()
+	if err != nil {
+		return nil, err
+	}
+	exists := false
+	for _, name := range names {
+		if name == tn {
+			exists = true
+			break
+		}
+	}
+	if exists == false {
+		return nil, fmt.Errorf("table %s doesn't exist", tn)
+	}
+
+	table, err := kv.CreateOrGetTable(tn)
+	if err != nil {
+		return nil, err
+	}
+	return table.Begin()
+}
+
+//value which isn't json is printed as string
+func jsonValue(value []byte) json.RawMessage {
+	if json.Valid(value) {
+		return json.RawMessage(value)
+	}
+	data, _ := json.Marshal(string(value))
+	return json.RawMessage(data)
+}
+
+func printJSON(v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(data))
+	return nil
+}
+
+func runDump(args []string) error {
+	flags := newFlagSet("dump", true)
+	flags.StringVar(&tableName, "t", "", "table name")
+	flags.Parse(args)
+	tn, err := parseTableName()
+	if err != nil {
+		return err
+	}
+
+	kv, err := openDB()
+	if err != nil {
+		return err
+	}
+	defer kv.Close()
+
+	tx, err := beginTransaction(kv, tn)
+	if err != nil {
+		return err
+	}
+	values, err := tx.List()
+	tx.Rollback()
+	if err != nil {
+		return err
+	}
+
+	dump := make(map[string]json.RawMessage, len(values))
+	for k, v := range values {
+		dump[k] = jsonValue(v)
+	}
+	return printJSON(dump)
+}
+
+func runGet(args []string) error {
+	flags := newFlagSet("get", true)
+	flags.StringVar(&tableName, "t", "", "table name")
+	flags.StringVar(&key, "k", "", "key")
+	flags.Parse(args)
+	tn, k, err := parseTableAndKey()
+	if err != nil {
+		return err
+	}
+
+	kv, err := openDB()
+	if err != nil {
+		return err
+	}
+	defer kv.Close()
+
+	tx, err := beginTransaction(kv, tn)
+	if err != nil {
+		return err
+	}
+	value, err := tx.Get(k)
+	tx.Rollback()
+	if err != nil {
+		return fmt.Errorf("get %s failed:%s", k, err.Error())
+	}
+	return printJSON(jsonValue(value))
+}
+
+//value should be valid json, key is added if it doesn't exist
+func runPut(args []string) error {
+	var value, valueFile string
+	flags := newFlagSet("put", true)
+	flags.StringVar(&tableName, "t", "", "table name")
+	flags.StringVar(&key, "k", "", "key")
+	flags.StringVar(&value, "v", "", "json value")
+	flags.StringVar(&valueFile, "f", "", "file which has the json value")
+	flags.Parse(args)
+	tn, k, err := parseTableAndKey()
+	if err != nil {
+		return err
+	}
+
+	data := []byte(value)
+	if valueFile != "" {
+		if data, err = ioutil.ReadFile(valueFile); err != nil {
+			return err
+		}
+	}
+	if json.Valid(data) == false {
+		return fmt.Errorf("value isn't valid json")
+	}
+
+	kv, err := openDB()
+	if err != nil {
+		return err
+	}
+	defer kv.Close()
+
+	table, err := kv.CreateOrGetTable(tn)
+	if err != nil {
+		return err
+	}
+	tx, err := table.Begin()
+	if err != nil {
+		return err
+	}
+
+	if _, err = tx.Get(k); err == kvzoo.ErrNotFound {
+		err = tx.Add(k, data)
+	} else if err == nil {
+		err = tx.Update(k, data)
+	}
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("put %s failed:%s", k, err.Error())
+	}
+	return tx.Commit()
+}
+
+func runDelete(args []string) error {
+	flags := newFlagSet("delete", true)
+	flags.StringVar(&tableName, "t", "", "table name")
+	flags.StringVar(&key, "k", "", "key")
+	flags.Parse(args)
+	tn, k, err := parseTableAndKey()
+	if err != nil {
+		return err
+	}
+
+	kv, err := openDB()
+	if err != nil {
+		return err
+	}
+	defer kv.Close()
+
+	tx, err := beginTransaction(kv, tn)
+	if err != nil {
+		return err
+	}
+	if err := tx.Delete(k); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("delete %s failed:%s", k, err.Error())
+	}
+	return tx.Commit()
+}
+
+type addrList []string
+
+func (l *addrList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *addrList) Set(value string) error {
+	*l = strings.Split(value, ",")
+	return nil
+}
+
+//print tables whose checksum on slave is different from master
+func runChecksum(args []string) error {
+	var masterAddr string
+	var slaveAddrs addrList
+	flags := newFlagSet("checksum", false)
+	flags.StringVar(&masterAddr, "master", "", "master address, default is the master in configure file")
+	flags.Var(&slaveAddrs, "slaves", "comma separated slave addresses, default is the slave in configure file")
+	flags.Parse(args)
+
+	conf, err := loadConfig(masterAddr != "")
+	if err != nil {
+		return err
+	}
+	if masterAddr == "" {
+		if masterAddr, slaveAddrs, err = db.NodeAddrs(conf); err != nil {
+			return err
+		}
+	}
+
+	addrs := append([]string{masterAddr}, slaveAddrs...)
+	checksums := make([]map[string]string, 0, len(addrs))
+	names := make(map[string]struct{})
+	for _, addr := range addrs {
+		c, err := db.NewNodeClient(conf, addr)
+		if err != nil {
+			return err
+		}
+		cs, err := client.TableChecksums(c)
+		c.Close()
+		if err != nil {
+			return err
+		}
+
+		checksums = append(checksums, cs)
+		for name := range cs {
+			names[name] = struct{}{}
+		}
+	}
+
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	inconsistent := false
+	for _, name := range sorted {
+		masterChecksum := checksums[0][name]
+		for i, cs := range checksums[1:] {
+			if cs[name] != masterChecksum {
+				if inconsistent == false {
+					inconsistent = true
+					fmt.Fprintf(w, "TABLE\tNODE\tCHECKSUM\n")
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\n", name, addrs[0], checksumOrMissing(masterChecksum))
+				fmt.Fprintf(w, "%s\t%s\t%s\n", name, addrs[i+1], checksumOrMissing(cs[name]))
+			}
+		}
+	}
+	w.Flush()
+
+	if inconsistent {
+		return errInconsistent
+	}
+	fmt.Printf("%d tables are consistent on %d nodes\n", len(sorted), len(addrs))
+	return nil
+}
+
+func checksumOrMissing(checksum string) string {
+	if checksum == "" {
+		return "missing"
+	}
+	return checksum
+}
+
+//overwrite the table on dst with the table on src, the change isn't
+//replicated, normally it's used to repair slave
+func runCopy(args []string) error {
+	var src, dst string
+	flags := newFlagSet("copy", false)
+	flags.StringVar(&tableName, "t", "", "table name")
+	flags.StringVar(&src, "from", "", "address of the node which has the right data")
+	flags.StringVar(&dst, "to", "", "address of the node to repair")
+	flags.Parse(args)
+	tn, err := parseTableName()
+	if err != nil {
+		return err
+	}
+	if src == "" || dst == "" {
+		return fmt.Errorf("from and to should be specified")
+	}
+
+	conf, err := loadConfig(true)
+	if err != nil {
+		return err
+	}
+
+	srcClient, err := db.NewNodeClient(conf, src)
+	if err != nil {
+		return err
+	}
+	defer srcClient.Close()
+
+	dstClient, err := db.NewNodeClient(conf, dst)
+	if err != nil {
+		return err
+	}
+	defer dstClient.Close()
+
+	if err := client.CopyTable(srcClient, dstClient, string(tn)); err != nil {
+		return fmt.Errorf("copy table %s failed:%s", tn, err.Error())
+	}
+	fmt.Printf("table %s is copied from %s to %s\n", tn, src, dst)
+	return nil
+}
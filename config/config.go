@@ -2,6 +2,8 @@ package config
 
 import (
 	"errors"
+	"fmt"
+	"strings"
 
 	"cement/configure"
 	"cement/log"
@@ -15,11 +17,17 @@ const (
 )
 
 type GaoCloudConf struct {
-	Path     string         `yaml:"-"`
-	Server   ServerConf     `yaml:"server"`
-	DB       DBConf         `yaml:"db"`
-	Chart    ChartConf      `yaml:"chart"`
-	Registry RegistryCAConf `yaml:"registry"`
+	Path      string         `yaml:"-"`
+	Server    ServerConf     `yaml:"server"`
+	DB        DBConf         `yaml:"db"`
+	Chart     ChartConf      `yaml:"chart"`
+	Registry  RegistryCAConf `yaml:"registry"`
+	AuditLog  AuditLogConf   `yaml:"audit_log"`
+	LDAP      LDAPConf       `yaml:"ldap"`
+	OIDC      OIDCConf       `yaml:"oidc"`
+	JWT       JWTConf        `yaml:"jwt"`
+	Login     LoginConf      `yaml:"login"`
+	UserQuota UserQuotaConf  `yaml:"user_quota"`
 }
 
 type ServerConf struct {
@@ -31,11 +39,41 @@ type ServerConf struct {
 	EnableDebug bool   `yaml:"enable_debug"`
 }
 
+type DBBackend string
+
+const (
+	BoltBackend       DBBackend = "bolt"
+	MemoryBackend     DBBackend = "memory"
+	Sqlite3Backend    DBBackend = "sqlite3"
+	PostgresqlBackend DBBackend = "postgresql"
+)
+
 type DBConf struct {
 	Path        string `yaml:"path"`
 	Port        int    `yaml:"port"`
 	Role        DBRole `yaml:"role"`
 	SlaveDBAddr string `yaml:"slave_db_addr"`
+	//proxy beside each node checks master and promotes slave if master
+	//is down, advertise address is the address of the node used by the
+	//other node, it should be the same with slave_db_addr of master for
+	//slave, and master_db_addr of slave for master
+	MasterDBAddr    string `yaml:"master_db_addr"`
+	AdvertiseDBAddr string `yaml:"advertise_db_addr"`
+	//data is saved in Path for bolt and sqlite3, in DSN for postgresql,
+	//memory backend loses all the data after restart
+	Backend DBBackend `yaml:"backend"`
+	DSN     string    `yaml:"dsn"`
+	//cert is used as both server and client certificate, if ca file
+	//is set, client certificate is required
+	TlsCertFile   string `yaml:"tls_cert_file"`
+	TlsKeyFile    string `yaml:"tls_key_file"`
+	TlsCaFile     string `yaml:"tls_ca_file"`
+	TlsServerName string `yaml:"tls_server_name"`
+	Token         string `yaml:"token"`
+}
+
+func (c *DBConf) TLSEnabled() bool {
+	return c.TlsCertFile != ""
 }
 
 type ChartConf struct {
@@ -48,14 +86,127 @@ type RegistryCAConf struct {
 	CaKeyPath  string `yaml:"ca_key_path"`
 }
 
+type AuditLogConf struct {
+	//audit logs exceed any limit are deleted from the oldest one,
+	//zero means no limit
+	MaxCount      int `yaml:"max_count"`
+	RetentionDays int `yaml:"retention_days"`
+	//logs are forwarded to all the sinks besides saved in db
+	Syslogs  []SyslogSinkConf  `yaml:"syslog"`
+	Webhooks []WebhookSinkConf `yaml:"webhook"`
+	Files    []FileSinkConf    `yaml:"file"`
+}
+
+type SyslogSinkConf struct {
+	//udp or tcp
+	Network string `yaml:"network"`
+	Addr    string `yaml:"addr"`
+	AppName string `yaml:"app_name"`
+}
+
+type WebhookSinkConf struct {
+	URL string `yaml:"url"`
+	//sent as bearer token
+	Token                string `yaml:"token"`
+	BatchSize            int    `yaml:"batch_size"`
+	FlushIntervalSeconds int    `yaml:"flush_interval_seconds"`
+}
+
+type FileSinkConf struct {
+	Path       string `yaml:"path"`
+	MaxSizeMB  int    `yaml:"max_size_mb"`
+	MaxBackups int    `yaml:"max_backups"`
+}
+
+type LDAPConf struct {
+	//ldap://host:389 or ldaps://host:636, empty means ldap is disabled
+	Addr               string `yaml:"addr"`
+	StartTLS           bool   `yaml:"start_tls"`
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify"`
+	//account to search the user, anonymous bind is used if it's empty
+	BindDN       string `yaml:"bind_dn"`
+	BindPassword string `yaml:"bind_password"`
+	BaseDN       string `yaml:"base_dn"`
+	//%s is replaced by the user name, (sAMAccountName=%s) for AD
+	UserFilter     string `yaml:"user_filter"`
+	GroupAttribute string `yaml:"group_attribute"`
+	//ldap group dn to gaocloud group name, cn of the ldap group is
+	//used as the group name if it isn't in the map
+	GroupMapping map[string]string `yaml:"group_mapping"`
+}
+
+type OIDCConf struct {
+	//empty issuer means oidc is disabled
+	Issuer       string `yaml:"issuer"`
+	ClientID     string `yaml:"client_id"`
+	ClientSecret string `yaml:"client_secret"`
+	//it should be the external address of /web/oidc/callback
+	RedirectURL string   `yaml:"redirect_url"`
+	Scopes      []string `yaml:"scopes"`
+	//claims of id token used as user name and groups
+	UserClaim   string `yaml:"user_claim"`
+	GroupsClaim string `yaml:"groups_claim"`
+	//oidc group to gaocloud group name, oidc group is used as the
+	//group name if it isn't in the map
+	GroupMapping map[string]string `yaml:"group_mapping"`
+}
+
+type JWTConf struct {
+	//signing key is generated and saved in db, it's replaced by a new
+	//key after the days, zero means the key is never rotated
+	KeyRotationDays int `yaml:"key_rotation_days"`
+}
+
+type LoginConf struct {
+	//user or client ip is locked after the failures, zero means no
+	//lockout, login is delayed exponentially after each failure
+	MaxUserFailures int `yaml:"max_user_failures"`
+	MaxIPFailures   int `yaml:"max_ip_failures"`
+	//failures are forgotten after the minutes without failure
+	LockoutMinutes int `yaml:"lockout_minutes"`
+	//admin can only enroll totp before totp is enabled
+	RequireAdminTOTP bool `yaml:"require_admin_totp"`
+}
+
+type UserQuotaConf struct {
+	//state changes of user quota are posted to the webhooks
+	Webhooks []UserQuotaWebhookConf `yaml:"webhook"`
+}
+
+type UserQuotaWebhookConf struct {
+	URL string `yaml:"url"`
+	//sent as bearer token
+	Token string `yaml:"token"`
+}
+
 func CreateDefaultConfig() GaoCloudConf {
 	return GaoCloudConf{
 		Server: ServerConf{
 			Addr: ":80",
 		},
 		DB: DBConf{
-			Port: 6666,
-			Role: Master,
+			Port:    6666,
+			Role:    Master,
+			Backend: BoltBackend,
+		},
+		AuditLog: AuditLogConf{
+			MaxCount: 1000,
+		},
+		LDAP: LDAPConf{
+			UserFilter:     "(uid=%s)",
+			GroupAttribute: "memberOf",
+		},
+		OIDC: OIDCConf{
+			UserClaim:   "email",
+			GroupsClaim: "groups",
+		},
+		JWT: JWTConf{
+			KeyRotationDays: 30,
+		},
+		Login: LoginConf{
+			MaxUserFailures: 5,
+			MaxIPFailures:   20,
+			LockoutMinutes:  15,
 		},
 	}
 }
@@ -92,10 +243,109 @@ func (c *GaoCloudConf) Verify() error {
 		return errors.New("slave node cann't have other slaves")
 	}
 
+	if c.DB.Role == Master && c.DB.MasterDBAddr != "" {
+		return errors.New("master node cann't have master_db_addr")
+	}
+
+	if c.DB.MasterDBAddr != "" && c.DB.AdvertiseDBAddr == "" {
+		return errors.New("advertise_db_addr should be specified to promote slave")
+	}
+
 	if c.DB.Role == Master && c.DB.SlaveDBAddr == "" {
 		log.Warnf("no slave node is specified, if master node is crashed, data will be lost\n")
 	}
 
+	if c.DB.Role == Master && c.DB.SlaveDBAddr != "" && c.DB.AdvertiseDBAddr == "" {
+		log.Warnf("advertise_db_addr isn't specified, master can't be switched back after slave is promoted\n")
+	}
+
+	switch c.DB.Backend {
+	case BoltBackend, MemoryBackend, Sqlite3Backend:
+	case PostgresqlBackend:
+		if c.DB.DSN == "" {
+			return errors.New("dsn should be specified for postgresql db backend")
+		}
+	default:
+		return fmt.Errorf("unknown db backend %s", c.DB.Backend)
+	}
+
+	if c.DB.Backend == MemoryBackend {
+		log.Warnf("db backend is memory, all the data will be lost after restart\n")
+	}
+
+	if (c.DB.TlsCertFile == "") != (c.DB.TlsKeyFile == "") {
+		return errors.New("db tls cert file and key file should be specified together")
+	}
+
+	if c.DB.TLSEnabled() == false && (c.DB.TlsCaFile != "" || c.DB.TlsServerName != "") {
+		return errors.New("db tls ca file or server name is specified without cert file")
+	}
+
+	if c.DB.TLSEnabled() == false && c.DB.Token != "" {
+		log.Warnf("db token is sent in plain text without tls\n")
+	}
+
+	if c.AuditLog.MaxCount < 0 || c.AuditLog.RetentionDays < 0 {
+		return errors.New("audit log max count and retention days cann't be negative")
+	}
+
+	for _, sink := range c.AuditLog.Syslogs {
+		if sink.Network != "udp" && sink.Network != "tcp" {
+			return fmt.Errorf("audit log syslog network %s isn't udp or tcp", sink.Network)
+		}
+		if sink.Addr == "" {
+			return errors.New("audit log syslog addr should be specified")
+		}
+	}
+
+	for _, sink := range c.AuditLog.Webhooks {
+		if sink.URL == "" {
+			return errors.New("audit log webhook url should be specified")
+		}
+	}
+
+	for _, sink := range c.AuditLog.Files {
+		if sink.Path == "" {
+			return errors.New("audit log file path should be specified")
+		}
+	}
+
+	for _, hook := range c.UserQuota.Webhooks {
+		if hook.URL == "" {
+			return errors.New("user quota webhook url should be specified")
+		}
+	}
+
+	if c.LDAP.Addr != "" {
+		if c.LDAP.BaseDN == "" {
+			return errors.New("ldap base dn should be specified")
+		}
+		if strings.Count(c.LDAP.UserFilter, "%s") != 1 {
+			return fmt.Errorf("ldap user filter %s should have one %%s", c.LDAP.UserFilter)
+		}
+	}
+
+	if c.OIDC.Issuer != "" {
+		if c.OIDC.ClientID == "" || c.OIDC.RedirectURL == "" {
+			return errors.New("oidc client id and redirect url should be specified")
+		}
+		if c.Server.CasAddr != "" {
+			return errors.New("cas and oidc cann't be enabled together")
+		}
+	}
+
+	if c.JWT.KeyRotationDays < 0 {
+		return errors.New("jwt key rotation days cann't be negative")
+	}
+
+	if c.Login.MaxUserFailures < 0 || c.Login.MaxIPFailures < 0 {
+		return errors.New("login max failures cann't be negative")
+	}
+
+	if c.Login.LockoutMinutes <= 0 {
+		return errors.New("login lockout minutes should be positive")
+	}
+
 	if c.Registry.CaCertPath == "" || c.Registry.CaKeyPath == "" {
 		return errors.New("registry ca must be specified")
 	}
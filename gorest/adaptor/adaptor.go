@@ -23,6 +23,10 @@ func RegisterHandler(router gin.IRoutes, handler http.Handler, route resource.Re
 			for _, path := range paths {
 				router.PUT(path, handlerFunc)
 			}
+		case http.MethodPatch:
+			for _, path := range paths {
+				router.PATCH(path, handlerFunc)
+			}
 		case http.MethodGet:
 			for _, path := range paths {
 				router.GET(path, handlerFunc)
@@ -0,0 +1,91 @@
+package gorest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"reflect"
+
+	jsonpatch "github.com/evanphx/json-patch"
+
+	goresterr "gorest/error"
+	"gorest/resource"
+)
+
+const (
+	MergePatchContentType = "application/merge-patch+json"
+	JSONPatchContentType  = "application/json-patch+json"
+)
+
+//patch is applied to the resource returned by get handler, the result
+//is validated like the body of PUT, then update handler is called with
+//it, so update handler needn't know it's a patch
+func handlePatch(ctx *resource.Context) *goresterr.APIError {
+	schema := ctx.Resource.GetSchema()
+	get := schema.GetHandler().GetGetHandler()
+	if get == nil || schema.GetHandler().GetUpdateHandler() == nil {
+		return goresterr.NewAPIError(goresterr.NotFound, "no handler for patch")
+	}
+
+	if ctx.Request.Body == nil {
+		return goresterr.NewAPIError(goresterr.InvalidBodyContent, "patch is empty")
+	}
+	patch, err := ioutil.ReadAll(ctx.Request.Body)
+	ctx.Request.Body.Close()
+	if err != nil {
+		return goresterr.NewAPIError(goresterr.InvalidBodyContent, fmt.Sprintf("failed to read request body: %s", err.Error()))
+	}
+
+	current, err_ := get(ctx)
+	if err_ != nil {
+		return err_
+	}
+	if current == nil || (reflect.ValueOf(current).Kind() == reflect.Ptr && reflect.ValueOf(current).IsNil()) {
+		return goresterr.NewAPIError(goresterr.NotFound,
+			fmt.Sprintf("%s resource with id %s doesn't exist", ctx.Resource.GetType(), ctx.Resource.GetID()))
+	}
+
+	doc, err := json.Marshal(current)
+	if err != nil {
+		return goresterr.NewAPIError(goresterr.ServerError, fmt.Sprintf("marshal failed:%s", err.Error()))
+	}
+	patched, err := applyPatch(ctx.Request.Header.Get(ContentTypeKey), doc, patch)
+	if err != nil {
+		return goresterr.NewAPIError(goresterr.InvalidBodyContent, fmt.Sprintf("apply patch failed:%s", err.Error()))
+	}
+
+	id := ctx.Resource.GetID()
+	if err_ := schema.ValidateAndFillResource(ctx.Resource, patched); err_ != nil {
+		return err_
+	}
+	if ctx.Resource.GetID() != id {
+		return goresterr.NewAPIError(goresterr.InvalidBodyContent, "id of resource cann't be patched")
+	}
+	return handleUpdate(ctx)
+}
+
+//json patch (rfc 6902) is used only if the content type is specified,
+//otherwise the body is a json merge patch (rfc 7386)
+func applyPatch(contentType string, doc, patch []byte) ([]byte, error) {
+	mediaType := contentType
+	if contentType != "" {
+		var err error
+		if mediaType, _, err = mime.ParseMediaType(contentType); err != nil {
+			return nil, fmt.Errorf("invalid content type %s", contentType)
+		}
+	}
+
+	switch mediaType {
+	case JSONPatchContentType:
+		p, err := jsonpatch.DecodePatch(patch)
+		if err != nil {
+			return nil, err
+		}
+		return p.Apply(doc)
+	case MergePatchContentType, "application/json", "":
+		return jsonpatch.MergePatch(doc, patch)
+	default:
+		return nil, fmt.Errorf("unsupported content type %s", contentType)
+	}
+}
@@ -0,0 +1,105 @@
+package gorest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	ut "cement/unittest"
+	goresterr "gorest/error"
+	"gorest/resource"
+	"gorest/resource/schema"
+)
+
+type Deploy struct {
+	resource.ResourceBase `json:",inline"`
+	Replicas              int               `json:"replicas" rest:"min=0,max=10"`
+	Image                 string            `json:"image" rest:"required=true"`
+	Labels                map[string]string `json:"labels"`
+}
+
+type deployHandler struct {
+	deploy  *Deploy
+	updated *Deploy
+}
+
+func (h *deployHandler) Get(ctx *resource.Context) (resource.Resource, *goresterr.APIError) {
+	if ctx.Resource.GetID() != h.deploy.GetID() {
+		return nil, nil
+	}
+	d := *h.deploy
+	return &d, nil
+}
+
+func (h *deployHandler) Update(ctx *resource.Context) (resource.Resource, *goresterr.APIError) {
+	h.updated = ctx.Resource.(*Deploy)
+	return h.updated, nil
+}
+
+func doPatch(s *Server, id, contentType, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(http.MethodPatch, "/apis/testing/v1/deploys/"+id, strings.NewReader(body))
+	if contentType != "" {
+		req.Header.Set(ContentTypeKey, contentType)
+	}
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	return w
+}
+
+func TestPatch(t *testing.T) {
+	deploy := &Deploy{
+		Replicas: 1,
+		Image:    "nginx:1.16",
+		Labels:   map[string]string{"app": "web", "tier": "frontend"},
+	}
+	deploy.SetID("web")
+	handler := &deployHandler{deploy: deploy}
+	schemas := schema.NewSchemaManager()
+	schemas.MustImport(&version, Deploy{}, handler)
+	s := NewAPIServer(schemas)
+
+	route := schemas.GenerateResourceRoute()
+	ut.Equal(t, route[http.MethodPatch], []string{"/apis/testing/v1/deploys/:deploy_id"})
+
+	w := doPatch(s, "web", "", `{"replicas":3,"labels":{"tier":null}}`)
+	ut.Equal(t, w.Code, http.StatusOK)
+	ut.Equal(t, handler.updated.Replicas, 3)
+	ut.Equal(t, handler.updated.Image, "nginx:1.16")
+	ut.Equal(t, handler.updated.Labels, map[string]string{"app": "web"})
+	var result Deploy
+	ut.Assert(t, json.Unmarshal(w.Body.Bytes(), &result) == nil, "")
+	ut.Equal(t, result.Replicas, 3)
+
+	w = doPatch(s, "web", MergePatchContentType+"; charset=utf-8", `{"image":"nginx:1.17"}`)
+	ut.Equal(t, w.Code, http.StatusOK)
+	ut.Equal(t, handler.updated.Replicas, 1)
+	ut.Equal(t, handler.updated.Image, "nginx:1.17")
+
+	w = doPatch(s, "web", JSONPatchContentType, `[{"op":"test","path":"/image","value":"nginx:1.16"},{"op":"replace","path":"/replicas","value":5},{"op":"add","path":"/labels/env","value":"prod"}]`)
+	ut.Equal(t, w.Code, http.StatusOK)
+	ut.Equal(t, handler.updated.Replicas, 5)
+	ut.Equal(t, handler.updated.Labels, map[string]string{"app": "web", "tier": "frontend", "env": "prod"})
+
+	handler.updated = nil
+	cases := []struct {
+		id          string
+		contentType string
+		body        string
+		code        int
+	}{
+		{"web", "", `{"replicas":11}`, goresterr.InvalidBodyContent.Status},
+		{"web", "", `{"image":null}`, goresterr.InvalidBodyContent.Status},
+		{"web", "", `{"id":"db"}`, goresterr.InvalidBodyContent.Status},
+		{"web", "", `not json`, goresterr.InvalidBodyContent.Status},
+		{"web", JSONPatchContentType, `[{"op":"test","path":"/image","value":"redis"}]`, goresterr.InvalidBodyContent.Status},
+		{"web", "text/plain", `{"replicas":2}`, goresterr.InvalidBodyContent.Status},
+		{"db", "", `{"replicas":2}`, goresterr.NotFound.Status},
+	}
+	for _, tc := range cases {
+		w := doPatch(s, tc.id, tc.contentType, tc.body)
+		ut.Equal(t, w.Code, tc.code)
+	}
+	ut.Assert(t, handler.updated == nil, "invalid patch shouldn't be updated")
+}
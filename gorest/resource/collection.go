@@ -9,9 +9,13 @@ type ResourceCollection struct {
 	Type         string                            `json:"type,omitempty"`
 	ResourceType string                            `json:"resourceType,omitempty"`
 	Links        map[ResourceLinkType]ResourceLink `json:"links,omitempty"`
+	Total        *int                              `json:"total,omitempty"`
 	Resources    []Resource                        `json:"data"`
 
 	collection Resource `json:"-"`
+	nextMarker string   `json:"-"`
+	prevMarker string   `json:"-"`
+	hasPrev    bool     `json:"-"`
 }
 
 func NewResourceCollection(collection Resource, i interface{}) (*ResourceCollection, error) {
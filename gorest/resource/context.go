@@ -9,18 +9,19 @@ import (
 )
 
 const (
-	Eq      Modifier = "eq"
-	Ne      Modifier = "ne"
-	Lt      Modifier = "lt"
-	Gt      Modifier = "gt"
-	Lte     Modifier = "lte"
-	Gte     Modifier = "gte"
-	Prefix  Modifier = "prefix"
-	Suffix  Modifier = "suffix"
-	Like    Modifier = "like"
-	NotLike Modifier = "notlike"
-	Null    Modifier = "null"
-	NotNull Modifier = "notnull"
+	Eq       Modifier = "eq"
+	Ne       Modifier = "ne"
+	Lt       Modifier = "lt"
+	Gt       Modifier = "gt"
+	Lte      Modifier = "lte"
+	Gte      Modifier = "gte"
+	Prefix   Modifier = "prefix"
+	Suffix   Modifier = "suffix"
+	Contains Modifier = "contains"
+	Like     Modifier = "like"
+	NotLike  Modifier = "notlike"
+	Null     Modifier = "null"
+	NotNull  Modifier = "notnull"
 )
 
 type Context struct {
@@ -31,6 +32,10 @@ type Context struct {
 	Method   string
 	params   map[string]interface{}
 	filters  []Filter
+
+	pagination     Pagination
+	skipListQuery  bool
+	skipListFilter bool
 }
 
 type Filter struct {
@@ -47,6 +52,11 @@ func NewContext(resp http.ResponseWriter, req *http.Request, schemas SchemaManag
 		return nil, err
 	}
 
+	pagination, err := genPagination(req.URL)
+	if err != nil {
+		return nil, err
+	}
+
 	return &Context{
 		Request:  req,
 		Response: resp,
@@ -55,6 +65,8 @@ func NewContext(resp http.ResponseWriter, req *http.Request, schemas SchemaManag
 		Method:   req.Method,
 		params:   make(map[string]interface{}),
 		filters:  genFilters(req.URL),
+
+		pagination: pagination,
 	}, nil
 }
 
@@ -71,9 +83,37 @@ func (ctx *Context) GetFilters() []Filter {
 	return ctx.filters
 }
 
+func (ctx *Context) GetPagination() Pagination {
+	return ctx.pagination
+}
+
+//handler which filters and paginates the list by itself should call
+//it, then gorest returns the list as it is
+func (ctx *Context) SkipListQuery() {
+	ctx.skipListQuery = true
+}
+
+func (ctx *Context) IsListQuerySkipped() bool {
+	return ctx.skipListQuery
+}
+
+//handler which uses the filters by itself should call it, gorest
+//still sorts and paginates the list
+func (ctx *Context) SkipListFilter() {
+	ctx.skipListFilter = true
+}
+
+func (ctx *Context) IsListFilterSkipped() bool {
+	return ctx.skipListFilter
+}
+
 func genFilters(url *url.URL) []Filter {
 	filters := make([]Filter, 0)
 	for k, v := range url.Query() {
+		if isPaginationParam(k) {
+			continue
+		}
+
 		filter := Filter{
 			Name:     k,
 			Modifier: Eq,
@@ -107,6 +147,8 @@ func VerifyModifier(str string) Modifier {
 		return Prefix
 	case "suffix":
 		return Suffix
+	case "contains":
+		return Contains
 	case "like":
 		return Like
 	case "notlike":
@@ -156,6 +156,10 @@ func GetResourceMethods(handler Handler) []HttpMethod {
 	if handler.GetUpdateHandler() != nil {
 		resourceMethods = append(resourceMethods, http.MethodPut)
 	}
+	//patch is applied to the resource got by get handler
+	if handler.GetGetHandler() != nil && handler.GetUpdateHandler() != nil {
+		resourceMethods = append(resourceMethods, http.MethodPatch)
+	}
 	if handler.GetActionHandler() != nil {
 		resourceMethods = append(resourceMethods, http.MethodPost)
 	}
@@ -20,7 +20,7 @@ func TestHandlerGen(t *testing.T) {
 	handler, _ := HandlerAdaptor(&DumbHandler{})
 	resourceMethods := GetResourceMethods(handler)
 	collectionMethods := GetCollectionMethods(handler)
-	ut.Equal(t, resourceMethods, []HttpMethod{http.MethodGet, http.MethodDelete, http.MethodPut, http.MethodPost})
+	ut.Equal(t, resourceMethods, []HttpMethod{http.MethodGet, http.MethodDelete, http.MethodPut, http.MethodPatch, http.MethodPost})
 	ut.Equal(t, collectionMethods, []HttpMethod{http.MethodGet, http.MethodPost})
 
 	createResult, err := handler.GetCreateHandler()(nil)
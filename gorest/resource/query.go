@@ -0,0 +1,374 @@
+package resource
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	goresterr "gorest/error"
+)
+
+//query parameters to sort and paginate the list, they aren't filters
+const (
+	SortParam   = "sort"
+	OrderParam  = "order"
+	LimitParam  = "limit"
+	MarkerParam = "marker"
+
+	OrderAsc  = "asc"
+	OrderDesc = "desc"
+)
+
+//marker is the id of the last resource in previous page, limit 0
+//means no limit
+type Pagination struct {
+	Sort   string
+	Order  string
+	Limit  int
+	Marker string
+}
+
+func isPaginationParam(key string) bool {
+	return key == SortParam || key == OrderParam || key == LimitParam || key == MarkerParam
+}
+
+func genPagination(url *url.URL) (Pagination, *goresterr.APIError) {
+	q := url.Query()
+	p := Pagination{
+		Sort:   q.Get(SortParam),
+		Order:  q.Get(OrderParam),
+		Marker: q.Get(MarkerParam),
+	}
+
+	switch p.Order {
+	case "", OrderAsc, OrderDesc:
+	default:
+		return p, goresterr.NewAPIError(goresterr.InvalidOption, fmt.Sprintf("invalid order %s, it should be asc or desc", p.Order))
+	}
+
+	if limit := q.Get(LimitParam); limit != "" {
+		var err_ error
+		if p.Limit, err_ = strconv.Atoi(limit); err_ != nil || p.Limit <= 0 {
+			return p, goresterr.NewAPIError(goresterr.InvalidOption, fmt.Sprintf("invalid limit %s", limit))
+		}
+	}
+	return p, nil
+}
+
+//filters and sort key refer to the json name of resource field, filter
+//on unknown field is ignored since some handlers use query parameters
+//as their own options, resources are sorted by id if only order is
+//specified, total is the count of resources after filtering
+func (rc *ResourceCollection) ApplyQuery(filters []Filter, p Pagination) error {
+	if len(rc.Resources) == 0 {
+		total := 0
+		rc.Total = &total
+		return nil
+	}
+
+	fields := jsonFieldIndexes(reflect.TypeOf(rc.Resources[0]))
+	resources := rc.Resources
+	for _, filter := range filters {
+		index, ok := fields[filter.Name]
+		if ok == false {
+			continue
+		}
+
+		var matched []Resource
+		for _, r := range resources {
+			if ok, err := filter.match(fieldByIndex(r, index)); err != nil {
+				return fmt.Errorf("invalid filter %s:%s", filter.Name, err.Error())
+			} else if ok {
+				matched = append(matched, r)
+			}
+		}
+		resources = matched
+	}
+
+	if p.Sort == "" && p.Order != "" {
+		p.Sort = "id"
+	}
+	if p.Sort != "" {
+		index, ok := fields[p.Sort]
+		if ok == false {
+			return fmt.Errorf("unknown sort field %s", p.Sort)
+		}
+		sort.SliceStable(resources, func(i, j int) bool {
+			a, b := fieldByIndex(resources[i], index), fieldByIndex(resources[j], index)
+			if p.Order == OrderDesc {
+				return compareValue(b, a) < 0
+			}
+			return compareValue(a, b) < 0
+		})
+	}
+
+	total := len(resources)
+	rc.Total = &total
+	start := 0
+	if p.Marker != "" {
+		start = -1
+		for i, r := range resources {
+			if r.GetID() == p.Marker {
+				start = i + 1
+				break
+			}
+		}
+		if start == -1 {
+			return fmt.Errorf("marker %s doesn't exist", p.Marker)
+		}
+	}
+
+	end := len(resources)
+	if p.Limit > 0 {
+		if start+p.Limit < end {
+			end = start + p.Limit
+			rc.nextMarker = resources[end-1].GetID()
+		}
+		if start > 0 {
+			rc.hasPrev = true
+			if start > p.Limit {
+				rc.prevMarker = resources[start-p.Limit-1].GetID()
+			}
+		}
+	}
+
+	rc.Resources = append([]Resource{}, resources[start:end]...)
+	return nil
+}
+
+//next and prev links are the self link with marker changed, other
+//query parameters are kept
+func (rc *ResourceCollection) AddPageLinks(query url.Values) {
+	self, ok := rc.Links[SelfLink]
+	if ok == false {
+		return
+	}
+
+	pageLink := func(marker string) ResourceLink {
+		q := url.Values{}
+		for k, v := range query {
+			q[k] = v
+		}
+		if marker == "" {
+			q.Del(MarkerParam)
+		} else {
+			q.Set(MarkerParam, marker)
+		}
+		return ResourceLink(string(self) + "?" + q.Encode())
+	}
+
+	if rc.nextMarker != "" {
+		rc.Links[NextLink] = pageLink(rc.nextMarker)
+	}
+	if rc.hasPrev {
+		rc.Links[PrevLink] = pageLink(rc.prevMarker)
+	}
+}
+
+func jsonFieldIndexes(typ reflect.Type) map[string][]int {
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+
+	indexes := make(map[string][]int)
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		tag := field.Tag.Get("json")
+		name := strings.Split(tag, ",")[0]
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			for n, index := range jsonFieldIndexes(field.Type) {
+				if _, ok := indexes[n]; ok == false {
+					indexes[n] = append([]int{i}, index...)
+				}
+			}
+			continue
+		}
+
+		if field.PkgPath != "" || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		indexes[name] = []int{i}
+	}
+	return indexes
+}
+
+func fieldByIndex(r Resource, index []int) reflect.Value {
+	return reflect.ValueOf(r).Elem().FieldByIndex(index)
+}
+
+//slice field matches if any of its element matches, for ne, none of
+//the elements should equal to the values
+func (f Filter) match(v reflect.Value) (bool, error) {
+	switch f.Modifier {
+	case Null:
+		return isEmptyValue(v), nil
+	case NotNull:
+		return isEmptyValue(v) == false, nil
+	case Like, NotLike:
+		return true, nil
+	}
+
+	elems := []reflect.Value{v}
+	if v.Kind() == reflect.Slice || v.Kind() == reflect.Array {
+		elems = elems[:0]
+		for i := 0; i < v.Len(); i++ {
+			elems = append(elems, v.Index(i))
+		}
+	}
+
+	for _, elem := range elems {
+		for _, value := range f.Values {
+			ok, err := matchValue(elem, f.Modifier, value)
+			if err != nil {
+				return false, err
+			}
+			if ok && f.Modifier != Ne {
+				return true, nil
+			} else if ok == false && f.Modifier == Ne {
+				return false, nil
+			}
+		}
+	}
+	return f.Modifier == Ne, nil
+}
+
+func matchValue(v reflect.Value, modifier Modifier, value string) (bool, error) {
+	switch modifier {
+	case Prefix:
+		return strings.HasPrefix(valueToString(v), value), nil
+	case Suffix:
+		return strings.HasSuffix(valueToString(v), value), nil
+	case Contains:
+		return strings.Contains(valueToString(v), value), nil
+	}
+
+	target, err := parseValue(v, value)
+	if err != nil {
+		return false, err
+	}
+	c := compare(comparableValue(v), target)
+	switch modifier {
+	case Ne:
+		return c != 0, nil
+	case Lt:
+		return c < 0, nil
+	case Gt:
+		return c > 0, nil
+	case Lte:
+		return c <= 0, nil
+	case Gte:
+		return c >= 0, nil
+	default:
+		return c == 0, nil
+	}
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return v.Len() == 0
+	default:
+		return v.IsZero()
+	}
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+//time, number and bool fields are compared by value, others are
+//compared as string
+func comparableValue(v reflect.Value) interface{} {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+
+	if v.Type().ConvertibleTo(timeType) && v.Kind() == reflect.Struct {
+		return v.Convert(timeType).Interface().(time.Time)
+	}
+
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		return v.Float()
+	case reflect.Bool:
+		return v.Bool()
+	case reflect.String:
+		return v.String()
+	default:
+		return fmt.Sprint(v.Interface())
+	}
+}
+
+func parseValue(v reflect.Value, value string) (interface{}, error) {
+	switch comparableValue(v).(type) {
+	case time.Time:
+		return time.Parse(time.RFC3339, value)
+	case float64:
+		return strconv.ParseFloat(value, 64)
+	case bool:
+		return strconv.ParseBool(value)
+	default:
+		return value, nil
+	}
+}
+
+func valueToString(v reflect.Value) string {
+	switch c := comparableValue(v).(type) {
+	case time.Time:
+		return c.Format(time.RFC3339)
+	case float64:
+		return strconv.FormatFloat(c, 'f', -1, 64)
+	default:
+		return fmt.Sprint(c)
+	}
+}
+
+func compareValue(a, b reflect.Value) int {
+	return compare(comparableValue(a), comparableValue(b))
+}
+
+//nil pointer is compared as empty string with others
+func compare(a, b interface{}) int {
+	switch av := a.(type) {
+	case time.Time:
+		if bv, ok := b.(time.Time); ok {
+			if av.Before(bv) {
+				return -1
+			} else if av.After(bv) {
+				return 1
+			}
+			return 0
+		}
+	case float64:
+		if bv, ok := b.(float64); ok {
+			if av < bv {
+				return -1
+			} else if av > bv {
+				return 1
+			}
+			return 0
+		}
+	case bool:
+		if bv, ok := b.(bool); ok {
+			if av == bv {
+				return 0
+			} else if bv {
+				return -1
+			}
+			return 1
+		}
+	}
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
@@ -0,0 +1,163 @@
+package resource
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	ut "cement/unittest"
+)
+
+type queryResource struct {
+	ResourceBase `json:",inline"`
+	Name         string   `json:"name"`
+	Replicas     int      `json:"replicas"`
+	Ready        bool     `json:"ready"`
+	Labels       []string `json:"labels"`
+}
+
+func newQueryCollection(t *testing.T) *ResourceCollection {
+	now := time.Now()
+	var rs []*queryResource
+	for i, name := range []string{"web", "db", "cache", "worker", "web-canary"} {
+		r := &queryResource{
+			Name:     name,
+			Replicas: i + 1,
+			Ready:    i%2 == 0,
+		}
+		if name == "web" || name == "web-canary" {
+			r.Labels = []string{"frontend"}
+		}
+		r.SetID(name)
+		r.SetCreationTimestamp(now.Add(time.Duration(i) * time.Hour))
+		rs = append(rs, r)
+	}
+	collection := &queryResource{}
+	collection.SetType(DefaultKindName(queryResource{}))
+	rc, err := NewResourceCollection(collection, rs)
+	ut.Assert(t, err == nil, "create collection should succeed: %v", err)
+	return rc
+}
+
+func resourceIDs(rc *ResourceCollection) []string {
+	var ids []string
+	for _, r := range rc.GetResources() {
+		ids = append(ids, r.GetID())
+	}
+	return ids
+}
+
+func queryFilters(query string) []Filter {
+	u, _ := url.Parse("/queryresources?" + query)
+	return genFilters(u)
+}
+
+func TestGenPagination(t *testing.T) {
+	u, _ := url.Parse("/queryresources?name_prefix=web&sort=name&order=desc&limit=2&marker=db")
+	p, err := genPagination(u)
+	ut.Assert(t, err == nil, "")
+	ut.Equal(t, p, Pagination{Sort: "name", Order: OrderDesc, Limit: 2, Marker: "db"})
+	filters := genFilters(u)
+	ut.Equal(t, len(filters), 1)
+	ut.Equal(t, filters[0], Filter{Name: "name", Modifier: Prefix, Values: []string{"web"}})
+
+	for _, query := range []string{"limit=0", "limit=abc", "order=up"} {
+		u, _ := url.Parse("/queryresources?" + query)
+		_, err := genPagination(u)
+		ut.Assert(t, err != nil, "%s should be invalid", query)
+	}
+}
+
+func TestApplyFilters(t *testing.T) {
+	cases := []struct {
+		query string
+		ids   []string
+	}{
+		{"name=web", []string{"web"}},
+		{"name=web&name=db", []string{"web", "db"}},
+		{"name_ne=web", []string{"db", "cache", "worker", "web-canary"}},
+		{"name_prefix=web", []string{"web", "web-canary"}},
+		{"name_suffix=er", []string{"worker"}},
+		{"name_contains=a", []string{"cache", "web-canary"}},
+		{"replicas_gt=3", []string{"worker", "web-canary"}},
+		{"replicas_lte=2", []string{"web", "db"}},
+		{"replicas_gte=2&replicas_lt=4", []string{"db", "cache"}},
+		{"ready=true", []string{"web", "cache", "web-canary"}},
+		{"labels=frontend", []string{"web", "web-canary"}},
+		{"labels_null=true", []string{"db", "cache", "worker"}},
+		{"id=db", []string{"db"}},
+		{"unknown=abc", []string{"web", "db", "cache", "worker", "web-canary"}},
+	}
+
+	for _, tc := range cases {
+		rc := newQueryCollection(t)
+		err := rc.ApplyQuery(queryFilters(tc.query), Pagination{})
+		ut.Assert(t, err == nil, "query %s should succeed: %v", tc.query, err)
+		ut.Equal(t, resourceIDs(rc), tc.ids)
+		ut.Equal(t, *rc.Total, len(tc.ids))
+	}
+
+	rc := newQueryCollection(t)
+	ut.Assert(t, rc.ApplyQuery(queryFilters("replicas_gt=abc"), Pagination{}) != nil, "invalid number should fail")
+	rc = newQueryCollection(t)
+	ut.Assert(t, rc.ApplyQuery(queryFilters("creationTimestamp_gt=yesterday"), Pagination{}) != nil, "invalid time should fail")
+}
+
+func TestApplySort(t *testing.T) {
+	rc := newQueryCollection(t)
+	ut.Assert(t, rc.ApplyQuery(nil, Pagination{Sort: "name"}) == nil, "")
+	ut.Equal(t, resourceIDs(rc), []string{"cache", "db", "web", "web-canary", "worker"})
+
+	rc = newQueryCollection(t)
+	ut.Assert(t, rc.ApplyQuery(nil, Pagination{Sort: "creationTimestamp", Order: OrderDesc}) == nil, "")
+	ut.Equal(t, resourceIDs(rc), []string{"web-canary", "worker", "cache", "db", "web"})
+
+	rc = newQueryCollection(t)
+	ut.Assert(t, rc.ApplyQuery(nil, Pagination{Order: OrderAsc}) == nil, "")
+	ut.Equal(t, resourceIDs(rc), []string{"cache", "db", "web", "web-canary", "worker"})
+
+	rc = newQueryCollection(t)
+	ut.Assert(t, rc.ApplyQuery(nil, Pagination{Sort: "unknown"}) != nil, "sort by unknown field should fail")
+}
+
+func TestApplyPagination(t *testing.T) {
+	query := url.Values{}
+	query.Set("sort", "replicas")
+	query.Set("limit", "2")
+	p := Pagination{Sort: "replicas", Limit: 2}
+
+	var pages [][]string
+	var links []map[ResourceLinkType]ResourceLink
+	for {
+		rc := newQueryCollection(t)
+		ut.Assert(t, rc.ApplyQuery(nil, p) == nil, "")
+		ut.Equal(t, *rc.Total, 5)
+		rc.SetLinks(map[ResourceLinkType]ResourceLink{SelfLink: "/queryresources"})
+		rc.AddPageLinks(query)
+		pages = append(pages, resourceIDs(rc))
+		links = append(links, rc.GetLinks())
+		if rc.nextMarker == "" {
+			break
+		}
+		p.Marker = rc.nextMarker
+		query.Set("marker", rc.nextMarker)
+	}
+
+	ut.Equal(t, pages, [][]string{{"web", "db"}, {"cache", "worker"}, {"web-canary"}})
+	ut.Equal(t, links[0], map[ResourceLinkType]ResourceLink{
+		SelfLink: "/queryresources",
+		NextLink: "/queryresources?limit=2&marker=db&sort=replicas",
+	})
+	ut.Equal(t, links[1], map[ResourceLinkType]ResourceLink{
+		SelfLink: "/queryresources",
+		NextLink: "/queryresources?limit=2&marker=worker&sort=replicas",
+		PrevLink: "/queryresources?limit=2&sort=replicas",
+	})
+	ut.Equal(t, links[2], map[ResourceLinkType]ResourceLink{
+		SelfLink: "/queryresources",
+		PrevLink: "/queryresources?limit=2&marker=db&sort=replicas",
+	})
+
+	rc := newQueryCollection(t)
+	ut.Assert(t, rc.ApplyQuery(nil, Pagination{Marker: "unknown"}) != nil, "unknown marker should fail")
+}
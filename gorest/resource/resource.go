@@ -16,6 +16,8 @@ const (
 	UpdateLink     ResourceLinkType = "update"
 	RemoveLink     ResourceLinkType = "remove"
 	CollectionLink ResourceLinkType = "collection"
+	NextLink       ResourceLinkType = "next"
+	PrevLink       ResourceLinkType = "prev"
 )
 
 type Resource interface {
@@ -25,5 +25,7 @@ type Schema interface {
 	GetHandler() Handler
 	AddLinksToResource(r Resource, httpSchemeAndHost string) error
 	AddLinksToResourceCollection(rs *ResourceCollection, httpSchemeAndHost string) error
+	//fill the resource with body and validate it like PUT
+	ValidateAndFillResource(r Resource, body []byte) *goresterr.APIError
 	WriteJsonDoc(path string) error
 }
@@ -133,6 +133,10 @@ func (s *Schema) validateAndFillResource(r resource.Resource, method, action str
 	return nil
 }
 
+func (s *Schema) ValidateAndFillResource(r resource.Resource, body []byte) *goresterr.APIError {
+	return s.validateAndFillResource(r, http.MethodPut, "", body)
+}
+
 func (s *Schema) parseAction(name string, body []byte) (*resource.Action, *goresterr.APIError) {
 	if s.handler.GetActionHandler() == nil {
 		return nil, goresterr.NewAPIError(goresterr.NotFound,
@@ -23,6 +23,8 @@ func restHandler(ctx *resource.Context) *goresterr.APIError {
 		return handleCreate(ctx)
 	case http.MethodPut:
 		return handleUpdate(ctx)
+	case http.MethodPatch:
+		return handlePatch(ctx)
 	case http.MethodDelete:
 		return handleDelete(ctx)
 	default:
@@ -110,10 +112,22 @@ func handleList(ctx *resource.Context) *goresterr.APIError {
 			return goresterr.NewAPIError(goresterr.ServerError, err.Error())
 		}
 
+		//total is omitted if handler paginates the list by itself
+		if ctx.IsListQuerySkipped() == false {
+			filters := ctx.GetFilters()
+			if ctx.IsListFilterSkipped() {
+				filters = nil
+			}
+			if err := rc.ApplyQuery(filters, ctx.GetPagination()); err != nil {
+				return goresterr.NewAPIError(goresterr.InvalidOption, err.Error())
+			}
+		}
+
 		httpSchemeAndHost := path.Join(ctx.Request.URL.Scheme, ctx.Request.URL.Host)
 		if err := schema.AddLinksToResourceCollection(rc, httpSchemeAndHost); err != nil {
 			return goresterr.NewAPIError(goresterr.ServerError, fmt.Sprintf("generate links failed:%s", err.Error()))
 		}
+		rc.AddPageLinks(ctx.Request.URL.Query())
 		result = rc
 	} else {
 		handler := schema.GetHandler().GetGetHandler()
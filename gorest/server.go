@@ -10,9 +10,15 @@ import (
 type HandlerFunc func(*resource.Context) *goresterr.APIError
 type HandlersChain []HandlerFunc
 
+//called after the request is handled, even if it's rejected by a
+//handler, status is the response status code, err is nil if the
+//request succeeds
+type AfterHandlerFunc func(ctx *resource.Context, status int, err *goresterr.APIError)
+
 type Server struct {
-	Schemas  resource.SchemaManager
-	handlers HandlersChain
+	Schemas       resource.SchemaManager
+	handlers      HandlersChain
+	afterHandlers []AfterHandlerFunc
 }
 
 func NewAPIServer(schemas resource.SchemaManager) *Server {
@@ -25,6 +31,10 @@ func (s *Server) Use(h HandlerFunc) {
 	s.handlers = append(s.handlers, h)
 }
 
+func (s *Server) UseAfter(h AfterHandlerFunc) {
+	s.afterHandlers = append(s.afterHandlers, h)
+}
+
 func (s *Server) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 	ctx, err := resource.NewContext(rw, req, s.Schemas)
 	if err != nil {
@@ -32,14 +42,36 @@ func (s *Server) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	w := &statusWriter{
+		ResponseWriter: rw,
+		status:         http.StatusOK,
+	}
+	ctx.Response = w
+	err = s.handle(ctx)
+	if err != nil {
+		WriteResponse(w, err.Status, err)
+	}
+
+	for _, h := range s.afterHandlers {
+		h(ctx, w.status, err)
+	}
+}
+
+func (s *Server) handle(ctx *resource.Context) *goresterr.APIError {
 	for _, h := range s.handlers {
 		if err := h(ctx); err != nil {
-			WriteResponse(rw, err.Status, err)
-			return
+			return err
 		}
 	}
+	return restHandler(ctx)
+}
 
-	if err := restHandler(ctx); err != nil {
-		WriteResponse(rw, err.Status, err)
-	}
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
 }
@@ -1,6 +1,7 @@
 package gorest
 
 import (
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -83,3 +84,85 @@ func TestContextPassChain(t *testing.T) {
 	s.ServeHTTP(w, req)
 	ut.Equal(t, w.Code, http.StatusNoContent)
 }
+
+func TestAfterHandler(t *testing.T) {
+	schemas.Import(&version, Foo{}, &dumbHandler{})
+	s := NewAPIServer(schemas)
+	s.Use(func(ctx *resource.Context) *goresterr.APIError {
+		if ctx.Request.Method == http.MethodPost {
+			return goresterr.NewAPIError(goresterr.PermissionDenied, "no permission")
+		}
+		return nil
+	})
+
+	var status int
+	var err *goresterr.APIError
+	s.UseAfter(func(ctx *resource.Context, status_ int, err_ *goresterr.APIError) {
+		status = status_
+		err = err_
+	})
+
+	req, _ := http.NewRequest("DELETE", "/apis/testing/v1/foos/1", nil)
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	ut.Equal(t, status, http.StatusAccepted)
+	ut.Assert(t, err == nil, "delete should succeed")
+
+	req, _ = http.NewRequest("POST", "/apis/testing/v1/foos", nil)
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	ut.Equal(t, w.Code, goresterr.PermissionDenied.Status)
+	ut.Equal(t, status, goresterr.PermissionDenied.Status)
+	ut.Equal(t, err.Message, "no permission")
+}
+
+type Baz struct {
+	resource.ResourceBase `json:",inline"`
+	Name                  string `json:"name"`
+}
+
+//handler uses name filter by itself, or paginates the list by itself
+type bazHandler struct{}
+
+func (h *bazHandler) List(ctx *resource.Context) (interface{}, *goresterr.APIError) {
+	var bazs []*Baz
+	for _, name := range []string{"a", "b", "c"} {
+		baz := &Baz{Name: name}
+		baz.SetID(name)
+		bazs = append(bazs, baz)
+	}
+
+	if ctx.Request.URL.Query().Get("paginated") == "true" {
+		ctx.SkipListQuery()
+		return bazs[:1], nil
+	}
+	ctx.SkipListFilter()
+	return bazs, nil
+}
+
+func TestSkipListFilter(t *testing.T) {
+	schemas.Import(&version, Baz{}, &bazHandler{})
+	s := NewAPIServer(schemas)
+
+	list := func(query string) map[string]interface{} {
+		req, _ := http.NewRequest("GET", "/apis/testing/v1/bazs?"+query, nil)
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, req)
+		ut.Equal(t, w.Code, http.StatusOK)
+		var rc map[string]interface{}
+		ut.Assert(t, json.Unmarshal(w.Body.Bytes(), &rc) == nil, "response should be collection")
+		return rc
+	}
+
+	//filter is skipped, sort and limit still work
+	rc := list("name=x&order=desc&limit=2")
+	ut.Equal(t, rc["total"], float64(3))
+	data := rc["data"].([]interface{})
+	ut.Equal(t, len(data), 2)
+	ut.Equal(t, data[0].(map[string]interface{})["name"], "c")
+
+	rc = list("paginated=true")
+	_, ok := rc["total"]
+	ut.Equal(t, ok, false)
+	ut.Equal(t, len(rc["data"].([]interface{})), 1)
+}
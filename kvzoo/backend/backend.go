@@ -0,0 +1,61 @@
+package backend
+
+import (
+	"fmt"
+
+	"kvzoo"
+	"kvzoo/backend/bolt"
+	"kvzoo/backend/memory"
+	"kvzoo/backend/sql"
+)
+
+type Type string
+
+const (
+	Bolt       Type = "bolt"
+	Memory     Type = "memory"
+	Sqlite3    Type = "sqlite3"
+	Postgresql Type = "postgresql"
+)
+
+const opLogSuffix = "_oplog"
+
+type Config struct {
+	Type Type
+	//file path of bolt and sqlite3
+	Path string
+	//connection string of postgresql
+	DSN string
+	//sql table to save the data, default is kvzoo
+	Table string
+}
+
+//all the backends implement kvzoo.DB with the same behavior, so data
+//can be replicated between different backends
+func New(conf Config) (kvzoo.DB, error) {
+	switch conf.Type {
+	case Bolt, "":
+		return bolt.New(conf.Path)
+	case Memory:
+		return memory.New()
+	case Sqlite3:
+		return sql.New(sql.Sqlite3, conf.Path, conf.Table)
+	case Postgresql:
+		return sql.New(sql.Postgresql, conf.DSN, conf.Table)
+	default:
+		return nil, fmt.Errorf("unknown backend %s", conf.Type)
+	}
+}
+
+//operation log is saved in another file or sql table with the
+//same backend
+func (c Config) OpLogConfig(fileSuffix string) Config {
+	conf := c
+	conf.Path = c.Path + fileSuffix
+	if c.Table == "" {
+		conf.Table = sql.DefaultTableName + opLogSuffix
+	} else {
+		conf.Table = c.Table + opLogSuffix
+	}
+	return conf
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/hex"
 	"fmt"
 	"hash"
+	"io"
 	"os"
 	stdpath "path"
 	"strings"
@@ -27,6 +28,7 @@ var (
 type BoltDB struct {
 	path string
 	db   *bbolt.DB
+	hub  *kvzoo.WatchHub
 }
 
 func New(path string) (kvzoo.DB, error) {
@@ -51,6 +53,7 @@ func New(path string) (kvzoo.DB, error) {
 	return &BoltDB{
 		db:   db,
 		path: path,
+		hub:  kvzoo.NewWatchHub(),
 	}, nil
 }
 
@@ -112,7 +115,74 @@ func (db *BoltDB) bucketCheckSum(h hash.Hash, b *bbolt.Bucket) {
 	}
 }
 
+func (db *BoltDB) TableChecksums() (map[kvzoo.TableName]string, error) {
+	tx, err := db.db.Begin(false)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	checksums := make(map[kvzoo.TableName]string)
+	if err := tx.ForEach(func(name []byte, b *bbolt.Bucket) error {
+		return bucketTableChecksums(checksums, stdpath.Join(kvzoo.Root, string(name)), b)
+	}); err != nil {
+		return nil, err
+	}
+	return checksums, nil
+}
+
+func bucketTableChecksums(checksums map[kvzoo.TableName]string, tableName string, b *bbolt.Bucket) error {
+	h := md5.New()
+	var children []string
+	if err := b.ForEach(func(k, v []byte) error {
+		if v == nil {
+			children = append(children, string(k))
+		} else {
+			h.Write(k)
+			h.Write(v)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	checksums[kvzoo.TableName(tableName)] = hex.EncodeToString(h.Sum(nil)[:16])
+
+	for _, child := range children {
+		if err := bucketTableChecksums(checksums, stdpath.Join(tableName, child), b.Bucket([]byte(child))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (db *BoltDB) TableNames() ([]kvzoo.TableName, error) {
+	tx, err := db.db.Begin(false)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var names []kvzoo.TableName
+	if err := tx.ForEach(func(name []byte, b *bbolt.Bucket) error {
+		return bucketTableNames(&names, stdpath.Join(kvzoo.Root, string(name)), b)
+	}); err != nil {
+		return nil, err
+	}
+	return names, nil
+}
+
+func bucketTableNames(names *[]kvzoo.TableName, tableName string, b *bbolt.Bucket) error {
+	*names = append(*names, kvzoo.TableName(tableName))
+	return b.ForEach(func(k, v []byte) error {
+		if v != nil {
+			return nil
+		}
+		return bucketTableNames(names, stdpath.Join(tableName, string(k)), b.Bucket(k))
+	})
+}
+
 func (db *BoltDB) Close() error {
+	db.hub.StopAll()
 	return db.db.Close()
 }
 
@@ -123,6 +193,111 @@ func (db *BoltDB) Destroy() error {
 	return os.Remove(db.path)
 }
 
+//bolt read transaction provides a consistent view of the db
+func (db *BoltDB) Snapshot(w io.Writer) error {
+	tx, err := db.db.Begin(false)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	sw, err := kvzoo.NewSnapshotWriter(w)
+	if err != nil {
+		return err
+	}
+
+	if err := tx.ForEach(func(name []byte, b *bbolt.Bucket) error {
+		return snapshotBucket(sw, stdpath.Join(kvzoo.Root, string(name)), b)
+	}); err != nil {
+		return err
+	}
+	return sw.Close()
+}
+
+func snapshotBucket(sw *kvzoo.SnapshotWriter, tableName string, b *bbolt.Bucket) error {
+	if err := sw.WriteTable(kvzoo.TableName(tableName)); err != nil {
+		return err
+	}
+
+	var children []string
+	if err := b.ForEach(func(k, v []byte) error {
+		if v == nil {
+			children = append(children, string(k))
+			return nil
+		}
+		return sw.Write(kvzoo.TableName(tableName), string(k), v)
+	}); err != nil {
+		return err
+	}
+
+	for _, child := range children {
+		if err := snapshotBucket(sw, stdpath.Join(tableName, child), b.Bucket([]byte(child))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//all the tables are replaced in one transaction, if snapshot is
+//invalid, db is kept unchanged
+func (db *BoltDB) Restore(r io.Reader) error {
+	sr, err := kvzoo.NewSnapshotReader(r)
+	if err != nil {
+		return err
+	}
+	defer sr.Close()
+
+	tx, err := db.db.Begin(true)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var buckets [][]byte
+	if err := tx.ForEach(func(name []byte, _ *bbolt.Bucket) error {
+		buckets = append(buckets, append([]byte(nil), name...))
+		return nil
+	}); err != nil {
+		return err
+	}
+	for _, name := range buckets {
+		if err := tx.DeleteBucket(name); err != nil {
+			return err
+		}
+	}
+
+	var tableName kvzoo.TableName
+	var bucket *bbolt.Bucket
+	for {
+		record, err := sr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+
+		if bucket == nil || record.Table != tableName {
+			if bucket, err = createOrGetBucket(tx, string(record.Table)); err != nil {
+				return err
+			}
+			tableName = record.Table
+		}
+
+		if record.IsTable() == false {
+			if err := bucket.Put([]byte(record.Key), record.Value); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	//all the tables are changed, watchers have to reload the data
+	db.hub.StopAll()
+	return nil
+}
+
 func (db *BoltDB) CreateOrGetTable(tableName kvzoo.TableName) (kvzoo.Table, error) {
 	tx, err := db.db.Begin(true)
 	if err != nil {
@@ -142,6 +317,7 @@ func (db *BoltDB) CreateOrGetTable(tableName kvzoo.TableName) (kvzoo.Table, erro
 	return &DBTable{
 		name: string(tableName),
 		db:   db.db,
+		hub:  db.hub,
 	}, nil
 }
 
@@ -173,7 +349,11 @@ func (db *BoltDB) DeleteTable(tableName kvzoo.TableName) error {
 		}
 	}
 
-	return tx.Commit()
+	return db.hub.Publish(tableName, []kvzoo.Event{kvzoo.Event{Type: kvzoo.EventDeleteTable}}, tx.Commit)
+}
+
+func (db *BoltDB) Watch(tableName kvzoo.TableName) (kvzoo.Watcher, error) {
+	return db.hub.Watch(tableName), nil
 }
 
 func createOrGetBucket(tx *bbolt.Tx, tableName string) (*bbolt.Bucket, error) {
@@ -201,6 +381,7 @@ func createOrGetBucket(tx *bbolt.Tx, tableName string) (*bbolt.Bucket, error) {
 type DBTable struct {
 	name string
 	db   *bbolt.DB
+	hub  *kvzoo.WatchHub
 }
 
 func (db *DBTable) Begin() (kvzoo.Transaction, error) {
@@ -220,13 +401,18 @@ func (db *DBTable) Begin() (kvzoo.Transaction, error) {
 		return nil, fmt.Errorf("table %s is non-exists", db.name)
 	}
 
-	return &TableTX{
+	return kvzoo.NewTransaction(&TableTX{
 		bucket: bucket,
-	}, nil
+		name:   kvzoo.TableName(db.name),
+		hub:    db.hub,
+	}), nil
 }
 
 type TableTX struct {
 	bucket *bbolt.Bucket
+	name   kvzoo.TableName
+	hub    *kvzoo.WatchHub
+	events []kvzoo.Event
 }
 
 func (tx *TableTX) Rollback() error {
@@ -234,18 +420,33 @@ func (tx *TableTX) Rollback() error {
 }
 
 func (tx *TableTX) Commit() error {
-	return tx.bucket.Tx().Commit()
+	if len(tx.events) == 0 {
+		return tx.bucket.Tx().Commit()
+	}
+	return tx.hub.Publish(tx.name, tx.events, tx.bucket.Tx().Commit)
 }
 
 func (tx *TableTX) Add(key string, value []byte) error {
 	if v := tx.bucket.Get([]byte(key)); v != nil {
 		return ErrDuplicateResource
 	}
-	return tx.bucket.Put([]byte(key), value)
+	if err := tx.bucket.Put([]byte(key), value); err != nil {
+		return err
+	}
+	tx.addEvent(kvzoo.EventAdd, key, value)
+	return nil
 }
 
+//delete non-exist key doesn't generate event
 func (tx *TableTX) Delete(key string) error {
-	return tx.bucket.Delete([]byte(key))
+	if v := tx.bucket.Get([]byte(key)); v == nil {
+		return tx.bucket.Delete([]byte(key))
+	}
+	if err := tx.bucket.Delete([]byte(key)); err != nil {
+		return err
+	}
+	tx.addEvent(kvzoo.EventDelete, key, nil)
+	return nil
 }
 
 func (tx *TableTX) Update(key string, value []byte) error {
@@ -253,7 +454,47 @@ func (tx *TableTX) Update(key string, value []byte) error {
 		return kvzoo.ErrNotFound
 	}
 
-	return tx.bucket.Put([]byte(key), value)
+	if err := tx.bucket.Put([]byte(key), value); err != nil {
+		return err
+	}
+	tx.addEvent(kvzoo.EventUpdate, key, value)
+	return nil
+}
+
+//expire table is a nested bucket in the same bolt transaction, its
+//changes are committed with the table and aren't sent to watcher
+func (tx *TableTX) ExpireTable(create bool) (kvzoo.BaseTransaction, error) {
+	name := []byte(kvzoo.ExpireTableName)
+	bucket := tx.bucket.Bucket(name)
+	if bucket == nil {
+		if create == false {
+			return nil, nil
+		}
+		var err error
+		if bucket, err = tx.bucket.CreateBucket(name); err != nil {
+			return nil, err
+		}
+	}
+
+	return &TableTX{
+		bucket: bucket,
+		name:   tx.name.ExpireTable(),
+		hub:    tx.hub,
+	}, nil
+}
+
+//value is copied, since caller may reuse it after commit
+func (tx *TableTX) addEvent(typ kvzoo.EventType, key string, value []byte) {
+	var tmp []byte
+	if value != nil {
+		tmp = make([]byte, len(value))
+		copy(tmp, value)
+	}
+	tx.events = append(tx.events, kvzoo.Event{
+		Type:  typ,
+		Key:   key,
+		Value: tmp,
+	})
 }
 
 func (tx *TableTX) Get(key string) ([]byte, error) {
@@ -266,9 +507,13 @@ func (tx *TableTX) Get(key string) ([]byte, error) {
 	}
 }
 
+//child tables aren't included
 func (tx *TableTX) List() (map[string][]byte, error) {
 	resourceMap := make(map[string][]byte)
 	if err := tx.bucket.ForEach(func(k, v []byte) error {
+		if v == nil {
+			return nil
+		}
 		tmp := make([]byte, len(v))
 		copy(tmp, v)
 		resourceMap[string(k)] = tmp
@@ -279,3 +524,58 @@ func (tx *TableTX) List() (map[string][]byte, error) {
 
 	return resourceMap, nil
 }
+
+func (tx *TableTX) Scan(opts kvzoo.ScanOptions) ([]kvzoo.KeyValue, string, error) {
+	lower, upper := opts.Bounds()
+	c := tx.bucket.Cursor()
+	next := c.Next
+	var k, v []byte
+	if opts.Reverse {
+		next = c.Prev
+		if opts.Cursor != "" && (upper == "" || opts.Cursor < upper) {
+			upper = opts.Cursor
+		}
+		if upper == "" {
+			k, v = c.Last()
+		} else if k, v = c.Seek([]byte(upper)); k == nil {
+			k, v = c.Last()
+		} else {
+			k, v = c.Prev()
+		}
+	} else {
+		from := lower
+		if opts.Cursor > from {
+			from = opts.Cursor
+		}
+		if k, v = c.Seek([]byte(from)); k != nil && opts.Cursor != "" && string(k) == opts.Cursor {
+			k, v = c.Next()
+		}
+	}
+
+	var kvs []kvzoo.KeyValue
+	for ; k != nil; k, v = next() {
+		key := string(k)
+		if opts.Reverse && key < lower {
+			break
+		} else if opts.Reverse == false && upper != "" && key >= upper {
+			break
+		}
+
+		//nested table
+		if v == nil {
+			continue
+		}
+
+		if opts.Limit > 0 && len(kvs) == opts.Limit {
+			return kvs, kvs[len(kvs)-1].Key, nil
+		}
+
+		tmp := make([]byte, len(v))
+		copy(tmp, v)
+		kvs = append(kvs, kvzoo.KeyValue{
+			Key:   key,
+			Value: tmp,
+		})
+	}
+	return kvs, "", nil
+}
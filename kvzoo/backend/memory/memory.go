@@ -0,0 +1,484 @@
+package memory
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	stdpath "path"
+	"sort"
+	"sync"
+
+	"kvzoo"
+)
+
+var ErrDuplicateResource = fmt.Errorf("duplicate resource")
+
+//data is lost after close, it's used in test and ephemeral deployment
+//like boltdb, write transactions are mutually exclusive, read
+//operation like checksum and snapshot see the last committed data
+type MemoryDB struct {
+	root *table
+	hub  *kvzoo.WatchHub
+	//held by write transaction until commit or rollback
+	writeLock sync.Mutex
+	//protect the tables while committing
+	dataLock sync.RWMutex
+}
+
+type table struct {
+	values   map[string][]byte
+	children map[string]*table
+}
+
+func newTable() *table {
+	return &table{
+		values:   make(map[string][]byte),
+		children: make(map[string]*table),
+	}
+}
+
+func New() (kvzoo.DB, error) {
+	return &MemoryDB{
+		root: newTable(),
+		hub:  kvzoo.NewWatchHub(),
+	}, nil
+}
+
+func (db *MemoryDB) getTable(tableName kvzoo.TableName) *table {
+	t := db.root
+	for _, seg := range tableName.Segments() {
+		if t = t.children[seg]; t == nil {
+			return nil
+		}
+	}
+	return t
+}
+
+func (db *MemoryDB) createOrGetTable(tableName kvzoo.TableName) *table {
+	t := db.root
+	for _, seg := range tableName.Segments() {
+		child, ok := t.children[seg]
+		if ok == false {
+			child = newTable()
+			t.children[seg] = child
+		}
+		t = child
+	}
+	return t
+}
+
+//keys and child tables are iterated in byte-wise order, same as boltdb,
+//so checksum is same with other backends
+func (db *MemoryDB) Checksum() (string, error) {
+	db.dataLock.RLock()
+	defer db.dataLock.RUnlock()
+
+	h := md5.New()
+	tableCheckSum(h, db.root)
+	return hex.EncodeToString(h.Sum(nil)[:16]), nil
+}
+
+func tableCheckSum(h hash.Hash, t *table) {
+	for _, name := range sortedNames(t) {
+		h.Write([]byte(name))
+		if child, ok := t.children[name]; ok {
+			tableCheckSum(h, child)
+		} else {
+			h.Write(t.values[name])
+		}
+	}
+}
+
+func sortedNames(t *table) []string {
+	names := make([]string, 0, len(t.values)+len(t.children))
+	for k := range t.values {
+		names = append(names, k)
+	}
+	for name := range t.children {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func sortedKeys(values map[string][]byte) []string {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedChildren(t *table) []string {
+	names := make([]string, 0, len(t.children))
+	for name := range t.children {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (db *MemoryDB) TableChecksums() (map[kvzoo.TableName]string, error) {
+	db.dataLock.RLock()
+	defer db.dataLock.RUnlock()
+
+	checksums := make(map[kvzoo.TableName]string)
+	for name, child := range db.root.children {
+		tableChecksums(checksums, stdpath.Join(kvzoo.Root, name), child)
+	}
+	return checksums, nil
+}
+
+func tableChecksums(checksums map[kvzoo.TableName]string, tableName string, t *table) {
+	h := md5.New()
+	for _, k := range sortedKeys(t.values) {
+		h.Write([]byte(k))
+		h.Write(t.values[k])
+	}
+	checksums[kvzoo.TableName(tableName)] = hex.EncodeToString(h.Sum(nil)[:16])
+
+	for name, child := range t.children {
+		tableChecksums(checksums, stdpath.Join(tableName, name), child)
+	}
+}
+
+func (db *MemoryDB) TableNames() ([]kvzoo.TableName, error) {
+	db.dataLock.RLock()
+	defer db.dataLock.RUnlock()
+
+	var names []kvzoo.TableName
+	for _, name := range sortedChildren(db.root) {
+		names = tableNames(names, stdpath.Join(kvzoo.Root, name), db.root.children[name])
+	}
+	return names, nil
+}
+
+func tableNames(names []kvzoo.TableName, tableName string, t *table) []kvzoo.TableName {
+	names = append(names, kvzoo.TableName(tableName))
+	for _, name := range sortedChildren(t) {
+		names = tableNames(names, stdpath.Join(tableName, name), t.children[name])
+	}
+	return names
+}
+
+func (db *MemoryDB) Close() error {
+	db.hub.StopAll()
+	return nil
+}
+
+func (db *MemoryDB) Destroy() error {
+	db.writeLock.Lock()
+	defer db.writeLock.Unlock()
+	db.dataLock.Lock()
+	db.root = newTable()
+	db.dataLock.Unlock()
+	return db.Close()
+}
+
+func (db *MemoryDB) Snapshot(w io.Writer) error {
+	db.dataLock.RLock()
+	defer db.dataLock.RUnlock()
+
+	sw, err := kvzoo.NewSnapshotWriter(w)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range sortedChildren(db.root) {
+		if err := snapshotTable(sw, stdpath.Join(kvzoo.Root, name), db.root.children[name]); err != nil {
+			return err
+		}
+	}
+	return sw.Close()
+}
+
+func snapshotTable(sw *kvzoo.SnapshotWriter, tableName string, t *table) error {
+	if err := sw.WriteTable(kvzoo.TableName(tableName)); err != nil {
+		return err
+	}
+
+	for _, k := range sortedKeys(t.values) {
+		if err := sw.Write(kvzoo.TableName(tableName), k, t.values[k]); err != nil {
+			return err
+		}
+	}
+
+	for _, name := range sortedChildren(t) {
+		if err := snapshotTable(sw, stdpath.Join(tableName, name), t.children[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//snapshot is loaded into a new tree, so db is kept unchanged if
+//snapshot is invalid
+func (db *MemoryDB) Restore(r io.Reader) error {
+	sr, err := kvzoo.NewSnapshotReader(r)
+	if err != nil {
+		return err
+	}
+	defer sr.Close()
+
+	db.writeLock.Lock()
+	defer db.writeLock.Unlock()
+
+	restored := &MemoryDB{root: newTable()}
+	for {
+		record, err := sr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+
+		t := restored.createOrGetTable(record.Table)
+		if record.IsTable() == false {
+			t.values[record.Key] = record.Value
+		}
+	}
+
+	db.dataLock.Lock()
+	db.root = restored.root
+	db.dataLock.Unlock()
+	db.hub.StopAll()
+	return nil
+}
+
+func (db *MemoryDB) CreateOrGetTable(tableName kvzoo.TableName) (kvzoo.Table, error) {
+	db.writeLock.Lock()
+	defer db.writeLock.Unlock()
+	db.dataLock.Lock()
+	defer db.dataLock.Unlock()
+
+	db.createOrGetTable(tableName)
+	return &MemoryTable{
+		name: tableName,
+		db:   db,
+	}, nil
+}
+
+func (db *MemoryDB) DeleteTable(tableName kvzoo.TableName) error {
+	db.writeLock.Lock()
+	defer db.writeLock.Unlock()
+
+	return db.hub.Publish(tableName, []kvzoo.Event{kvzoo.Event{Type: kvzoo.EventDeleteTable}}, func() error {
+		db.dataLock.Lock()
+		defer db.dataLock.Unlock()
+
+		segs := tableName.Segments()
+		parent := db.root
+		for _, seg := range segs[:len(segs)-1] {
+			if parent = parent.children[seg]; parent == nil {
+				return fmt.Errorf("no found table %s", seg)
+			}
+		}
+
+		name := segs[len(segs)-1]
+		if _, ok := parent.children[name]; ok == false {
+			return fmt.Errorf("no found table %s", name)
+		}
+		delete(parent.children, name)
+		return nil
+	})
+}
+
+func (db *MemoryDB) Watch(tableName kvzoo.TableName) (kvzoo.Watcher, error) {
+	return db.hub.Watch(tableName), nil
+}
+
+type MemoryTable struct {
+	name kvzoo.TableName
+	db   *MemoryDB
+}
+
+//table is created when transaction is committed if it's deleted
+func (t *MemoryTable) Begin() (kvzoo.Transaction, error) {
+	t.db.writeLock.Lock()
+	var values map[string][]byte
+	if table := t.db.getTable(t.name); table != nil {
+		values = table.values
+	}
+	return kvzoo.NewTransaction(&TableTX{
+		name:   t.name,
+		db:     t.db,
+		values: values,
+	}), nil
+}
+
+//values is copied before the first change, so rollback just
+//discard the copy
+type TableTX struct {
+	name    kvzoo.TableName
+	db      *MemoryDB
+	values  map[string][]byte
+	changed bool
+	closed  bool
+	events  []kvzoo.Event
+	expire  *TableTX
+}
+
+func (tx *TableTX) Rollback() error {
+	if tx.closed {
+		return nil
+	}
+	tx.closed = true
+	tx.db.writeLock.Unlock()
+	return nil
+}
+
+func (tx *TableTX) Commit() error {
+	if tx.closed {
+		return fmt.Errorf("tx closed")
+	}
+	tx.closed = true
+	defer tx.db.writeLock.Unlock()
+
+	return tx.db.hub.Publish(tx.name, tx.events, func() error {
+		tx.db.dataLock.Lock()
+		defer tx.db.dataLock.Unlock()
+		t := tx.db.createOrGetTable(tx.name)
+		if tx.changed {
+			t.values = tx.values
+		}
+		if tx.expire != nil && tx.expire.changed {
+			tx.db.createOrGetTable(tx.expire.name).values = tx.expire.values
+		}
+		return nil
+	})
+}
+
+//expire table is created when it's changed, its changes are saved
+//with the table and aren't sent to watcher
+func (tx *TableTX) ExpireTable(create bool) (kvzoo.BaseTransaction, error) {
+	if tx.expire == nil {
+		name := tx.name.ExpireTable()
+		var values map[string][]byte
+		if t := tx.db.getTable(name); t != nil {
+			values = t.values
+		} else if create == false {
+			return nil, nil
+		}
+		tx.expire = &TableTX{
+			name:   name,
+			db:     tx.db,
+			values: values,
+		}
+	}
+	return tx.expire, nil
+}
+
+func (tx *TableTX) copyOnWrite() {
+	if tx.changed {
+		return
+	}
+
+	values := make(map[string][]byte, len(tx.values))
+	for k, v := range tx.values {
+		values[k] = v
+	}
+	tx.values = values
+	tx.changed = true
+}
+
+func (tx *TableTX) Add(key string, value []byte) error {
+	if key == "" {
+		return fmt.Errorf("key required")
+	}
+	if _, ok := tx.values[key]; ok {
+		return ErrDuplicateResource
+	}
+	tx.put(kvzoo.EventAdd, key, value)
+	return nil
+}
+
+//delete non-exist key doesn't generate event
+func (tx *TableTX) Delete(key string) error {
+	if _, ok := tx.values[key]; ok == false {
+		return nil
+	}
+	tx.copyOnWrite()
+	delete(tx.values, key)
+	tx.events = append(tx.events, kvzoo.Event{
+		Type: kvzoo.EventDelete,
+		Key:  key,
+	})
+	return nil
+}
+
+func (tx *TableTX) Update(key string, value []byte) error {
+	if _, ok := tx.values[key]; ok == false {
+		return kvzoo.ErrNotFound
+	}
+	tx.put(kvzoo.EventUpdate, key, value)
+	return nil
+}
+
+//value is copied, since caller may reuse it
+func (tx *TableTX) put(typ kvzoo.EventType, key string, value []byte) {
+	tmp := make([]byte, len(value))
+	copy(tmp, value)
+	tx.copyOnWrite()
+	tx.values[key] = tmp
+	tx.events = append(tx.events, kvzoo.Event{
+		Type:  typ,
+		Key:   key,
+		Value: tmp,
+	})
+}
+
+func (tx *TableTX) Get(key string) ([]byte, error) {
+	if v, ok := tx.values[key]; ok {
+		tmp := make([]byte, len(v))
+		copy(tmp, v)
+		return tmp, nil
+	} else {
+		return nil, kvzoo.ErrNotFound
+	}
+}
+
+func (tx *TableTX) List() (map[string][]byte, error) {
+	resourceMap := make(map[string][]byte, len(tx.values))
+	for k, v := range tx.values {
+		tmp := make([]byte, len(v))
+		copy(tmp, v)
+		resourceMap[k] = tmp
+	}
+	return resourceMap, nil
+}
+
+func (tx *TableTX) Scan(opts kvzoo.ScanOptions) ([]kvzoo.KeyValue, string, error) {
+	lower, upper := opts.Bounds()
+	keys := sortedKeys(tx.values)
+	if opts.Reverse {
+		for i, j := 0, len(keys)-1; i < j; i, j = i+1, j-1 {
+			keys[i], keys[j] = keys[j], keys[i]
+		}
+	}
+
+	var kvs []kvzoo.KeyValue
+	for _, k := range keys {
+		if k < lower || (upper != "" && k >= upper) {
+			continue
+		}
+		if opts.Cursor != "" && ((opts.Reverse && k >= opts.Cursor) || (opts.Reverse == false && k <= opts.Cursor)) {
+			continue
+		}
+
+		if opts.Limit > 0 && len(kvs) == opts.Limit {
+			return kvs, kvs[len(kvs)-1].Key, nil
+		}
+
+		v := tx.values[k]
+		tmp := make([]byte, len(v))
+		copy(tmp, v)
+		kvs = append(kvs, kvzoo.KeyValue{
+			Key:   k,
+			Value: tmp,
+		})
+	}
+	return kvs, "", nil
+}
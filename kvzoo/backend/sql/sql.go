@@ -0,0 +1,730 @@
+package sql
+
+import (
+	"crypto/md5"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	stdpath "path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+
+	"kvzoo"
+)
+
+type Driver string
+
+const (
+	Sqlite3    Driver = "sqlite3"
+	Postgresql Driver = "postgres"
+)
+
+const DefaultTableName = "kvzoo"
+
+var ErrDuplicateResource = fmt.Errorf("duplicate resource")
+
+//all the kvzoo tables are saved in one sql table, each row is a key
+//of a table, row with empty key marks the existence of a table, key
+//and value are compared byte-wise like boltdb
+//
+//like boltdb, write transactions are mutually exclusive, which is
+//required by resync, so it's only safe when the sql table is used by
+//one kvzoo server
+type SQLDB struct {
+	driver    Driver
+	dsn       string
+	tableName string
+	db        *sql.DB
+	hub       *kvzoo.WatchHub
+	writeLock sync.Mutex
+}
+
+//dsn is file path for sqlite3 and connection string for postgresql
+func New(driver Driver, dsn string, tableName string) (kvzoo.DB, error) {
+	if tableName == "" {
+		tableName = DefaultTableName
+	}
+
+	var db *sql.DB
+	var err error
+	var schema string
+	switch driver {
+	case Sqlite3:
+		if dir := stdpath.Dir(dsn); dir != "" {
+			if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+				return nil, err
+			}
+		}
+		db, err = sql.Open(string(driver), dsn+"?_journal_mode=WAL&_busy_timeout=5000")
+		schema = "CREATE TABLE IF NOT EXISTS %s (table_name TEXT NOT NULL, item_key TEXT NOT NULL, item_value BLOB, PRIMARY KEY (table_name, item_key))"
+	case Postgresql:
+		db, err = sql.Open(string(driver), dsn)
+		schema = `CREATE TABLE IF NOT EXISTS %s (table_name TEXT COLLATE "C" NOT NULL, item_key TEXT COLLATE "C" NOT NULL, item_value BYTEA, PRIMARY KEY (table_name, item_key))`
+	default:
+		return nil, fmt.Errorf("unknown sql driver %s", driver)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := db.Exec(fmt.Sprintf(schema, tableName)); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("create table %s failed:%s", tableName, err.Error())
+	}
+
+	return &SQLDB{
+		driver:    driver,
+		dsn:       dsn,
+		tableName: tableName,
+		db:        db,
+		hub:       kvzoo.NewWatchHub(),
+	}, nil
+}
+
+//query is written with ? as placeholder, and table name as %s
+func (db *SQLDB) query(q string) string {
+	q = fmt.Sprintf(q, db.tableName)
+	if db.driver != Postgresql {
+		return q
+	}
+
+	var b strings.Builder
+	n := 0
+	for _, c := range q {
+		if c == '?' {
+			n += 1
+			b.WriteString("$" + strconv.Itoa(n))
+		} else {
+			b.WriteRune(c)
+		}
+	}
+	return b.String()
+}
+
+//read operation uses a read only transaction to get a consistent view
+func (db *SQLDB) beginRead() (*sql.Tx, error) {
+	tx, err := db.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	if db.driver == Postgresql {
+		if _, err := tx.Exec("SET TRANSACTION ISOLATION LEVEL REPEATABLE READ READ ONLY"); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+	return tx, nil
+}
+
+type tableNode struct {
+	name     string
+	children []*tableNode
+}
+
+//tables are loaded as a tree, children are sorted by name
+func (db *SQLDB) loadTables(tx *sql.Tx) (*tableNode, error) {
+	rows, err := tx.Query(db.query("SELECT table_name FROM %s WHERE item_key = '' ORDER BY table_name"))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	root := &tableNode{name: kvzoo.Root}
+	nodes := map[string]*tableNode{kvzoo.Root: root}
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+		nodes[name] = &tableNode{name: name}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, name := range names {
+		if parent, ok := nodes[stdpath.Dir(name)]; ok {
+			parent.children = append(parent.children, nodes[name])
+		}
+	}
+	for _, node := range nodes {
+		sort.Slice(node.children, func(i, j int) bool {
+			return stdpath.Base(node.children[i].name) < stdpath.Base(node.children[j].name)
+		})
+	}
+	return root, nil
+}
+
+func (db *SQLDB) loadValues(tx *sql.Tx, tableName string) ([]kvzoo.KeyValue, error) {
+	rows, err := tx.Query(db.query("SELECT item_key, item_value FROM %s WHERE table_name = ? AND item_key <> '' ORDER BY item_key"), tableName)
+	if err != nil {
+		return nil, err
+	}
+	return scanRows(rows)
+}
+
+func scanRows(rows *sql.Rows) ([]kvzoo.KeyValue, error) {
+	defer rows.Close()
+	var kvs []kvzoo.KeyValue
+	for rows.Next() {
+		var kv kvzoo.KeyValue
+		if err := rows.Scan(&kv.Key, &kv.Value); err != nil {
+			return nil, err
+		}
+		if kv.Value == nil {
+			kv.Value = []byte{}
+		}
+		kvs = append(kvs, kv)
+	}
+	return kvs, rows.Err()
+}
+
+//keys and child tables are iterated in byte-wise order, same as boltdb,
+//so checksum is same with other backends
+func (db *SQLDB) Checksum() (string, error) {
+	tx, err := db.beginRead()
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	root, err := db.loadTables(tx)
+	if err != nil {
+		return "", err
+	}
+
+	h := md5.New()
+	if err := db.tableCheckSum(tx, h, root); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)[:16]), nil
+}
+
+func (db *SQLDB) tableCheckSum(tx *sql.Tx, h hash.Hash, node *tableNode) error {
+	var kvs []kvzoo.KeyValue
+	if node.name != kvzoo.Root {
+		var err error
+		if kvs, err = db.loadValues(tx, node.name); err != nil {
+			return err
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(kvs) || j < len(node.children) {
+		if j == len(node.children) || (i < len(kvs) && kvs[i].Key < stdpath.Base(node.children[j].name)) {
+			h.Write([]byte(kvs[i].Key))
+			h.Write(kvs[i].Value)
+			i += 1
+		} else {
+			h.Write([]byte(stdpath.Base(node.children[j].name)))
+			if err := db.tableCheckSum(tx, h, node.children[j]); err != nil {
+				return err
+			}
+			j += 1
+		}
+	}
+	return nil
+}
+
+func (db *SQLDB) TableChecksums() (map[kvzoo.TableName]string, error) {
+	tx, err := db.beginRead()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	root, err := db.loadTables(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	checksums := make(map[kvzoo.TableName]string)
+	if err := db.walkTables(tx, root, func(tableName string, kvs []kvzoo.KeyValue) error {
+		h := md5.New()
+		for _, kv := range kvs {
+			h.Write([]byte(kv.Key))
+			h.Write(kv.Value)
+		}
+		checksums[kvzoo.TableName(tableName)] = hex.EncodeToString(h.Sum(nil)[:16])
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return checksums, nil
+}
+
+//parent table is visited before its children
+func (db *SQLDB) walkTables(tx *sql.Tx, node *tableNode, f func(string, []kvzoo.KeyValue) error) error {
+	if node.name != kvzoo.Root {
+		kvs, err := db.loadValues(tx, node.name)
+		if err != nil {
+			return err
+		}
+		if err := f(node.name, kvs); err != nil {
+			return err
+		}
+	}
+
+	for _, child := range node.children {
+		if err := db.walkTables(tx, child, f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (db *SQLDB) TableNames() ([]kvzoo.TableName, error) {
+	tx, err := db.beginRead()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	root, err := db.loadTables(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []kvzoo.TableName
+	var walk func(*tableNode)
+	walk = func(node *tableNode) {
+		for _, child := range node.children {
+			names = append(names, kvzoo.TableName(child.name))
+			walk(child)
+		}
+	}
+	walk(root)
+	return names, nil
+}
+
+func (db *SQLDB) Close() error {
+	db.hub.StopAll()
+	return db.db.Close()
+}
+
+//sqlite3 file is removed, table is dropped in postgresql
+func (db *SQLDB) Destroy() error {
+	if db.driver == Postgresql {
+		if _, err := db.db.Exec(db.query("DROP TABLE IF EXISTS %s")); err != nil {
+			return err
+		}
+		return db.Close()
+	}
+
+	if err := db.Close(); err != nil {
+		return err
+	}
+	for _, suffix := range []string{"-wal", "-shm"} {
+		os.Remove(db.dsn + suffix)
+	}
+	return os.Remove(db.dsn)
+}
+
+func (db *SQLDB) Snapshot(w io.Writer) error {
+	tx, err := db.beginRead()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	root, err := db.loadTables(tx)
+	if err != nil {
+		return err
+	}
+
+	sw, err := kvzoo.NewSnapshotWriter(w)
+	if err != nil {
+		return err
+	}
+
+	if err := db.walkTables(tx, root, func(tableName string, kvs []kvzoo.KeyValue) error {
+		if err := sw.WriteTable(kvzoo.TableName(tableName)); err != nil {
+			return err
+		}
+		for _, kv := range kvs {
+			if err := sw.Write(kvzoo.TableName(tableName), kv.Key, kv.Value); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	return sw.Close()
+}
+
+//all the tables are replaced in one transaction, if snapshot is
+//invalid, db is kept unchanged
+func (db *SQLDB) Restore(r io.Reader) error {
+	sr, err := kvzoo.NewSnapshotReader(r)
+	if err != nil {
+		return err
+	}
+	defer sr.Close()
+
+	db.writeLock.Lock()
+	defer db.writeLock.Unlock()
+
+	tx, err := db.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(db.query("DELETE FROM %s")); err != nil {
+		return err
+	}
+
+	for {
+		record, err := sr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+
+		if record.IsTable() {
+			err = db.createTable(tx, record.Table)
+		} else {
+			_, err = tx.Exec(db.query("INSERT INTO %s (table_name, item_key, item_value) VALUES (?, ?, ?)"), string(record.Table), record.Key, record.Value)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	//all the tables are changed, watchers have to reload the data
+	db.hub.StopAll()
+	return nil
+}
+
+//parent tables are created too
+func (db *SQLDB) createTable(tx *sql.Tx, tableName kvzoo.TableName) error {
+	name := ""
+	for _, seg := range tableName.Segments() {
+		if seg == "" {
+			return fmt.Errorf("table name %s is invalid, contains empty table name", tableName)
+		}
+
+		name = name + "/" + seg
+		var count int
+		if err := tx.QueryRow(db.query("SELECT COUNT(*) FROM %s WHERE table_name = ? AND item_key = ''"), name).Scan(&count); err != nil {
+			return err
+		}
+		if count == 0 {
+			if _, err := tx.Exec(db.query("INSERT INTO %s (table_name, item_key) VALUES (?, '')"), name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (db *SQLDB) CreateOrGetTable(tableName kvzoo.TableName) (kvzoo.Table, error) {
+	db.writeLock.Lock()
+	defer db.writeLock.Unlock()
+
+	tx, err := db.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := db.createTable(tx, tableName); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return &SQLTable{
+		name: tableName,
+		db:   db,
+	}, nil
+}
+
+//rows of child tables are deleted too
+func (db *SQLDB) DeleteTable(tableName kvzoo.TableName) error {
+	db.writeLock.Lock()
+	defer db.writeLock.Unlock()
+
+	tx, err := db.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	name := string(tableName)
+	//'0' is the next character of '/'
+	result, err := tx.Exec(db.query("DELETE FROM %s WHERE table_name = ? OR (table_name > ? AND table_name < ?)"), name, name+"/", name+"0")
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return fmt.Errorf("no found table %s", stdpath.Base(name))
+	}
+
+	return db.hub.Publish(tableName, []kvzoo.Event{kvzoo.Event{Type: kvzoo.EventDeleteTable}}, tx.Commit)
+}
+
+func (db *SQLDB) Watch(tableName kvzoo.TableName) (kvzoo.Watcher, error) {
+	return db.hub.Watch(tableName), nil
+}
+
+type SQLTable struct {
+	name kvzoo.TableName
+	db   *SQLDB
+}
+
+//like boltdb, table is created in the transaction if it's deleted
+func (t *SQLTable) Begin() (kvzoo.Transaction, error) {
+	t.db.writeLock.Lock()
+	tx, err := t.db.db.Begin()
+	if err != nil {
+		t.db.writeLock.Unlock()
+		return nil, err
+	}
+
+	if err := t.db.createTable(tx, t.name); err != nil {
+		tx.Rollback()
+		t.db.writeLock.Unlock()
+		return nil, err
+	}
+
+	return kvzoo.NewTransaction(&TableTX{
+		name: t.name,
+		db:   t.db,
+		tx:   tx,
+	}), nil
+}
+
+type TableTX struct {
+	name   kvzoo.TableName
+	db     *SQLDB
+	tx     *sql.Tx
+	closed bool
+	events []kvzoo.Event
+	expire *TableTX
+}
+
+func (tx *TableTX) Rollback() error {
+	if tx.closed {
+		return nil
+	}
+	tx.closed = true
+	defer tx.db.writeLock.Unlock()
+	return tx.tx.Rollback()
+}
+
+func (tx *TableTX) Commit() error {
+	if tx.closed {
+		return fmt.Errorf("tx closed")
+	}
+	tx.closed = true
+	defer tx.db.writeLock.Unlock()
+
+	if len(tx.events) == 0 {
+		return tx.tx.Commit()
+	}
+	return tx.db.hub.Publish(tx.name, tx.events, tx.tx.Commit)
+}
+
+//expire table shares the sql transaction, its changes are committed
+//with the table and aren't sent to watcher
+func (tx *TableTX) ExpireTable(create bool) (kvzoo.BaseTransaction, error) {
+	if tx.expire != nil {
+		return tx.expire, nil
+	}
+
+	name := tx.name.ExpireTable()
+	if create {
+		if err := tx.db.createTable(tx.tx, name); err != nil {
+			return nil, err
+		}
+	} else {
+		var count int
+		if err := tx.tx.QueryRow(tx.db.query("SELECT COUNT(*) FROM %s WHERE table_name = ? AND item_key = ''"), string(name)).Scan(&count); err != nil {
+			return nil, err
+		} else if count == 0 {
+			return nil, nil
+		}
+	}
+
+	tx.expire = &TableTX{
+		name: name,
+		db:   tx.db,
+		tx:   tx.tx,
+	}
+	return tx.expire, nil
+}
+
+func (tx *TableTX) exists(key string) (bool, error) {
+	var count int
+	if err := tx.tx.QueryRow(tx.db.query("SELECT COUNT(*) FROM %s WHERE table_name = ? AND item_key = ?"), string(tx.name), key).Scan(&count); err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (tx *TableTX) Add(key string, value []byte) error {
+	if key == "" {
+		return fmt.Errorf("key required")
+	}
+	if exists, err := tx.exists(key); err != nil {
+		return err
+	} else if exists {
+		return ErrDuplicateResource
+	}
+
+	value = copyValue(value)
+	if _, err := tx.tx.Exec(tx.db.query("INSERT INTO %s (table_name, item_key, item_value) VALUES (?, ?, ?)"), string(tx.name), key, value); err != nil {
+		return err
+	}
+	tx.addEvent(kvzoo.EventAdd, key, value)
+	return nil
+}
+
+//delete non-exist key doesn't generate event
+func (tx *TableTX) Delete(key string) error {
+	if key == "" {
+		return nil
+	}
+
+	result, err := tx.tx.Exec(tx.db.query("DELETE FROM %s WHERE table_name = ? AND item_key = ?"), string(tx.name), key)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n > 0 {
+		tx.addEvent(kvzoo.EventDelete, key, nil)
+	}
+	return nil
+}
+
+func (tx *TableTX) Update(key string, value []byte) error {
+	if key == "" {
+		return kvzoo.ErrNotFound
+	}
+
+	value = copyValue(value)
+	result, err := tx.tx.Exec(tx.db.query("UPDATE %s SET item_value = ? WHERE table_name = ? AND item_key = ?"), value, string(tx.name), key)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return kvzoo.ErrNotFound
+	}
+	tx.addEvent(kvzoo.EventUpdate, key, value)
+	return nil
+}
+
+//nil value is saved as empty value, since null means no value
+func copyValue(value []byte) []byte {
+	tmp := make([]byte, len(value))
+	copy(tmp, value)
+	return tmp
+}
+
+func (tx *TableTX) addEvent(typ kvzoo.EventType, key string, value []byte) {
+	tx.events = append(tx.events, kvzoo.Event{
+		Type:  typ,
+		Key:   key,
+		Value: value,
+	})
+}
+
+func (tx *TableTX) Get(key string) ([]byte, error) {
+	if key == "" {
+		return nil, kvzoo.ErrNotFound
+	}
+
+	var value []byte
+	err := tx.tx.QueryRow(tx.db.query("SELECT item_value FROM %s WHERE table_name = ? AND item_key = ?"), string(tx.name), key).Scan(&value)
+	if err == sql.ErrNoRows {
+		return nil, kvzoo.ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	if value == nil {
+		value = []byte{}
+	}
+	return value, nil
+}
+
+func (tx *TableTX) List() (map[string][]byte, error) {
+	kvs, err := tx.db.loadValues(tx.tx, string(tx.name))
+	if err != nil {
+		return nil, err
+	}
+
+	resourceMap := make(map[string][]byte, len(kvs))
+	for _, kv := range kvs {
+		resourceMap[kv.Key] = kv.Value
+	}
+	return resourceMap, nil
+}
+
+//one more row is queried to know whether there is next page
+func (tx *TableTX) Scan(opts kvzoo.ScanOptions) ([]kvzoo.KeyValue, string, error) {
+	lower, upper := opts.Bounds()
+	q := "SELECT item_key, item_value FROM %s WHERE table_name = ? AND item_key <> ''"
+	args := []interface{}{string(tx.name)}
+	if lower != "" {
+		q += " AND item_key >= ?"
+		args = append(args, lower)
+	}
+	if upper != "" {
+		q += " AND item_key < ?"
+		args = append(args, upper)
+	}
+	if opts.Cursor != "" {
+		if opts.Reverse {
+			q += " AND item_key < ?"
+		} else {
+			q += " AND item_key > ?"
+		}
+		args = append(args, opts.Cursor)
+	}
+	if opts.Reverse {
+		q += " ORDER BY item_key DESC"
+	} else {
+		q += " ORDER BY item_key"
+	}
+	if opts.Limit > 0 {
+		q += " LIMIT " + strconv.Itoa(opts.Limit+1)
+	}
+
+	rows, err := tx.tx.Query(tx.db.query(q), args...)
+	if err != nil {
+		return nil, "", err
+	}
+	kvs, err := scanRows(rows)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if opts.Limit > 0 && len(kvs) > opts.Limit {
+		kvs = kvs[:opts.Limit]
+		return kvs, kvs[len(kvs)-1].Key, nil
+	}
+	return kvs, "", nil
+}
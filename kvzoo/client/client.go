@@ -1,21 +1,67 @@
 package client
 
 import (
+	"context"
+	"strconv"
+	"sync/atomic"
 	"time"
 
+	"kvzoo"
 	pb "kvzoo/proto"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
 )
 
 type Client struct {
 	pb.KVSClient
-	conn *grpc.ClientConn
+	conn  *grpc.ClientConn
+	epoch uint64
+	token string
 }
 
-func NewClient(addr string, timeout time.Duration) (*Client, error) {
+type options struct {
+	tls   *kvzoo.TLSConfig
+	token string
+}
+
+type Option func(*options)
+
+//client certificate is presented if cert file is set
+func WithTLS(conf kvzoo.TLSConfig) Option {
+	return func(o *options) {
+		o.tls = &conf
+	}
+}
+
+func WithToken(token string) Option {
+	return func(o *options) {
+		o.token = token
+	}
+}
+
+func NewClient(addr string, timeout time.Duration, opts ...Option) (*Client, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	c := &Client{
+		token: o.token,
+	}
 	dialOptions := []grpc.DialOption{
-		grpc.WithInsecure(),
 		grpc.WithTimeout(timeout),
+		grpc.WithUnaryInterceptor(c.withMetadata),
+		grpc.WithStreamInterceptor(c.withMetadataStream),
+	}
+	if o.tls != nil {
+		conf, err := o.tls.ClientConfig()
+		if err != nil {
+			return nil, err
+		}
+		dialOptions = append(dialOptions, grpc.WithTransportCredentials(credentials.NewTLS(conf)))
+	} else {
+		dialOptions = append(dialOptions, grpc.WithInsecure())
 	}
 
 	conn, err := grpc.Dial(addr, dialOptions...)
@@ -23,10 +69,9 @@ func NewClient(addr string, timeout time.Duration) (*Client, error) {
 		return nil, err
 	}
 
-	return &Client{
-		KVSClient: pb.NewKVSClient(conn),
-		conn:      conn,
-	}, nil
+	c.KVSClient = pb.NewKVSClient(conn)
+	c.conn = conn
+	return c, nil
 }
 
 func (c *Client) Close() error {
@@ -36,3 +81,23 @@ func (c *Client) Close() error {
 func (c *Client) Target() string {
 	return c.conn.Target()
 }
+
+func (c *Client) setEpoch(epoch uint64) {
+	atomic.StoreUint64(&c.epoch, epoch)
+}
+
+func (c *Client) withMetadata(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	return invoker(c.outgoingContext(ctx), method, req, reply, cc, opts...)
+}
+
+func (c *Client) withMetadataStream(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	return streamer(c.outgoingContext(ctx), desc, cc, method, opts...)
+}
+
+func (c *Client) outgoingContext(ctx context.Context) context.Context {
+	ctx = metadata.AppendToOutgoingContext(ctx, pb.EpochMetadataKey, strconv.FormatUint(atomic.LoadUint64(&c.epoch), 10))
+	if c.token != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, pb.TokenMetadataKey, c.token)
+	}
+	return ctx
+}
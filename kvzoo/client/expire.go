@@ -0,0 +1,33 @@
+package client
+
+import (
+	"time"
+
+	"cement/log"
+	"kvzoo"
+)
+
+const DeleteExpiredInterval = 10 * time.Minute
+
+//expired keys are invisible but still occupy the space, they are
+//deleted through proxy, so the deletion is replicated to slaves, only
+//the proxy of the master should start it, otherwise every proxy
+//deletes the same keys
+func (p *Proxy) StartDeleteExpired() {
+	go p.deleteExpiredLoop()
+}
+
+func (p *Proxy) deleteExpiredLoop() {
+	ticker := time.NewTicker(DeleteExpiredInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.stopCh:
+			return
+		case <-ticker.C:
+			if err := kvzoo.DeleteExpiredKeys(p); err != nil {
+				log.Warnf("delete expired keys failed:%s", err.Error())
+			}
+		}
+	}
+}
@@ -0,0 +1,273 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"cement/log"
+	pb "kvzoo/proto"
+)
+
+const (
+	HealthCheckInterval = 5 * time.Second
+	HealthCheckTimeout  = 3 * time.Second
+	//failover happens after master fails health check continuously
+	MaxMasterFailures = 3
+)
+
+func (p *Proxy) currentEpoch() uint64 {
+	p.nodeLock.RLock()
+	defer p.nodeLock.RUnlock()
+	return p.epoch
+}
+
+func (p *Proxy) setEpoch(epoch uint64) {
+	p.nodeLock.Lock()
+	defer p.nodeLock.Unlock()
+
+	p.epoch = epoch
+	p.master.setEpoch(epoch)
+	for _, slave := range p.slaves {
+		slave.setEpoch(epoch)
+	}
+}
+
+//the highest epoch among all the nodes is adopted, if master has been
+//switched by failover of other proxy or the last run, the promoted
+//node is used as master
+func (p *Proxy) initEpoch() {
+	master, slaves := p.nodes()
+	var epoch uint64
+	var promoted string
+	for _, c := range append([]*Client{master}, slaves...) {
+		reply, err := getNodeEpoch(c)
+		if err != nil {
+			log.Warnf("get epoch of %s failed:%s", c.Target(), err.Error())
+			continue
+		}
+		if reply.Epoch > epoch {
+			epoch = reply.Epoch
+			promoted = reply.Master
+		}
+	}
+
+	if epoch <= p.currentEpoch() {
+		return
+	}
+
+	for _, slave := range slaves {
+		if slave.Target() == promoted {
+			log.Infof("master has been switched to %s with epoch %d", promoted, epoch)
+			p.switchMaster(slave, epoch)
+			return
+		}
+	}
+	p.setEpoch(epoch)
+}
+
+//old master becomes a fenced slave, it will be synced with the new
+//master before serve again
+func (p *Proxy) switchMaster(newMaster *Client, epoch uint64) {
+	p.nodeLock.Lock()
+	defer p.nodeLock.Unlock()
+
+	oldMaster := p.master
+	slaves := make([]*Client, 0, len(p.slaves))
+	for _, slave := range p.slaves {
+		if slave != newMaster {
+			slaves = append(slaves, slave)
+		}
+	}
+	slaves = append(slaves, oldMaster)
+
+	p.statusLock.Lock()
+	delete(p.syncStatus, newMaster)
+	p.syncStatus[oldMaster] = &SlaveSyncStatus{
+		Addr:      oldMaster.Target(),
+		State:     SyncStateStale,
+		LastError: fmt.Sprintf("master is switched to %s", newMaster.Target()),
+		Fenced:    true,
+	}
+	p.statusLock.Unlock()
+
+	p.master = newMaster
+	p.slaves = slaves
+	p.epoch = epoch
+	//log index of new master is learned again
+	p.masterIndex = 0
+	newMaster.setEpoch(epoch)
+	for _, slave := range slaves {
+		slave.setEpoch(epoch)
+	}
+}
+
+//proxy follows the newest epoch, so it switches to the master promoted
+//by other proxy, if failover is started, master is health checked in
+//each round and the most up-to-date slave is promoted once master
+//fails continuously
+func (p *Proxy) epochLoop() {
+	ticker := time.NewTicker(HealthCheckInterval)
+	defer ticker.Stop()
+	failures := 0
+	for {
+		select {
+		case <-p.stopCh:
+			return
+		case <-ticker.C:
+			p.initEpoch()
+			if p.failoverStarted() {
+				failures = p.checkMaster(failures)
+			}
+		}
+	}
+}
+
+//failover should only be started by the proxies beside the nodes, the
+//proxy beside slave has to do it, since the proxy beside master goes
+//down with the master
+func (p *Proxy) StartFailover() {
+	p.nodeLock.Lock()
+	defer p.nodeLock.Unlock()
+	p.failover = true
+}
+
+func (p *Proxy) failoverStarted() bool {
+	p.nodeLock.RLock()
+	defer p.nodeLock.RUnlock()
+	return p.failover
+}
+
+//return the count of continuous failures of master
+func (p *Proxy) checkMaster(failures int) int {
+	master, _ := p.nodes()
+	_, err := getNodeEpoch(master)
+	if err == nil {
+		if index, err := lastLogIndexWithTimeout(master); err == nil {
+			p.seeMasterIndex(master, index)
+		}
+		return 0
+	}
+
+	failures += 1
+	log.Warnf("health check of master %s failed:%s", master.Target(), err.Error())
+	if failures < MaxMasterFailures {
+		return failures
+	}
+
+	if err := p.Failover(); err != nil {
+		log.Warnf("failover failed:%s", err.Error())
+		return failures
+	}
+	return 0
+}
+
+//the largest log index of master known by proxy, which comes from the
+//writes through proxy and health check, index of other node is ignored
+//since master may be switched during the write
+func (p *Proxy) seeMasterIndex(master *Client, index uint64) {
+	p.nodeLock.Lock()
+	defer p.nodeLock.Unlock()
+
+	if master == p.master && index > p.masterIndex {
+		p.masterIndex = index
+	}
+}
+
+func (p *Proxy) knownMasterIndex() uint64 {
+	p.nodeLock.RLock()
+	defer p.nodeLock.RUnlock()
+	return p.masterIndex
+}
+
+//promote the most up-to-date slave to master, the epoch is increased
+//and set to all the reachable nodes, so request from proxy which
+//doesn't know the failover will be rejected
+func (p *Proxy) Failover() error {
+	p.resyncLock.Lock()
+	defer p.resyncLock.Unlock()
+
+	master, slaves := p.nodes()
+	candidate, index := p.selectCandidate(slaves)
+	if candidate == nil {
+		return fmt.Errorf("no slave can be promoted to master")
+	}
+
+	//writes which the candidate misses will be lost after it's promoted,
+	//master may come back, so wait for it instead
+	if masterIndex := p.knownMasterIndex(); index < masterIndex {
+		return fmt.Errorf("%s falls behind master, log index %d is less than %d", candidate.Target(), index, masterIndex)
+	}
+
+	epoch := p.currentEpoch() + 1
+	if err := setNodeEpoch(candidate, epoch, candidate.Target()); err != nil {
+		return fmt.Errorf("set epoch of %s failed:%s", candidate.Target(), err.Error())
+	}
+	p.switchMaster(candidate, epoch)
+	log.Warnf("master %s is down, %s is promoted to master with epoch %d", master.Target(), candidate.Target(), epoch)
+
+	//other slaves may fall behind the new master, they are synced
+	//in next round
+	for _, slave := range slaves {
+		if slave == candidate {
+			continue
+		}
+		if err := setNodeEpoch(slave, epoch, candidate.Target()); err != nil {
+			p.markStale(slave, err)
+		} else {
+			p.markStale(slave, fmt.Errorf("master is switched to %s", candidate.Target()))
+		}
+	}
+	return nil
+}
+
+//slave with the largest log index is selected, in sync slave is
+//preferred if log index is the same, without operation log, only
+//in sync slave can be selected
+func (p *Proxy) selectCandidate(slaves []*Client) (*Client, uint64) {
+	var candidate *Client
+	var candidateIndex uint64
+	candidateInSync := false
+	for _, slave := range slaves {
+		inSync := p.isStale(slave) == false
+		index, err := lastLogIndexWithTimeout(slave)
+		if err != nil {
+			if _, err := getNodeEpoch(slave); err != nil || inSync == false {
+				continue
+			}
+		}
+
+		if candidate == nil || index > candidateIndex || (index == candidateIndex && inSync && candidateInSync == false) {
+			candidate = slave
+			candidateIndex = index
+			candidateInSync = inSync
+		}
+	}
+	return candidate, candidateIndex
+}
+
+func getNodeEpoch(c *Client) (*pb.GetEpochReply, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), HealthCheckTimeout)
+	defer cancel()
+	return c.GetEpoch(ctx, &pb.GetEpochRequest{})
+}
+
+func setNodeEpoch(c *Client, epoch uint64, master string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), HealthCheckTimeout)
+	defer cancel()
+	_, err := c.SetEpoch(ctx, &pb.SetEpochRequest{
+		Epoch:  epoch,
+		Master: master,
+	})
+	return err
+}
+
+func lastLogIndexWithTimeout(c *Client) (uint64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), HealthCheckTimeout)
+	defer cancel()
+	reply, err := c.LastLogIndex(ctx, &pb.LastLogIndexRequest{})
+	if err != nil {
+		return 0, err
+	}
+	return reply.Index, nil
+}
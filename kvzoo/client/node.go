@@ -0,0 +1,63 @@
+package client
+
+import (
+	"context"
+
+	"kvzoo"
+	pb "kvzoo/proto"
+)
+
+//operations on single node, they are used by tools which inspect
+//and repair the data of nodes
+
+//node rejects modification with smaller epoch
+func (c *Client) UseNodeEpoch() error {
+	reply, err := getNodeEpoch(c)
+	if err != nil {
+		return err
+	}
+	c.setEpoch(reply.Epoch)
+	return nil
+}
+
+func TableChecksums(c *Client) (map[string]string, error) {
+	return getTableChecksums(c)
+}
+
+func TableNames(c *Client) ([]string, error) {
+	return getTableNames(c)
+}
+
+//keys in dst which don't exist in src are deleted, ttl of keys is
+//copied too by copying the expire table
+func CopyTable(src, dst *Client, tableName string) error {
+	if err := copyTable(src, dst, tableName); err != nil {
+		return err
+	}
+
+	expireTable := string(kvzoo.TableName(tableName).ExpireTable())
+	if ok, err := hasTable(src, expireTable); err != nil {
+		return err
+	} else if ok {
+		return copyTable(src, dst, expireTable)
+	}
+
+	if ok, err := hasTable(dst, expireTable); err != nil || ok == false {
+		return err
+	}
+	_, err := dst.DeleteTable(context.TODO(), &pb.DeleteTableRequest{Name: expireTable})
+	return err
+}
+
+func hasTable(c *Client, tableName string) (bool, error) {
+	names, err := getTableNames(c)
+	if err != nil {
+		return false, err
+	}
+	for _, name := range names {
+		if name == tableName {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -3,7 +3,9 @@ package client
 import (
 	"context"
 	"fmt"
+	"io"
 	"strings"
+	"sync"
 	"time"
 
 	"cement/log"
@@ -14,6 +16,18 @@ import (
 type Proxy struct {
 	master *Client
 	slaves []*Client
+	epoch  uint64
+	//the largest log index of master known by proxy, slave whose
+	//log index is less than it can't be promoted
+	masterIndex uint64
+	failover    bool
+	nodeLock    sync.RWMutex
+
+	syncStatus map[*Client]*SlaveSyncStatus
+	statusLock sync.Mutex
+	resyncLock sync.Mutex
+
+	stopCh chan struct{}
 }
 
 const (
@@ -21,52 +35,113 @@ const (
 	InvalidTxID    = int64(-1)
 )
 
-func New(masterAddr string, slaveAddrs []string) (kvzoo.DB, error) {
-	master, err := NewClient(masterAddr, ConnectTimeout)
+func New(masterAddr string, slaveAddrs []string, opts ...Option) (kvzoo.DB, error) {
+	return NewProxy(masterAddr, slaveAddrs, opts...)
+}
+
+//all the nodes share the same options
+func NewProxy(masterAddr string, slaveAddrs []string, opts ...Option) (*Proxy, error) {
+	master, err := NewClient(masterAddr, ConnectTimeout, opts...)
 	if err != nil {
 		return nil, err
 	}
 
 	slaves := make([]*Client, 0, len(slaveAddrs))
+	syncStatus := make(map[*Client]*SlaveSyncStatus, len(slaveAddrs))
 	for _, addr := range slaveAddrs {
-		slave, err := NewClient(addr, ConnectTimeout)
+		slave, err := NewClient(addr, ConnectTimeout, opts...)
 		if err != nil {
 			return nil, err
 		}
 		slaves = append(slaves, slave)
+		syncStatus[slave] = &SlaveSyncStatus{
+			Addr:  addr,
+			State: SyncStateInSync,
+		}
 	}
 
-	return &Proxy{
-		master: master,
-		slaves: slaves,
-	}, nil
+	p := &Proxy{
+		master:     master,
+		slaves:     slaves,
+		syncStatus: syncStatus,
+		stopCh:     make(chan struct{}),
+	}
+	p.initEpoch()
+	if len(slaves) > 0 {
+		go p.resyncLoop()
+		go p.epochLoop()
+	}
+	return p, nil
+}
+
+func (p *Proxy) nodes() (*Client, []*Client) {
+	p.nodeLock.RLock()
+	defer p.nodeLock.RUnlock()
+	return p.master, p.slaves
+}
+
+func (p *Proxy) Master() string {
+	master, _ := p.nodes()
+	return master.Target()
 }
 
 func (p *Proxy) Checksum() (string, error) {
 	req := &pb.ChecksumRequest{}
-	reply, err := p.master.Checksum(context.TODO(), req)
+	master, slaves := p.nodes()
+	reply, err := master.Checksum(context.TODO(), req)
 	if err != nil {
 		return "", err
 	}
 
 	cs := reply.Checksum
-	for _, slave := range p.slaves {
+	for _, slave := range slaves {
 		if reply, err := slave.Checksum(context.TODO(), req); err != nil {
 			return "", fmt.Errorf("%s get checksum failed:%s", slave.Target(), err.Error())
 		} else if reply.Checksum != cs {
-			return "", fmt.Errorf("checksum of %s isn't same with master %s", slave.Target(), p.master.Target())
+			return "", fmt.Errorf("checksum of %s isn't same with master %s", slave.Target(), master.Target())
 		}
 	}
 	return cs, nil
 }
 
+func (p *Proxy) TableChecksums() (map[kvzoo.TableName]string, error) {
+	master, _ := p.nodes()
+	checksums, err := getTableChecksums(master)
+	if err != nil {
+		return nil, err
+	}
+
+	tableChecksums := make(map[kvzoo.TableName]string, len(checksums))
+	for name, cs := range checksums {
+		tableChecksums[kvzoo.TableName(name)] = cs
+	}
+	return tableChecksums, nil
+}
+
+func (p *Proxy) TableNames() ([]kvzoo.TableName, error) {
+	master, _ := p.nodes()
+	names, err := getTableNames(master)
+	if err != nil {
+		return nil, err
+	}
+
+	tableNames := make([]kvzoo.TableName, 0, len(names))
+	for _, name := range names {
+		tableNames = append(tableNames, kvzoo.TableName(name))
+	}
+	return tableNames, nil
+}
+
 func (p *Proxy) Close() error {
+	close(p.stopCh)
+
+	master, slaves := p.nodes()
 	var err error
-	if err_ := p.master.Close(); err_ != nil {
+	if err_ := master.Close(); err_ != nil {
 		err = err_
 	}
 
-	for _, slave := range p.slaves {
+	for _, slave := range slaves {
 		if err_ := slave.Close(); err == nil && err_ != nil {
 			err = err_
 		}
@@ -77,11 +152,12 @@ func (p *Proxy) Close() error {
 
 func (p *Proxy) Destroy() error {
 	req := &pb.DestroyRequest{}
-	if _, err := p.master.Destroy(context.TODO(), req); err != nil {
+	master, slaves := p.nodes()
+	if _, err := master.Destroy(context.TODO(), req); err != nil {
 		return err
 	}
 
-	for _, slave := range p.slaves {
+	for _, slave := range slaves {
 		if _, err := slave.Destroy(context.TODO(), req); err != nil {
 			log.Warnf("%s Destroy failed:%s", slave.Target(), err.Error())
 		}
@@ -99,13 +175,15 @@ func (p *Proxy) CreateOrGetTable(tableName kvzoo.TableName) (kvzoo.Table, error)
 		Name: string(tableName),
 	}
 
-	if _, err := p.master.CreateOrGetTable(context.TODO(), req); err != nil {
+	master, slaves := p.nodes()
+	if _, err := master.CreateOrGetTable(context.TODO(), req); err != nil {
 		return nil, err
 	}
 
-	for _, slave := range p.slaves {
+	for _, slave := range slaves {
 		if _, err := slave.CreateOrGetTable(context.TODO(), req); err != nil {
 			log.Warnf("%s CreateOrGetTable failed:%s", slave.Target(), err.Error())
+			p.markStale(slave, err)
 		}
 	}
 
@@ -120,21 +198,31 @@ func (p *Proxy) DeleteTable(tableName kvzoo.TableName) error {
 		Name: string(tableName),
 	}
 
-	if _, err := p.master.DeleteTable(context.TODO(), req); err != nil {
+	master, slaves := p.nodes()
+	reply, err := master.DeleteTable(context.TODO(), req)
+	if err != nil {
 		return err
 	}
 
-	for _, slave := range p.slaves {
+	//slave should record the operation with the same log index as master
+	req.Index = reply.Index
+	p.seeMasterIndex(master, reply.Index)
+	for _, slave := range slaves {
 		if _, err := slave.DeleteTable(context.TODO(), req); err != nil {
 			log.Warnf("%s DeleteTable failed:%s", slave.Target(), err.Error())
+			p.markStale(slave, err)
 		}
 	}
 	return nil
 }
 
+//master and slaves are fixed when transaction begins, so switching
+//master won't affect the opened transactions
 type ProxyTransaction struct {
-	proxy *Proxy
-	ids   []int64
+	proxy  *Proxy
+	master *Client
+	slaves []*Client
+	ids    []int64
 }
 
 func (tb *ProxyTable) Begin() (kvzoo.Transaction, error) {
@@ -143,21 +231,25 @@ func (tb *ProxyTable) Begin() (kvzoo.Transaction, error) {
 	}
 
 	p := tb.proxy
+	master, slaves := p.nodes()
 	var tx *ProxyTransaction
-	if reply, err := p.master.BeginTransaction(context.TODO(), req); err != nil {
+	if reply, err := master.BeginTransaction(context.TODO(), req); err != nil {
 		return nil, err
 	} else {
-		ids := make([]int64, 0, 1+len(p.slaves))
+		ids := make([]int64, 0, 1+len(slaves))
 		ids = append(ids, reply.TxId)
 		tx = &ProxyTransaction{
-			proxy: tb.proxy,
-			ids:   ids,
+			proxy:  tb.proxy,
+			master: master,
+			slaves: slaves,
+			ids:    ids,
 		}
 	}
 
-	for _, slave := range p.slaves {
+	for _, slave := range slaves {
 		if reply, err := slave.BeginTransaction(context.TODO(), req); err != nil {
 			log.Warnf("%s BeginTransaction failed:%s", slave.Target(), err.Error())
+			p.markStale(slave, err)
 			tx.ids = append(tx.ids, InvalidTxID)
 		} else {
 			tx.ids = append(tx.ids, reply.TxId)
@@ -172,12 +264,11 @@ func (tx *ProxyTransaction) Rollback() error {
 		TxId: tx.ids[0],
 	}
 
-	p := tx.proxy
-	if _, err := p.master.RollbackTransaction(context.TODO(), req); err != nil {
+	if _, err := tx.master.RollbackTransaction(context.TODO(), req); err != nil {
 		return err
 	}
 
-	for i, slave := range p.slaves {
+	for i, slave := range tx.slaves {
 		id := tx.ids[i+1]
 		if id == InvalidTxID {
 			continue
@@ -199,21 +290,25 @@ func (tx *ProxyTransaction) Commit() error {
 	}
 
 	p := tx.proxy
-	if _, err := p.master.CommitTransaction(context.TODO(), req); err != nil {
+	reply, err := tx.master.CommitTransaction(context.TODO(), req)
+	if err != nil {
 		return err
 	}
+	p.seeMasterIndex(tx.master, reply.Index)
 
-	for i, slave := range p.slaves {
+	for i, slave := range tx.slaves {
 		id := tx.ids[i+1]
 		if id == InvalidTxID {
 			continue
 		}
 
 		req := &pb.CommitTransactionRequest{
-			TxId: id,
+			TxId:  id,
+			Index: reply.Index,
 		}
 		if _, err := slave.CommitTransaction(context.TODO(), req); err != nil {
 			log.Warnf("%s commit failed:%s", slave.Target(), err.Error())
+			p.markStale(slave, err)
 		}
 	}
 	return nil
@@ -226,11 +321,11 @@ func (tx *ProxyTransaction) Add(key string, value []byte) error {
 		Value: value,
 	}
 	p := tx.proxy
-	if _, err := p.master.Add(context.TODO(), req); err != nil {
+	if _, err := tx.master.Add(context.TODO(), req); err != nil {
 		return err
 	}
 
-	for i, slave := range p.slaves {
+	for i, slave := range tx.slaves {
 		id := tx.ids[i+1]
 		if id == InvalidTxID {
 			continue
@@ -243,6 +338,7 @@ func (tx *ProxyTransaction) Add(key string, value []byte) error {
 		}
 		if _, err := slave.Add(context.TODO(), req); err != nil {
 			log.Warnf("%s Add %s failed:%s", slave.Target(), key, err.Error())
+			p.markStale(slave, err)
 		}
 	}
 	return nil
@@ -254,11 +350,11 @@ func (tx *ProxyTransaction) Delete(key string) error {
 		Key:  key,
 	}
 	p := tx.proxy
-	if _, err := p.master.Delete(context.TODO(), req); err != nil {
+	if _, err := tx.master.Delete(context.TODO(), req); err != nil {
 		return err
 	}
 
-	for i, slave := range p.slaves {
+	for i, slave := range tx.slaves {
 		id := tx.ids[i+1]
 		if id == InvalidTxID {
 			continue
@@ -270,6 +366,7 @@ func (tx *ProxyTransaction) Delete(key string) error {
 		}
 		if _, err := slave.Delete(context.TODO(), req); err != nil {
 			log.Warnf("%s delete %s failed:%s", slave.Target(), key, err.Error())
+			p.markStale(slave, err)
 		}
 	}
 	return nil
@@ -282,11 +379,11 @@ func (tx *ProxyTransaction) Update(key string, value []byte) error {
 		Value: value,
 	}
 	p := tx.proxy
-	if _, err := p.master.Update(context.TODO(), req); err != nil {
-		return err
+	if _, err := tx.master.Update(context.TODO(), req); err != nil {
+		return kvError(err)
 	}
 
-	for i, slave := range p.slaves {
+	for i, slave := range tx.slaves {
 		id := tx.ids[i+1]
 		if id == InvalidTxID {
 			continue
@@ -299,22 +396,130 @@ func (tx *ProxyTransaction) Update(key string, value []byte) error {
 		}
 		if _, err := slave.Update(context.TODO(), req); err != nil {
 			log.Warnf("%s Update %s failed:%s", slave.Target(), key, err.Error())
+			p.markStale(slave, err)
+		}
+	}
+	return nil
+}
+
+func (tx *ProxyTransaction) ExpireAt(key string, expireAt time.Time) error {
+	var nano int64
+	if expireAt.IsZero() == false {
+		nano = expireAt.UnixNano()
+	}
+
+	req := &pb.ExpireAtRequest{
+		TxId:     tx.ids[0],
+		Key:      key,
+		ExpireAt: nano,
+	}
+	p := tx.proxy
+	if _, err := tx.master.ExpireAt(context.TODO(), req); err != nil {
+		return kvError(err)
+	}
+
+	for i, slave := range tx.slaves {
+		id := tx.ids[i+1]
+		if id == InvalidTxID {
+			continue
+		}
+
+		req := &pb.ExpireAtRequest{
+			TxId:     id,
+			Key:      key,
+			ExpireAt: nano,
+		}
+		if _, err := slave.ExpireAt(context.TODO(), req); err != nil {
+			log.Warnf("%s ExpireAt %s failed:%s", slave.Target(), key, err.Error())
+			p.markStale(slave, err)
 		}
 	}
 	return nil
 }
 
+//value is compared on master, slaves put the new value, since the
+//key may have expired on slave because of clock skew
+func (tx *ProxyTransaction) CompareAndSwap(key string, oldValue, newValue []byte) error {
+	req := &pb.CompareAndSwapRequest{
+		TxId:     tx.ids[0],
+		Key:      key,
+		OldValue: oldValue,
+		NewValue: newValue,
+	}
+	p := tx.proxy
+	if _, err := tx.master.CompareAndSwap(context.TODO(), req); err != nil {
+		return kvError(err)
+	}
+
+	for i, slave := range tx.slaves {
+		id := tx.ids[i+1]
+		if id == InvalidTxID {
+			continue
+		}
+
+		req := &pb.PutRequest{
+			TxId:  id,
+			Key:   key,
+			Value: newValue,
+		}
+		if _, err := slave.Put(context.TODO(), req); err != nil {
+			log.Warnf("%s Put %s failed:%s", slave.Target(), key, err.Error())
+			p.markStale(slave, err)
+		}
+	}
+	return nil
+}
+
+//expired keys are decided by master, since clocks of the nodes may
+//be different, slaves delete the same keys
+func (tx *ProxyTransaction) DeleteExpired() ([]string, error) {
+	req := &pb.DeleteExpiredRequest{
+		TxId: tx.ids[0],
+	}
+	p := tx.proxy
+	reply, err := tx.master.DeleteExpired(context.TODO(), req)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, slave := range tx.slaves {
+		id := tx.ids[i+1]
+		if id == InvalidTxID {
+			continue
+		}
+
+		for _, key := range reply.Keys {
+			req := &pb.DeleteRequest{
+				TxId: id,
+				Key:  key,
+			}
+			if _, err := slave.Delete(context.TODO(), req); err != nil {
+				log.Warnf("%s delete %s failed:%s", slave.Target(), key, err.Error())
+				p.markStale(slave, err)
+				break
+			}
+		}
+	}
+	return reply.Keys, nil
+}
+
+//error returned by grpc only keeps the message
+func kvError(err error) error {
+	if strings.Contains(err.Error(), kvzoo.ErrNotFound.Error()) {
+		return kvzoo.ErrNotFound
+	} else if strings.Contains(err.Error(), kvzoo.ErrConflict.Error()) {
+		return kvzoo.ErrConflict
+	}
+	return err
+}
+
 func (tx *ProxyTransaction) Get(key string) ([]byte, error) {
 	req := &pb.GetRequest{
 		TxId: tx.ids[0],
 		Key:  key,
 	}
-	if reply, err := tx.proxy.master.Get(context.TODO(), req); err != nil {
-		if strings.Contains(err.Error(), kvzoo.ErrNotFound.Error()) {
-			return nil, kvzoo.ErrNotFound
-		} else {
-			return nil, err
-		}
+	if reply, err := tx.master.Get(context.TODO(), req); err != nil {
+		return nil, kvError(err)
 	} else {
 		return reply.Value, nil
 	}
@@ -324,9 +529,44 @@ func (tx *ProxyTransaction) List() (map[string][]byte, error) {
 	req := &pb.ListRequest{
 		TxId: tx.ids[0],
 	}
-	if reply, err := tx.proxy.master.List(context.TODO(), req); err != nil {
+	if reply, err := tx.master.List(context.TODO(), req); err != nil {
 		return nil, err
 	} else {
 		return reply.Values, nil
 	}
 }
+
+func (tx *ProxyTransaction) Scan(opts kvzoo.ScanOptions) ([]kvzoo.KeyValue, string, error) {
+	req := &pb.ScanRequest{
+		TxId:    tx.ids[0],
+		Prefix:  opts.Prefix,
+		Start:   opts.Start,
+		End:     opts.End,
+		Limit:   uint32(opts.Limit),
+		Cursor:  opts.Cursor,
+		Reverse: opts.Reverse,
+	}
+	stream, err := tx.master.Scan(context.TODO(), req)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var kvs []kvzoo.KeyValue
+	var cursor string
+	for {
+		reply, err := stream.Recv()
+		if err == io.EOF {
+			return kvs, cursor, nil
+		} else if err != nil {
+			return nil, "", err
+		}
+
+		for _, kv := range reply.Values {
+			kvs = append(kvs, kvzoo.KeyValue{
+				Key:   kv.Key,
+				Value: kv.Value,
+			})
+		}
+		cursor = reply.NextCursor
+	}
+}
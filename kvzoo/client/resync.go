@@ -0,0 +1,439 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"cement/log"
+	pb "kvzoo/proto"
+)
+
+const ResyncInterval = 30 * time.Second
+
+type SyncState string
+
+const (
+	SyncStateInSync  SyncState = "insync"
+	SyncStateStale   SyncState = "stale"
+	SyncStateSyncing SyncState = "syncing"
+)
+
+type SlaveSyncStatus struct {
+	Addr         string    `json:"addr"`
+	State        SyncState `json:"state"`
+	StaleTables  []string  `json:"staleTables,omitempty"`
+	SyncedTables []string  `json:"syncedTables,omitempty"`
+	LastError    string    `json:"lastError,omitempty"`
+	LastSyncTime time.Time `json:"lastSyncTime,omitempty"`
+	//the last log index applied by slave and the last log index of master
+	LogIndex       uint64 `json:"logIndex"`
+	MasterLogIndex uint64 `json:"masterLogIndex"`
+	//old master which is demoted by failover, it won't accept request
+	//from proxy with old epoch, and will be synced before serve again
+	Fenced bool `json:"fenced,omitempty"`
+}
+
+func (p *Proxy) SyncStatus() []SlaveSyncStatus {
+	_, slaves := p.nodes()
+	p.statusLock.Lock()
+	defer p.statusLock.Unlock()
+
+	status := make([]SlaveSyncStatus, 0, len(slaves))
+	for _, slave := range slaves {
+		s, ok := p.syncStatus[slave]
+		if ok == false {
+			continue
+		}
+		cp := *s
+		cp.StaleTables = append([]string(nil), s.StaleTables...)
+		cp.SyncedTables = append([]string(nil), s.SyncedTables...)
+		status = append(status, cp)
+	}
+	return status
+}
+
+//node may be promoted to master when transaction on it is still
+//running, status of master isn't tracked
+func (p *Proxy) slaveStatus(slave *Client) (*SlaveSyncStatus, bool) {
+	status, ok := p.syncStatus[slave]
+	return status, ok
+}
+
+func (p *Proxy) markStale(slave *Client, err error) {
+	p.statusLock.Lock()
+	defer p.statusLock.Unlock()
+
+	if status, ok := p.slaveStatus(slave); ok {
+		status.State = SyncStateStale
+		status.LastError = err.Error()
+	}
+}
+
+func (p *Proxy) startSync(slave *Client) {
+	p.statusLock.Lock()
+	defer p.statusLock.Unlock()
+
+	if status, ok := p.slaveStatus(slave); ok {
+		status.State = SyncStateSyncing
+		status.StaleTables = nil
+		status.SyncedTables = nil
+	}
+}
+
+func (p *Proxy) setStaleTables(slave *Client, staleTables []string) {
+	p.statusLock.Lock()
+	defer p.statusLock.Unlock()
+
+	if status, ok := p.slaveStatus(slave); ok {
+		status.StaleTables = staleTables
+	}
+}
+
+//if any write to the slave failed during sync, slave is marked
+//as stale again and will be synced in next round
+func (p *Proxy) finishSync(slave *Client) {
+	p.statusLock.Lock()
+	defer p.statusLock.Unlock()
+
+	if status, ok := p.slaveStatus(slave); ok && status.State == SyncStateSyncing {
+		status.State = SyncStateInSync
+		status.StaleTables = nil
+		status.LastError = ""
+		status.LastSyncTime = time.Now()
+		status.Fenced = false
+	}
+}
+
+func (p *Proxy) tableSynced(slave *Client, tableName string) {
+	p.statusLock.Lock()
+	defer p.statusLock.Unlock()
+
+	status, ok := p.slaveStatus(slave)
+	if ok == false {
+		return
+	}
+	for i, name := range status.StaleTables {
+		if name == tableName {
+			status.StaleTables = append(status.StaleTables[:i], status.StaleTables[i+1:]...)
+			break
+		}
+	}
+	status.SyncedTables = append(status.SyncedTables, tableName)
+}
+
+func (p *Proxy) setLogIndex(slave *Client, index, masterIndex uint64) {
+	p.statusLock.Lock()
+	defer p.statusLock.Unlock()
+
+	if status, ok := p.slaveStatus(slave); ok {
+		status.LogIndex = index
+		status.MasterLogIndex = masterIndex
+	}
+}
+
+func (p *Proxy) isStale(slave *Client) bool {
+	p.statusLock.Lock()
+	defer p.statusLock.Unlock()
+
+	status, ok := p.slaveStatus(slave)
+	return ok && status.State != SyncStateInSync
+}
+
+func (p *Proxy) resyncLoop() {
+	ticker := time.NewTicker(ResyncInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.stopCh:
+			return
+		case <-ticker.C:
+			_, slaves := p.nodes()
+			for _, slave := range slaves {
+				if p.isStale(slave) {
+					if err := p.resyncSlave(slave); err != nil {
+						log.Warnf("resync %s failed:%s", slave.Target(), err.Error())
+					}
+				}
+			}
+		}
+	}
+}
+
+//replay the log entries which slave missed first, then compare checksum
+//of each table between master and all the slaves, copy tables which are
+//different from master to slave
+func (p *Proxy) Resync() error {
+	_, slaves := p.nodes()
+	var err error
+	for _, slave := range slaves {
+		if err_ := p.resyncSlave(slave); err_ != nil {
+			log.Warnf("resync %s failed:%s", slave.Target(), err_.Error())
+			if err == nil {
+				err = fmt.Errorf("resync %s failed:%s", slave.Target(), err_.Error())
+			}
+		}
+	}
+	return err
+}
+
+func (p *Proxy) resyncSlave(slave *Client) error {
+	p.resyncLock.Lock()
+	defer p.resyncLock.Unlock()
+
+	//master may be switched before the lock is acquired
+	master, _ := p.nodes()
+	if slave == master {
+		return nil
+	}
+
+	p.startSync(slave)
+	//fence the slave first, so the old master won't accept any write
+	//from other proxy which doesn't know the failover
+	if err := setNodeEpoch(slave, p.currentEpoch(), master.Target()); err != nil {
+		p.markStale(slave, err)
+		return err
+	}
+
+	replayErr := p.replayLog(master, slave)
+	if replayErr != nil {
+		log.Debugf("replay log to %s failed:%s, stale tables will be copied", slave.Target(), replayErr.Error())
+	}
+
+	//writes after masterIndex will be replayed in next round
+	masterIndex, indexErr := lastLogIndex(master)
+	masterChecksums, err := getTableChecksums(master)
+	if err != nil {
+		p.markStale(slave, err)
+		return err
+	}
+
+	slaveChecksums, err := getTableChecksums(slave)
+	if err != nil {
+		p.markStale(slave, err)
+		return err
+	}
+
+	var staleTables, redundantTables []string
+	for name, cs := range masterChecksums {
+		if slaveCS, ok := slaveChecksums[name]; ok == false || slaveCS != cs {
+			staleTables = append(staleTables, name)
+		}
+	}
+	for name := range slaveChecksums {
+		if _, ok := masterChecksums[name]; ok == false {
+			redundantTables = append(redundantTables, name)
+		}
+	}
+
+	if len(staleTables) == 0 && len(redundantTables) == 0 {
+		if replayErr != nil && indexErr == nil {
+			if err := p.resetLog(slave, masterIndex); err != nil {
+				return err
+			}
+		}
+		p.finishSync(slave)
+		return nil
+	}
+
+	sort.Strings(staleTables)
+	sort.Strings(redundantTables)
+	log.Infof("start to resync %s, %d tables are stale, %d tables should be deleted", slave.Target(), len(staleTables), len(redundantTables))
+	p.setStaleTables(slave, append([]string(nil), staleTables...))
+
+	if err := deleteTables(slave, redundantTables); err != nil {
+		p.markStale(slave, err)
+		return err
+	}
+
+	for _, name := range staleTables {
+		if err := copyTable(master, slave, name); err != nil {
+			err = fmt.Errorf("copy table %s failed:%s", name, err.Error())
+			p.markStale(slave, err)
+			return err
+		}
+		p.tableSynced(slave, name)
+		log.Debugf("table %s on %s is synced", name, slave.Target())
+	}
+
+	if indexErr == nil {
+		if err := p.resetLog(slave, masterIndex); err != nil {
+			return err
+		}
+	}
+
+	p.finishSync(slave)
+	log.Infof("resync %s succeed", slave.Target())
+	return nil
+}
+
+func (p *Proxy) replayLog(master, slave *Client) error {
+	since, err := lastLogIndex(slave)
+	if err != nil {
+		return err
+	}
+
+	for {
+		reply, err := master.GetLogEntries(context.TODO(), &pb.GetLogEntriesRequest{
+			Since: since,
+		})
+		if err != nil {
+			return err
+		}
+
+		p.setLogIndex(slave, since, reply.LastIndex)
+		if len(reply.Entries) == 0 {
+			return nil
+		}
+
+		if _, err := slave.ReplayLogEntries(context.TODO(), &pb.ReplayLogEntriesRequest{
+			Entries: reply.Entries,
+		}); err != nil {
+			return err
+		}
+		since = reply.Entries[len(reply.Entries)-1].Index
+		log.Debugf("log entries to %d are replayed on %s", since, slave.Target())
+	}
+}
+
+//after data is copied from master, slave log is reset to master log index
+func (p *Proxy) resetLog(slave *Client, masterIndex uint64) error {
+	if _, err := slave.ResetLog(context.TODO(), &pb.ResetLogRequest{Index: masterIndex}); err != nil {
+		err = fmt.Errorf("reset log index failed:%s", err.Error())
+		p.markStale(slave, err)
+		return err
+	}
+	p.setLogIndex(slave, masterIndex, masterIndex)
+	return nil
+}
+
+func lastLogIndex(c *Client) (uint64, error) {
+	reply, err := c.LastLogIndex(context.TODO(), &pb.LastLogIndexRequest{})
+	if err != nil {
+		return 0, err
+	}
+	return reply.Index, nil
+}
+
+func getTableChecksums(c *Client) (map[string]string, error) {
+	reply, err := c.TableChecksums(context.TODO(), &pb.TableChecksumsRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("%s get table checksums failed:%s", c.Target(), err.Error())
+	}
+	return reply.Checksums, nil
+}
+
+func getTableNames(c *Client) ([]string, error) {
+	reply, err := c.TableNames(context.TODO(), &pb.TableNamesRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("%s get table names failed:%s", c.Target(), err.Error())
+	}
+	return reply.Names, nil
+}
+
+//tables should be sorted, so parent table is always deleted before its children
+func deleteTables(c *Client, tables []string) error {
+	var deleted []string
+	for _, name := range tables {
+		if hasParentIn(name, deleted) {
+			continue
+		}
+
+		if _, err := c.DeleteTable(context.TODO(), &pb.DeleteTableRequest{Name: name}); err != nil {
+			return fmt.Errorf("delete table %s failed:%s", name, err.Error())
+		}
+		deleted = append(deleted, name)
+	}
+	return nil
+}
+
+func hasParentIn(name string, parents []string) bool {
+	for _, parent := range parents {
+		if strings.HasPrefix(name, parent+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+//master transaction is kept open until slave is updated, since write
+//transaction is exclusive, no other write can happen on the master
+//during the copy, raw transaction is used so ttl of keys is copied
+func copyTable(master, slave *Client, name string) error {
+	req := &pb.CreateOrGetTableRequest{
+		Name: name,
+	}
+	if _, err := master.CreateOrGetTable(context.TODO(), req); err != nil {
+		return err
+	}
+	if _, err := slave.CreateOrGetTable(context.TODO(), req); err != nil {
+		return err
+	}
+
+	masterTxId, err := beginRawTransaction(master, name)
+	if err != nil {
+		return err
+	}
+	defer master.RollbackTransaction(context.TODO(), &pb.RollbackTransactionRequest{TxId: masterTxId})
+
+	reply, err := master.List(context.TODO(), &pb.ListRequest{TxId: masterTxId})
+	if err != nil {
+		return err
+	}
+	values := reply.Values
+
+	slaveTxId, err := beginRawTransaction(slave, name)
+	if err != nil {
+		return err
+	}
+
+	if err := applyTableValues(slave, slaveTxId, values); err != nil {
+		slave.RollbackTransaction(context.TODO(), &pb.RollbackTransactionRequest{TxId: slaveTxId})
+		return err
+	}
+
+	_, err = slave.CommitTransaction(context.TODO(), &pb.CommitTransactionRequest{TxId: slaveTxId})
+	return err
+}
+
+func beginRawTransaction(c *Client, tableName string) (int64, error) {
+	reply, err := c.BeginTransaction(context.TODO(), &pb.BeginTransactionRequest{
+		TableName: tableName,
+		Raw:       true,
+	})
+	if err != nil {
+		return InvalidTxID, err
+	}
+	return reply.TxId, nil
+}
+
+func applyTableValues(c *Client, txId int64, values map[string][]byte) error {
+	reply, err := c.List(context.TODO(), &pb.ListRequest{TxId: txId})
+	if err != nil {
+		return err
+	}
+
+	for key := range reply.Values {
+		if _, ok := values[key]; ok == false {
+			if _, err := c.Delete(context.TODO(), &pb.DeleteRequest{TxId: txId, Key: key}); err != nil {
+				return err
+			}
+		}
+	}
+
+	for key, value := range values {
+		if old, ok := reply.Values[key]; ok == false {
+			if _, err := c.Add(context.TODO(), &pb.AddRequest{TxId: txId, Key: key, Value: value}); err != nil {
+				return err
+			}
+		} else if bytes.Equal(old, value) == false {
+			if _, err := c.Update(context.TODO(), &pb.UpdateRequest{TxId: txId, Key: key, Value: value}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"cement/log"
+	pb "kvzoo/proto"
+)
+
+const SnapshotChunkSize = 64 * 1024
+
+//snapshot is taken from master
+func (p *Proxy) Snapshot(w io.Writer) error {
+	master, _ := p.nodes()
+	return SnapshotNode(master, w)
+}
+
+//snapshot is restored to master, slaves are synced with master
+//by copying the tables
+func (p *Proxy) Restore(r io.Reader) error {
+	master, slaves := p.nodes()
+	if err := RestoreNode(master, r); err != nil {
+		return err
+	}
+
+	if len(slaves) == 0 {
+		return nil
+	}
+
+	for _, slave := range slaves {
+		p.markStale(slave, fmt.Errorf("master is restored from snapshot"))
+	}
+	if err := p.Resync(); err != nil {
+		log.Warnf("resync slaves after restore failed:%s", err.Error())
+	}
+	return nil
+}
+
+//snapshot of single node, used by tools which don't need the proxy
+func SnapshotNode(c *Client, w io.Writer) error {
+	stream, err := c.Snapshot(context.TODO(), &pb.SnapshotRequest{})
+	if err != nil {
+		return err
+	}
+
+	for {
+		chunk, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("receive snapshot from %s failed:%s", c.Target(), err.Error())
+		}
+
+		if _, err := w.Write(chunk.Data); err != nil {
+			return err
+		}
+	}
+}
+
+//if read snapshot failed, stream is canceled so node won't apply
+//the partial snapshot, slaves of the node aren't synced
+func RestoreNode(c *Client, r io.Reader) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream, err := c.Restore(ctx)
+	if err != nil {
+		return err
+	}
+
+	buf := make([]byte, SnapshotChunkSize)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			chunk := &pb.SnapshotChunk{
+				Data: append([]byte(nil), buf[:n]...),
+			}
+			//node closes the stream early only when restore failed,
+			//the error is returned by CloseAndRecv
+			if sendErr := stream.Send(chunk); sendErr == io.EOF {
+				break
+			} else if sendErr != nil {
+				return sendErr
+			}
+		}
+
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+	}
+
+	if _, err := stream.CloseAndRecv(); err != nil {
+		return fmt.Errorf("restore snapshot to %s failed:%s", c.Target(), err.Error())
+	}
+	return nil
+}
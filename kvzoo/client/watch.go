@@ -0,0 +1,79 @@
+package client
+
+import (
+	"context"
+
+	"cement/log"
+	"kvzoo"
+	pb "kvzoo/proto"
+)
+
+var eventTypes = map[pb.OperationType]kvzoo.EventType{
+	pb.OperationType_ADD:          kvzoo.EventAdd,
+	pb.OperationType_UPDATE:       kvzoo.EventUpdate,
+	pb.OperationType_DELETE:       kvzoo.EventDelete,
+	pb.OperationType_DELETE_TABLE: kvzoo.EventDeleteTable,
+}
+
+type proxyWatcher struct {
+	ch     chan kvzoo.Event
+	cancel context.CancelFunc
+}
+
+//only master is watched, if master is switched the stream will be
+//broken and the channel is closed
+func (p *Proxy) Watch(tableName kvzoo.TableName) (kvzoo.Watcher, error) {
+	master, _ := p.nodes()
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := master.Watch(ctx, &pb.WatchRequest{
+		TableName: string(tableName),
+	})
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	//wait until the watcher is created in server
+	if _, err := stream.Header(); err != nil {
+		cancel()
+		return nil, err
+	}
+
+	w := &proxyWatcher{
+		ch:     make(chan kvzoo.Event, kvzoo.WatchEventBufferSize),
+		cancel: cancel,
+	}
+	go w.run(ctx, stream, string(tableName))
+	return w, nil
+}
+
+func (w *proxyWatcher) run(ctx context.Context, stream pb.KVS_WatchClient, tableName string) {
+	defer close(w.ch)
+	for {
+		op, err := stream.Recv()
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Warnf("watch table %s failed:%s", tableName, err.Error())
+			}
+			return
+		}
+
+		select {
+		case w.ch <- kvzoo.Event{
+			Type:  eventTypes[op.Type],
+			Key:   op.Key,
+			Value: op.Value,
+		}:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (w *proxyWatcher) Events() <-chan kvzoo.Event {
+	return w.ch
+}
+
+func (w *proxyWatcher) Stop() {
+	w.cancel()
+}
@@ -2,6 +2,8 @@ package kvzoo
 
 import (
 	"errors"
+	"io"
+	"time"
 )
 
 var ErrNotFound = errors.New("key doesn't exist")
@@ -11,22 +13,37 @@ type DB interface {
 	//normally it will iterate all the key and values
 	//calculate the hash
 	Checksum() (string, error)
+	//footprint of each table, key is the table name
+	//child table is calculated separately from its parent
+	TableChecksums() (map[TableName]string, error)
+	//name of all the tables, parent table is followed by its
+	//children, child tables are sorted by name
+	TableNames() ([]TableName, error)
 	//Close and Destroy are mutually exclusive
 	//release the conn
 	Close() error
 	//clean all the data and release the conn
 	Destroy() error
+	//write a consistent copy of all the tables to the writer
+	//in snapshot format, writes are not blocked during snapshot
+	Snapshot(io.Writer) error
+	//replace all the tables with the data in snapshot
+	Restore(io.Reader) error
 
 	//like path, create child table, will create all parent table too
 	CreateOrGetTable(TableName) (Table, error)
 	//delete parent table will delete all child table
 	DeleteTable(TableName) error
+	//changes of the table are sent to watcher after commit,
+	//changes of child table aren't included
+	Watch(TableName) (Watcher, error)
 }
 
 type Table interface {
 	Begin() (Transaction, error)
 }
 
+//expired key is treated as non-exist key
 type Transaction interface {
 	Commit() error
 	Rollback() error
@@ -34,8 +51,20 @@ type Transaction interface {
 	Add(string, []byte) error
 	//delete non-exist key returns nil
 	Delete(string) error
+	//ttl of the key is kept
 	Update(string, []byte) error
 	//get non-exist key return ErrNotFound
 	Get(string) ([]byte, error)
 	List() (map[string][]byte, error)
+	//return keys in order with the cursor of next page,
+	//cursor is empty if there is no more keys
+	Scan(ScanOptions) ([]KeyValue, string, error)
+
+	//key expires at the time, zero time removes the ttl
+	ExpireAt(string, time.Time) error
+	//update the key only if current value equals to the old value,
+	//otherwise return ErrConflict
+	CompareAndSwap(key string, oldValue, newValue []byte) error
+	//delete expired keys and return them
+	DeleteExpired() ([]string, error)
 }
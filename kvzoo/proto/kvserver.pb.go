@@ -25,6 +25,40 @@ var _ = math.Inf
 // proto package needs to be updated.
 const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
 
+type OperationType int32
+
+const (
+	OperationType_ADD          OperationType = 0
+	OperationType_UPDATE       OperationType = 1
+	OperationType_DELETE       OperationType = 2
+	OperationType_DELETE_TABLE OperationType = 3
+	OperationType_EXPIRE       OperationType = 4
+)
+
+var OperationType_name = map[int32]string{
+	0: "ADD",
+	1: "UPDATE",
+	2: "DELETE",
+	3: "DELETE_TABLE",
+	4: "EXPIRE",
+}
+
+var OperationType_value = map[string]int32{
+	"ADD":          0,
+	"UPDATE":       1,
+	"DELETE":       2,
+	"DELETE_TABLE": 3,
+	"EXPIRE":       4,
+}
+
+func (x OperationType) String() string {
+	return proto.EnumName(OperationType_name, int32(x))
+}
+
+func (OperationType) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_1b14dcbe5169b67b, []int{0}
+}
+
 type ChecksumRequest struct {
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
@@ -95,6 +129,146 @@ func (m *ChecksumReply) GetChecksum() string {
 	return ""
 }
 
+type TableChecksumsRequest struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *TableChecksumsRequest) Reset()         { *m = TableChecksumsRequest{} }
+func (m *TableChecksumsRequest) String() string { return proto.CompactTextString(m) }
+func (*TableChecksumsRequest) ProtoMessage()    {}
+func (*TableChecksumsRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1b14dcbe5169b67b, []int{2}
+}
+
+func (m *TableChecksumsRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_TableChecksumsRequest.Unmarshal(m, b)
+}
+func (m *TableChecksumsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_TableChecksumsRequest.Marshal(b, m, deterministic)
+}
+func (m *TableChecksumsRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_TableChecksumsRequest.Merge(m, src)
+}
+func (m *TableChecksumsRequest) XXX_Size() int {
+	return xxx_messageInfo_TableChecksumsRequest.Size(m)
+}
+func (m *TableChecksumsRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_TableChecksumsRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_TableChecksumsRequest proto.InternalMessageInfo
+
+type TableChecksumsReply struct {
+	Checksums            map[string]string `protobuf:"bytes,1,rep,name=checksums,proto3" json:"checksums,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
+}
+
+func (m *TableChecksumsReply) Reset()         { *m = TableChecksumsReply{} }
+func (m *TableChecksumsReply) String() string { return proto.CompactTextString(m) }
+func (*TableChecksumsReply) ProtoMessage()    {}
+func (*TableChecksumsReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1b14dcbe5169b67b, []int{3}
+}
+
+func (m *TableChecksumsReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_TableChecksumsReply.Unmarshal(m, b)
+}
+func (m *TableChecksumsReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_TableChecksumsReply.Marshal(b, m, deterministic)
+}
+func (m *TableChecksumsReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_TableChecksumsReply.Merge(m, src)
+}
+func (m *TableChecksumsReply) XXX_Size() int {
+	return xxx_messageInfo_TableChecksumsReply.Size(m)
+}
+func (m *TableChecksumsReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_TableChecksumsReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_TableChecksumsReply proto.InternalMessageInfo
+
+func (m *TableChecksumsReply) GetChecksums() map[string]string {
+	if m != nil {
+		return m.Checksums
+	}
+	return nil
+}
+
+type TableNamesRequest struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *TableNamesRequest) Reset()         { *m = TableNamesRequest{} }
+func (m *TableNamesRequest) String() string { return proto.CompactTextString(m) }
+func (*TableNamesRequest) ProtoMessage()    {}
+func (*TableNamesRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1b14dcbe5169b67b, []int{4}
+}
+
+func (m *TableNamesRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_TableNamesRequest.Unmarshal(m, b)
+}
+func (m *TableNamesRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_TableNamesRequest.Marshal(b, m, deterministic)
+}
+func (m *TableNamesRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_TableNamesRequest.Merge(m, src)
+}
+func (m *TableNamesRequest) XXX_Size() int {
+	return xxx_messageInfo_TableNamesRequest.Size(m)
+}
+func (m *TableNamesRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_TableNamesRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_TableNamesRequest proto.InternalMessageInfo
+
+type TableNamesReply struct {
+	Names                []string `protobuf:"bytes,1,rep,name=names,proto3" json:"names,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *TableNamesReply) Reset()         { *m = TableNamesReply{} }
+func (m *TableNamesReply) String() string { return proto.CompactTextString(m) }
+func (*TableNamesReply) ProtoMessage()    {}
+func (*TableNamesReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1b14dcbe5169b67b, []int{5}
+}
+
+func (m *TableNamesReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_TableNamesReply.Unmarshal(m, b)
+}
+func (m *TableNamesReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_TableNamesReply.Marshal(b, m, deterministic)
+}
+func (m *TableNamesReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_TableNamesReply.Merge(m, src)
+}
+func (m *TableNamesReply) XXX_Size() int {
+	return xxx_messageInfo_TableNamesReply.Size(m)
+}
+func (m *TableNamesReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_TableNamesReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_TableNamesReply proto.InternalMessageInfo
+
+func (m *TableNamesReply) GetNames() []string {
+	if m != nil {
+		return m.Names
+	}
+	return nil
+}
+
 type DestroyRequest struct {
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
@@ -105,7 +279,7 @@ func (m *DestroyRequest) Reset()         { *m = DestroyRequest{} }
 func (m *DestroyRequest) String() string { return proto.CompactTextString(m) }
 func (*DestroyRequest) ProtoMessage()    {}
 func (*DestroyRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_1b14dcbe5169b67b, []int{2}
+	return fileDescriptor_1b14dcbe5169b67b, []int{6}
 }
 
 func (m *DestroyRequest) XXX_Unmarshal(b []byte) error {
@@ -137,7 +311,7 @@ func (m *CreateOrGetTableRequest) Reset()         { *m = CreateOrGetTableRequest
 func (m *CreateOrGetTableRequest) String() string { return proto.CompactTextString(m) }
 func (*CreateOrGetTableRequest) ProtoMessage()    {}
 func (*CreateOrGetTableRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_1b14dcbe5169b67b, []int{3}
+	return fileDescriptor_1b14dcbe5169b67b, []int{7}
 }
 
 func (m *CreateOrGetTableRequest) XXX_Unmarshal(b []byte) error {
@@ -167,6 +341,7 @@ func (m *CreateOrGetTableRequest) GetName() string {
 
 type DeleteTableRequest struct {
 	Name                 string   `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Index                uint64   `protobuf:"varint,2,opt,name=index,proto3" json:"index,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -176,7 +351,7 @@ func (m *DeleteTableRequest) Reset()         { *m = DeleteTableRequest{} }
 func (m *DeleteTableRequest) String() string { return proto.CompactTextString(m) }
 func (*DeleteTableRequest) ProtoMessage()    {}
 func (*DeleteTableRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_1b14dcbe5169b67b, []int{4}
+	return fileDescriptor_1b14dcbe5169b67b, []int{8}
 }
 
 func (m *DeleteTableRequest) XXX_Unmarshal(b []byte) error {
@@ -204,8 +379,55 @@ func (m *DeleteTableRequest) GetName() string {
 	return ""
 }
 
+func (m *DeleteTableRequest) GetIndex() uint64 {
+	if m != nil {
+		return m.Index
+	}
+	return 0
+}
+
+type DeleteTableReply struct {
+	Index                uint64   `protobuf:"varint,1,opt,name=index,proto3" json:"index,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DeleteTableReply) Reset()         { *m = DeleteTableReply{} }
+func (m *DeleteTableReply) String() string { return proto.CompactTextString(m) }
+func (*DeleteTableReply) ProtoMessage()    {}
+func (*DeleteTableReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1b14dcbe5169b67b, []int{9}
+}
+
+func (m *DeleteTableReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DeleteTableReply.Unmarshal(m, b)
+}
+func (m *DeleteTableReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DeleteTableReply.Marshal(b, m, deterministic)
+}
+func (m *DeleteTableReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DeleteTableReply.Merge(m, src)
+}
+func (m *DeleteTableReply) XXX_Size() int {
+	return xxx_messageInfo_DeleteTableReply.Size(m)
+}
+func (m *DeleteTableReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_DeleteTableReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DeleteTableReply proto.InternalMessageInfo
+
+func (m *DeleteTableReply) GetIndex() uint64 {
+	if m != nil {
+		return m.Index
+	}
+	return 0
+}
+
 type BeginTransactionRequest struct {
 	TableName            string   `protobuf:"bytes,1,opt,name=table_name,json=tableName,proto3" json:"table_name,omitempty"`
+	Raw                  bool     `protobuf:"varint,2,opt,name=raw,proto3" json:"raw,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -215,7 +437,7 @@ func (m *BeginTransactionRequest) Reset()         { *m = BeginTransactionRequest
 func (m *BeginTransactionRequest) String() string { return proto.CompactTextString(m) }
 func (*BeginTransactionRequest) ProtoMessage()    {}
 func (*BeginTransactionRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_1b14dcbe5169b67b, []int{5}
+	return fileDescriptor_1b14dcbe5169b67b, []int{10}
 }
 
 func (m *BeginTransactionRequest) XXX_Unmarshal(b []byte) error {
@@ -243,6 +465,13 @@ func (m *BeginTransactionRequest) GetTableName() string {
 	return ""
 }
 
+func (m *BeginTransactionRequest) GetRaw() bool {
+	if m != nil {
+		return m.Raw
+	}
+	return false
+}
+
 type BeginTransactionReply struct {
 	TxId                 int64    `protobuf:"varint,1,opt,name=tx_id,json=txId,proto3" json:"tx_id,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
@@ -254,7 +483,7 @@ func (m *BeginTransactionReply) Reset()         { *m = BeginTransactionReply{} }
 func (m *BeginTransactionReply) String() string { return proto.CompactTextString(m) }
 func (*BeginTransactionReply) ProtoMessage()    {}
 func (*BeginTransactionReply) Descriptor() ([]byte, []int) {
-	return fileDescriptor_1b14dcbe5169b67b, []int{6}
+	return fileDescriptor_1b14dcbe5169b67b, []int{11}
 }
 
 func (m *BeginTransactionReply) XXX_Unmarshal(b []byte) error {
@@ -284,6 +513,7 @@ func (m *BeginTransactionReply) GetTxId() int64 {
 
 type CommitTransactionRequest struct {
 	TxId                 int64    `protobuf:"varint,1,opt,name=tx_id,json=txId,proto3" json:"tx_id,omitempty"`
+	Index                uint64   `protobuf:"varint,2,opt,name=index,proto3" json:"index,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -293,7 +523,7 @@ func (m *CommitTransactionRequest) Reset()         { *m = CommitTransactionReque
 func (m *CommitTransactionRequest) String() string { return proto.CompactTextString(m) }
 func (*CommitTransactionRequest) ProtoMessage()    {}
 func (*CommitTransactionRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_1b14dcbe5169b67b, []int{7}
+	return fileDescriptor_1b14dcbe5169b67b, []int{12}
 }
 
 func (m *CommitTransactionRequest) XXX_Unmarshal(b []byte) error {
@@ -321,6 +551,52 @@ func (m *CommitTransactionRequest) GetTxId() int64 {
 	return 0
 }
 
+func (m *CommitTransactionRequest) GetIndex() uint64 {
+	if m != nil {
+		return m.Index
+	}
+	return 0
+}
+
+type CommitTransactionReply struct {
+	Index                uint64   `protobuf:"varint,1,opt,name=index,proto3" json:"index,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CommitTransactionReply) Reset()         { *m = CommitTransactionReply{} }
+func (m *CommitTransactionReply) String() string { return proto.CompactTextString(m) }
+func (*CommitTransactionReply) ProtoMessage()    {}
+func (*CommitTransactionReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1b14dcbe5169b67b, []int{13}
+}
+
+func (m *CommitTransactionReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CommitTransactionReply.Unmarshal(m, b)
+}
+func (m *CommitTransactionReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CommitTransactionReply.Marshal(b, m, deterministic)
+}
+func (m *CommitTransactionReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CommitTransactionReply.Merge(m, src)
+}
+func (m *CommitTransactionReply) XXX_Size() int {
+	return xxx_messageInfo_CommitTransactionReply.Size(m)
+}
+func (m *CommitTransactionReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_CommitTransactionReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CommitTransactionReply proto.InternalMessageInfo
+
+func (m *CommitTransactionReply) GetIndex() uint64 {
+	if m != nil {
+		return m.Index
+	}
+	return 0
+}
+
 type RollbackTransactionRequest struct {
 	TxId                 int64    `protobuf:"varint,1,opt,name=tx_id,json=txId,proto3" json:"tx_id,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
@@ -332,7 +608,7 @@ func (m *RollbackTransactionRequest) Reset()         { *m = RollbackTransactionR
 func (m *RollbackTransactionRequest) String() string { return proto.CompactTextString(m) }
 func (*RollbackTransactionRequest) ProtoMessage()    {}
 func (*RollbackTransactionRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_1b14dcbe5169b67b, []int{8}
+	return fileDescriptor_1b14dcbe5169b67b, []int{14}
 }
 
 func (m *RollbackTransactionRequest) XXX_Unmarshal(b []byte) error {
@@ -373,7 +649,7 @@ func (m *AddRequest) Reset()         { *m = AddRequest{} }
 func (m *AddRequest) String() string { return proto.CompactTextString(m) }
 func (*AddRequest) ProtoMessage()    {}
 func (*AddRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_1b14dcbe5169b67b, []int{9}
+	return fileDescriptor_1b14dcbe5169b67b, []int{15}
 }
 
 func (m *AddRequest) XXX_Unmarshal(b []byte) error {
@@ -427,7 +703,7 @@ func (m *DeleteRequest) Reset()         { *m = DeleteRequest{} }
 func (m *DeleteRequest) String() string { return proto.CompactTextString(m) }
 func (*DeleteRequest) ProtoMessage()    {}
 func (*DeleteRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_1b14dcbe5169b67b, []int{10}
+	return fileDescriptor_1b14dcbe5169b67b, []int{16}
 }
 
 func (m *DeleteRequest) XXX_Unmarshal(b []byte) error {
@@ -475,7 +751,7 @@ func (m *UpdateRequest) Reset()         { *m = UpdateRequest{} }
 func (m *UpdateRequest) String() string { return proto.CompactTextString(m) }
 func (*UpdateRequest) ProtoMessage()    {}
 func (*UpdateRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_1b14dcbe5169b67b, []int{11}
+	return fileDescriptor_1b14dcbe5169b67b, []int{17}
 }
 
 func (m *UpdateRequest) XXX_Unmarshal(b []byte) error {
@@ -529,7 +805,7 @@ func (m *GetRequest) Reset()         { *m = GetRequest{} }
 func (m *GetRequest) String() string { return proto.CompactTextString(m) }
 func (*GetRequest) ProtoMessage()    {}
 func (*GetRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_1b14dcbe5169b67b, []int{12}
+	return fileDescriptor_1b14dcbe5169b67b, []int{18}
 }
 
 func (m *GetRequest) XXX_Unmarshal(b []byte) error {
@@ -575,7 +851,7 @@ func (m *GetResponse) Reset()         { *m = GetResponse{} }
 func (m *GetResponse) String() string { return proto.CompactTextString(m) }
 func (*GetResponse) ProtoMessage()    {}
 func (*GetResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_1b14dcbe5169b67b, []int{13}
+	return fileDescriptor_1b14dcbe5169b67b, []int{19}
 }
 
 func (m *GetResponse) XXX_Unmarshal(b []byte) error {
@@ -614,7 +890,7 @@ func (m *ListRequest) Reset()         { *m = ListRequest{} }
 func (m *ListRequest) String() string { return proto.CompactTextString(m) }
 func (*ListRequest) ProtoMessage()    {}
 func (*ListRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_1b14dcbe5169b67b, []int{14}
+	return fileDescriptor_1b14dcbe5169b67b, []int{20}
 }
 
 func (m *ListRequest) XXX_Unmarshal(b []byte) error {
@@ -653,7 +929,7 @@ func (m *ListResponse) Reset()         { *m = ListResponse{} }
 func (m *ListResponse) String() string { return proto.CompactTextString(m) }
 func (*ListResponse) ProtoMessage()    {}
 func (*ListResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_1b14dcbe5169b67b, []int{15}
+	return fileDescriptor_1b14dcbe5169b67b, []int{21}
 }
 
 func (m *ListResponse) XXX_Unmarshal(b []byte) error {
@@ -681,132 +957,1277 @@ func (m *ListResponse) GetValues() map[string][]byte {
 	return nil
 }
 
-func init() {
-	proto.RegisterType((*ChecksumRequest)(nil), "pb.ChecksumRequest")
-	proto.RegisterType((*ChecksumReply)(nil), "pb.ChecksumReply")
-	proto.RegisterType((*DestroyRequest)(nil), "pb.DestroyRequest")
-	proto.RegisterType((*CreateOrGetTableRequest)(nil), "pb.CreateOrGetTableRequest")
-	proto.RegisterType((*DeleteTableRequest)(nil), "pb.DeleteTableRequest")
-	proto.RegisterType((*BeginTransactionRequest)(nil), "pb.BeginTransactionRequest")
-	proto.RegisterType((*BeginTransactionReply)(nil), "pb.BeginTransactionReply")
-	proto.RegisterType((*CommitTransactionRequest)(nil), "pb.CommitTransactionRequest")
-	proto.RegisterType((*RollbackTransactionRequest)(nil), "pb.RollbackTransactionRequest")
-	proto.RegisterType((*AddRequest)(nil), "pb.AddRequest")
-	proto.RegisterType((*DeleteRequest)(nil), "pb.DeleteRequest")
-	proto.RegisterType((*UpdateRequest)(nil), "pb.UpdateRequest")
-	proto.RegisterType((*GetRequest)(nil), "pb.GetRequest")
-	proto.RegisterType((*GetResponse)(nil), "pb.GetResponse")
-	proto.RegisterType((*ListRequest)(nil), "pb.ListRequest")
-	proto.RegisterType((*ListResponse)(nil), "pb.ListResponse")
-	proto.RegisterMapType((map[string][]byte)(nil), "pb.ListResponse.ValuesEntry")
+type ExpireAtRequest struct {
+	TxId                 int64    `protobuf:"varint,1,opt,name=tx_id,json=txId,proto3" json:"tx_id,omitempty"`
+	Key                  string   `protobuf:"bytes,2,opt,name=key,proto3" json:"key,omitempty"`
+	ExpireAt             int64    `protobuf:"varint,3,opt,name=expire_at,json=expireAt,proto3" json:"expire_at,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
 }
 
-func init() { proto.RegisterFile("kvserver.proto", fileDescriptor_1b14dcbe5169b67b) }
+func (m *ExpireAtRequest) Reset()         { *m = ExpireAtRequest{} }
+func (m *ExpireAtRequest) String() string { return proto.CompactTextString(m) }
+func (*ExpireAtRequest) ProtoMessage()    {}
+func (*ExpireAtRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1b14dcbe5169b67b, []int{22}
+}
 
-var fileDescriptor_1b14dcbe5169b67b = []byte{
-	// 580 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x94, 0x94, 0xdf, 0x73, 0xd2, 0x40,
-	0x10, 0xc7, 0x09, 0x29, 0xd8, 0x2e, 0xe5, 0xd7, 0xa1, 0x2d, 0xa6, 0xea, 0x30, 0xe7, 0x0b, 0x33,
-	0xd5, 0x30, 0xb6, 0x55, 0xab, 0x2f, 0x4e, 0xa5, 0x1d, 0x86, 0x29, 0x63, 0x67, 0x62, 0xed, 0x6b,
-	0x27, 0x21, 0x2b, 0x32, 0x84, 0x24, 0x26, 0x07, 0xd3, 0x3c, 0xf9, 0xbf, 0xfa, 0x97, 0x38, 0xb9,
-	0x0b, 0x4d, 0xa0, 0x21, 0x96, 0xb7, 0xdc, 0xee, 0x7e, 0xbf, 0xb7, 0x97, 0xdb, 0xcf, 0x41, 0x65,
-	0x32, 0xf7, 0xd1, 0x9b, 0xa3, 0xa7, 0xba, 0x9e, 0xc3, 0x1c, 0x92, 0x77, 0x0d, 0xe5, 0x60, 0xe4,
-	0x38, 0x23, 0x0b, 0x3b, 0x3c, 0x62, 0xcc, 0x7e, 0x76, 0x70, 0xea, 0xb2, 0x40, 0x14, 0xd0, 0x3a,
-	0x54, 0xbb, 0xbf, 0x70, 0x38, 0xf1, 0x67, 0x53, 0x0d, 0x7f, 0xcf, 0xd0, 0x67, 0xf4, 0x10, 0xca,
-	0x71, 0xc8, 0xb5, 0x02, 0xa2, 0xc0, 0xf6, 0x30, 0x0a, 0x34, 0xa5, 0x96, 0xd4, 0xde, 0xd1, 0xee,
-	0xd7, 0xb4, 0x06, 0x95, 0x73, 0xf4, 0x99, 0xe7, 0x04, 0x0b, 0xf9, 0x5b, 0xd8, 0xef, 0x7a, 0xa8,
-	0x33, 0xbc, 0xf2, 0x7a, 0xc8, 0xae, 0x75, 0xc3, 0xc2, 0x28, 0x45, 0x08, 0x6c, 0xd9, 0xfa, 0x14,
-	0x23, 0x13, 0xfe, 0x4d, 0xdb, 0x40, 0xce, 0xd1, 0x42, 0x86, 0xff, 0xad, 0x3c, 0x85, 0xfd, 0xaf,
-	0x38, 0x1a, 0xdb, 0xd7, 0x9e, 0x6e, 0xfb, 0xfa, 0x90, 0x8d, 0x1d, 0x7b, 0x51, 0xfe, 0x12, 0x80,
-	0x85, 0xf2, 0xdb, 0x84, 0x68, 0x87, 0x47, 0xbe, 0x85, 0xca, 0x37, 0xf0, 0xec, 0xa1, 0x32, 0x3c,
-	0x59, 0x03, 0x0a, 0xec, 0xee, 0x76, 0x6c, 0x72, 0x89, 0xac, 0x6d, 0xb1, 0xbb, 0xbe, 0x49, 0x3b,
-	0xd0, 0xec, 0x3a, 0xd3, 0xe9, 0x98, 0xa5, 0x6c, 0x94, 0x2a, 0x78, 0x07, 0x8a, 0xe6, 0x58, 0x96,
-	0xa1, 0x0f, 0x27, 0x8f, 0x95, 0xf4, 0x01, 0xce, 0x4c, 0x33, 0xab, 0x84, 0xd4, 0x40, 0x9e, 0x60,
-	0xd0, 0xcc, 0xf3, 0xc3, 0x84, 0x9f, 0xe4, 0x29, 0x14, 0xe6, 0xba, 0x35, 0xc3, 0xa6, 0xdc, 0x92,
-	0xda, 0xbb, 0x9a, 0x58, 0xd0, 0x0f, 0x50, 0x16, 0x3f, 0x70, 0x33, 0x37, 0x3a, 0x80, 0xf2, 0x0f,
-	0xd7, 0xd4, 0x37, 0xd5, 0xad, 0xe9, 0xe2, 0x18, 0xa0, 0x87, 0x6c, 0xc3, 0x16, 0x5e, 0x43, 0x89,
-	0x8b, 0x7c, 0xd7, 0xb1, 0x7d, 0x8c, 0x9d, 0xa5, 0xa4, 0x33, 0x85, 0xd2, 0x60, 0xec, 0x67, 0x5a,
-	0xd3, 0x3f, 0xb0, 0x2b, 0x6a, 0x22, 0xa7, 0x13, 0x28, 0x72, 0xb1, 0xdf, 0x94, 0x5a, 0x72, 0xbb,
-	0x74, 0xf4, 0x42, 0x75, 0x0d, 0x35, 0x59, 0xa1, 0xde, 0xf0, 0xf4, 0x85, 0xcd, 0xbc, 0x40, 0x8b,
-	0x6a, 0x95, 0x4f, 0x50, 0x4a, 0x84, 0x17, 0xfd, 0x4a, 0x29, 0x47, 0xcf, 0x27, 0x1a, 0xfc, 0x9c,
-	0x3f, 0x95, 0x8e, 0xfe, 0x16, 0x40, 0xbe, 0xbc, 0xf9, 0x4e, 0x4e, 0x60, 0x7b, 0xc1, 0x0e, 0x69,
-	0x84, 0x9b, 0xae, 0xc0, 0xa5, 0xd4, 0x97, 0x83, 0xae, 0x15, 0xd0, 0x1c, 0xf9, 0x08, 0x4f, 0x22,
-	0x88, 0x08, 0x09, 0xf3, 0xcb, 0x44, 0x29, 0x7b, 0xaa, 0x20, 0x58, 0x5d, 0x10, 0xac, 0x5e, 0x84,
-	0x04, 0xd3, 0x1c, 0xe9, 0x43, 0x6d, 0x95, 0x35, 0x72, 0xc0, 0x77, 0x48, 0x27, 0x30, 0xc3, 0xea,
-	0x0b, 0x94, 0x12, 0x1c, 0x92, 0x3d, 0xd1, 0xc7, 0x2a, 0x98, 0x19, 0x06, 0x03, 0xa8, 0xad, 0x42,
-	0x26, 0x7a, 0x59, 0x03, 0xad, 0xf2, 0x3c, 0x3d, 0x29, 0x7e, 0xc9, 0x25, 0xd4, 0x1f, 0x40, 0x48,
-	0xf8, 0x35, 0xae, 0x63, 0x33, 0xa3, 0xb5, 0x2b, 0x68, 0xa4, 0x00, 0x4a, 0x5e, 0x85, 0x76, 0xeb,
-	0xc9, 0xcd, 0x30, 0x6c, 0x83, 0xdc, 0x43, 0x46, 0x2a, 0xa1, 0x41, 0x3c, 0xf6, 0x4a, 0xf5, 0x7e,
-	0x2d, 0xa6, 0x8c, 0xe6, 0xc8, 0x21, 0x6c, 0x85, 0x73, 0x47, 0xaa, 0xf1, 0x04, 0x8a, 0xda, 0xda,
-	0xea, 0x48, 0xd2, 0x1c, 0xe9, 0x80, 0x7c, 0x66, 0x9a, 0xc2, 0x36, 0x7e, 0x1e, 0x32, 0xfa, 0x78,
-	0x0f, 0x45, 0x71, 0x47, 0xa4, 0x1e, 0xdf, 0xd7, 0xa3, 0x64, 0x02, 0x7d, 0x21, 0x5b, 0x7a, 0x06,
-	0xd6, 0xcb, 0x8c, 0x22, 0x8f, 0x1c, 0xff, 0x0b, 0x00, 0x00, 0xff, 0xff, 0x05, 0xf8, 0xe3, 0x0b,
-	0x65, 0x06, 0x00, 0x00,
+func (m *ExpireAtRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ExpireAtRequest.Unmarshal(m, b)
+}
+func (m *ExpireAtRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ExpireAtRequest.Marshal(b, m, deterministic)
+}
+func (m *ExpireAtRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ExpireAtRequest.Merge(m, src)
+}
+func (m *ExpireAtRequest) XXX_Size() int {
+	return xxx_messageInfo_ExpireAtRequest.Size(m)
+}
+func (m *ExpireAtRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ExpireAtRequest.DiscardUnknown(m)
 }
 
-// Reference imports to suppress errors if they are not otherwise used.
-var _ context.Context
-var _ grpc.ClientConn
+var xxx_messageInfo_ExpireAtRequest proto.InternalMessageInfo
 
-// This is a compile-time assertion to ensure that this generated file
-// is compatible with the grpc package it is being compiled against.
-const _ = grpc.SupportPackageIsVersion4
+func (m *ExpireAtRequest) GetTxId() int64 {
+	if m != nil {
+		return m.TxId
+	}
+	return 0
+}
 
-// KVSClient is the client API for KVS service.
-//
-// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
-type KVSClient interface {
-	Checksum(ctx context.Context, in *ChecksumRequest, opts ...grpc.CallOption) (*ChecksumReply, error)
-	Destroy(ctx context.Context, in *DestroyRequest, opts ...grpc.CallOption) (*empty.Empty, error)
-	CreateOrGetTable(ctx context.Context, in *CreateOrGetTableRequest, opts ...grpc.CallOption) (*empty.Empty, error)
-	DeleteTable(ctx context.Context, in *DeleteTableRequest, opts ...grpc.CallOption) (*empty.Empty, error)
-	BeginTransaction(ctx context.Context, in *BeginTransactionRequest, opts ...grpc.CallOption) (*BeginTransactionReply, error)
-	CommitTransaction(ctx context.Context, in *CommitTransactionRequest, opts ...grpc.CallOption) (*empty.Empty, error)
-	RollbackTransaction(ctx context.Context, in *RollbackTransactionRequest, opts ...grpc.CallOption) (*empty.Empty, error)
-	Get(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*GetResponse, error)
-	List(ctx context.Context, in *ListRequest, opts ...grpc.CallOption) (*ListResponse, error)
-	Add(ctx context.Context, in *AddRequest, opts ...grpc.CallOption) (*empty.Empty, error)
-	Delete(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*empty.Empty, error)
-	Update(ctx context.Context, in *UpdateRequest, opts ...grpc.CallOption) (*empty.Empty, error)
+func (m *ExpireAtRequest) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
 }
 
-type kVSClient struct {
-	cc *grpc.ClientConn
+func (m *ExpireAtRequest) GetExpireAt() int64 {
+	if m != nil {
+		return m.ExpireAt
+	}
+	return 0
 }
 
-func NewKVSClient(cc *grpc.ClientConn) KVSClient {
-	return &kVSClient{cc}
+type CompareAndSwapRequest struct {
+	TxId                 int64    `protobuf:"varint,1,opt,name=tx_id,json=txId,proto3" json:"tx_id,omitempty"`
+	Key                  string   `protobuf:"bytes,2,opt,name=key,proto3" json:"key,omitempty"`
+	OldValue             []byte   `protobuf:"bytes,3,opt,name=old_value,json=oldValue,proto3" json:"old_value,omitempty"`
+	NewValue             []byte   `protobuf:"bytes,4,opt,name=new_value,json=newValue,proto3" json:"new_value,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
 }
 
-func (c *kVSClient) Checksum(ctx context.Context, in *ChecksumRequest, opts ...grpc.CallOption) (*ChecksumReply, error) {
-	out := new(ChecksumReply)
-	err := c.cc.Invoke(ctx, "/pb.KVS/Checksum", in, out, opts...)
-	if err != nil {
-		return nil, err
-	}
-	return out, nil
+func (m *CompareAndSwapRequest) Reset()         { *m = CompareAndSwapRequest{} }
+func (m *CompareAndSwapRequest) String() string { return proto.CompactTextString(m) }
+func (*CompareAndSwapRequest) ProtoMessage()    {}
+func (*CompareAndSwapRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1b14dcbe5169b67b, []int{23}
 }
 
-func (c *kVSClient) Destroy(ctx context.Context, in *DestroyRequest, opts ...grpc.CallOption) (*empty.Empty, error) {
-	out := new(empty.Empty)
-	err := c.cc.Invoke(ctx, "/pb.KVS/Destroy", in, out, opts...)
-	if err != nil {
-		return nil, err
-	}
-	return out, nil
+func (m *CompareAndSwapRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CompareAndSwapRequest.Unmarshal(m, b)
+}
+func (m *CompareAndSwapRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CompareAndSwapRequest.Marshal(b, m, deterministic)
+}
+func (m *CompareAndSwapRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CompareAndSwapRequest.Merge(m, src)
+}
+func (m *CompareAndSwapRequest) XXX_Size() int {
+	return xxx_messageInfo_CompareAndSwapRequest.Size(m)
+}
+func (m *CompareAndSwapRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_CompareAndSwapRequest.DiscardUnknown(m)
 }
 
-func (c *kVSClient) CreateOrGetTable(ctx context.Context, in *CreateOrGetTableRequest, opts ...grpc.CallOption) (*empty.Empty, error) {
-	out := new(empty.Empty)
-	err := c.cc.Invoke(ctx, "/pb.KVS/CreateOrGetTable", in, out, opts...)
-	if err != nil {
-		return nil, err
+var xxx_messageInfo_CompareAndSwapRequest proto.InternalMessageInfo
+
+func (m *CompareAndSwapRequest) GetTxId() int64 {
+	if m != nil {
+		return m.TxId
 	}
-	return out, nil
+	return 0
 }
 
-func (c *kVSClient) DeleteTable(ctx context.Context, in *DeleteTableRequest, opts ...grpc.CallOption) (*empty.Empty, error) {
+func (m *CompareAndSwapRequest) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+func (m *CompareAndSwapRequest) GetOldValue() []byte {
+	if m != nil {
+		return m.OldValue
+	}
+	return nil
+}
+
+func (m *CompareAndSwapRequest) GetNewValue() []byte {
+	if m != nil {
+		return m.NewValue
+	}
+	return nil
+}
+
+type PutRequest struct {
+	TxId                 int64    `protobuf:"varint,1,opt,name=tx_id,json=txId,proto3" json:"tx_id,omitempty"`
+	Key                  string   `protobuf:"bytes,2,opt,name=key,proto3" json:"key,omitempty"`
+	Value                []byte   `protobuf:"bytes,3,opt,name=value,proto3" json:"value,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *PutRequest) Reset()         { *m = PutRequest{} }
+func (m *PutRequest) String() string { return proto.CompactTextString(m) }
+func (*PutRequest) ProtoMessage()    {}
+func (*PutRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1b14dcbe5169b67b, []int{24}
+}
+
+func (m *PutRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PutRequest.Unmarshal(m, b)
+}
+func (m *PutRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PutRequest.Marshal(b, m, deterministic)
+}
+func (m *PutRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PutRequest.Merge(m, src)
+}
+func (m *PutRequest) XXX_Size() int {
+	return xxx_messageInfo_PutRequest.Size(m)
+}
+func (m *PutRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_PutRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PutRequest proto.InternalMessageInfo
+
+func (m *PutRequest) GetTxId() int64 {
+	if m != nil {
+		return m.TxId
+	}
+	return 0
+}
+
+func (m *PutRequest) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+func (m *PutRequest) GetValue() []byte {
+	if m != nil {
+		return m.Value
+	}
+	return nil
+}
+
+type DeleteExpiredRequest struct {
+	TxId                 int64    `protobuf:"varint,1,opt,name=tx_id,json=txId,proto3" json:"tx_id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DeleteExpiredRequest) Reset()         { *m = DeleteExpiredRequest{} }
+func (m *DeleteExpiredRequest) String() string { return proto.CompactTextString(m) }
+func (*DeleteExpiredRequest) ProtoMessage()    {}
+func (*DeleteExpiredRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1b14dcbe5169b67b, []int{25}
+}
+
+func (m *DeleteExpiredRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DeleteExpiredRequest.Unmarshal(m, b)
+}
+func (m *DeleteExpiredRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DeleteExpiredRequest.Marshal(b, m, deterministic)
+}
+func (m *DeleteExpiredRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DeleteExpiredRequest.Merge(m, src)
+}
+func (m *DeleteExpiredRequest) XXX_Size() int {
+	return xxx_messageInfo_DeleteExpiredRequest.Size(m)
+}
+func (m *DeleteExpiredRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_DeleteExpiredRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DeleteExpiredRequest proto.InternalMessageInfo
+
+func (m *DeleteExpiredRequest) GetTxId() int64 {
+	if m != nil {
+		return m.TxId
+	}
+	return 0
+}
+
+type DeleteExpiredReply struct {
+	Keys                 []string `protobuf:"bytes,1,rep,name=keys,proto3" json:"keys,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DeleteExpiredReply) Reset()         { *m = DeleteExpiredReply{} }
+func (m *DeleteExpiredReply) String() string { return proto.CompactTextString(m) }
+func (*DeleteExpiredReply) ProtoMessage()    {}
+func (*DeleteExpiredReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1b14dcbe5169b67b, []int{26}
+}
+
+func (m *DeleteExpiredReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DeleteExpiredReply.Unmarshal(m, b)
+}
+func (m *DeleteExpiredReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DeleteExpiredReply.Marshal(b, m, deterministic)
+}
+func (m *DeleteExpiredReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DeleteExpiredReply.Merge(m, src)
+}
+func (m *DeleteExpiredReply) XXX_Size() int {
+	return xxx_messageInfo_DeleteExpiredReply.Size(m)
+}
+func (m *DeleteExpiredReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_DeleteExpiredReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DeleteExpiredReply proto.InternalMessageInfo
+
+func (m *DeleteExpiredReply) GetKeys() []string {
+	if m != nil {
+		return m.Keys
+	}
+	return nil
+}
+
+type ScanRequest struct {
+	TxId                 int64    `protobuf:"varint,1,opt,name=tx_id,json=txId,proto3" json:"tx_id,omitempty"`
+	Prefix               string   `protobuf:"bytes,2,opt,name=prefix,proto3" json:"prefix,omitempty"`
+	Start                string   `protobuf:"bytes,3,opt,name=start,proto3" json:"start,omitempty"`
+	End                  string   `protobuf:"bytes,4,opt,name=end,proto3" json:"end,omitempty"`
+	Limit                uint32   `protobuf:"varint,5,opt,name=limit,proto3" json:"limit,omitempty"`
+	Cursor               string   `protobuf:"bytes,6,opt,name=cursor,proto3" json:"cursor,omitempty"`
+	Reverse              bool     `protobuf:"varint,7,opt,name=reverse,proto3" json:"reverse,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ScanRequest) Reset()         { *m = ScanRequest{} }
+func (m *ScanRequest) String() string { return proto.CompactTextString(m) }
+func (*ScanRequest) ProtoMessage()    {}
+func (*ScanRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1b14dcbe5169b67b, []int{27}
+}
+
+func (m *ScanRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ScanRequest.Unmarshal(m, b)
+}
+func (m *ScanRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ScanRequest.Marshal(b, m, deterministic)
+}
+func (m *ScanRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ScanRequest.Merge(m, src)
+}
+func (m *ScanRequest) XXX_Size() int {
+	return xxx_messageInfo_ScanRequest.Size(m)
+}
+func (m *ScanRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ScanRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ScanRequest proto.InternalMessageInfo
+
+func (m *ScanRequest) GetTxId() int64 {
+	if m != nil {
+		return m.TxId
+	}
+	return 0
+}
+
+func (m *ScanRequest) GetPrefix() string {
+	if m != nil {
+		return m.Prefix
+	}
+	return ""
+}
+
+func (m *ScanRequest) GetStart() string {
+	if m != nil {
+		return m.Start
+	}
+	return ""
+}
+
+func (m *ScanRequest) GetEnd() string {
+	if m != nil {
+		return m.End
+	}
+	return ""
+}
+
+func (m *ScanRequest) GetLimit() uint32 {
+	if m != nil {
+		return m.Limit
+	}
+	return 0
+}
+
+func (m *ScanRequest) GetCursor() string {
+	if m != nil {
+		return m.Cursor
+	}
+	return ""
+}
+
+func (m *ScanRequest) GetReverse() bool {
+	if m != nil {
+		return m.Reverse
+	}
+	return false
+}
+
+type KeyValue struct {
+	Key                  string   `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Value                []byte   `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *KeyValue) Reset()         { *m = KeyValue{} }
+func (m *KeyValue) String() string { return proto.CompactTextString(m) }
+func (*KeyValue) ProtoMessage()    {}
+func (*KeyValue) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1b14dcbe5169b67b, []int{28}
+}
+
+func (m *KeyValue) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_KeyValue.Unmarshal(m, b)
+}
+func (m *KeyValue) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_KeyValue.Marshal(b, m, deterministic)
+}
+func (m *KeyValue) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_KeyValue.Merge(m, src)
+}
+func (m *KeyValue) XXX_Size() int {
+	return xxx_messageInfo_KeyValue.Size(m)
+}
+func (m *KeyValue) XXX_DiscardUnknown() {
+	xxx_messageInfo_KeyValue.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_KeyValue proto.InternalMessageInfo
+
+func (m *KeyValue) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+func (m *KeyValue) GetValue() []byte {
+	if m != nil {
+		return m.Value
+	}
+	return nil
+}
+
+type ScanResponse struct {
+	Values               []*KeyValue `protobuf:"bytes,1,rep,name=values,proto3" json:"values,omitempty"`
+	NextCursor           string      `protobuf:"bytes,2,opt,name=next_cursor,json=nextCursor,proto3" json:"next_cursor,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}    `json:"-"`
+	XXX_unrecognized     []byte      `json:"-"`
+	XXX_sizecache        int32       `json:"-"`
+}
+
+func (m *ScanResponse) Reset()         { *m = ScanResponse{} }
+func (m *ScanResponse) String() string { return proto.CompactTextString(m) }
+func (*ScanResponse) ProtoMessage()    {}
+func (*ScanResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1b14dcbe5169b67b, []int{29}
+}
+
+func (m *ScanResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ScanResponse.Unmarshal(m, b)
+}
+func (m *ScanResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ScanResponse.Marshal(b, m, deterministic)
+}
+func (m *ScanResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ScanResponse.Merge(m, src)
+}
+func (m *ScanResponse) XXX_Size() int {
+	return xxx_messageInfo_ScanResponse.Size(m)
+}
+func (m *ScanResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ScanResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ScanResponse proto.InternalMessageInfo
+
+func (m *ScanResponse) GetValues() []*KeyValue {
+	if m != nil {
+		return m.Values
+	}
+	return nil
+}
+
+func (m *ScanResponse) GetNextCursor() string {
+	if m != nil {
+		return m.NextCursor
+	}
+	return ""
+}
+
+type Operation struct {
+	Type                 OperationType `protobuf:"varint,1,opt,name=type,proto3,enum=pb.OperationType" json:"type,omitempty"`
+	Key                  string        `protobuf:"bytes,2,opt,name=key,proto3" json:"key,omitempty"`
+	Value                []byte        `protobuf:"bytes,3,opt,name=value,proto3" json:"value,omitempty"`
+	ExpireAt             int64         `protobuf:"varint,4,opt,name=expire_at,json=expireAt,proto3" json:"expire_at,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}      `json:"-"`
+	XXX_unrecognized     []byte        `json:"-"`
+	XXX_sizecache        int32         `json:"-"`
+}
+
+func (m *Operation) Reset()         { *m = Operation{} }
+func (m *Operation) String() string { return proto.CompactTextString(m) }
+func (*Operation) ProtoMessage()    {}
+func (*Operation) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1b14dcbe5169b67b, []int{30}
+}
+
+func (m *Operation) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Operation.Unmarshal(m, b)
+}
+func (m *Operation) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Operation.Marshal(b, m, deterministic)
+}
+func (m *Operation) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Operation.Merge(m, src)
+}
+func (m *Operation) XXX_Size() int {
+	return xxx_messageInfo_Operation.Size(m)
+}
+func (m *Operation) XXX_DiscardUnknown() {
+	xxx_messageInfo_Operation.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Operation proto.InternalMessageInfo
+
+func (m *Operation) GetType() OperationType {
+	if m != nil {
+		return m.Type
+	}
+	return OperationType_ADD
+}
+
+func (m *Operation) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+func (m *Operation) GetValue() []byte {
+	if m != nil {
+		return m.Value
+	}
+	return nil
+}
+
+func (m *Operation) GetExpireAt() int64 {
+	if m != nil {
+		return m.ExpireAt
+	}
+	return 0
+}
+
+type LogEntry struct {
+	Index                uint64       `protobuf:"varint,1,opt,name=index,proto3" json:"index,omitempty"`
+	TableName            string       `protobuf:"bytes,2,opt,name=table_name,json=tableName,proto3" json:"table_name,omitempty"`
+	Operations           []*Operation `protobuf:"bytes,3,rep,name=operations,proto3" json:"operations,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}     `json:"-"`
+	XXX_unrecognized     []byte       `json:"-"`
+	XXX_sizecache        int32        `json:"-"`
+}
+
+func (m *LogEntry) Reset()         { *m = LogEntry{} }
+func (m *LogEntry) String() string { return proto.CompactTextString(m) }
+func (*LogEntry) ProtoMessage()    {}
+func (*LogEntry) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1b14dcbe5169b67b, []int{31}
+}
+
+func (m *LogEntry) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_LogEntry.Unmarshal(m, b)
+}
+func (m *LogEntry) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_LogEntry.Marshal(b, m, deterministic)
+}
+func (m *LogEntry) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_LogEntry.Merge(m, src)
+}
+func (m *LogEntry) XXX_Size() int {
+	return xxx_messageInfo_LogEntry.Size(m)
+}
+func (m *LogEntry) XXX_DiscardUnknown() {
+	xxx_messageInfo_LogEntry.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_LogEntry proto.InternalMessageInfo
+
+func (m *LogEntry) GetIndex() uint64 {
+	if m != nil {
+		return m.Index
+	}
+	return 0
+}
+
+func (m *LogEntry) GetTableName() string {
+	if m != nil {
+		return m.TableName
+	}
+	return ""
+}
+
+func (m *LogEntry) GetOperations() []*Operation {
+	if m != nil {
+		return m.Operations
+	}
+	return nil
+}
+
+type LastLogIndexRequest struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *LastLogIndexRequest) Reset()         { *m = LastLogIndexRequest{} }
+func (m *LastLogIndexRequest) String() string { return proto.CompactTextString(m) }
+func (*LastLogIndexRequest) ProtoMessage()    {}
+func (*LastLogIndexRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1b14dcbe5169b67b, []int{32}
+}
+
+func (m *LastLogIndexRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_LastLogIndexRequest.Unmarshal(m, b)
+}
+func (m *LastLogIndexRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_LastLogIndexRequest.Marshal(b, m, deterministic)
+}
+func (m *LastLogIndexRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_LastLogIndexRequest.Merge(m, src)
+}
+func (m *LastLogIndexRequest) XXX_Size() int {
+	return xxx_messageInfo_LastLogIndexRequest.Size(m)
+}
+func (m *LastLogIndexRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_LastLogIndexRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_LastLogIndexRequest proto.InternalMessageInfo
+
+type LastLogIndexReply struct {
+	Index                uint64   `protobuf:"varint,1,opt,name=index,proto3" json:"index,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *LastLogIndexReply) Reset()         { *m = LastLogIndexReply{} }
+func (m *LastLogIndexReply) String() string { return proto.CompactTextString(m) }
+func (*LastLogIndexReply) ProtoMessage()    {}
+func (*LastLogIndexReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1b14dcbe5169b67b, []int{33}
+}
+
+func (m *LastLogIndexReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_LastLogIndexReply.Unmarshal(m, b)
+}
+func (m *LastLogIndexReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_LastLogIndexReply.Marshal(b, m, deterministic)
+}
+func (m *LastLogIndexReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_LastLogIndexReply.Merge(m, src)
+}
+func (m *LastLogIndexReply) XXX_Size() int {
+	return xxx_messageInfo_LastLogIndexReply.Size(m)
+}
+func (m *LastLogIndexReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_LastLogIndexReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_LastLogIndexReply proto.InternalMessageInfo
+
+func (m *LastLogIndexReply) GetIndex() uint64 {
+	if m != nil {
+		return m.Index
+	}
+	return 0
+}
+
+type GetLogEntriesRequest struct {
+	Since                uint64   `protobuf:"varint,1,opt,name=since,proto3" json:"since,omitempty"`
+	Limit                uint32   `protobuf:"varint,2,opt,name=limit,proto3" json:"limit,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetLogEntriesRequest) Reset()         { *m = GetLogEntriesRequest{} }
+func (m *GetLogEntriesRequest) String() string { return proto.CompactTextString(m) }
+func (*GetLogEntriesRequest) ProtoMessage()    {}
+func (*GetLogEntriesRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1b14dcbe5169b67b, []int{34}
+}
+
+func (m *GetLogEntriesRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetLogEntriesRequest.Unmarshal(m, b)
+}
+func (m *GetLogEntriesRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetLogEntriesRequest.Marshal(b, m, deterministic)
+}
+func (m *GetLogEntriesRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetLogEntriesRequest.Merge(m, src)
+}
+func (m *GetLogEntriesRequest) XXX_Size() int {
+	return xxx_messageInfo_GetLogEntriesRequest.Size(m)
+}
+func (m *GetLogEntriesRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetLogEntriesRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetLogEntriesRequest proto.InternalMessageInfo
+
+func (m *GetLogEntriesRequest) GetSince() uint64 {
+	if m != nil {
+		return m.Since
+	}
+	return 0
+}
+
+func (m *GetLogEntriesRequest) GetLimit() uint32 {
+	if m != nil {
+		return m.Limit
+	}
+	return 0
+}
+
+type GetLogEntriesReply struct {
+	Entries              []*LogEntry `protobuf:"bytes,1,rep,name=entries,proto3" json:"entries,omitempty"`
+	LastIndex            uint64      `protobuf:"varint,2,opt,name=last_index,json=lastIndex,proto3" json:"last_index,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}    `json:"-"`
+	XXX_unrecognized     []byte      `json:"-"`
+	XXX_sizecache        int32       `json:"-"`
+}
+
+func (m *GetLogEntriesReply) Reset()         { *m = GetLogEntriesReply{} }
+func (m *GetLogEntriesReply) String() string { return proto.CompactTextString(m) }
+func (*GetLogEntriesReply) ProtoMessage()    {}
+func (*GetLogEntriesReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1b14dcbe5169b67b, []int{35}
+}
+
+func (m *GetLogEntriesReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetLogEntriesReply.Unmarshal(m, b)
+}
+func (m *GetLogEntriesReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetLogEntriesReply.Marshal(b, m, deterministic)
+}
+func (m *GetLogEntriesReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetLogEntriesReply.Merge(m, src)
+}
+func (m *GetLogEntriesReply) XXX_Size() int {
+	return xxx_messageInfo_GetLogEntriesReply.Size(m)
+}
+func (m *GetLogEntriesReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetLogEntriesReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetLogEntriesReply proto.InternalMessageInfo
+
+func (m *GetLogEntriesReply) GetEntries() []*LogEntry {
+	if m != nil {
+		return m.Entries
+	}
+	return nil
+}
+
+func (m *GetLogEntriesReply) GetLastIndex() uint64 {
+	if m != nil {
+		return m.LastIndex
+	}
+	return 0
+}
+
+type ReplayLogEntriesRequest struct {
+	Entries              []*LogEntry `protobuf:"bytes,1,rep,name=entries,proto3" json:"entries,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}    `json:"-"`
+	XXX_unrecognized     []byte      `json:"-"`
+	XXX_sizecache        int32       `json:"-"`
+}
+
+func (m *ReplayLogEntriesRequest) Reset()         { *m = ReplayLogEntriesRequest{} }
+func (m *ReplayLogEntriesRequest) String() string { return proto.CompactTextString(m) }
+func (*ReplayLogEntriesRequest) ProtoMessage()    {}
+func (*ReplayLogEntriesRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1b14dcbe5169b67b, []int{36}
+}
+
+func (m *ReplayLogEntriesRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ReplayLogEntriesRequest.Unmarshal(m, b)
+}
+func (m *ReplayLogEntriesRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ReplayLogEntriesRequest.Marshal(b, m, deterministic)
+}
+func (m *ReplayLogEntriesRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ReplayLogEntriesRequest.Merge(m, src)
+}
+func (m *ReplayLogEntriesRequest) XXX_Size() int {
+	return xxx_messageInfo_ReplayLogEntriesRequest.Size(m)
+}
+func (m *ReplayLogEntriesRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ReplayLogEntriesRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ReplayLogEntriesRequest proto.InternalMessageInfo
+
+func (m *ReplayLogEntriesRequest) GetEntries() []*LogEntry {
+	if m != nil {
+		return m.Entries
+	}
+	return nil
+}
+
+type ResetLogRequest struct {
+	Index                uint64   `protobuf:"varint,1,opt,name=index,proto3" json:"index,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ResetLogRequest) Reset()         { *m = ResetLogRequest{} }
+func (m *ResetLogRequest) String() string { return proto.CompactTextString(m) }
+func (*ResetLogRequest) ProtoMessage()    {}
+func (*ResetLogRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1b14dcbe5169b67b, []int{37}
+}
+
+func (m *ResetLogRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ResetLogRequest.Unmarshal(m, b)
+}
+func (m *ResetLogRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ResetLogRequest.Marshal(b, m, deterministic)
+}
+func (m *ResetLogRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ResetLogRequest.Merge(m, src)
+}
+func (m *ResetLogRequest) XXX_Size() int {
+	return xxx_messageInfo_ResetLogRequest.Size(m)
+}
+func (m *ResetLogRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ResetLogRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ResetLogRequest proto.InternalMessageInfo
+
+func (m *ResetLogRequest) GetIndex() uint64 {
+	if m != nil {
+		return m.Index
+	}
+	return 0
+}
+
+type GetEpochRequest struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetEpochRequest) Reset()         { *m = GetEpochRequest{} }
+func (m *GetEpochRequest) String() string { return proto.CompactTextString(m) }
+func (*GetEpochRequest) ProtoMessage()    {}
+func (*GetEpochRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1b14dcbe5169b67b, []int{38}
+}
+
+func (m *GetEpochRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetEpochRequest.Unmarshal(m, b)
+}
+func (m *GetEpochRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetEpochRequest.Marshal(b, m, deterministic)
+}
+func (m *GetEpochRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetEpochRequest.Merge(m, src)
+}
+func (m *GetEpochRequest) XXX_Size() int {
+	return xxx_messageInfo_GetEpochRequest.Size(m)
+}
+func (m *GetEpochRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetEpochRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetEpochRequest proto.InternalMessageInfo
+
+type GetEpochReply struct {
+	Epoch                uint64   `protobuf:"varint,1,opt,name=epoch,proto3" json:"epoch,omitempty"`
+	Master               string   `protobuf:"bytes,2,opt,name=master,proto3" json:"master,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetEpochReply) Reset()         { *m = GetEpochReply{} }
+func (m *GetEpochReply) String() string { return proto.CompactTextString(m) }
+func (*GetEpochReply) ProtoMessage()    {}
+func (*GetEpochReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1b14dcbe5169b67b, []int{39}
+}
+
+func (m *GetEpochReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetEpochReply.Unmarshal(m, b)
+}
+func (m *GetEpochReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetEpochReply.Marshal(b, m, deterministic)
+}
+func (m *GetEpochReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetEpochReply.Merge(m, src)
+}
+func (m *GetEpochReply) XXX_Size() int {
+	return xxx_messageInfo_GetEpochReply.Size(m)
+}
+func (m *GetEpochReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetEpochReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetEpochReply proto.InternalMessageInfo
+
+func (m *GetEpochReply) GetEpoch() uint64 {
+	if m != nil {
+		return m.Epoch
+	}
+	return 0
+}
+
+func (m *GetEpochReply) GetMaster() string {
+	if m != nil {
+		return m.Master
+	}
+	return ""
+}
+
+type SetEpochRequest struct {
+	Epoch                uint64   `protobuf:"varint,1,opt,name=epoch,proto3" json:"epoch,omitempty"`
+	Master               string   `protobuf:"bytes,2,opt,name=master,proto3" json:"master,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SetEpochRequest) Reset()         { *m = SetEpochRequest{} }
+func (m *SetEpochRequest) String() string { return proto.CompactTextString(m) }
+func (*SetEpochRequest) ProtoMessage()    {}
+func (*SetEpochRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1b14dcbe5169b67b, []int{40}
+}
+
+func (m *SetEpochRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SetEpochRequest.Unmarshal(m, b)
+}
+func (m *SetEpochRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SetEpochRequest.Marshal(b, m, deterministic)
+}
+func (m *SetEpochRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SetEpochRequest.Merge(m, src)
+}
+func (m *SetEpochRequest) XXX_Size() int {
+	return xxx_messageInfo_SetEpochRequest.Size(m)
+}
+func (m *SetEpochRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_SetEpochRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SetEpochRequest proto.InternalMessageInfo
+
+func (m *SetEpochRequest) GetEpoch() uint64 {
+	if m != nil {
+		return m.Epoch
+	}
+	return 0
+}
+
+func (m *SetEpochRequest) GetMaster() string {
+	if m != nil {
+		return m.Master
+	}
+	return ""
+}
+
+type SnapshotRequest struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SnapshotRequest) Reset()         { *m = SnapshotRequest{} }
+func (m *SnapshotRequest) String() string { return proto.CompactTextString(m) }
+func (*SnapshotRequest) ProtoMessage()    {}
+func (*SnapshotRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1b14dcbe5169b67b, []int{41}
+}
+
+func (m *SnapshotRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SnapshotRequest.Unmarshal(m, b)
+}
+func (m *SnapshotRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SnapshotRequest.Marshal(b, m, deterministic)
+}
+func (m *SnapshotRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SnapshotRequest.Merge(m, src)
+}
+func (m *SnapshotRequest) XXX_Size() int {
+	return xxx_messageInfo_SnapshotRequest.Size(m)
+}
+func (m *SnapshotRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_SnapshotRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SnapshotRequest proto.InternalMessageInfo
+
+type SnapshotChunk struct {
+	Data                 []byte   `protobuf:"bytes,1,opt,name=data,proto3" json:"data,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SnapshotChunk) Reset()         { *m = SnapshotChunk{} }
+func (m *SnapshotChunk) String() string { return proto.CompactTextString(m) }
+func (*SnapshotChunk) ProtoMessage()    {}
+func (*SnapshotChunk) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1b14dcbe5169b67b, []int{42}
+}
+
+func (m *SnapshotChunk) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SnapshotChunk.Unmarshal(m, b)
+}
+func (m *SnapshotChunk) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SnapshotChunk.Marshal(b, m, deterministic)
+}
+func (m *SnapshotChunk) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SnapshotChunk.Merge(m, src)
+}
+func (m *SnapshotChunk) XXX_Size() int {
+	return xxx_messageInfo_SnapshotChunk.Size(m)
+}
+func (m *SnapshotChunk) XXX_DiscardUnknown() {
+	xxx_messageInfo_SnapshotChunk.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SnapshotChunk proto.InternalMessageInfo
+
+func (m *SnapshotChunk) GetData() []byte {
+	if m != nil {
+		return m.Data
+	}
+	return nil
+}
+
+type WatchRequest struct {
+	TableName            string   `protobuf:"bytes,1,opt,name=table_name,json=tableName,proto3" json:"table_name,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *WatchRequest) Reset()         { *m = WatchRequest{} }
+func (m *WatchRequest) String() string { return proto.CompactTextString(m) }
+func (*WatchRequest) ProtoMessage()    {}
+func (*WatchRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1b14dcbe5169b67b, []int{43}
+}
+
+func (m *WatchRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_WatchRequest.Unmarshal(m, b)
+}
+func (m *WatchRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_WatchRequest.Marshal(b, m, deterministic)
+}
+func (m *WatchRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_WatchRequest.Merge(m, src)
+}
+func (m *WatchRequest) XXX_Size() int {
+	return xxx_messageInfo_WatchRequest.Size(m)
+}
+func (m *WatchRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_WatchRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_WatchRequest proto.InternalMessageInfo
+
+func (m *WatchRequest) GetTableName() string {
+	if m != nil {
+		return m.TableName
+	}
+	return ""
+}
+
+func init() {
+	proto.RegisterEnum("pb.OperationType", OperationType_name, OperationType_value)
+	proto.RegisterType((*ChecksumRequest)(nil), "pb.ChecksumRequest")
+	proto.RegisterType((*ChecksumReply)(nil), "pb.ChecksumReply")
+	proto.RegisterType((*TableChecksumsRequest)(nil), "pb.TableChecksumsRequest")
+	proto.RegisterType((*TableChecksumsReply)(nil), "pb.TableChecksumsReply")
+	proto.RegisterMapType((map[string]string)(nil), "pb.TableChecksumsReply.ChecksumsEntry")
+	proto.RegisterType((*TableNamesRequest)(nil), "pb.TableNamesRequest")
+	proto.RegisterType((*TableNamesReply)(nil), "pb.TableNamesReply")
+	proto.RegisterType((*DestroyRequest)(nil), "pb.DestroyRequest")
+	proto.RegisterType((*CreateOrGetTableRequest)(nil), "pb.CreateOrGetTableRequest")
+	proto.RegisterType((*DeleteTableRequest)(nil), "pb.DeleteTableRequest")
+	proto.RegisterType((*DeleteTableReply)(nil), "pb.DeleteTableReply")
+	proto.RegisterType((*BeginTransactionRequest)(nil), "pb.BeginTransactionRequest")
+	proto.RegisterType((*BeginTransactionReply)(nil), "pb.BeginTransactionReply")
+	proto.RegisterType((*CommitTransactionRequest)(nil), "pb.CommitTransactionRequest")
+	proto.RegisterType((*CommitTransactionReply)(nil), "pb.CommitTransactionReply")
+	proto.RegisterType((*RollbackTransactionRequest)(nil), "pb.RollbackTransactionRequest")
+	proto.RegisterType((*AddRequest)(nil), "pb.AddRequest")
+	proto.RegisterType((*DeleteRequest)(nil), "pb.DeleteRequest")
+	proto.RegisterType((*UpdateRequest)(nil), "pb.UpdateRequest")
+	proto.RegisterType((*GetRequest)(nil), "pb.GetRequest")
+	proto.RegisterType((*GetResponse)(nil), "pb.GetResponse")
+	proto.RegisterType((*ListRequest)(nil), "pb.ListRequest")
+	proto.RegisterType((*ListResponse)(nil), "pb.ListResponse")
+	proto.RegisterMapType((map[string][]byte)(nil), "pb.ListResponse.ValuesEntry")
+	proto.RegisterType((*ExpireAtRequest)(nil), "pb.ExpireAtRequest")
+	proto.RegisterType((*CompareAndSwapRequest)(nil), "pb.CompareAndSwapRequest")
+	proto.RegisterType((*PutRequest)(nil), "pb.PutRequest")
+	proto.RegisterType((*DeleteExpiredRequest)(nil), "pb.DeleteExpiredRequest")
+	proto.RegisterType((*DeleteExpiredReply)(nil), "pb.DeleteExpiredReply")
+	proto.RegisterType((*ScanRequest)(nil), "pb.ScanRequest")
+	proto.RegisterType((*KeyValue)(nil), "pb.KeyValue")
+	proto.RegisterType((*ScanResponse)(nil), "pb.ScanResponse")
+	proto.RegisterType((*Operation)(nil), "pb.Operation")
+	proto.RegisterType((*LogEntry)(nil), "pb.LogEntry")
+	proto.RegisterType((*LastLogIndexRequest)(nil), "pb.LastLogIndexRequest")
+	proto.RegisterType((*LastLogIndexReply)(nil), "pb.LastLogIndexReply")
+	proto.RegisterType((*GetLogEntriesRequest)(nil), "pb.GetLogEntriesRequest")
+	proto.RegisterType((*GetLogEntriesReply)(nil), "pb.GetLogEntriesReply")
+	proto.RegisterType((*ReplayLogEntriesRequest)(nil), "pb.ReplayLogEntriesRequest")
+	proto.RegisterType((*ResetLogRequest)(nil), "pb.ResetLogRequest")
+	proto.RegisterType((*GetEpochRequest)(nil), "pb.GetEpochRequest")
+	proto.RegisterType((*GetEpochReply)(nil), "pb.GetEpochReply")
+	proto.RegisterType((*SetEpochRequest)(nil), "pb.SetEpochRequest")
+	proto.RegisterType((*SnapshotRequest)(nil), "pb.SnapshotRequest")
+	proto.RegisterType((*SnapshotChunk)(nil), "pb.SnapshotChunk")
+	proto.RegisterType((*WatchRequest)(nil), "pb.WatchRequest")
+}
+
+func init() { proto.RegisterFile("kvserver.proto", fileDescriptor_1b14dcbe5169b67b) }
+
+var fileDescriptor_1b14dcbe5169b67b = []byte{
+	// 1457 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x56, 0xe9, 0x6e, 0xdb, 0xc6,
+	0x13, 0xb7, 0x0e, 0xdb, 0xd2, 0xe8, 0xa2, 0x56, 0x3e, 0x14, 0xe6, 0x7f, 0x18, 0x9b, 0x36, 0x55,
+	0x9b, 0x58, 0x6e, 0x9d, 0x34, 0x4d, 0x82, 0xa6, 0xad, 0x22, 0xb1, 0x86, 0x1b, 0x21, 0x31, 0x68,
+	0x3b, 0x2d, 0xd0, 0x0f, 0x02, 0x2d, 0x6e, 0x6c, 0xc1, 0x12, 0xc9, 0x92, 0x2b, 0x5b, 0xfa, 0xd4,
+	0xe7, 0xe8, 0x2b, 0x14, 0xe8, 0x3b, 0x16, 0xbb, 0xcb, 0x15, 0x0f, 0x53, 0x8c, 0x0d, 0xe4, 0x1b,
+	0xe7, 0xfa, 0xed, 0xec, 0xec, 0x0c, 0xe7, 0x07, 0xd5, 0xcb, 0x2b, 0x8f, 0xb8, 0x57, 0xc4, 0x6d,
+	0x3b, 0xae, 0x4d, 0x6d, 0x94, 0x75, 0xce, 0xd4, 0xfb, 0xe7, 0xb6, 0x7d, 0x3e, 0x26, 0x7b, 0x5c,
+	0x73, 0x36, 0xfd, 0xb0, 0x47, 0x26, 0x0e, 0x9d, 0x0b, 0x07, 0x5c, 0x87, 0x5a, 0xf7, 0x82, 0x0c,
+	0x2f, 0xbd, 0xe9, 0x44, 0x27, 0x7f, 0x4c, 0x89, 0x47, 0xf1, 0x23, 0xa8, 0x04, 0x2a, 0x67, 0x3c,
+	0x47, 0x2a, 0x14, 0x86, 0xbe, 0xa2, 0x99, 0xd9, 0xc9, 0xb4, 0x8a, 0xfa, 0x42, 0xc6, 0xdb, 0xb0,
+	0x79, 0x62, 0x9c, 0x8d, 0x89, 0x8c, 0xf0, 0x24, 0xca, 0x5f, 0x19, 0x68, 0xc4, 0x2d, 0x0c, 0xac,
+	0x07, 0x45, 0x19, 0xec, 0x35, 0x33, 0x3b, 0xb9, 0x56, 0x69, 0xff, 0x61, 0xdb, 0x39, 0x6b, 0x27,
+	0xf8, 0xb6, 0x17, 0xa2, 0x66, 0x51, 0x77, 0xae, 0x07, 0x81, 0xea, 0xf7, 0x50, 0x8d, 0x1a, 0x91,
+	0x02, 0xb9, 0x4b, 0x32, 0xf7, 0xf3, 0x63, 0x9f, 0x68, 0x03, 0x56, 0xaf, 0x8c, 0xf1, 0x94, 0x34,
+	0xb3, 0x5c, 0x27, 0x84, 0x97, 0xd9, 0xe7, 0x19, 0xdc, 0x80, 0x3a, 0x3f, 0xee, 0xad, 0x31, 0x21,
+	0x8b, 0x84, 0xbf, 0x80, 0x5a, 0x58, 0xc9, 0x72, 0xdd, 0x80, 0x55, 0x8b, 0x49, 0x3c, 0xcf, 0xa2,
+	0x2e, 0x04, 0xac, 0x40, 0xb5, 0x47, 0x3c, 0xea, 0xda, 0x73, 0x19, 0xba, 0x0b, 0xdb, 0x5d, 0x97,
+	0x18, 0x94, 0xbc, 0x73, 0x0f, 0x08, 0xe5, 0x28, 0xbe, 0x09, 0x21, 0xc8, 0xb3, 0x28, 0x3f, 0x2f,
+	0xfe, 0x8d, 0x7f, 0x00, 0xd4, 0x23, 0x63, 0x42, 0xc9, 0xc7, 0x3c, 0x59, 0x02, 0x23, 0xcb, 0x24,
+	0x33, 0x7e, 0x85, 0xbc, 0x2e, 0x04, 0xdc, 0x02, 0x25, 0x12, 0xef, 0xa7, 0x2a, 0x3c, 0x33, 0x61,
+	0xcf, 0x5f, 0x60, 0xfb, 0x35, 0x39, 0x1f, 0x59, 0x27, 0xae, 0x61, 0x79, 0xc6, 0x90, 0x8e, 0x6c,
+	0x4b, 0x1e, 0xf7, 0x5f, 0x00, 0xca, 0xc2, 0x07, 0xa1, 0x43, 0x8b, 0x54, 0x16, 0x80, 0x95, 0xd3,
+	0x35, 0xae, 0xf9, 0xb9, 0x05, 0x9d, 0x7d, 0xe2, 0xc7, 0xb0, 0x79, 0x13, 0x8b, 0x1d, 0xdd, 0x80,
+	0x55, 0x3a, 0x1b, 0x8c, 0x4c, 0x0e, 0x92, 0xd3, 0xf3, 0x74, 0x76, 0x68, 0x62, 0x0d, 0x9a, 0x5d,
+	0x7b, 0x32, 0x19, 0xd1, 0x84, 0xa3, 0x93, 0x02, 0x96, 0x5c, 0xb5, 0x0d, 0x5b, 0x09, 0x30, 0xcb,
+	0x2f, 0xfc, 0x0d, 0xa8, 0xba, 0x3d, 0x1e, 0x9f, 0x19, 0xc3, 0xcb, 0x5b, 0x1e, 0x8c, 0x0f, 0x01,
+	0x3a, 0xa6, 0x99, 0x9a, 0x9b, 0xdf, 0x5b, 0xd9, 0x84, 0xde, 0xca, 0xed, 0x64, 0x5a, 0x65, 0xbf,
+	0xb7, 0xf0, 0x33, 0xa8, 0x88, 0x87, 0xb9, 0x1b, 0x1a, 0xee, 0x43, 0xe5, 0xd4, 0x31, 0x0d, 0x4a,
+	0x3e, 0x49, 0x16, 0x4f, 0x00, 0x0e, 0x08, 0xbd, 0x63, 0x0a, 0x0f, 0xa0, 0xc4, 0x83, 0x3c, 0xc7,
+	0xb6, 0x3c, 0x12, 0x20, 0x67, 0xc2, 0xc8, 0x18, 0x4a, 0xfd, 0x91, 0x97, 0x0a, 0x8d, 0xff, 0x84,
+	0xb2, 0xf0, 0xf1, 0x91, 0x9e, 0xc2, 0x1a, 0x0f, 0x96, 0xc3, 0xfe, 0x1f, 0x36, 0xec, 0x61, 0x8f,
+	0xf6, 0x7b, 0x6e, 0x16, 0x23, 0xee, 0xfb, 0xaa, 0x2f, 0xa0, 0x14, 0x52, 0x7f, 0x6c, 0xb8, 0xcb,
+	0xe1, 0xe1, 0x3e, 0x85, 0x9a, 0x36, 0x73, 0x46, 0x2e, 0xe9, 0xdc, 0xb1, 0x06, 0xe8, 0x3e, 0x14,
+	0x09, 0x8f, 0x1c, 0x18, 0x94, 0x97, 0x34, 0xa7, 0x17, 0x88, 0x0f, 0x85, 0x67, 0xb0, 0xd9, 0xb5,
+	0x27, 0x8e, 0xe1, 0x92, 0x8e, 0x65, 0x1e, 0x5f, 0x1b, 0xce, 0xdd, 0xc1, 0xed, 0xb1, 0x39, 0x08,
+	0xbf, 0x57, 0xc1, 0x1e, 0x9b, 0xfc, 0x96, 0xcc, 0x68, 0x91, 0x6b, 0xdf, 0x98, 0x17, 0x46, 0x8b,
+	0x5c, 0x73, 0x23, 0x6b, 0xd0, 0xa3, 0x29, 0xfd, 0x24, 0xad, 0xf1, 0x08, 0x36, 0x44, 0x83, 0x8a,
+	0x0a, 0xa5, 0x76, 0x3d, 0x6e, 0x01, 0x8a, 0x39, 0xb3, 0xb9, 0x43, 0x90, 0xbf, 0x24, 0x73, 0xf9,
+	0x4b, 0xe4, 0xdf, 0xf8, 0xef, 0x0c, 0x94, 0x8e, 0x87, 0x46, 0xfa, 0x80, 0x6f, 0xc1, 0x9a, 0xe3,
+	0x92, 0x0f, 0xa3, 0x99, 0x9f, 0xa6, 0x2f, 0xb1, 0x4c, 0x3d, 0x6a, 0xb8, 0xa2, 0xe2, 0x45, 0x5d,
+	0x08, 0xec, 0x46, 0xc4, 0x32, 0x79, 0x2d, 0x8a, 0x3a, 0xfb, 0x64, 0x7e, 0xe3, 0xd1, 0x64, 0x44,
+	0x9b, 0xab, 0x3b, 0x99, 0x56, 0x45, 0x17, 0x02, 0x43, 0x1d, 0x4e, 0x5d, 0xcf, 0x76, 0x9b, 0x6b,
+	0x02, 0x55, 0x48, 0xa8, 0x09, 0xeb, 0x2e, 0xb9, 0x22, 0xae, 0x47, 0x9a, 0xeb, 0xfc, 0x1f, 0x26,
+	0x45, 0xbc, 0x0f, 0x85, 0x37, 0x64, 0x2e, 0xea, 0x7e, 0xcb, 0xbe, 0xc2, 0xa7, 0x50, 0x16, 0xf7,
+	0xf3, 0x9b, 0xfa, 0xb3, 0x58, 0x53, 0x97, 0x59, 0x53, 0x4b, 0x54, 0xd9, 0xc4, 0xe8, 0xff, 0x50,
+	0xb2, 0xc8, 0x8c, 0x0e, 0xfc, 0x04, 0xc5, 0xb5, 0x81, 0xa9, 0xba, 0x5c, 0x83, 0xaf, 0xa1, 0xf8,
+	0xce, 0x21, 0xae, 0xc1, 0xfe, 0x51, 0xe8, 0x73, 0xc8, 0xd3, 0xb9, 0x23, 0x26, 0xae, 0xba, 0x5f,
+	0x67, 0x88, 0x0b, 0xe3, 0xc9, 0xdc, 0x21, 0x3a, 0x37, 0xdf, 0xf6, 0xa9, 0xa3, 0xcd, 0x9c, 0x8f,
+	0x35, 0xb3, 0x05, 0x85, 0xbe, 0x7d, 0x2e, 0x66, 0x2b, 0xf1, 0x47, 0x1a, 0x5b, 0x0f, 0xd9, 0xf8,
+	0x7a, 0xd8, 0x05, 0xb0, 0x65, 0x72, 0x5e, 0x33, 0xc7, 0x8b, 0x50, 0x89, 0xa4, 0xac, 0x87, 0x1c,
+	0xf0, 0x26, 0x34, 0xfa, 0x86, 0x47, 0xfb, 0xf6, 0xf9, 0x21, 0x43, 0x97, 0x7b, 0xf3, 0x4b, 0xa8,
+	0x47, 0xd5, 0xcb, 0x7f, 0xec, 0xaf, 0x61, 0xe3, 0x80, 0x50, 0x3f, 0xe9, 0xd1, 0x62, 0x6b, 0xf3,
+	0xee, 0x19, 0x59, 0x43, 0x22, 0xbd, 0xb9, 0x10, 0xf4, 0x4a, 0x36, 0xd4, 0x2b, 0xf8, 0x77, 0x40,
+	0x31, 0x0c, 0x76, 0xde, 0x43, 0x58, 0x27, 0x42, 0x0e, 0x3f, 0xa6, 0x2c, 0x8f, 0x2e, 0x8d, 0xac,
+	0x22, 0x63, 0xc3, 0xa3, 0x83, 0xf0, 0x96, 0x2a, 0x32, 0x0d, 0xcf, 0x1d, 0x77, 0x60, 0x9b, 0xe1,
+	0x19, 0xf3, 0x9b, 0x39, 0xde, 0xf2, 0x04, 0xc6, 0x40, 0x74, 0xe2, 0xf1, 0x0c, 0x43, 0xd7, 0x4b,
+	0x28, 0x46, 0x1d, 0x6a, 0x07, 0x84, 0x6a, 0x8e, 0x3d, 0xbc, 0x90, 0xa5, 0x7c, 0x05, 0x95, 0x40,
+	0xe5, 0x97, 0x91, 0x30, 0x49, 0x46, 0x72, 0x81, 0x8d, 0xcb, 0xc4, 0xf0, 0x28, 0x91, 0xdd, 0xe8,
+	0x4b, 0xf8, 0x47, 0xa8, 0x1d, 0x47, 0x11, 0xef, 0x08, 0x50, 0x87, 0xda, 0xb1, 0x65, 0x38, 0xde,
+	0x85, 0x2d, 0xff, 0x54, 0xf8, 0x01, 0x54, 0xa4, 0xaa, 0x7b, 0x31, 0xb5, 0x2e, 0xd9, 0xaf, 0xc3,
+	0x34, 0xa8, 0xe1, 0xef, 0x14, 0xfe, 0x8d, 0x77, 0xa1, 0xfc, 0xab, 0x41, 0x83, 0x53, 0xd3, 0x69,
+	0xc9, 0x57, 0x6f, 0xa1, 0x12, 0x19, 0x0a, 0xb4, 0x0e, 0xb9, 0x4e, 0xaf, 0xa7, 0xac, 0x20, 0x80,
+	0xb5, 0xd3, 0xa3, 0x5e, 0xe7, 0x44, 0x53, 0x32, 0xec, 0xbb, 0xa7, 0xf5, 0xb5, 0x13, 0x4d, 0xc9,
+	0x22, 0x05, 0xca, 0xe2, 0x7b, 0x70, 0xd2, 0x79, 0xdd, 0xd7, 0x94, 0x1c, 0xb3, 0x6a, 0xbf, 0x1d,
+	0x1d, 0xea, 0x9a, 0x92, 0xdf, 0xff, 0xa7, 0x02, 0xb9, 0x37, 0xef, 0x8f, 0xd1, 0x53, 0x28, 0x48,
+	0x3e, 0x89, 0x1a, 0xec, 0x75, 0x62, 0xa4, 0x58, 0xad, 0x47, 0x95, 0xce, 0x78, 0x8e, 0x57, 0xd0,
+	0xcf, 0x50, 0x8d, 0xd2, 0x56, 0x74, 0x2f, 0x89, 0xca, 0x0a, 0x84, 0xed, 0x25, 0x2c, 0x17, 0xaf,
+	0xa0, 0x97, 0x00, 0x01, 0xf5, 0x44, 0x9b, 0x0b, 0xc7, 0x30, 0x3f, 0x55, 0x1b, 0x71, 0xb5, 0x88,
+	0xfd, 0x0e, 0xd6, 0x7d, 0x36, 0x8a, 0x10, 0xf3, 0x88, 0x52, 0x53, 0x75, 0xab, 0x2d, 0xd8, 0x7f,
+	0x5b, 0xb2, 0xff, 0xb6, 0xc6, 0xd8, 0x3f, 0x5e, 0x41, 0x87, 0xa0, 0xc4, 0x49, 0x2b, 0xba, 0xcf,
+	0x6f, 0x99, 0x4c, 0x65, 0x53, 0xa0, 0x5e, 0x41, 0x29, 0x44, 0x48, 0xd1, 0x96, 0xc8, 0x23, 0xce,
+	0x70, 0xd5, 0x8d, 0x1b, 0x7a, 0x71, 0x85, 0x3e, 0x28, 0x71, 0x66, 0x29, 0x32, 0x59, 0xc2, 0x5d,
+	0xd5, 0x7b, 0xc9, 0x46, 0x81, 0xf6, 0x0e, 0xea, 0x37, 0x28, 0x23, 0xe2, 0xac, 0x63, 0x19, 0x21,
+	0x55, 0xd5, 0x25, 0x56, 0x09, 0xd8, 0x48, 0xe0, 0x94, 0xe8, 0x7f, 0x2c, 0x68, 0x39, 0xd9, 0x4c,
+	0x29, 0x57, 0x0b, 0x72, 0x07, 0x84, 0xa2, 0x2a, 0x03, 0x08, 0x98, 0x9a, 0x5a, 0x5b, 0xc8, 0x62,
+	0xcb, 0xe0, 0x15, 0xf4, 0x08, 0xf2, 0x8c, 0x2a, 0xa1, 0x5a, 0x40, 0x9a, 0x84, 0xaf, 0x12, 0x67,
+	0x51, 0x78, 0x05, 0xed, 0x42, 0x9e, 0x2d, 0x29, 0xe1, 0x1c, 0x5a, 0xc7, 0xaa, 0x12, 0x28, 0xa4,
+	0xf3, 0xd7, 0x19, 0xb4, 0x07, 0xb9, 0x8e, 0x69, 0x8a, 0x2c, 0x02, 0x02, 0x9c, 0x92, 0xf6, 0xb7,
+	0xb0, 0x26, 0x1e, 0x0f, 0xd5, 0x83, 0x87, 0xbc, 0x55, 0x98, 0x20, 0xb7, 0x22, 0x2c, 0x42, 0x74,
+	0x53, 0xc2, 0x5e, 0x40, 0x41, 0xd2, 0x38, 0x31, 0x91, 0x31, 0x52, 0x97, 0x12, 0xaa, 0x41, 0x35,
+	0x4a, 0xd5, 0xc4, 0x58, 0x26, 0xd2, 0xb7, 0x14, 0x98, 0x3d, 0xc8, 0x1d, 0x4d, 0xfd, 0x67, 0x0a,
+	0x08, 0x58, 0x4a, 0x40, 0x57, 0xd2, 0x7f, 0x9f, 0x30, 0xa1, 0x66, 0x50, 0xa7, 0x28, 0xe1, 0x52,
+	0xb7, 0x12, 0x2c, 0xa2, 0xdb, 0x7e, 0x82, 0x72, 0x78, 0x27, 0x22, 0xfe, 0xdb, 0x48, 0x58, 0x9e,
+	0xea, 0xe6, 0x4d, 0x83, 0x40, 0xe8, 0xf2, 0x55, 0x10, 0xac, 0x21, 0x91, 0x46, 0xd2, 0xf6, 0x54,
+	0xb7, 0x12, 0x2c, 0x02, 0xe4, 0x10, 0x94, 0xf8, 0x3a, 0x13, 0x33, 0xb9, 0x64, 0xc9, 0xa5, 0xbf,
+	0xa4, 0x5c, 0x6b, 0xe2, 0x25, 0x63, 0x4b, 0x2e, 0x25, 0xf4, 0x29, 0x14, 0xe4, 0x56, 0x13, 0xa1,
+	0xb1, 0xb5, 0xa7, 0xd6, 0xa3, 0x4a, 0x91, 0xfb, 0x0b, 0x28, 0x1c, 0x47, 0xa2, 0x62, 0xab, 0x2d,
+	0xe5, 0xc0, 0x67, 0x50, 0x90, 0x3b, 0xcb, 0x0f, 0x8d, 0x2e, 0x35, 0xb5, 0x1e, 0x56, 0xf2, 0xb5,
+	0xc6, 0x87, 0xe9, 0x39, 0xac, 0xeb, 0xc4, 0xa3, 0xb6, 0x4b, 0xd0, 0x4d, 0x8f, 0xe5, 0xe7, 0xb5,
+	0x32, 0xe8, 0x31, 0xac, 0xf2, 0x05, 0x88, 0xf8, 0x94, 0x86, 0x77, 0xa1, 0x1a, 0x25, 0x54, 0xec,
+	0x9c, 0xb3, 0x35, 0x8e, 0xf0, 0xe4, 0xdf, 0x01, 0x00, 0x44, 0x23, 0x18, 0x54, 0xe8, 0x11, 0x00,
+	0x00,
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion4
+
+// KVSClient is the client API for KVS service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type KVSClient interface {
+	Checksum(ctx context.Context, in *ChecksumRequest, opts ...grpc.CallOption) (*ChecksumReply, error)
+	TableChecksums(ctx context.Context, in *TableChecksumsRequest, opts ...grpc.CallOption) (*TableChecksumsReply, error)
+	TableNames(ctx context.Context, in *TableNamesRequest, opts ...grpc.CallOption) (*TableNamesReply, error)
+	Destroy(ctx context.Context, in *DestroyRequest, opts ...grpc.CallOption) (*empty.Empty, error)
+	CreateOrGetTable(ctx context.Context, in *CreateOrGetTableRequest, opts ...grpc.CallOption) (*empty.Empty, error)
+	DeleteTable(ctx context.Context, in *DeleteTableRequest, opts ...grpc.CallOption) (*DeleteTableReply, error)
+	BeginTransaction(ctx context.Context, in *BeginTransactionRequest, opts ...grpc.CallOption) (*BeginTransactionReply, error)
+	CommitTransaction(ctx context.Context, in *CommitTransactionRequest, opts ...grpc.CallOption) (*CommitTransactionReply, error)
+	RollbackTransaction(ctx context.Context, in *RollbackTransactionRequest, opts ...grpc.CallOption) (*empty.Empty, error)
+	Get(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*GetResponse, error)
+	List(ctx context.Context, in *ListRequest, opts ...grpc.CallOption) (*ListResponse, error)
+	Scan(ctx context.Context, in *ScanRequest, opts ...grpc.CallOption) (KVS_ScanClient, error)
+	Add(ctx context.Context, in *AddRequest, opts ...grpc.CallOption) (*empty.Empty, error)
+	Delete(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*empty.Empty, error)
+	Update(ctx context.Context, in *UpdateRequest, opts ...grpc.CallOption) (*empty.Empty, error)
+	ExpireAt(ctx context.Context, in *ExpireAtRequest, opts ...grpc.CallOption) (*empty.Empty, error)
+	CompareAndSwap(ctx context.Context, in *CompareAndSwapRequest, opts ...grpc.CallOption) (*empty.Empty, error)
+	Put(ctx context.Context, in *PutRequest, opts ...grpc.CallOption) (*empty.Empty, error)
+	DeleteExpired(ctx context.Context, in *DeleteExpiredRequest, opts ...grpc.CallOption) (*DeleteExpiredReply, error)
+	LastLogIndex(ctx context.Context, in *LastLogIndexRequest, opts ...grpc.CallOption) (*LastLogIndexReply, error)
+	GetLogEntries(ctx context.Context, in *GetLogEntriesRequest, opts ...grpc.CallOption) (*GetLogEntriesReply, error)
+	ReplayLogEntries(ctx context.Context, in *ReplayLogEntriesRequest, opts ...grpc.CallOption) (*empty.Empty, error)
+	ResetLog(ctx context.Context, in *ResetLogRequest, opts ...grpc.CallOption) (*empty.Empty, error)
+	GetEpoch(ctx context.Context, in *GetEpochRequest, opts ...grpc.CallOption) (*GetEpochReply, error)
+	SetEpoch(ctx context.Context, in *SetEpochRequest, opts ...grpc.CallOption) (*empty.Empty, error)
+	Snapshot(ctx context.Context, in *SnapshotRequest, opts ...grpc.CallOption) (KVS_SnapshotClient, error)
+	Restore(ctx context.Context, opts ...grpc.CallOption) (KVS_RestoreClient, error)
+	Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (KVS_WatchClient, error)
+}
+
+type kVSClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewKVSClient(cc *grpc.ClientConn) KVSClient {
+	return &kVSClient{cc}
+}
+
+func (c *kVSClient) Checksum(ctx context.Context, in *ChecksumRequest, opts ...grpc.CallOption) (*ChecksumReply, error) {
+	out := new(ChecksumReply)
+	err := c.cc.Invoke(ctx, "/pb.KVS/Checksum", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kVSClient) TableChecksums(ctx context.Context, in *TableChecksumsRequest, opts ...grpc.CallOption) (*TableChecksumsReply, error) {
+	out := new(TableChecksumsReply)
+	err := c.cc.Invoke(ctx, "/pb.KVS/TableChecksums", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kVSClient) TableNames(ctx context.Context, in *TableNamesRequest, opts ...grpc.CallOption) (*TableNamesReply, error) {
+	out := new(TableNamesReply)
+	err := c.cc.Invoke(ctx, "/pb.KVS/TableNames", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kVSClient) Destroy(ctx context.Context, in *DestroyRequest, opts ...grpc.CallOption) (*empty.Empty, error) {
+	out := new(empty.Empty)
+	err := c.cc.Invoke(ctx, "/pb.KVS/Destroy", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kVSClient) CreateOrGetTable(ctx context.Context, in *CreateOrGetTableRequest, opts ...grpc.CallOption) (*empty.Empty, error) {
 	out := new(empty.Empty)
+	err := c.cc.Invoke(ctx, "/pb.KVS/CreateOrGetTable", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kVSClient) DeleteTable(ctx context.Context, in *DeleteTableRequest, opts ...grpc.CallOption) (*DeleteTableReply, error) {
+	out := new(DeleteTableReply)
 	err := c.cc.Invoke(ctx, "/pb.KVS/DeleteTable", in, out, opts...)
 	if err != nil {
 		return nil, err
@@ -823,8 +2244,8 @@ func (c *kVSClient) BeginTransaction(ctx context.Context, in *BeginTransactionRe
 	return out, nil
 }
 
-func (c *kVSClient) CommitTransaction(ctx context.Context, in *CommitTransactionRequest, opts ...grpc.CallOption) (*empty.Empty, error) {
-	out := new(empty.Empty)
+func (c *kVSClient) CommitTransaction(ctx context.Context, in *CommitTransactionRequest, opts ...grpc.CallOption) (*CommitTransactionReply, error) {
+	out := new(CommitTransactionReply)
 	err := c.cc.Invoke(ctx, "/pb.KVS/CommitTransaction", in, out, opts...)
 	if err != nil {
 		return nil, err
@@ -859,6 +2280,38 @@ func (c *kVSClient) List(ctx context.Context, in *ListRequest, opts ...grpc.Call
 	return out, nil
 }
 
+func (c *kVSClient) Scan(ctx context.Context, in *ScanRequest, opts ...grpc.CallOption) (KVS_ScanClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_KVS_serviceDesc.Streams[0], "/pb.KVS/Scan", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &kVSScanClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type KVS_ScanClient interface {
+	Recv() (*ScanResponse, error)
+	grpc.ClientStream
+}
+
+type kVSScanClient struct {
+	grpc.ClientStream
+}
+
+func (x *kVSScanClient) Recv() (*ScanResponse, error) {
+	m := new(ScanResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 func (c *kVSClient) Add(ctx context.Context, in *AddRequest, opts ...grpc.CallOption) (*empty.Empty, error) {
 	out := new(empty.Empty)
 	err := c.cc.Invoke(ctx, "/pb.KVS/Add", in, out, opts...)
@@ -874,32 +2327,236 @@ func (c *kVSClient) Delete(ctx context.Context, in *DeleteRequest, opts ...grpc.
 	if err != nil {
 		return nil, err
 	}
-	return out, nil
+	return out, nil
+}
+
+func (c *kVSClient) Update(ctx context.Context, in *UpdateRequest, opts ...grpc.CallOption) (*empty.Empty, error) {
+	out := new(empty.Empty)
+	err := c.cc.Invoke(ctx, "/pb.KVS/Update", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kVSClient) ExpireAt(ctx context.Context, in *ExpireAtRequest, opts ...grpc.CallOption) (*empty.Empty, error) {
+	out := new(empty.Empty)
+	err := c.cc.Invoke(ctx, "/pb.KVS/ExpireAt", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kVSClient) CompareAndSwap(ctx context.Context, in *CompareAndSwapRequest, opts ...grpc.CallOption) (*empty.Empty, error) {
+	out := new(empty.Empty)
+	err := c.cc.Invoke(ctx, "/pb.KVS/CompareAndSwap", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kVSClient) Put(ctx context.Context, in *PutRequest, opts ...grpc.CallOption) (*empty.Empty, error) {
+	out := new(empty.Empty)
+	err := c.cc.Invoke(ctx, "/pb.KVS/Put", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kVSClient) DeleteExpired(ctx context.Context, in *DeleteExpiredRequest, opts ...grpc.CallOption) (*DeleteExpiredReply, error) {
+	out := new(DeleteExpiredReply)
+	err := c.cc.Invoke(ctx, "/pb.KVS/DeleteExpired", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kVSClient) LastLogIndex(ctx context.Context, in *LastLogIndexRequest, opts ...grpc.CallOption) (*LastLogIndexReply, error) {
+	out := new(LastLogIndexReply)
+	err := c.cc.Invoke(ctx, "/pb.KVS/LastLogIndex", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kVSClient) GetLogEntries(ctx context.Context, in *GetLogEntriesRequest, opts ...grpc.CallOption) (*GetLogEntriesReply, error) {
+	out := new(GetLogEntriesReply)
+	err := c.cc.Invoke(ctx, "/pb.KVS/GetLogEntries", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kVSClient) ReplayLogEntries(ctx context.Context, in *ReplayLogEntriesRequest, opts ...grpc.CallOption) (*empty.Empty, error) {
+	out := new(empty.Empty)
+	err := c.cc.Invoke(ctx, "/pb.KVS/ReplayLogEntries", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kVSClient) ResetLog(ctx context.Context, in *ResetLogRequest, opts ...grpc.CallOption) (*empty.Empty, error) {
+	out := new(empty.Empty)
+	err := c.cc.Invoke(ctx, "/pb.KVS/ResetLog", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kVSClient) GetEpoch(ctx context.Context, in *GetEpochRequest, opts ...grpc.CallOption) (*GetEpochReply, error) {
+	out := new(GetEpochReply)
+	err := c.cc.Invoke(ctx, "/pb.KVS/GetEpoch", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kVSClient) SetEpoch(ctx context.Context, in *SetEpochRequest, opts ...grpc.CallOption) (*empty.Empty, error) {
+	out := new(empty.Empty)
+	err := c.cc.Invoke(ctx, "/pb.KVS/SetEpoch", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kVSClient) Snapshot(ctx context.Context, in *SnapshotRequest, opts ...grpc.CallOption) (KVS_SnapshotClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_KVS_serviceDesc.Streams[1], "/pb.KVS/Snapshot", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &kVSSnapshotClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type KVS_SnapshotClient interface {
+	Recv() (*SnapshotChunk, error)
+	grpc.ClientStream
+}
+
+type kVSSnapshotClient struct {
+	grpc.ClientStream
+}
+
+func (x *kVSSnapshotClient) Recv() (*SnapshotChunk, error) {
+	m := new(SnapshotChunk)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *kVSClient) Restore(ctx context.Context, opts ...grpc.CallOption) (KVS_RestoreClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_KVS_serviceDesc.Streams[2], "/pb.KVS/Restore", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &kVSRestoreClient{stream}
+	return x, nil
+}
+
+type KVS_RestoreClient interface {
+	Send(*SnapshotChunk) error
+	CloseAndRecv() (*empty.Empty, error)
+	grpc.ClientStream
+}
+
+type kVSRestoreClient struct {
+	grpc.ClientStream
+}
+
+func (x *kVSRestoreClient) Send(m *SnapshotChunk) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *kVSRestoreClient) CloseAndRecv() (*empty.Empty, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(empty.Empty)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *kVSClient) Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (KVS_WatchClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_KVS_serviceDesc.Streams[3], "/pb.KVS/Watch", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &kVSWatchClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
 }
 
-func (c *kVSClient) Update(ctx context.Context, in *UpdateRequest, opts ...grpc.CallOption) (*empty.Empty, error) {
-	out := new(empty.Empty)
-	err := c.cc.Invoke(ctx, "/pb.KVS/Update", in, out, opts...)
-	if err != nil {
+type KVS_WatchClient interface {
+	Recv() (*Operation, error)
+	grpc.ClientStream
+}
+
+type kVSWatchClient struct {
+	grpc.ClientStream
+}
+
+func (x *kVSWatchClient) Recv() (*Operation, error) {
+	m := new(Operation)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
 		return nil, err
 	}
-	return out, nil
+	return m, nil
 }
 
 // KVSServer is the server API for KVS service.
 type KVSServer interface {
 	Checksum(context.Context, *ChecksumRequest) (*ChecksumReply, error)
+	TableChecksums(context.Context, *TableChecksumsRequest) (*TableChecksumsReply, error)
+	TableNames(context.Context, *TableNamesRequest) (*TableNamesReply, error)
 	Destroy(context.Context, *DestroyRequest) (*empty.Empty, error)
 	CreateOrGetTable(context.Context, *CreateOrGetTableRequest) (*empty.Empty, error)
-	DeleteTable(context.Context, *DeleteTableRequest) (*empty.Empty, error)
+	DeleteTable(context.Context, *DeleteTableRequest) (*DeleteTableReply, error)
 	BeginTransaction(context.Context, *BeginTransactionRequest) (*BeginTransactionReply, error)
-	CommitTransaction(context.Context, *CommitTransactionRequest) (*empty.Empty, error)
+	CommitTransaction(context.Context, *CommitTransactionRequest) (*CommitTransactionReply, error)
 	RollbackTransaction(context.Context, *RollbackTransactionRequest) (*empty.Empty, error)
 	Get(context.Context, *GetRequest) (*GetResponse, error)
 	List(context.Context, *ListRequest) (*ListResponse, error)
+	Scan(*ScanRequest, KVS_ScanServer) error
 	Add(context.Context, *AddRequest) (*empty.Empty, error)
 	Delete(context.Context, *DeleteRequest) (*empty.Empty, error)
 	Update(context.Context, *UpdateRequest) (*empty.Empty, error)
+	ExpireAt(context.Context, *ExpireAtRequest) (*empty.Empty, error)
+	CompareAndSwap(context.Context, *CompareAndSwapRequest) (*empty.Empty, error)
+	Put(context.Context, *PutRequest) (*empty.Empty, error)
+	DeleteExpired(context.Context, *DeleteExpiredRequest) (*DeleteExpiredReply, error)
+	LastLogIndex(context.Context, *LastLogIndexRequest) (*LastLogIndexReply, error)
+	GetLogEntries(context.Context, *GetLogEntriesRequest) (*GetLogEntriesReply, error)
+	ReplayLogEntries(context.Context, *ReplayLogEntriesRequest) (*empty.Empty, error)
+	ResetLog(context.Context, *ResetLogRequest) (*empty.Empty, error)
+	GetEpoch(context.Context, *GetEpochRequest) (*GetEpochReply, error)
+	SetEpoch(context.Context, *SetEpochRequest) (*empty.Empty, error)
+	Snapshot(*SnapshotRequest, KVS_SnapshotServer) error
+	Restore(KVS_RestoreServer) error
+	Watch(*WatchRequest, KVS_WatchServer) error
 }
 
 // UnimplementedKVSServer can be embedded to have forward compatible implementations.
@@ -909,19 +2566,25 @@ type UnimplementedKVSServer struct {
 func (*UnimplementedKVSServer) Checksum(ctx context.Context, req *ChecksumRequest) (*ChecksumReply, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Checksum not implemented")
 }
+func (*UnimplementedKVSServer) TableChecksums(ctx context.Context, req *TableChecksumsRequest) (*TableChecksumsReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method TableChecksums not implemented")
+}
+func (*UnimplementedKVSServer) TableNames(ctx context.Context, req *TableNamesRequest) (*TableNamesReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method TableNames not implemented")
+}
 func (*UnimplementedKVSServer) Destroy(ctx context.Context, req *DestroyRequest) (*empty.Empty, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Destroy not implemented")
 }
 func (*UnimplementedKVSServer) CreateOrGetTable(ctx context.Context, req *CreateOrGetTableRequest) (*empty.Empty, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method CreateOrGetTable not implemented")
 }
-func (*UnimplementedKVSServer) DeleteTable(ctx context.Context, req *DeleteTableRequest) (*empty.Empty, error) {
+func (*UnimplementedKVSServer) DeleteTable(ctx context.Context, req *DeleteTableRequest) (*DeleteTableReply, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method DeleteTable not implemented")
 }
 func (*UnimplementedKVSServer) BeginTransaction(ctx context.Context, req *BeginTransactionRequest) (*BeginTransactionReply, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method BeginTransaction not implemented")
 }
-func (*UnimplementedKVSServer) CommitTransaction(ctx context.Context, req *CommitTransactionRequest) (*empty.Empty, error) {
+func (*UnimplementedKVSServer) CommitTransaction(ctx context.Context, req *CommitTransactionRequest) (*CommitTransactionReply, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method CommitTransaction not implemented")
 }
 func (*UnimplementedKVSServer) RollbackTransaction(ctx context.Context, req *RollbackTransactionRequest) (*empty.Empty, error) {
@@ -933,6 +2596,9 @@ func (*UnimplementedKVSServer) Get(ctx context.Context, req *GetRequest) (*GetRe
 func (*UnimplementedKVSServer) List(ctx context.Context, req *ListRequest) (*ListResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method List not implemented")
 }
+func (*UnimplementedKVSServer) Scan(req *ScanRequest, srv KVS_ScanServer) error {
+	return status.Errorf(codes.Unimplemented, "method Scan not implemented")
+}
 func (*UnimplementedKVSServer) Add(ctx context.Context, req *AddRequest) (*empty.Empty, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Add not implemented")
 }
@@ -942,6 +2608,45 @@ func (*UnimplementedKVSServer) Delete(ctx context.Context, req *DeleteRequest) (
 func (*UnimplementedKVSServer) Update(ctx context.Context, req *UpdateRequest) (*empty.Empty, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Update not implemented")
 }
+func (*UnimplementedKVSServer) ExpireAt(ctx context.Context, req *ExpireAtRequest) (*empty.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ExpireAt not implemented")
+}
+func (*UnimplementedKVSServer) CompareAndSwap(ctx context.Context, req *CompareAndSwapRequest) (*empty.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CompareAndSwap not implemented")
+}
+func (*UnimplementedKVSServer) Put(ctx context.Context, req *PutRequest) (*empty.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Put not implemented")
+}
+func (*UnimplementedKVSServer) DeleteExpired(ctx context.Context, req *DeleteExpiredRequest) (*DeleteExpiredReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteExpired not implemented")
+}
+func (*UnimplementedKVSServer) LastLogIndex(ctx context.Context, req *LastLogIndexRequest) (*LastLogIndexReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method LastLogIndex not implemented")
+}
+func (*UnimplementedKVSServer) GetLogEntries(ctx context.Context, req *GetLogEntriesRequest) (*GetLogEntriesReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetLogEntries not implemented")
+}
+func (*UnimplementedKVSServer) ReplayLogEntries(ctx context.Context, req *ReplayLogEntriesRequest) (*empty.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReplayLogEntries not implemented")
+}
+func (*UnimplementedKVSServer) ResetLog(ctx context.Context, req *ResetLogRequest) (*empty.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ResetLog not implemented")
+}
+func (*UnimplementedKVSServer) GetEpoch(ctx context.Context, req *GetEpochRequest) (*GetEpochReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetEpoch not implemented")
+}
+func (*UnimplementedKVSServer) SetEpoch(ctx context.Context, req *SetEpochRequest) (*empty.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetEpoch not implemented")
+}
+func (*UnimplementedKVSServer) Snapshot(req *SnapshotRequest, srv KVS_SnapshotServer) error {
+	return status.Errorf(codes.Unimplemented, "method Snapshot not implemented")
+}
+func (*UnimplementedKVSServer) Restore(srv KVS_RestoreServer) error {
+	return status.Errorf(codes.Unimplemented, "method Restore not implemented")
+}
+func (*UnimplementedKVSServer) Watch(req *WatchRequest, srv KVS_WatchServer) error {
+	return status.Errorf(codes.Unimplemented, "method Watch not implemented")
+}
 
 func RegisterKVSServer(s *grpc.Server, srv KVSServer) {
 	s.RegisterService(&_KVS_serviceDesc, srv)
@@ -965,6 +2670,42 @@ func _KVS_Checksum_Handler(srv interface{}, ctx context.Context, dec func(interf
 	return interceptor(ctx, in, info, handler)
 }
 
+func _KVS_TableChecksums_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TableChecksumsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KVSServer).TableChecksums(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.KVS/TableChecksums",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KVSServer).TableChecksums(ctx, req.(*TableChecksumsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _KVS_TableNames_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TableNamesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KVSServer).TableNames(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.KVS/TableNames",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KVSServer).TableNames(ctx, req.(*TableNamesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _KVS_Destroy_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(DestroyRequest)
 	if err := dec(in); err != nil {
@@ -1109,6 +2850,27 @@ func _KVS_List_Handler(srv interface{}, ctx context.Context, dec func(interface{
 	return interceptor(ctx, in, info, handler)
 }
 
+func _KVS_Scan_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ScanRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(KVSServer).Scan(m, &kVSScanServer{stream})
+}
+
+type KVS_ScanServer interface {
+	Send(*ScanResponse) error
+	grpc.ServerStream
+}
+
+type kVSScanServer struct {
+	grpc.ServerStream
+}
+
+func (x *kVSScanServer) Send(m *ScanResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 func _KVS_Add_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(AddRequest)
 	if err := dec(in); err != nil {
@@ -1163,6 +2925,254 @@ func _KVS_Update_Handler(srv interface{}, ctx context.Context, dec func(interfac
 	return interceptor(ctx, in, info, handler)
 }
 
+func _KVS_ExpireAt_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ExpireAtRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KVSServer).ExpireAt(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.KVS/ExpireAt",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KVSServer).ExpireAt(ctx, req.(*ExpireAtRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _KVS_CompareAndSwap_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CompareAndSwapRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KVSServer).CompareAndSwap(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.KVS/CompareAndSwap",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KVSServer).CompareAndSwap(ctx, req.(*CompareAndSwapRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _KVS_Put_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PutRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KVSServer).Put(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.KVS/Put",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KVSServer).Put(ctx, req.(*PutRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _KVS_DeleteExpired_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteExpiredRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KVSServer).DeleteExpired(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.KVS/DeleteExpired",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KVSServer).DeleteExpired(ctx, req.(*DeleteExpiredRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _KVS_LastLogIndex_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LastLogIndexRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KVSServer).LastLogIndex(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.KVS/LastLogIndex",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KVSServer).LastLogIndex(ctx, req.(*LastLogIndexRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _KVS_GetLogEntries_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetLogEntriesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KVSServer).GetLogEntries(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.KVS/GetLogEntries",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KVSServer).GetLogEntries(ctx, req.(*GetLogEntriesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _KVS_ReplayLogEntries_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReplayLogEntriesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KVSServer).ReplayLogEntries(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.KVS/ReplayLogEntries",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KVSServer).ReplayLogEntries(ctx, req.(*ReplayLogEntriesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _KVS_ResetLog_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResetLogRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KVSServer).ResetLog(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.KVS/ResetLog",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KVSServer).ResetLog(ctx, req.(*ResetLogRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _KVS_GetEpoch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetEpochRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KVSServer).GetEpoch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.KVS/GetEpoch",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KVSServer).GetEpoch(ctx, req.(*GetEpochRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _KVS_SetEpoch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetEpochRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KVSServer).SetEpoch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.KVS/SetEpoch",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KVSServer).SetEpoch(ctx, req.(*SetEpochRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _KVS_Snapshot_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SnapshotRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(KVSServer).Snapshot(m, &kVSSnapshotServer{stream})
+}
+
+type KVS_SnapshotServer interface {
+	Send(*SnapshotChunk) error
+	grpc.ServerStream
+}
+
+type kVSSnapshotServer struct {
+	grpc.ServerStream
+}
+
+func (x *kVSSnapshotServer) Send(m *SnapshotChunk) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _KVS_Restore_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(KVSServer).Restore(&kVSRestoreServer{stream})
+}
+
+type KVS_RestoreServer interface {
+	SendAndClose(*empty.Empty) error
+	Recv() (*SnapshotChunk, error)
+	grpc.ServerStream
+}
+
+type kVSRestoreServer struct {
+	grpc.ServerStream
+}
+
+func (x *kVSRestoreServer) SendAndClose(m *empty.Empty) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *kVSRestoreServer) Recv() (*SnapshotChunk, error) {
+	m := new(SnapshotChunk)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _KVS_Watch_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(KVSServer).Watch(m, &kVSWatchServer{stream})
+}
+
+type KVS_WatchServer interface {
+	Send(*Operation) error
+	grpc.ServerStream
+}
+
+type kVSWatchServer struct {
+	grpc.ServerStream
+}
+
+func (x *kVSWatchServer) Send(m *Operation) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 var _KVS_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "pb.KVS",
 	HandlerType: (*KVSServer)(nil),
@@ -1171,6 +3181,14 @@ var _KVS_serviceDesc = grpc.ServiceDesc{
 			MethodName: "Checksum",
 			Handler:    _KVS_Checksum_Handler,
 		},
+		{
+			MethodName: "TableChecksums",
+			Handler:    _KVS_TableChecksums_Handler,
+		},
+		{
+			MethodName: "TableNames",
+			Handler:    _KVS_TableNames_Handler,
+		},
 		{
 			MethodName: "Destroy",
 			Handler:    _KVS_Destroy_Handler,
@@ -1215,7 +3233,68 @@ var _KVS_serviceDesc = grpc.ServiceDesc{
 			MethodName: "Update",
 			Handler:    _KVS_Update_Handler,
 		},
+		{
+			MethodName: "ExpireAt",
+			Handler:    _KVS_ExpireAt_Handler,
+		},
+		{
+			MethodName: "CompareAndSwap",
+			Handler:    _KVS_CompareAndSwap_Handler,
+		},
+		{
+			MethodName: "Put",
+			Handler:    _KVS_Put_Handler,
+		},
+		{
+			MethodName: "DeleteExpired",
+			Handler:    _KVS_DeleteExpired_Handler,
+		},
+		{
+			MethodName: "LastLogIndex",
+			Handler:    _KVS_LastLogIndex_Handler,
+		},
+		{
+			MethodName: "GetLogEntries",
+			Handler:    _KVS_GetLogEntries_Handler,
+		},
+		{
+			MethodName: "ReplayLogEntries",
+			Handler:    _KVS_ReplayLogEntries_Handler,
+		},
+		{
+			MethodName: "ResetLog",
+			Handler:    _KVS_ResetLog_Handler,
+		},
+		{
+			MethodName: "GetEpoch",
+			Handler:    _KVS_GetEpoch_Handler,
+		},
+		{
+			MethodName: "SetEpoch",
+			Handler:    _KVS_SetEpoch_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Scan",
+			Handler:       _KVS_Scan_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "Snapshot",
+			Handler:       _KVS_Snapshot_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "Restore",
+			Handler:       _KVS_Restore_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "Watch",
+			Handler:       _KVS_Watch_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "kvserver.proto",
 }
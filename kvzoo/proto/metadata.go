@@ -0,0 +1,9 @@
+package pb
+
+//proxy carries its epoch in the grpc metadata of every request, so
+//node can reject request from proxy which doesn't know the failover
+const EpochMetadataKey = "kvzoo-epoch"
+
+//shared token is carried in the grpc metadata of every request when
+//token authentication is enabled
+const TokenMetadataKey = "kvzoo-token"
@@ -0,0 +1,48 @@
+package kvzoo
+
+//keys are iterated in byte-wise order, the range is the intersection
+//of [Start, End) and keys with Prefix, empty value means no bound
+type ScanOptions struct {
+	Prefix string
+	Start  string
+	End    string
+	//zero means no limit
+	Limit int
+	//continue after the key returned as cursor of last scan
+	Cursor string
+	//iterate from the largest key
+	Reverse bool
+}
+
+type KeyValue struct {
+	Key   string
+	Value []byte
+}
+
+//lower bound is inclusive and upper bound is exclusive, empty upper
+//bound means no upper bound
+func (o ScanOptions) Bounds() (string, string) {
+	lower, upper := o.Start, o.End
+	if o.Prefix != "" {
+		if o.Prefix > lower {
+			lower = o.Prefix
+		}
+		if end := prefixEnd(o.Prefix); end != "" && (upper == "" || end < upper) {
+			upper = end
+		}
+	}
+	return lower, upper
+}
+
+//the smallest key which is larger than all keys with the prefix,
+//empty if prefix only has 0xff
+func prefixEnd(prefix string) string {
+	end := []byte(prefix)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i] += 1
+			return string(end[:i+1])
+		}
+	}
+	return ""
+}
@@ -0,0 +1,135 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"kvzoo"
+	pb "kvzoo/proto"
+)
+
+type options struct {
+	tls     *kvzoo.TLSConfig
+	tlsConf *tls.Config
+	token   string
+}
+
+type Option func(*options)
+
+//client certificate is required if ca file is set
+func WithTLS(conf kvzoo.TLSConfig) Option {
+	return func(o *options) {
+		o.tls = &conf
+	}
+}
+
+//every request should carry the token
+func WithToken(token string) Option {
+	return func(o *options) {
+		o.token = token
+	}
+}
+
+//certificate is loaded here, so invalid tls configure is reported
+//before the server is created
+func loadOptions(opts []Option) (*options, error) {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	if o.tls != nil {
+		conf, err := o.tls.ServerConfig()
+		if err != nil {
+			return nil, err
+		}
+		o.tlsConf = conf
+	}
+	return o, nil
+}
+
+func (o *options) serverOptions(service *KVService) []grpc.ServerOption {
+	unaryInterceptors := []grpc.UnaryServerInterceptor{service.epoch.unaryInterceptor}
+	streamInterceptors := []grpc.StreamServerInterceptor{service.epoch.streamInterceptor}
+	if o.token != "" {
+		auth := &tokenAuth{token: o.token}
+		unaryInterceptors = append([]grpc.UnaryServerInterceptor{auth.unaryInterceptor}, unaryInterceptors...)
+		streamInterceptors = append([]grpc.StreamServerInterceptor{auth.streamInterceptor}, streamInterceptors...)
+	}
+
+	serverOptions := []grpc.ServerOption{
+		grpc.UnaryInterceptor(chainUnaryInterceptors(unaryInterceptors)),
+		grpc.StreamInterceptor(chainStreamInterceptors(streamInterceptors)),
+	}
+	if o.tlsConf != nil {
+		serverOptions = append(serverOptions, grpc.Creds(credentials.NewTLS(o.tlsConf)))
+	}
+	return serverOptions
+}
+
+type tokenAuth struct {
+	token string
+}
+
+func (a *tokenAuth) unaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if err := a.check(ctx); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (a *tokenAuth) streamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := a.check(ss.Context()); err != nil {
+		return err
+	}
+	return handler(srv, ss)
+}
+
+func (a *tokenAuth) check(ctx context.Context) error {
+	var token string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(pb.TokenMetadataKey); len(values) > 0 {
+			token = values[0]
+		}
+	}
+
+	if subtle.ConstantTimeCompare([]byte(token), []byte(a.token)) != 1 {
+		return status.Error(codes.Unauthenticated, "invalid token")
+	}
+	return nil
+}
+
+//grpc server only accepts one interceptor, the first one is the
+//outermost
+func chainUnaryInterceptors(interceptors []grpc.UnaryServerInterceptor) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		chained := handler
+		for i := len(interceptors) - 1; i >= 0; i-- {
+			interceptor, next := interceptors[i], chained
+			chained = func(ctx context.Context, req interface{}) (interface{}, error) {
+				return interceptor(ctx, req, info, next)
+			}
+		}
+		return chained(ctx, req)
+	}
+}
+
+func chainStreamInterceptors(interceptors []grpc.StreamServerInterceptor) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		chained := handler
+		for i := len(interceptors) - 1; i >= 0; i-- {
+			interceptor, next := interceptors[i], chained
+			chained = func(srv interface{}, ss grpc.ServerStream) error {
+				return interceptor(srv, ss, info, next)
+			}
+		}
+		return chained(srv, ss)
+	}
+}
@@ -0,0 +1,188 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"kvzoo"
+	pb "kvzoo/proto"
+)
+
+const (
+	epochTableName = "/epoch"
+	epochKey       = "epoch"
+)
+
+var ErrStaleEpoch = errors.New("epoch is stale, master has been switched")
+
+//request which doesn't change data is accepted whatever the epoch is,
+//proxy use them to check the node and find out the newest epoch
+var epochFreeMethods = map[string]bool{
+	"/pb.KVS/Checksum":       true,
+	"/pb.KVS/TableChecksums": true,
+	"/pb.KVS/LastLogIndex":   true,
+	"/pb.KVS/GetLogEntries":  true,
+	"/pb.KVS/Snapshot":       true,
+	"/pb.KVS/GetEpoch":       true,
+	"/pb.KVS/SetEpoch":       true,
+}
+
+//epoch is increased each time master is switched, with the address
+//of the new master, it's persisted if db is provided
+type epoch struct {
+	db     kvzoo.DB
+	table  kvzoo.Table
+	value  uint64
+	master string
+	lock   sync.RWMutex
+}
+
+func newEpoch(db kvzoo.DB) (*epoch, error) {
+	e := &epoch{
+		db: db,
+	}
+	if db == nil {
+		return e, nil
+	}
+
+	table, err := db.CreateOrGetTable(epochTableName)
+	if err != nil {
+		return nil, err
+	}
+	e.table = table
+
+	tx, err := table.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	value, err := tx.Get(epochKey)
+	if err == kvzoo.ErrNotFound {
+		return e, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var reply pb.GetEpochReply
+	if err := proto.Unmarshal(value, &reply); err != nil {
+		return nil, err
+	}
+	e.value = reply.Epoch
+	e.master = reply.Master
+	return e, nil
+}
+
+func (e *epoch) get() (uint64, string) {
+	e.lock.RLock()
+	defer e.lock.RUnlock()
+	return e.value, e.master
+}
+
+//epoch never goes back
+func (e *epoch) set(value uint64, master string) error {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	if value < e.value {
+		return fmt.Errorf("%s, current epoch is %d", ErrStaleEpoch.Error(), e.value)
+	} else if value == e.value && master == e.master {
+		return nil
+	}
+
+	if e.table != nil {
+		if err := e.save(value, master); err != nil {
+			return err
+		}
+	}
+	e.value = value
+	e.master = master
+	return nil
+}
+
+func (e *epoch) save(value uint64, master string) error {
+	data, err := proto.Marshal(&pb.GetEpochReply{
+		Epoch:  value,
+		Master: master,
+	})
+	if err != nil {
+		return err
+	}
+
+	tx, err := e.table.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Get(epochKey); err == kvzoo.ErrNotFound {
+		err = tx.Add(epochKey, data)
+	} else if err == nil {
+		err = tx.Update(epochKey, data)
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+//data of the node is cleaned with the db
+func (e *epoch) destroy() {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	e.table = nil
+	e.value = 0
+	e.master = ""
+}
+
+func (e *epoch) unaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if err := e.check(ctx, info.FullMethod); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (e *epoch) streamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := e.check(ss.Context(), info.FullMethod); err != nil {
+		return err
+	}
+	return handler(srv, ss)
+}
+
+func (e *epoch) check(ctx context.Context, method string) error {
+	if epochFreeMethods[method] {
+		return nil
+	}
+
+	current, _ := e.get()
+	if epochFromContext(ctx) < current {
+		return fmt.Errorf("%s, current epoch is %d", ErrStaleEpoch.Error(), current)
+	}
+	return nil
+}
+
+//request without epoch is treated as epoch zero
+func epochFromContext(ctx context.Context) uint64 {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if ok == false {
+		return 0
+	}
+
+	values := md.Get(pb.EpochMetadataKey)
+	if len(values) == 0 {
+		return 0
+	}
+
+	value, err := strconv.ParseUint(values[0], 10, 64)
+	if err != nil {
+		return 0
+	}
+	return value
+}
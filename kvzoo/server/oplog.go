@@ -0,0 +1,247 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/golang/protobuf/proto"
+
+	"cement/log"
+	"kvzoo"
+	pb "kvzoo/proto"
+)
+
+const (
+	MaxOpLogEntryCount      = 100000
+	MaxLogEntriesPerRequest = 1000
+	opLogTableName          = "/oplog"
+)
+
+var (
+	ErrOpLogDisabled   = errors.New("operation log isn't enabled")
+	ErrLogTruncated    = errors.New("operation log has been truncated")
+	ErrIndexOutOfOrder = errors.New("log index is out of order")
+)
+
+//opLog records every committed transaction with a monotonically
+//increasing index, the oldest entries are removed once the count
+//exceeds MaxOpLogEntryCount
+type opLog struct {
+	db    kvzoo.DB
+	table kvzoo.Table
+	//index of the oldest entry, zero means log is empty
+	first uint64
+	last  uint64
+	//oldest entry is the mark left by reset which has no operations
+	firstIsMark bool
+	lock        sync.Mutex
+}
+
+func newOpLog(db kvzoo.DB) (*opLog, error) {
+	table, err := db.CreateOrGetTable(opLogTableName)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := table.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	entries, err := tx.List()
+	if err != nil {
+		return nil, err
+	}
+
+	l := &opLog{
+		db:    db,
+		table: table,
+	}
+	for key := range entries {
+		index, err := strconv.ParseUint(key, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid log entry key %s", key)
+		}
+		if l.first == 0 || index < l.first {
+			l.first = index
+		}
+		if index > l.last {
+			l.last = index
+		}
+	}
+
+	if l.first != 0 {
+		var entry pb.LogEntry
+		if err := proto.Unmarshal(entries[logEntryKey(l.first)], &entry); err != nil {
+			return nil, err
+		}
+		l.firstIsMark = isMark(&entry)
+	}
+	return l, nil
+}
+
+func isMark(entry *pb.LogEntry) bool {
+	return entry.TableName == "" && len(entry.Operations) == 0
+}
+
+func logEntryKey(index uint64) string {
+	return fmt.Sprintf("%020d", index)
+}
+
+func (l *opLog) lastIndex() uint64 {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return l.last
+}
+
+//index zero means assign next index to the entry, otherwise the index
+//should be exactly next to the last one, commit is called only when
+//the index is valid and the entry will be recorded only if commit succeed
+//
+//log is in another db, so it can't be written in the same transaction
+//with the data, if the entry can't be recorded after the data is
+//committed, log is truncated to the index, the node which misses the
+//entry can't replay the log and will be resynced by copying tables
+func (l *opLog) append(entry *pb.LogEntry, commit func() error) (uint64, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if entry.Index == 0 {
+		entry.Index = l.last + 1
+	} else if entry.Index != l.last+1 {
+		return 0, fmt.Errorf("%s, expect %d but get %d", ErrIndexOutOfOrder.Error(), l.last+1, entry.Index)
+	}
+
+	if err := commit(); err != nil {
+		return 0, err
+	}
+
+	if err := l.add(entry); err != nil {
+		log.Warnf("record log entry %d failed:%s, log is truncated", entry.Index, err.Error())
+		if err := l.clear(entry.Index); err != nil {
+			log.Warnf("truncate log to %d failed:%s", entry.Index, err.Error())
+			//entries before the index aren't served any more
+			l.first = entry.Index
+			l.last = entry.Index
+			l.firstIsMark = true
+		}
+	}
+	return entry.Index, nil
+}
+
+func (l *opLog) add(entry *pb.LogEntry) error {
+	value, err := proto.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	tx, err := l.table.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := tx.Add(logEntryKey(entry.Index), value); err != nil {
+		return err
+	}
+
+	first := l.first
+	if first == 0 {
+		first = entry.Index
+	}
+	for ; entry.Index-first+1 > MaxOpLogEntryCount; first++ {
+		if err := tx.Delete(logEntryKey(first)); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	if l.first == 0 {
+		l.firstIsMark = isMark(entry)
+	} else if l.first != first {
+		l.firstIsMark = false
+	}
+	l.first = first
+	l.last = entry.Index
+	return nil
+}
+
+//return entries after since, at most limit entries are returned
+func (l *opLog) entriesSince(since uint64, limit int) ([]*pb.LogEntry, uint64, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if since > l.last {
+		return nil, 0, fmt.Errorf("index %d is ahead of last log index %d", since, l.last)
+	} else if since == l.last {
+		return nil, l.last, nil
+	}
+
+	if since+1 < l.first || (since+1 == l.first && l.firstIsMark) {
+		return nil, 0, fmt.Errorf("%s, oldest entry is %d", ErrLogTruncated.Error(), l.first)
+	}
+
+	tx, err := l.table.Begin()
+	if err != nil {
+		return nil, 0, err
+	}
+	defer tx.Rollback()
+
+	var entries []*pb.LogEntry
+	for index := since + 1; index <= l.last && len(entries) < limit; index++ {
+		value, err := tx.Get(logEntryKey(index))
+		if err != nil {
+			return nil, 0, fmt.Errorf("get log entry %d failed:%s", index, err.Error())
+		}
+
+		var entry pb.LogEntry
+		if err := proto.Unmarshal(value, &entry); err != nil {
+			return nil, 0, err
+		}
+		entries = append(entries, &entry)
+	}
+	return entries, l.last, nil
+}
+
+//remove all the entries, and leave an empty entry as the mark of
+//the index, which normally is the master log index when the data
+//is copied from master
+func (l *opLog) reset(index uint64) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return l.clear(index)
+}
+
+//caller should hold the lock
+func (l *opLog) clear(index uint64) error {
+	if err := l.db.DeleteTable(opLogTableName); err != nil {
+		return err
+	}
+
+	table, err := l.db.CreateOrGetTable(opLogTableName)
+	if err != nil {
+		return err
+	}
+	l.table = table
+	l.first = 0
+	l.last = 0
+	l.firstIsMark = false
+
+	if index == 0 {
+		return nil
+	}
+	return l.add(&pb.LogEntry{Index: index})
+}
+
+func (l *opLog) close() error {
+	return l.db.Close()
+}
+
+func (l *opLog) destroy() error {
+	return l.db.Destroy()
+}
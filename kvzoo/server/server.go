@@ -4,7 +4,7 @@ import (
 	"net"
 
 	"kvzoo"
-	"kvzoo/backend/bolt"
+	"kvzoo/backend"
 	pb "kvzoo/proto"
 	"google.golang.org/grpc"
 )
@@ -15,24 +15,79 @@ type KVGRPCServer struct {
 	listener net.Listener
 }
 
-func NewWithBoltDB(addr string, dbFilePath string) (*KVGRPCServer, error) {
-	db, err := bolt.New(dbFilePath)
+const OpLogFileSuffix = ".oplog"
+
+func NewWithBoltDB(addr string, dbFilePath string, opts ...Option) (*KVGRPCServer, error) {
+	return NewWithBackend(addr, backend.Config{
+		Type: backend.Bolt,
+		Path: dbFilePath,
+	}, opts...)
+}
+
+//operation log is saved with the same backend, data is kept if server
+//fails to start, since the backend may be shared like postgresql
+func NewWithBackend(addr string, conf backend.Config, opts ...Option) (*KVGRPCServer, error) {
+	o, err := loadOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := backend.New(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	logDB, err := backend.New(conf.OpLogConfig(OpLogFileSuffix))
 	if err != nil {
+		db.Close()
 		return nil, err
 	}
 
-	if s, err := New(addr, db); err == nil {
+	if s, err := newWithOpLog(addr, db, logDB, o); err == nil {
 		return s, err
 	} else {
-		db.Destroy()
+		db.Close()
+		logDB.Close()
 		return nil, err
 	}
 }
 
-func New(addr string, db kvzoo.DB) (*KVGRPCServer, error) {
-	server := grpc.NewServer()
+func New(addr string, db kvzoo.DB, opts ...Option) (*KVGRPCServer, error) {
+	o, err := loadOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	epoch, _ := newEpoch(nil)
+	return newServer(addr, newKVService(db, nil, epoch), o)
+}
+
+//every committed transaction will be recorded into logDB,
+//epoch of master is saved in logDB too
+func NewWithOpLog(addr string, db kvzoo.DB, logDB kvzoo.DB, opts ...Option) (*KVGRPCServer, error) {
+	o, err := loadOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	return newWithOpLog(addr, db, logDB, o)
+}
+
+func newWithOpLog(addr string, db kvzoo.DB, logDB kvzoo.DB, o *options) (*KVGRPCServer, error) {
+	opLog, err := newOpLog(logDB)
+	if err != nil {
+		return nil, err
+	}
+
+	epoch, err := newEpoch(logDB)
+	if err != nil {
+		return nil, err
+	}
+
+	return newServer(addr, newKVService(db, opLog, epoch), o)
+}
 
-	service := newKVService(db)
+func newServer(addr string, service *KVService, o *options) (*KVGRPCServer, error) {
+	server := grpc.NewServer(o.serverOptions(service)...)
 	pb.RegisterKVSServer(server, service)
 
 	listener, err := net.Listen("tcp", addr)
@@ -52,6 +107,7 @@ func (s *KVGRPCServer) Start() error {
 }
 
 func (s *KVGRPCServer) Stop() error {
+	s.service.stopWatch()
 	s.server.GracefulStop()
 	s.service.Close()
 	return nil
@@ -5,37 +5,62 @@ import (
 	"fmt"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/golang/protobuf/ptypes/empty"
 
+	"cement/log"
 	"kvzoo"
 	pb "kvzoo/proto"
 )
 
-const MaxOpenTxCount = 2000
+const (
+	MaxOpenTxCount = 2000
+	ScanBatchSize  = 500
+)
 
 type KVService struct {
 	db       kvzoo.DB
+	opLog    *opLog
+	epoch    *epoch
 	nextTxId int64
 
 	openedTables map[string]kvzoo.Table
 	tableLock    sync.RWMutex
 
-	openedTxs map[int64]kvzoo.Transaction
+	openedTxs map[int64]*transaction
 	txLock    sync.RWMutex
+	//transaction begins with read lock, delete table holds the write
+	//lock until it's deleted, so no transaction is opened meanwhile
+	deleteLock sync.RWMutex
+
+	stopCh   chan struct{}
+	stopOnce sync.Once
+}
+
+type transaction struct {
+	kvzoo.Transaction
+	tableName  string
+	operations []*pb.Operation
 }
 
-func newKVService(db kvzoo.DB) *KVService {
+func newKVService(db kvzoo.DB, opLog *opLog, epoch *epoch) *KVService {
 	return &KVService{
 		db:           db,
+		opLog:        opLog,
+		epoch:        epoch,
 		nextTxId:     0,
 		openedTables: make(map[string]kvzoo.Table),
-		openedTxs:    make(map[int64]kvzoo.Transaction),
+		openedTxs:    make(map[int64]*transaction),
+		stopCh:       make(chan struct{}),
 	}
 }
 
 func (s *KVService) Close() {
 	s.db.Close()
+	if s.opLog != nil {
+		s.opLog.close()
+	}
 }
 
 func (s *KVService) Checksum(ctx context.Context, in *pb.ChecksumRequest) (*pb.ChecksumReply, error) {
@@ -49,13 +74,43 @@ func (s *KVService) Checksum(ctx context.Context, in *pb.ChecksumRequest) (*pb.C
 	}
 }
 
+func (s *KVService) TableChecksums(ctx context.Context, in *pb.TableChecksumsRequest) (*pb.TableChecksumsReply, error) {
+	checksums, err := s.db.TableChecksums()
+	if err != nil {
+		return nil, err
+	}
+
+	reply := &pb.TableChecksumsReply{
+		Checksums: make(map[string]string, len(checksums)),
+	}
+	for tn, cs := range checksums {
+		reply.Checksums[string(tn)] = cs
+	}
+	return reply, nil
+}
+
+func (s *KVService) TableNames(ctx context.Context, in *pb.TableNamesRequest) (*pb.TableNamesReply, error) {
+	names, err := s.db.TableNames()
+	if err != nil {
+		return nil, err
+	}
+
+	reply := &pb.TableNamesReply{
+		Names: make([]string, 0, len(names)),
+	}
+	for _, tn := range names {
+		reply.Names = append(reply.Names, string(tn))
+	}
+	return reply, nil
+}
+
 func (s *KVService) Destroy(ctx context.Context, in *pb.DestroyRequest) (*empty.Empty, error) {
 	s.tableLock.Lock()
 	defer s.tableLock.Unlock()
 	s.openedTables = make(map[string]kvzoo.Table)
 	s.txLock.Lock()
 	defer s.txLock.Unlock()
-	s.openedTxs = make(map[int64]kvzoo.Transaction)
+	s.openedTxs = make(map[int64]*transaction)
 
 	if err := s.db.Close(); err != nil {
 		return nil, err
@@ -63,9 +118,15 @@ func (s *KVService) Destroy(ctx context.Context, in *pb.DestroyRequest) (*empty.
 
 	if err := s.db.Destroy(); err != nil {
 		return nil, err
-	} else {
-		return &empty.Empty{}, nil
 	}
+
+	if s.opLog != nil {
+		if err := s.opLog.destroy(); err != nil {
+			return nil, err
+		}
+	}
+	s.epoch.destroy()
+	return &empty.Empty{}, nil
 }
 
 func (s *KVService) CreateOrGetTable(ctx context.Context, in *pb.CreateOrGetTableRequest) (*empty.Empty, error) {
@@ -88,20 +149,71 @@ func (s *KVService) CreateOrGetTable(ctx context.Context, in *pb.CreateOrGetTabl
 	return &empty.Empty{}, nil
 }
 
-func (s *KVService) DeleteTable(ctx context.Context, in *pb.DeleteTableRequest) (*empty.Empty, error) {
-	s.tableLock.Lock()
-	delete(s.openedTables, in.Name)
-	s.tableLock.Unlock()
-
+func (s *KVService) DeleteTable(ctx context.Context, in *pb.DeleteTableRequest) (*pb.DeleteTableReply, error) {
 	tn, err := kvzoo.NewTableName(in.Name)
 	if err != nil {
 		return nil, err
 	}
 
-	if err := s.db.DeleteTable(tn); err != nil {
+	index, err := s.deleteTable(tn, newDeleteTableEntry(in.Index, in.Name), false)
+	if err != nil {
 		return nil, err
-	} else {
-		return &empty.Empty{}, nil
+	}
+	return &pb.DeleteTableReply{
+		Index: index,
+	}, nil
+}
+
+//delete table waits for the opened transactions, whose commit needs
+//the log lock, so opened transactions are finished first, then the
+//table is deleted with the log lock held, index is checked before the
+//table is deleted, and no commit could take an index before it, when
+//log is replayed, table may not exist on this node, the entry is still
+//recorded
+func (s *KVService) deleteTable(tn kvzoo.TableName, entry *pb.LogEntry, replay bool) (uint64, error) {
+	s.deleteLock.Lock()
+	defer s.deleteLock.Unlock()
+	s.waitOpenedTxs()
+
+	deleteTable := func() error {
+		s.tableLock.Lock()
+		delete(s.openedTables, entry.TableName)
+		s.tableLock.Unlock()
+
+		err := s.db.DeleteTable(tn)
+		if err != nil && replay {
+			log.Warnf("delete table %s failed:%s", entry.TableName, err.Error())
+			return nil
+		}
+		return err
+	}
+	if s.opLog == nil {
+		return 0, deleteTable()
+	}
+	return s.opLog.append(entry, deleteTable)
+}
+
+func (s *KVService) waitOpenedTxs() {
+	for {
+		s.txLock.RLock()
+		count := len(s.openedTxs)
+		s.txLock.RUnlock()
+		if count == 0 {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func newDeleteTableEntry(index uint64, tableName string) *pb.LogEntry {
+	return &pb.LogEntry{
+		Index:     index,
+		TableName: tableName,
+		Operations: []*pb.Operation{
+			&pb.Operation{
+				Type: pb.OperationType_DELETE_TABLE,
+			},
+		},
 	}
 }
 
@@ -114,6 +226,9 @@ func (s *KVService) BeginTransaction(ctx context.Context, in *pb.BeginTransactio
 		return nil, fmt.Errorf("table %s doesn't exists", in.TableName)
 	}
 
+	s.deleteLock.RLock()
+	defer s.deleteLock.RUnlock()
+
 	s.txLock.RLock()
 	if len(s.openedTxs) > MaxOpenTxCount {
 		s.txLock.RUnlock()
@@ -125,17 +240,23 @@ func (s *KVService) BeginTransaction(ctx context.Context, in *pb.BeginTransactio
 	if err != nil {
 		return nil, err
 	}
+	if in.Raw {
+		tx = kvzoo.RawTransaction(tx)
+	}
 
 	id := atomic.AddInt64(&s.nextTxId, 1)
 	s.txLock.Lock()
-	s.openedTxs[id] = tx
+	s.openedTxs[id] = &transaction{
+		Transaction: tx,
+		tableName:   in.TableName,
+	}
 	s.txLock.Unlock()
 	return &pb.BeginTransactionReply{
 		TxId: id,
 	}, nil
 }
 
-func (s *KVService) CommitTransaction(ctx context.Context, in *pb.CommitTransactionRequest) (*empty.Empty, error) {
+func (s *KVService) CommitTransaction(ctx context.Context, in *pb.CommitTransactionRequest) (*pb.CommitTransactionReply, error) {
 	s.txLock.Lock()
 	defer s.txLock.Unlock()
 
@@ -143,14 +264,29 @@ func (s *KVService) CommitTransaction(ctx context.Context, in *pb.CommitTransact
 	if ok == false {
 		return nil, fmt.Errorf("invalid transaction id")
 	}
-
-	err := tx.Commit()
 	delete(s.openedTxs, in.TxId)
+
+	if s.opLog == nil || len(tx.operations) == 0 {
+		if err := tx.Commit(); err != nil {
+			return nil, err
+		} else {
+			return &pb.CommitTransactionReply{}, nil
+		}
+	}
+
+	index, err := s.opLog.append(&pb.LogEntry{
+		Index:      in.Index,
+		TableName:  tx.tableName,
+		Operations: tx.operations,
+	}, tx.Commit)
 	if err != nil {
+		tx.Rollback()
 		return nil, err
-	} else {
-		return &empty.Empty{}, nil
 	}
+
+	return &pb.CommitTransactionReply{
+		Index: index,
+	}, nil
 }
 
 func (s *KVService) RollbackTransaction(ctx context.Context, in *pb.RollbackTransactionRequest) (*empty.Empty, error) {
@@ -209,6 +345,56 @@ func (s *KVService) List(ctx context.Context, in *pb.ListRequest) (*pb.ListRespo
 	}, nil
 }
 
+//scan result is sent in batches, each batch has at most
+//ScanBatchSize values, cursor is set in the last batch
+func (s *KVService) Scan(in *pb.ScanRequest, stream pb.KVS_ScanServer) error {
+	s.txLock.RLock()
+	tx, ok := s.openedTxs[in.TxId]
+	if ok == false {
+		s.txLock.RUnlock()
+		return fmt.Errorf("invalid transaction id")
+	}
+
+	kvs, cursor, err := tx.Scan(kvzoo.ScanOptions{
+		Prefix:  in.Prefix,
+		Start:   in.Start,
+		End:     in.End,
+		Limit:   int(in.Limit),
+		Cursor:  in.Cursor,
+		Reverse: in.Reverse,
+	})
+	s.txLock.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	for {
+		size := len(kvs)
+		if size > ScanBatchSize {
+			size = ScanBatchSize
+		}
+
+		reply := &pb.ScanResponse{
+			Values: make([]*pb.KeyValue, 0, size),
+		}
+		for _, kv := range kvs[:size] {
+			reply.Values = append(reply.Values, &pb.KeyValue{
+				Key:   kv.Key,
+				Value: kv.Value,
+			})
+		}
+		kvs = kvs[size:]
+		if len(kvs) == 0 {
+			reply.NextCursor = cursor
+			return stream.Send(reply)
+		}
+
+		if err := stream.Send(reply); err != nil {
+			return err
+		}
+	}
+}
+
 func (s *KVService) Add(ctx context.Context, in *pb.AddRequest) (*empty.Empty, error) {
 	s.txLock.RLock()
 	defer s.txLock.RUnlock()
@@ -220,9 +406,14 @@ func (s *KVService) Add(ctx context.Context, in *pb.AddRequest) (*empty.Empty, e
 
 	if err := tx.Add(in.Key, in.Value); err != nil {
 		return nil, err
-	} else {
-		return &empty.Empty{}, nil
 	}
+
+	tx.operations = append(tx.operations, &pb.Operation{
+		Type:  pb.OperationType_ADD,
+		Key:   in.Key,
+		Value: in.Value,
+	})
+	return &empty.Empty{}, nil
 }
 
 func (s *KVService) Delete(ctx context.Context, in *pb.DeleteRequest) (*empty.Empty, error) {
@@ -236,9 +427,13 @@ func (s *KVService) Delete(ctx context.Context, in *pb.DeleteRequest) (*empty.Em
 
 	if err := tx.Delete(in.Key); err != nil {
 		return nil, err
-	} else {
-		return &empty.Empty{}, nil
 	}
+
+	tx.operations = append(tx.operations, &pb.Operation{
+		Type: pb.OperationType_DELETE,
+		Key:  in.Key,
+	})
+	return &empty.Empty{}, nil
 }
 
 func (s *KVService) Update(ctx context.Context, in *pb.UpdateRequest) (*empty.Empty, error) {
@@ -252,7 +447,277 @@ func (s *KVService) Update(ctx context.Context, in *pb.UpdateRequest) (*empty.Em
 
 	if err := tx.Update(in.Key, in.Value); err != nil {
 		return nil, err
-	} else {
-		return &empty.Empty{}, nil
 	}
+
+	tx.operations = append(tx.operations, &pb.Operation{
+		Type:  pb.OperationType_UPDATE,
+		Key:   in.Key,
+		Value: in.Value,
+	})
+	return &empty.Empty{}, nil
+}
+
+func (s *KVService) ExpireAt(ctx context.Context, in *pb.ExpireAtRequest) (*empty.Empty, error) {
+	s.txLock.RLock()
+	defer s.txLock.RUnlock()
+
+	tx, ok := s.openedTxs[in.TxId]
+	if ok == false {
+		return nil, fmt.Errorf("invalid transaction id")
+	}
+
+	if err := tx.ExpireAt(in.Key, expireTime(in.ExpireAt)); err != nil {
+		return nil, err
+	}
+
+	tx.operations = append(tx.operations, &pb.Operation{
+		Type:     pb.OperationType_EXPIRE,
+		Key:      in.Key,
+		ExpireAt: in.ExpireAt,
+	})
+	return &empty.Empty{}, nil
+}
+
+//zero means no ttl
+func expireTime(nano int64) time.Time {
+	if nano == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, nano)
+}
+
+//compare is done on master, it's logged as update
+func (s *KVService) CompareAndSwap(ctx context.Context, in *pb.CompareAndSwapRequest) (*empty.Empty, error) {
+	s.txLock.RLock()
+	defer s.txLock.RUnlock()
+
+	tx, ok := s.openedTxs[in.TxId]
+	if ok == false {
+		return nil, fmt.Errorf("invalid transaction id")
+	}
+
+	if err := tx.CompareAndSwap(in.Key, in.OldValue, in.NewValue); err != nil {
+		return nil, err
+	}
+
+	tx.operations = append(tx.operations, &pb.Operation{
+		Type:  pb.OperationType_UPDATE,
+		Key:   in.Key,
+		Value: in.NewValue,
+	})
+	return &empty.Empty{}, nil
+}
+
+//slave applies the result of compare-and-swap on master, key which
+//has expired on this node because of clock skew is still updated,
+//it's logged as update
+func (s *KVService) Put(ctx context.Context, in *pb.PutRequest) (*empty.Empty, error) {
+	s.txLock.RLock()
+	defer s.txLock.RUnlock()
+
+	tx, ok := s.openedTxs[in.TxId]
+	if ok == false {
+		return nil, fmt.Errorf("invalid transaction id")
+	}
+
+	if err := kvzoo.Put(tx.Transaction, in.Key, in.Value); err != nil {
+		return nil, err
+	}
+
+	tx.operations = append(tx.operations, &pb.Operation{
+		Type:  pb.OperationType_UPDATE,
+		Key:   in.Key,
+		Value: in.Value,
+	})
+	return &empty.Empty{}, nil
+}
+
+//expiration is decided by master, deleted keys are logged as delete
+func (s *KVService) DeleteExpired(ctx context.Context, in *pb.DeleteExpiredRequest) (*pb.DeleteExpiredReply, error) {
+	s.txLock.RLock()
+	defer s.txLock.RUnlock()
+
+	tx, ok := s.openedTxs[in.TxId]
+	if ok == false {
+		return nil, fmt.Errorf("invalid transaction id")
+	}
+
+	keys, err := tx.DeleteExpired()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, key := range keys {
+		tx.operations = append(tx.operations, &pb.Operation{
+			Type: pb.OperationType_DELETE,
+			Key:  key,
+		})
+	}
+	return &pb.DeleteExpiredReply{
+		Keys: keys,
+	}, nil
+}
+
+func (s *KVService) LastLogIndex(ctx context.Context, in *pb.LastLogIndexRequest) (*pb.LastLogIndexReply, error) {
+	if s.opLog == nil {
+		return nil, ErrOpLogDisabled
+	}
+
+	return &pb.LastLogIndexReply{
+		Index: s.opLog.lastIndex(),
+	}, nil
+}
+
+func (s *KVService) GetLogEntries(ctx context.Context, in *pb.GetLogEntriesRequest) (*pb.GetLogEntriesReply, error) {
+	if s.opLog == nil {
+		return nil, ErrOpLogDisabled
+	}
+
+	limit := int(in.Limit)
+	if limit == 0 || limit > MaxLogEntriesPerRequest {
+		limit = MaxLogEntriesPerRequest
+	}
+
+	entries, lastIndex, err := s.opLog.entriesSince(in.Since, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.GetLogEntriesReply{
+		Entries:   entries,
+		LastIndex: lastIndex,
+	}, nil
+}
+
+//entries which are already applied will be skipped, and applying
+//add and update operation is idempotent, so replay the same entries
+//more than once is safe
+func (s *KVService) ReplayLogEntries(ctx context.Context, in *pb.ReplayLogEntriesRequest) (*empty.Empty, error) {
+	if s.opLog == nil {
+		return nil, ErrOpLogDisabled
+	}
+
+	for _, entry := range in.Entries {
+		if entry.Index <= s.opLog.lastIndex() {
+			continue
+		}
+
+		if err := s.replayLogEntry(entry); err != nil {
+			return nil, fmt.Errorf("replay log entry %d failed:%s", entry.Index, err.Error())
+		}
+	}
+	return &empty.Empty{}, nil
+}
+
+func (s *KVService) replayLogEntry(entry *pb.LogEntry) error {
+	if isMark(entry) {
+		_, err := s.opLog.append(entry, func() error { return nil })
+		return err
+	}
+
+	tn, err := kvzoo.NewTableName(entry.TableName)
+	if err != nil {
+		return err
+	}
+
+	if len(entry.Operations) == 1 && entry.Operations[0].Type == pb.OperationType_DELETE_TABLE {
+		_, err := s.deleteTable(tn, entry, true)
+		return err
+	}
+
+	table, err := s.db.CreateOrGetTable(tn)
+	if err != nil {
+		return err
+	}
+
+	tx, err := table.Begin()
+	if err != nil {
+		return err
+	}
+
+	for _, op := range entry.Operations {
+		if err := replayOperation(tx, op); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	if _, err := s.opLog.append(entry, tx.Commit); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return nil
+}
+
+func replayOperation(tx kvzoo.Transaction, op *pb.Operation) error {
+	switch op.Type {
+	case pb.OperationType_DELETE:
+		return tx.Delete(op.Key)
+	case pb.OperationType_ADD:
+		if _, err := tx.Get(op.Key); err == kvzoo.ErrNotFound {
+			return tx.Add(op.Key, op.Value)
+		} else if err != nil {
+			return err
+		}
+		return tx.Update(op.Key, op.Value)
+	case pb.OperationType_UPDATE:
+		//update keeps the ttl on master, even if the key has expired
+		//on this node
+		return kvzoo.Put(tx, op.Key, op.Value)
+	case pb.OperationType_EXPIRE:
+		//key may be already expired on this node
+		if err := tx.ExpireAt(op.Key, expireTime(op.ExpireAt)); err != nil && err != kvzoo.ErrNotFound {
+			return err
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown operation %s", op.Type.String())
+	}
+}
+
+func (s *KVService) ResetLog(ctx context.Context, in *pb.ResetLogRequest) (*empty.Empty, error) {
+	if s.opLog == nil {
+		return nil, ErrOpLogDisabled
+	}
+
+	if err := s.opLog.reset(in.Index); err != nil {
+		return nil, err
+	}
+	return &empty.Empty{}, nil
+}
+
+func (s *KVService) GetEpoch(ctx context.Context, in *pb.GetEpochRequest) (*pb.GetEpochReply, error) {
+	epoch, master := s.epoch.get()
+	return &pb.GetEpochReply{
+		Epoch:  epoch,
+		Master: master,
+	}, nil
+}
+
+func (s *KVService) SetEpoch(ctx context.Context, in *pb.SetEpochRequest) (*empty.Empty, error) {
+	if err := s.epoch.set(in.Epoch, in.Master); err != nil {
+		return nil, err
+	}
+	return &empty.Empty{}, nil
+}
+
+//data of the snapshot is sent in chunks, each chunk is no more
+//than MaxSnapshotChunkSize
+func (s *KVService) Snapshot(in *pb.SnapshotRequest, stream pb.KVS_SnapshotServer) error {
+	return s.db.Snapshot(&snapshotChunkWriter{stream: stream})
+}
+
+//logs before restore cann't be applied to the restored data, so log
+//is reset, slave will copy data from master instead of replay logs
+func (s *KVService) Restore(stream pb.KVS_RestoreServer) error {
+	if err := s.db.Restore(&snapshotChunkReader{stream: stream}); err != nil {
+		return err
+	}
+
+	if s.opLog != nil {
+		if err := s.opLog.reset(s.opLog.lastIndex() + 1); err != nil {
+			return err
+		}
+	}
+	return stream.SendAndClose(&empty.Empty{})
 }
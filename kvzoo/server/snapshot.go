@@ -0,0 +1,47 @@
+package server
+
+import (
+	pb "kvzoo/proto"
+)
+
+const MaxSnapshotChunkSize = 1024 * 1024
+
+type snapshotChunkWriter struct {
+	stream pb.KVS_SnapshotServer
+}
+
+func (w *snapshotChunkWriter) Write(data []byte) (int, error) {
+	written := 0
+	for len(data) > 0 {
+		size := len(data)
+		if size > MaxSnapshotChunkSize {
+			size = MaxSnapshotChunkSize
+		}
+
+		if err := w.stream.Send(&pb.SnapshotChunk{Data: data[:size]}); err != nil {
+			return written, err
+		}
+		written += size
+		data = data[size:]
+	}
+	return written, nil
+}
+
+type snapshotChunkReader struct {
+	stream pb.KVS_RestoreServer
+	data   []byte
+}
+
+func (r *snapshotChunkReader) Read(buf []byte) (int, error) {
+	for len(r.data) == 0 {
+		chunk, err := r.stream.Recv()
+		if err != nil {
+			return 0, err
+		}
+		r.data = chunk.Data
+	}
+
+	n := copy(buf, r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
@@ -0,0 +1,67 @@
+package server
+
+import (
+	"errors"
+
+	"google.golang.org/grpc/metadata"
+
+	"kvzoo"
+	pb "kvzoo/proto"
+)
+
+var ErrWatcherStopped = errors.New("watcher is stopped")
+
+var eventTypes = map[kvzoo.EventType]pb.OperationType{
+	kvzoo.EventAdd:         pb.OperationType_ADD,
+	kvzoo.EventUpdate:      pb.OperationType_UPDATE,
+	kvzoo.EventDelete:      pb.OperationType_DELETE,
+	kvzoo.EventDeleteTable: pb.OperationType_DELETE_TABLE,
+}
+
+//header is sent once the watcher is created, so client knows the
+//watch is ready, the stream ends with error when the watcher is
+//stopped by db, client should reload the table and watch again
+func (s *KVService) Watch(in *pb.WatchRequest, stream pb.KVS_WatchServer) error {
+	tn, err := kvzoo.NewTableName(in.TableName)
+	if err != nil {
+		return err
+	}
+
+	watcher, err := s.db.Watch(tn)
+	if err != nil {
+		return err
+	}
+	defer watcher.Stop()
+
+	if err := stream.SendHeader(metadata.MD{}); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		case <-s.stopCh:
+			return ErrWatcherStopped
+		case e, ok := <-watcher.Events():
+			if ok == false {
+				return ErrWatcherStopped
+			}
+			if err := stream.Send(&pb.Operation{
+				Type:  eventTypes[e.Type],
+				Key:   e.Key,
+				Value: e.Value,
+			}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+//watch streams never end by themselves, they have to be stopped
+//before graceful stop of grpc server
+func (s *KVService) stopWatch() {
+	s.stopOnce.Do(func() {
+		close(s.stopCh)
+	})
+}
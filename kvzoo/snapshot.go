@@ -0,0 +1,166 @@
+package kvzoo
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+//snapshot is a gzipped stream of records, each record is table name,
+//key and value, every field is prefixed with its length in uvarint,
+//record with empty key marks the begin of a table, so empty table is
+//kept in snapshot too
+const (
+	snapshotMagic      = "KVZOO-SNAPSHOT-V1"
+	maxSnapshotField   = 64 * 1024 * 1024
+	snapshotBufferSize = 64 * 1024
+)
+
+var ErrInvalidSnapshot = errors.New("invalid snapshot")
+
+type SnapshotRecord struct {
+	Table TableName
+	Key   string
+	Value []byte
+}
+
+func (r *SnapshotRecord) IsTable() bool {
+	return r.Key == ""
+}
+
+type SnapshotWriter struct {
+	zw *gzip.Writer
+	w  *bufio.Writer
+}
+
+func NewSnapshotWriter(w io.Writer) (*SnapshotWriter, error) {
+	zw := gzip.NewWriter(w)
+	sw := &SnapshotWriter{
+		zw: zw,
+		w:  bufio.NewWriterSize(zw, snapshotBufferSize),
+	}
+	if err := sw.writeField([]byte(snapshotMagic)); err != nil {
+		return nil, err
+	}
+	return sw, nil
+}
+
+func (w *SnapshotWriter) WriteTable(tableName TableName) error {
+	return w.write(tableName, "", nil)
+}
+
+func (w *SnapshotWriter) Write(tableName TableName, key string, value []byte) error {
+	if key == "" {
+		return fmt.Errorf("empty key in table %s", tableName)
+	}
+	return w.write(tableName, key, value)
+}
+
+func (w *SnapshotWriter) write(tableName TableName, key string, value []byte) error {
+	if err := w.writeField([]byte(tableName)); err != nil {
+		return err
+	}
+	if err := w.writeField([]byte(key)); err != nil {
+		return err
+	}
+	return w.writeField(value)
+}
+
+func (w *SnapshotWriter) writeField(data []byte) error {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], uint64(len(data)))
+	if _, err := w.w.Write(buf[:n]); err != nil {
+		return err
+	}
+	_, err := w.w.Write(data)
+	return err
+}
+
+//flush the data, the underlying writer isn't closed
+func (w *SnapshotWriter) Close() error {
+	if err := w.w.Flush(); err != nil {
+		return err
+	}
+	return w.zw.Close()
+}
+
+type SnapshotReader struct {
+	zr *gzip.Reader
+	r  *bufio.Reader
+}
+
+func NewSnapshotReader(r io.Reader) (*SnapshotReader, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%s", ErrInvalidSnapshot.Error(), err.Error())
+	}
+
+	sr := &SnapshotReader{
+		zr: zr,
+		r:  bufio.NewReaderSize(zr, snapshotBufferSize),
+	}
+	if magic, err := sr.readField(); err != nil || string(magic) != snapshotMagic {
+		return nil, ErrInvalidSnapshot
+	}
+	return sr, nil
+}
+
+//return io.EOF when all the records are read
+func (r *SnapshotReader) Next() (*SnapshotRecord, error) {
+	table, err := r.readField()
+	if err != nil {
+		return nil, err
+	}
+
+	tableName, err := NewTableName(string(table))
+	if err != nil {
+		return nil, fmt.Errorf("%s:%s", ErrInvalidSnapshot.Error(), err.Error())
+	}
+
+	key, err := r.readField()
+	if err != nil {
+		return nil, unexpectedEOF(err)
+	}
+
+	value, err := r.readField()
+	if err != nil {
+		return nil, unexpectedEOF(err)
+	}
+
+	return &SnapshotRecord{
+		Table: tableName,
+		Key:   string(key),
+		Value: value,
+	}, nil
+}
+
+func (r *SnapshotReader) readField() ([]byte, error) {
+	size, err := binary.ReadUvarint(r.r)
+	if err != nil {
+		return nil, err
+	}
+
+	if size > maxSnapshotField {
+		return nil, fmt.Errorf("%s:field size %d exceeds the limit", ErrInvalidSnapshot.Error(), size)
+	}
+
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r.r, data); err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	return data, nil
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+func (r *SnapshotReader) Close() error {
+	return r.zr.Close()
+}
@@ -0,0 +1,130 @@
+package tests
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	ut "cement/unittest"
+	"kvzoo"
+	"kvzoo/backend/bolt"
+	"kvzoo/client"
+	"kvzoo/server"
+)
+
+func withSecureDB(t *testing.T, opts []server.Option, test func(addr string)) {
+	db, err := bolt.New("secure.db")
+	ut.Equal(t, err, nil)
+	addr := "127.0.0.1:7790"
+	rdb, err := server.New(addr, db, opts...)
+	ut.Equal(t, err, nil)
+	go rdb.Start()
+	defer func() {
+		rdb.Stop()
+		db.Destroy()
+	}()
+	test(addr)
+}
+
+func createTable(addr string, opts ...client.Option) error {
+	c, err := client.NewProxy(addr, nil, opts...)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	_, err = c.CreateOrGetTable(kvzoo.TableName("/secure"))
+	return err
+}
+
+func TestTokenAuth(t *testing.T) {
+	withSecureDB(t, []server.Option{server.WithToken("secret")}, func(addr string) {
+		ut.Assert(t, createTable(addr) != nil, "request without token should be rejected")
+		ut.Assert(t, createTable(addr, client.WithToken("xxx")) != nil, "request with wrong token should be rejected")
+		ut.Equal(t, createTable(addr, client.WithToken("secret")), nil)
+	})
+}
+
+func TestMutualTLS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kvzoo-tls")
+	ut.Equal(t, err, nil)
+	defer os.RemoveAll(dir)
+	caFile, serverConf, clientConf := genCerts(t, dir)
+
+	opts := []server.Option{server.WithTLS(serverConf), server.WithToken("secret")}
+	withSecureDB(t, opts, func(addr string) {
+		ut.Equal(t, createTable(addr, client.WithTLS(clientConf), client.WithToken("secret")), nil)
+		ut.Assert(t, createTable(addr, client.WithToken("secret")) != nil, "plain connection should be rejected")
+		noCert := kvzoo.TLSConfig{CAFile: caFile}
+		ut.Assert(t, createTable(addr, client.WithTLS(noCert), client.WithToken("secret")) != nil, "client without certificate should be rejected")
+		ut.Assert(t, createTable(addr, client.WithTLS(clientConf)) != nil, "request without token should be rejected")
+	})
+
+	badConf := serverConf
+	badConf.KeyFile = path.Join(dir, "non-exists.key")
+	_, err = server.New("127.0.0.1:7791", nil, server.WithTLS(badConf))
+	ut.Assert(t, err != nil, "invalid certificate should be reported")
+}
+
+func genCerts(t *testing.T, dir string) (string, kvzoo.TLSConfig, kvzoo.TLSConfig) {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	ut.Equal(t, err, nil)
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "kvzoo-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDer, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	ut.Equal(t, err, nil)
+	caCert, _ := x509.ParseCertificate(caDer)
+	caFile := path.Join(dir, "ca.crt")
+	writePEM(t, caFile, "CERTIFICATE", caDer)
+
+	genCert := func(name string, serial int64, usage x509.ExtKeyUsage) kvzoo.TLSConfig {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		ut.Equal(t, err, nil)
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(serial),
+			Subject:      pkix.Name{CommonName: name},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+			IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		}
+		der, err := x509.CreateCertificate(rand.Reader, template, caCert, &key.PublicKey, caKey)
+		ut.Equal(t, err, nil)
+		keyDer, err := x509.MarshalECPrivateKey(key)
+		ut.Equal(t, err, nil)
+
+		conf := kvzoo.TLSConfig{
+			CertFile: path.Join(dir, name+".crt"),
+			KeyFile:  path.Join(dir, name+".key"),
+			CAFile:   caFile,
+		}
+		writePEM(t, conf.CertFile, "CERTIFICATE", der)
+		writePEM(t, conf.KeyFile, "EC PRIVATE KEY", keyDer)
+		return conf
+	}
+
+	return caFile, genCert("server", 2, x509.ExtKeyUsageServerAuth), genCert("client", 3, x509.ExtKeyUsageClientAuth)
+}
+
+func writePEM(t *testing.T, file, typ string, der []byte) {
+	data := pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der})
+	ut.Equal(t, ioutil.WriteFile(file, data, 0600), nil)
+}
@@ -1,12 +1,16 @@
 package tests
 
 import (
+	"bytes"
+	"fmt"
 	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	ut "cement/unittest"
 	"kvzoo"
-	"kvzoo/backend/bolt"
+	"kvzoo/backend"
 	"kvzoo/client"
 	"kvzoo/server"
 )
@@ -19,34 +23,64 @@ func mustChecksum(db kvzoo.DB) string {
 	return cs
 }
 
-func TestBoltDBChecksum(t *testing.T) {
-	db1, err := bolt.New("test1.db")
-	ut.Assert(t, err == nil, "")
-	db2, err := bolt.New("test2.db")
-	ut.Assert(t, err == nil, "")
-	defer func() {
-		db1.Destroy()
-		db2.Destroy()
-	}()
-	ut.Equal(t, mustChecksum(db1), mustChecksum(db2))
+//checksum and snapshot are same for all the backends, so they
+//can be replicated to each other
+func TestDBChecksum(t *testing.T) {
+	var dbs []kvzoo.DB
+	for _, typ := range testBackends() {
+		db := newBackend(t, typ, "test")
+		defer db.Destroy()
+		dbs = append(dbs, db)
+	}
 
 	tableName, _ := kvzoo.NewTableName("/xxxx/xx")
-	keyPrefix, valuePrefix := "key", "v"
-	keys, values := genData(keyPrefix, valuePrefix, 1000)
-	loadDataToTableInParal(db1, tableName, keys, values)
-	loadDataToTableInParal(db2, tableName, keys, values)
-	ut.Equal(t, mustChecksum(db1), mustChecksum(db2))
+	keys, values := genData("key", "v", 1000)
+	child, _ := kvzoo.NewTableName("/xxxx/xx/key5x")
+	empty, _ := kvzoo.NewTableName("/xxxx/empty")
+	for _, db := range dbs[1:] {
+		ut.Equal(t, mustChecksum(db), mustChecksum(dbs[0]))
+	}
+	for _, db := range dbs {
+		err := loadDataToTableInParal(db, tableName, keys, values)
+		ut.Equal(t, err, nil)
+		err = loadDataToTable(db, child, keys[:10], values[:10])
+		ut.Equal(t, err, nil)
+		_, err = db.CreateOrGetTable(empty)
+		ut.Equal(t, err, nil)
+	}
+
+	checksums, err := dbs[0].TableChecksums()
+	ut.Equal(t, err, nil)
+	ut.Equal(t, len(checksums), 4)
+	names, err := dbs[0].TableNames()
+	ut.Equal(t, err, nil)
+	ut.Equal(t, names, []kvzoo.TableName{"/xxxx", "/xxxx/empty", "/xxxx/xx", "/xxxx/xx/key5x"})
+	var snapshot bytes.Buffer
+	ut.Equal(t, dbs[0].Snapshot(&snapshot), nil)
+	for _, db := range dbs[1:] {
+		ut.Equal(t, mustChecksum(db), mustChecksum(dbs[0]))
+		cs, err := db.TableChecksums()
+		ut.Equal(t, err, nil)
+		ut.Equal(t, cs, checksums)
+		dbNames, err := db.TableNames()
+		ut.Equal(t, err, nil)
+		ut.Equal(t, dbNames, names)
+		var buf bytes.Buffer
+		ut.Equal(t, db.Snapshot(&buf), nil)
+		ut.Equal(t, buf.Bytes(), snapshot.Bytes())
+	}
 }
 
-func TestBoltDBTable(t *testing.T) {
-	withBoltDB(t, testTable)
+func TestLocalDBTable(t *testing.T) {
+	withLocalDB(t, testTable)
 }
 
-func withBoltDB(t *testing.T, test func(t *testing.T, db kvzoo.DB)) {
-	db, err := bolt.New("test.db")
-	ut.Equal(t, err, nil)
-	defer db.Destroy()
-	test(t, db)
+func withLocalDB(t *testing.T, test func(t *testing.T, db kvzoo.DB)) {
+	forEachBackend(t, func(t *testing.T, typ backend.Type) {
+		db := newBackend(t, typ, "test")
+		defer db.Destroy()
+		test(t, db)
+	})
 }
 
 func TestRemoteDBTable(t *testing.T) {
@@ -54,30 +88,30 @@ func TestRemoteDBTable(t *testing.T) {
 }
 
 func withRemoteDB(t *testing.T, test func(t *testing.T, db kvzoo.DB)) {
-	db1, err := bolt.New("s1.db")
-	ut.Equal(t, err, nil)
-	saddr1 := "127.0.0.1:7777"
-	rdb1, err := server.New(saddr1, db1)
-	ut.Equal(t, err, nil)
-	go rdb1.Start()
-
-	db2, err := bolt.New("s2.db")
-	ut.Equal(t, err, nil)
-	saddr2 := "127.0.0.1:7778"
-	rdb2, err := server.New(saddr2, db2)
-	ut.Equal(t, err, nil)
-	go rdb2.Start()
-
-	ldb, err := client.New(saddr1, []string{saddr2})
-	ut.Equal(t, err, nil)
-	_, err = ldb.Checksum()
-	ut.Assert(t, err == nil, "")
-	defer func() {
-		ldb.Destroy()
-		rdb1.Stop()
-		rdb2.Stop()
-	}()
-	test(t, ldb)
+	forEachBackend(t, func(t *testing.T, typ backend.Type) {
+		db1 := newBackend(t, typ, "s1")
+		saddr1 := "127.0.0.1:7777"
+		rdb1, err := server.New(saddr1, db1)
+		ut.Equal(t, err, nil)
+		go rdb1.Start()
+
+		db2 := newBackend(t, typ, "s2")
+		saddr2 := "127.0.0.1:7778"
+		rdb2, err := server.New(saddr2, db2)
+		ut.Equal(t, err, nil)
+		go rdb2.Start()
+
+		ldb, err := client.New(saddr1, []string{saddr2})
+		ut.Equal(t, err, nil)
+		_, err = ldb.Checksum()
+		ut.Assert(t, err == nil, "")
+		defer func() {
+			ldb.Destroy()
+			rdb1.Stop()
+			rdb2.Stop()
+		}()
+		test(t, ldb)
+	})
 }
 
 func testTable(t *testing.T, db kvzoo.DB) {
@@ -99,8 +133,8 @@ func testTable(t *testing.T, db kvzoo.DB) {
 	ut.Assert(t, err == nil, "")
 }
 
-func TestBoltDBAddAndGet(t *testing.T) {
-	withBoltDB(t, testAddAndGet)
+func TestLocalDBAddAndGet(t *testing.T) {
+	withLocalDB(t, testAddAndGet)
 }
 
 func TestRemoteDBAddAndGet(t *testing.T) {
@@ -133,8 +167,8 @@ func testAddAndGet(t *testing.T, db kvzoo.DB) {
 	db.DeleteTable(tableName)
 }
 
-func TestBoltDBUpdate(t *testing.T) {
-	withBoltDB(t, testUpdate)
+func TestLocalDBUpdate(t *testing.T) {
+	withLocalDB(t, testUpdate)
 }
 
 func TestRemoteDBUpdate(t *testing.T) {
@@ -169,8 +203,8 @@ func testUpdate(t *testing.T, db kvzoo.DB) {
 	ut.Assert(t, tableHasData(db, tableName, keys, values), "")
 }
 
-func TestBoltDBDelete(t *testing.T) {
-	withBoltDB(t, testDelete)
+func TestLocalDBDelete(t *testing.T) {
+	withLocalDB(t, testDelete)
 }
 
 func TestRemoteDBDelete(t *testing.T) {
@@ -208,8 +242,8 @@ func testDelete(t *testing.T, db kvzoo.DB) {
 	ut.Equal(t, len(data), 0)
 }
 
-func TestBoltDBList(t *testing.T) {
-	withBoltDB(t, testList)
+func TestLocalDBList(t *testing.T) {
+	withLocalDB(t, testList)
 }
 
 func TestRemoteDBList(t *testing.T) {
@@ -238,8 +272,8 @@ func testList(t *testing.T, db kvzoo.DB) {
 	wg.Wait()
 }
 
-func TestBoltDBNestedTable(t *testing.T) {
-	withBoltDB(t, testNestedTable)
+func TestLocalDBNestedTable(t *testing.T) {
+	withLocalDB(t, testNestedTable)
 }
 
 func TestRemoteDBNestedTable(t *testing.T) {
@@ -274,8 +308,261 @@ func testNestedTable(t *testing.T, db kvzoo.DB) {
 	ut.Equal(t, len(data), 0)
 }
 
-func TestBoltDBTxRollback(t *testing.T) {
-	withBoltDB(t, testTxRollback)
+func TestLocalDBScan(t *testing.T) {
+	withLocalDB(t, testScan)
+}
+
+func TestRemoteDBScan(t *testing.T) {
+	withRemoteDB(t, testScan)
+}
+
+func scanKeys(t *testing.T, db kvzoo.DB, tableName kvzoo.TableName, opts kvzoo.ScanOptions) ([]string, string) {
+	table, err := db.CreateOrGetTable(tableName)
+	ut.Equal(t, err, nil)
+	tx, err := table.Begin()
+	ut.Equal(t, err, nil)
+	defer tx.Rollback()
+
+	kvs, cursor, err := tx.Scan(opts)
+	ut.Equal(t, err, nil)
+	keys := make([]string, 0, len(kvs))
+	for _, kv := range kvs {
+		ut.Equal(t, string(kv.Value), "v"+kv.Key)
+		keys = append(keys, kv.Key)
+	}
+	return keys, cursor
+}
+
+func testScan(t *testing.T, db kvzoo.DB) {
+	tableName, _ := kvzoo.NewTableName("/scan")
+	keys := []string{"a1", "a2", "a3", "b1", "b2", "c1"}
+	values := make([]string, 0, len(keys))
+	for _, k := range keys {
+		values = append(values, "v"+k)
+	}
+	err := loadDataToTable(db, tableName, keys, values)
+	ut.Equal(t, err, nil)
+	//nested table shouldn't be returned
+	nested, _ := kvzoo.NewTableName("/scan/b3")
+	err = loadDataToTable(db, nested, []string{"k"}, []string{"vk"})
+	ut.Equal(t, err, nil)
+
+	result, cursor := scanKeys(t, db, tableName, kvzoo.ScanOptions{})
+	ut.Equal(t, result, keys)
+	ut.Equal(t, cursor, "")
+
+	result, _ = scanKeys(t, db, tableName, kvzoo.ScanOptions{Prefix: "a"})
+	ut.Equal(t, result, []string{"a1", "a2", "a3"})
+
+	result, _ = scanKeys(t, db, tableName, kvzoo.ScanOptions{Start: "a2", End: "b2"})
+	ut.Equal(t, result, []string{"a2", "a3", "b1"})
+
+	result, _ = scanKeys(t, db, tableName, kvzoo.ScanOptions{Prefix: "b", Reverse: true})
+	ut.Equal(t, result, []string{"b2", "b1"})
+
+	var pages [][]string
+	opts := kvzoo.ScanOptions{Limit: 4}
+	for {
+		result, cursor = scanKeys(t, db, tableName, opts)
+		pages = append(pages, result)
+		if cursor == "" {
+			break
+		}
+		opts.Cursor = cursor
+	}
+	ut.Equal(t, pages, [][]string{[]string{"a1", "a2", "a3", "b1"}, []string{"b2", "c1"}})
+
+	result, cursor = scanKeys(t, db, tableName, kvzoo.ScanOptions{Limit: 2, Reverse: true})
+	ut.Equal(t, result, []string{"c1", "b2"})
+	result, cursor = scanKeys(t, db, tableName, kvzoo.ScanOptions{Limit: 2, Reverse: true, Cursor: cursor})
+	ut.Equal(t, result, []string{"b1", "a3"})
+
+	//exact limit has no more page
+	result, cursor = scanKeys(t, db, tableName, kvzoo.ScanOptions{Prefix: "a", Limit: 3})
+	ut.Equal(t, len(result), 3)
+	ut.Equal(t, cursor, "")
+}
+
+func TestLocalDBWatch(t *testing.T) {
+	withLocalDB(t, testWatch)
+}
+
+func TestRemoteDBWatch(t *testing.T) {
+	withRemoteDB(t, testWatch)
+}
+
+func nextEvent(t *testing.T, w kvzoo.Watcher) kvzoo.Event {
+	select {
+	case e, ok := <-w.Events():
+		ut.Assert(t, ok, "watcher shouldn't be stopped")
+		return e
+	case <-time.After(5 * time.Second):
+		t.Fatal("wait event timeout")
+	}
+	return kvzoo.Event{}
+}
+
+func testWatch(t *testing.T, db kvzoo.DB) {
+	tableName, _ := kvzoo.NewTableName("/watch/t1")
+	w, err := db.Watch(tableName)
+	ut.Equal(t, err, nil)
+
+	err = loadDataToTable(db, tableName, []string{"k1", "k2"}, []string{"v1", "v2"})
+	ut.Equal(t, err, nil)
+	ut.Equal(t, nextEvent(t, w), kvzoo.Event{Type: kvzoo.EventAdd, Key: "k1", Value: []byte("v1")})
+	ut.Equal(t, nextEvent(t, w), kvzoo.Event{Type: kvzoo.EventAdd, Key: "k2", Value: []byte("v2")})
+
+	//rollback and change of other table aren't watched
+	table, _ := db.CreateOrGetTable(tableName)
+	tx, _ := table.Begin()
+	tx.Update("k1", []byte("xx"))
+	tx.Rollback()
+	child, _ := kvzoo.NewTableName("/watch/t1/child")
+	loadDataToTable(db, child, []string{"k1"}, []string{"v1"})
+
+	tx, _ = table.Begin()
+	ut.Equal(t, tx.Update("k1", []byte("v11")), nil)
+	ut.Equal(t, tx.Delete("k2"), nil)
+	ut.Equal(t, tx.Delete("k3"), nil)
+	ut.Equal(t, tx.Commit(), nil)
+	ut.Equal(t, nextEvent(t, w), kvzoo.Event{Type: kvzoo.EventUpdate, Key: "k1", Value: []byte("v11")})
+	ut.Equal(t, nextEvent(t, w), kvzoo.Event{Type: kvzoo.EventDelete, Key: "k2"})
+
+	parent, _ := kvzoo.NewTableName("/watch")
+	ut.Equal(t, db.DeleteTable(parent), nil)
+	ut.Equal(t, nextEvent(t, w).Type, kvzoo.EventDeleteTable)
+
+	w.Stop()
+	for range w.Events() {
+	}
+}
+
+func TestLocalDBExpire(t *testing.T) {
+	withLocalDB(t, testExpire)
+}
+
+func TestRemoteDBExpire(t *testing.T) {
+	withRemoteDB(t, testExpire)
+}
+
+func testExpire(t *testing.T, db kvzoo.DB) {
+	tableName, _ := kvzoo.NewTableName("/expire")
+	err := loadDataToTable(db, tableName, []string{"k1", "k2", "k3"}, []string{"v1", "v2", "v3"})
+	ut.Equal(t, err, nil)
+
+	table, _ := db.CreateOrGetTable(tableName)
+	tx, _ := table.Begin()
+	ut.Equal(t, tx.ExpireAt("k1", time.Now().Add(-time.Second)), nil)
+	ut.Equal(t, tx.ExpireAt("k2", time.Now().Add(time.Hour)), nil)
+	ut.Equal(t, tx.ExpireAt("k4", time.Now().Add(time.Hour)), kvzoo.ErrNotFound)
+	ut.Equal(t, tx.Commit(), nil)
+
+	//ttl is saved in the expire table, value isn't changed
+	names, err := db.TableNames()
+	ut.Equal(t, err, nil)
+	ut.Equal(t, names, []kvzoo.TableName{tableName, tableName.ExpireTable()})
+	expireTable, _ := db.CreateOrGetTable(tableName.ExpireTable())
+	etx, _ := expireTable.Begin()
+	expireTimes, err := etx.List()
+	ut.Equal(t, err, nil)
+	ut.Equal(t, len(expireTimes), 2)
+	etx.Rollback()
+
+	ut.Assert(t, tableHasData(db, tableName, []string{"k2", "k3"}, []string{"v2", "v3"}), "")
+	tx, _ = table.Begin()
+	_, err = tx.Get("k1")
+	ut.Equal(t, err, kvzoo.ErrNotFound)
+	ut.Equal(t, tx.Update("k1", []byte("v11")), kvzoo.ErrNotFound)
+	kvs, _, err := tx.Scan(kvzoo.ScanOptions{})
+	ut.Equal(t, err, nil)
+	ut.Equal(t, kvs, []kvzoo.KeyValue{kvzoo.KeyValue{Key: "k2", Value: []byte("v2")}, kvzoo.KeyValue{Key: "k3", Value: []byte("v3")}})
+	//expired key can be added again without ttl
+	ut.Equal(t, tx.Add("k1", []byte("v11")), nil)
+	ut.Equal(t, tx.Update("k2", []byte("v22")), nil)
+	ut.Equal(t, tx.ExpireAt("k3", time.Now().Add(-time.Second)), nil)
+	ut.Equal(t, tx.Commit(), nil)
+
+	tx, _ = table.Begin()
+	keys, err := tx.DeleteExpired()
+	ut.Equal(t, err, nil)
+	ut.Equal(t, keys, []string{"k3"})
+	ut.Equal(t, tx.Commit(), nil)
+	ut.Assert(t, tableHasData(db, tableName, []string{"k1", "k2"}, []string{"v11", "v22"}), "")
+
+	//remove ttl
+	tx, _ = table.Begin()
+	ut.Equal(t, tx.ExpireAt("k2", time.Now().Add(time.Millisecond)), nil)
+	ut.Equal(t, tx.ExpireAt("k2", time.Time{}), nil)
+	ut.Equal(t, tx.Commit(), nil)
+	time.Sleep(10 * time.Millisecond)
+	ut.Equal(t, kvzoo.DeleteExpiredKeys(db), nil)
+	ut.Assert(t, tableHasData(db, tableName, []string{"k1", "k2"}, []string{"v11", "v22"}), "")
+
+	db.DeleteTable(tableName)
+}
+
+func TestLocalDBCompareAndSwap(t *testing.T) {
+	withLocalDB(t, testCompareAndSwap)
+}
+
+func TestRemoteDBCompareAndSwap(t *testing.T) {
+	withRemoteDB(t, testCompareAndSwap)
+}
+
+func testCompareAndSwap(t *testing.T, db kvzoo.DB) {
+	tableName, _ := kvzoo.NewTableName("/cas")
+	err := loadDataToTable(db, tableName, []string{"k1"}, []string{"v1"})
+	ut.Equal(t, err, nil)
+
+	table, _ := db.CreateOrGetTable(tableName)
+	tx, _ := table.Begin()
+	ut.Equal(t, tx.CompareAndSwap("k1", []byte("v0"), []byte("v2")), kvzoo.ErrConflict)
+	ut.Equal(t, tx.CompareAndSwap("k2", []byte("v1"), []byte("v2")), kvzoo.ErrNotFound)
+	ut.Equal(t, tx.CompareAndSwap("k1", []byte("v1"), []byte("v2")), nil)
+	ut.Equal(t, tx.Commit(), nil)
+	ut.Assert(t, tableHasData(db, tableName, []string{"k1"}, []string{"v2"}), "")
+
+	//only one of the concurrent swaps succeeds
+	var wg sync.WaitGroup
+	var succeed int32
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			tx, err := table.Begin()
+			if err != nil {
+				return
+			}
+			if err := tx.CompareAndSwap("k1", []byte("v2"), []byte(fmt.Sprintf("v3%d", i))); err != nil {
+				tx.Rollback()
+			} else if tx.Commit() == nil {
+				atomic.AddInt32(&succeed, 1)
+			}
+		}(i)
+	}
+	wg.Wait()
+	ut.Equal(t, succeed, int32(1))
+
+	//swap and update keep the ttl of the key
+	tx, _ = table.Begin()
+	value, err := tx.Get("k1")
+	ut.Equal(t, err, nil)
+	ut.Equal(t, tx.ExpireAt("k1", time.Now().Add(100*time.Millisecond)), nil)
+	ut.Equal(t, tx.CompareAndSwap("k1", value, []byte("v4")), nil)
+	ut.Equal(t, tx.Update("k1", []byte("v5")), nil)
+	ut.Equal(t, tx.Commit(), nil)
+	ut.Assert(t, tableHasData(db, tableName, []string{"k1"}, []string{"v5"}), "")
+	time.Sleep(200 * time.Millisecond)
+	tx, _ = table.Begin()
+	_, err = tx.Get("k1")
+	ut.Equal(t, err, kvzoo.ErrNotFound)
+	tx.Rollback()
+
+	db.DeleteTable(tableName)
+}
+
+func TestLocalDBTxRollback(t *testing.T) {
+	withLocalDB(t, testTxRollback)
 }
 
 func TestRemoteDBTxRollback(t *testing.T) {
@@ -318,3 +605,50 @@ func testTxRollback(t *testing.T, db kvzoo.DB) {
 	tx.Rollback()
 	ut.Assert(t, tableHasData(db, tn, keys, values), "")
 }
+
+func TestLocalDBSnapshot(t *testing.T) {
+	withLocalDB(t, testSnapshot)
+}
+
+func TestRemoteDBSnapshot(t *testing.T) {
+	withRemoteDB(t, testSnapshot)
+}
+
+func testSnapshot(t *testing.T, db kvzoo.DB) {
+	t1, _ := kvzoo.NewTableName("/app/cd/ns1")
+	keys, values := genData("key", "value", 1000)
+	err := loadDataToTable(db, t1, keys, values)
+	ut.Equal(t, err, nil)
+	t2, _ := kvzoo.NewTableName("/empty")
+	_, err = db.CreateOrGetTable(t2)
+	ut.Equal(t, err, nil)
+	cs := mustChecksum(db)
+
+	var buf bytes.Buffer
+	err = db.Snapshot(&buf)
+	ut.Equal(t, err, nil)
+	snapshot := buf.Bytes()
+
+	_, newValues := genData("key", "vv", 1000)
+	err = updateDataInTable(db, t1, keys, newValues)
+	ut.Equal(t, err, nil)
+	t3, _ := kvzoo.NewTableName("/other")
+	err = loadDataToTable(db, t3, keys, values)
+	ut.Equal(t, err, nil)
+
+	//invalid snapshot doesn't change the db
+	err = db.Restore(bytes.NewReader(snapshot[:len(snapshot)/2]))
+	ut.Assert(t, err != nil, "")
+	ut.Assert(t, tableHasData(db, t1, keys, newValues), "")
+
+	err = db.Restore(bytes.NewReader(snapshot))
+	ut.Equal(t, err, nil)
+	ut.Equal(t, mustChecksum(db), cs)
+	ut.Assert(t, tableHasData(db, t1, keys, values), "")
+	checksums, err := db.TableChecksums()
+	ut.Equal(t, err, nil)
+	_, ok := checksums[t2]
+	ut.Assert(t, ok, "")
+	_, ok = checksums[t3]
+	ut.Assert(t, ok == false, "")
+}
@@ -12,12 +12,59 @@ import (
 	"cement/log"
 	ut "cement/unittest"
 	"kvzoo"
+	"kvzoo/backend"
+	"kvzoo/server"
 )
 
+//postgresql is tested only if its connection string is set
+const PostgresqlDSNEnv = "KVZOO_TEST_POSTGRESQL"
+
 func init() {
 	log.InitLogger(log.Debug)
 }
 
+func testBackends() []backend.Type {
+	backends := []backend.Type{backend.Bolt, backend.Memory, backend.Sqlite3}
+	if os.Getenv(PostgresqlDSNEnv) != "" {
+		backends = append(backends, backend.Postgresql)
+	}
+	return backends
+}
+
+func forEachBackend(t *testing.T, test func(t *testing.T, typ backend.Type)) {
+	for _, typ := range testBackends() {
+		typ := typ
+		t.Run(string(typ), func(t *testing.T) {
+			test(t, typ)
+		})
+	}
+}
+
+//name is used as file name or sql table name
+func backendConfig(typ backend.Type, name string) backend.Config {
+	return backend.Config{
+		Type:  typ,
+		Path:  name + "." + string(typ),
+		DSN:   os.Getenv(PostgresqlDSNEnv),
+		Table: name,
+	}
+}
+
+func newBackend(t *testing.T, typ backend.Type, name string) kvzoo.DB {
+	db, err := backend.New(backendConfig(typ, name))
+	ut.Equal(t, err, nil)
+	return db
+}
+
+func newBackendWithOpLog(t *testing.T, typ backend.Type, name string) (kvzoo.DB, kvzoo.DB) {
+	conf := backendConfig(typ, name)
+	db, err := backend.New(conf)
+	ut.Equal(t, err, nil)
+	logDB, err := backend.New(conf.OpLogConfig(server.OpLogFileSuffix))
+	ut.Equal(t, err, nil)
+	return db, logDB
+}
+
 func genData(keyPrefix, valuePrefix string, count int) ([]string, []string) {
 	keys := make([]string, 0, count)
 	values := make([]string, 0, count)
@@ -1,44 +1,50 @@
 package tests
 
 import (
+	"context"
 	"fmt"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	ut "cement/unittest"
 	"kvzoo"
-	"kvzoo/backend/bolt"
+	"kvzoo/backend"
 	"kvzoo/client"
+	pb "kvzoo/proto"
 	"kvzoo/server"
 )
 
 type testEnv struct {
+	addrs    []string
 	backends []kvzoo.DB
 	servers  []*server.KVGRPCServer
-	proxy    kvzoo.DB
+	proxy    *client.Proxy
 }
 
-func newTestEnv(t *testing.T, count int) *testEnv {
+func newTestEnv(t *testing.T, typ backend.Type, count int) *testEnv {
 	var backends []kvzoo.DB
 	var servers []*server.KVGRPCServer
 	var addrs []string
 	startPort := 7700
 
 	for i := 0; i < count; i++ {
-		db, err := bolt.New(fmt.Sprintf("s%d.db", i))
-		ut.Equal(t, err, nil)
+		db, logDB := newBackendWithOpLog(t, typ, fmt.Sprintf("s%d", i))
 		addr := fmt.Sprintf("127.0.0.1:%d", startPort+i)
 		addrs = append(addrs, addr)
-		rdb, err := server.New(addr, db)
+		rdb, err := server.NewWithOpLog(addr, db, logDB)
 		ut.Equal(t, err, nil)
 		go rdb.Start()
 		backends = append(backends, db)
 		servers = append(servers, rdb)
 	}
 
-	proxy, err := client.New(addrs[0], addrs[1:])
+	proxy, err := client.NewProxy(addrs[0], addrs[1:])
 	ut.Equal(t, err, nil)
 
 	return &testEnv{
+		addrs:    addrs,
 		backends: backends,
 		servers:  servers,
 		proxy:    proxy,
@@ -52,6 +58,22 @@ func (e *testEnv) clean() {
 	}
 }
 
+//data of the node is kept if backend isn't memory
+func (e *testEnv) restartServer(t *testing.T, typ backend.Type, i int) {
+	db, logDB := newBackendWithOpLog(t, typ, fmt.Sprintf("s%d", i))
+	rdb, err := server.NewWithOpLog(e.addrs[i], db, logDB)
+	ut.Equal(t, err, nil)
+	go rdb.Start()
+	e.backends[i], e.servers[i] = db, rdb
+}
+
+//proxy can't destroy the data of the node which is down
+func destroyBackend(t *testing.T, typ backend.Type, name string) {
+	db, logDB := newBackendWithOpLog(t, typ, name)
+	ut.Equal(t, db.Destroy(), nil)
+	ut.Equal(t, logDB.Destroy(), nil)
+}
+
 func (e *testEnv) checkTableHasData(t *testing.T, tableName kvzoo.TableName, keys, values []string) {
 	for _, db := range e.backends {
 		ut.Assert(t, tableHasData(db, tableName, keys, values), "")
@@ -67,7 +89,11 @@ func (e *testEnv) checkTableIsEmpty(t *testing.T, tableName kvzoo.TableName) {
 }
 
 func TestDBReplication(t *testing.T) {
-	e := newTestEnv(t, 5)
+	forEachBackend(t, testReplication)
+}
+
+func testReplication(t *testing.T, typ backend.Type) {
+	e := newTestEnv(t, typ, 5)
 	defer e.clean()
 
 	//replication after add
@@ -116,3 +142,401 @@ func TestDBReplication(t *testing.T) {
 	_, err = e.proxy.Checksum()
 	ut.Assert(t, err == nil, "")
 }
+
+func TestDBExpireReplication(t *testing.T) {
+	forEachBackend(t, testExpireReplication)
+}
+
+//expire time and expired keys are decided by proxy and master, so
+//slaves have the same data
+func testExpireReplication(t *testing.T, typ backend.Type) {
+	e := newTestEnv(t, typ, 3)
+	defer e.clean()
+
+	keys, values := genData("key", "value", 100)
+	tableName, _ := kvzoo.NewTableName("/expire")
+	err := loadDataToTable(e.proxy, tableName, keys, values)
+	ut.Equal(t, err, nil)
+
+	table, _ := e.proxy.CreateOrGetTable(tableName)
+	tx, _ := table.Begin()
+	expireAt := time.Now().Add(100 * time.Millisecond)
+	for _, k := range keys[:10] {
+		ut.Equal(t, tx.ExpireAt(k, expireAt), nil)
+	}
+	ut.Equal(t, tx.ExpireAt(keys[10], time.Now().Add(time.Hour)), nil)
+	ut.Equal(t, tx.CompareAndSwap(keys[11], []byte(values[11]), []byte("vv")), nil)
+	ut.Equal(t, tx.Commit(), nil)
+	_, err = e.proxy.Checksum()
+	ut.Equal(t, err, nil)
+
+	time.Sleep(200 * time.Millisecond)
+	ut.Equal(t, kvzoo.DeleteExpiredKeys(e.proxy), nil)
+	values[11] = "vv"
+	e.checkTableHasData(t, tableName, keys[10:], values[10:])
+	_, err = e.proxy.Checksum()
+	ut.Equal(t, err, nil)
+
+	//ttl is copied by resync
+	slaveTable, _ := e.backends[1].CreateOrGetTable(tableName)
+	tx, _ = slaveTable.Begin()
+	ut.Equal(t, tx.ExpireAt(keys[10], time.Time{}), nil)
+	ut.Equal(t, tx.ExpireAt(keys[12], time.Now().Add(time.Hour)), nil)
+	ut.Equal(t, tx.Commit(), nil)
+	_, err = e.proxy.Checksum()
+	ut.Assert(t, err != nil, "")
+	ut.Equal(t, e.proxy.Resync(), nil)
+	_, err = e.proxy.Checksum()
+	ut.Equal(t, err, nil)
+
+	//key has expired on slave because of clock skew, the value swapped
+	//on master is still put on slave, so slave isn't stale
+	tx, _ = slaveTable.Begin()
+	ut.Equal(t, tx.ExpireAt(keys[13], time.Now().Add(-time.Second)), nil)
+	ut.Equal(t, tx.Commit(), nil)
+	tx, _ = table.Begin()
+	ut.Equal(t, tx.CompareAndSwap(keys[13], []byte(values[13]), []byte("vv")), nil)
+	ut.Equal(t, tx.Commit(), nil)
+	for _, status := range e.proxy.SyncStatus() {
+		ut.Equal(t, status.State, client.SyncStateInSync)
+	}
+	tx, _ = slaveTable.Begin()
+	value, err := kvzoo.RawTransaction(tx).Get(keys[13])
+	tx.Rollback()
+	ut.Equal(t, err, nil)
+	ut.Equal(t, string(value), "vv")
+}
+
+func TestDBResync(t *testing.T) {
+	forEachBackend(t, testResync)
+}
+
+func testResync(t *testing.T, typ backend.Type) {
+	e := newTestEnv(t, typ, 3)
+	defer e.clean()
+
+	keys, values := genData("key", "value", 100)
+	tableName1, _ := kvzoo.NewTableName("/xxxx/xx")
+	err := loadDataToTable(e.proxy, tableName1, keys, values)
+	ut.Equal(t, err, nil)
+	tableName2, _ := kvzoo.NewTableName("/yyyy")
+	err = loadDataToTable(e.proxy, tableName2, keys, values)
+	ut.Equal(t, err, nil)
+
+	//make slave diverge from master
+	slave := e.backends[1]
+	_, newValues := genData("key", "vv", 10)
+	err = updateDataInTable(slave, tableName1, keys[:10], newValues)
+	ut.Equal(t, err, nil)
+	err = deleteDataInTable(slave, tableName2, keys[10:20], values[10:20])
+	ut.Equal(t, err, nil)
+	tableName3, _ := kvzoo.NewTableName("/zzzz/zz")
+	err = loadDataToTable(slave, tableName3, keys, values)
+	ut.Equal(t, err, nil)
+	_, err = e.proxy.Checksum()
+	ut.Assert(t, err != nil, "")
+
+	err = e.proxy.Resync()
+	ut.Equal(t, err, nil)
+	_, err = e.proxy.Checksum()
+	ut.Assert(t, err == nil, "")
+	e.checkTableHasData(t, tableName1, keys, values)
+	e.checkTableHasData(t, tableName2, keys, values)
+	checksums, err := slave.TableChecksums()
+	ut.Equal(t, err, nil)
+	_, ok := checksums[tableName3]
+	ut.Assert(t, ok == false, "")
+
+	for _, status := range e.proxy.SyncStatus() {
+		ut.Equal(t, status.State, client.SyncStateInSync)
+		ut.Equal(t, len(status.StaleTables), 0)
+	}
+	status := e.proxy.SyncStatus()[0]
+	ut.Equal(t, status.SyncedTables, []string{"/xxxx/xx", "/yyyy"})
+}
+
+func TestDBLogReplay(t *testing.T) {
+	forEachBackend(t, testLogReplay)
+}
+
+func testLogReplay(t *testing.T, typ backend.Type) {
+	e := newTestEnv(t, typ, 3)
+	defer e.clean()
+
+	keys, values := genData("key", "value", 100)
+	tableName, _ := kvzoo.NewTableName("/xxxx/xx")
+	err := loadDataToTable(e.proxy, tableName, keys, values)
+	ut.Equal(t, err, nil)
+
+	//write to master only, slaves miss the write
+	master, err := client.NewClient(e.addrs[0], time.Second)
+	ut.Equal(t, err, nil)
+	defer master.Close()
+	_, err = master.CreateOrGetTable(context.TODO(), &pb.CreateOrGetTableRequest{Name: string(tableName)})
+	ut.Equal(t, err, nil)
+	tx, err := master.BeginTransaction(context.TODO(), &pb.BeginTransactionRequest{TableName: string(tableName)})
+	ut.Equal(t, err, nil)
+	_, err = master.Update(context.TODO(), &pb.UpdateRequest{TxId: tx.TxId, Key: keys[0], Value: []byte("vv")})
+	ut.Equal(t, err, nil)
+	reply, err := master.CommitTransaction(context.TODO(), &pb.CommitTransactionRequest{TxId: tx.TxId})
+	ut.Equal(t, err, nil)
+	ut.Equal(t, reply.Index, uint64(2))
+	_, err = e.proxy.Checksum()
+	ut.Assert(t, err != nil, "")
+
+	slave, err := client.NewClient(e.addrs[1], time.Second)
+	ut.Equal(t, err, nil)
+	defer slave.Close()
+	index, err := slave.LastLogIndex(context.TODO(), &pb.LastLogIndexRequest{})
+	ut.Equal(t, err, nil)
+	ut.Equal(t, index.Index, uint64(1))
+	missed, err := master.GetLogEntries(context.TODO(), &pb.GetLogEntriesRequest{Since: index.Index})
+	ut.Equal(t, err, nil)
+	ut.Equal(t, len(missed.Entries), 1)
+	ut.Equal(t, missed.Entries[0].TableName, string(tableName))
+	ut.Equal(t, missed.Entries[0].Operations[0].Key, keys[0])
+
+	//later write is rejected by slave since its log falls behind
+	err = updateDataInTable(e.proxy, tableName, keys[1:2], []string{"vvv"})
+	ut.Equal(t, err, nil)
+	for _, status := range e.proxy.SyncStatus() {
+		ut.Equal(t, status.State, client.SyncStateStale)
+	}
+
+	err = e.proxy.Resync()
+	ut.Equal(t, err, nil)
+	_, err = e.proxy.Checksum()
+	ut.Assert(t, err == nil, "")
+	values[0], values[1] = "vv", "vvv"
+	e.checkTableHasData(t, tableName, keys, values)
+	for _, status := range e.proxy.SyncStatus() {
+		ut.Equal(t, status.State, client.SyncStateInSync)
+		ut.Equal(t, status.LogIndex, uint64(3))
+		ut.Equal(t, status.MasterLogIndex, uint64(3))
+		ut.Equal(t, len(status.SyncedTables), 0)
+	}
+
+	//delete table with wrong index is rejected before table is deleted
+	_, err = slave.DeleteTable(context.TODO(), &pb.DeleteTableRequest{Name: string(tableName), Index: 100})
+	ut.Assert(t, err != nil, "")
+	ut.Assert(t, tableHasData(e.backends[1], tableName, keys, values), "")
+}
+
+//log db whose write fails when fail is set
+type failingLogDB struct {
+	kvzoo.DB
+	fail *int32
+}
+
+func (db *failingLogDB) CreateOrGetTable(tableName kvzoo.TableName) (kvzoo.Table, error) {
+	table, err := db.DB.CreateOrGetTable(tableName)
+	if err != nil {
+		return nil, err
+	}
+	return &failingLogTable{Table: table, fail: db.fail}, nil
+}
+
+type failingLogTable struct {
+	kvzoo.Table
+	fail *int32
+}
+
+func (tb *failingLogTable) Begin() (kvzoo.Transaction, error) {
+	if atomic.LoadInt32(tb.fail) == 1 {
+		return nil, fmt.Errorf("log db is broken")
+	}
+	return tb.Table.Begin()
+}
+
+func TestDBLogWriteFailure(t *testing.T) {
+	forEachBackend(t, testLogWriteFailure)
+}
+
+//data committed on master isn't reported as failure, log is truncated,
+//slave which misses the entry will copy tables from master
+func testLogWriteFailure(t *testing.T, typ backend.Type) {
+	var fail int32
+	var addrs []string
+	var backends []kvzoo.DB
+	for i := 0; i < 2; i++ {
+		db, logDB := newBackendWithOpLog(t, typ, fmt.Sprintf("s%d", i))
+		if i == 0 {
+			logDB = &failingLogDB{DB: logDB, fail: &fail}
+		}
+		addr := fmt.Sprintf("127.0.0.1:%d", 7700+i)
+		s, err := server.NewWithOpLog(addr, db, logDB)
+		ut.Equal(t, err, nil)
+		go s.Start()
+		defer s.Stop()
+		addrs = append(addrs, addr)
+		backends = append(backends, db)
+	}
+	proxy, err := client.NewProxy(addrs[0], addrs[1:])
+	ut.Equal(t, err, nil)
+	defer proxy.Destroy()
+
+	keys, values := genData("key", "value", 10)
+	tableName, _ := kvzoo.NewTableName("/xxxx")
+	ut.Equal(t, loadDataToTable(proxy, tableName, keys, values), nil)
+
+	atomic.StoreInt32(&fail, 1)
+	ut.Equal(t, updateDataInTable(proxy, tableName, keys[:1], []string{"vv"}), nil)
+	atomic.StoreInt32(&fail, 0)
+	ut.Equal(t, updateDataInTable(proxy, tableName, keys[1:2], []string{"vvv"}), nil)
+	values[0], values[1] = "vv", "vvv"
+	for _, db := range backends {
+		ut.Assert(t, tableHasData(db, tableName, keys, values), "")
+	}
+	for _, status := range proxy.SyncStatus() {
+		ut.Equal(t, status.State, client.SyncStateInSync)
+	}
+
+	//entries before the failed one can't be replayed from master
+	master, err := client.NewClient(addrs[0], time.Second)
+	ut.Equal(t, err, nil)
+	defer master.Close()
+	index, err := master.LastLogIndex(context.TODO(), &pb.LastLogIndexRequest{})
+	ut.Equal(t, err, nil)
+	ut.Equal(t, index.Index, uint64(3))
+	_, err = master.GetLogEntries(context.TODO(), &pb.GetLogEntriesRequest{Since: 1})
+	ut.Assert(t, err != nil, "")
+	entries, err := master.GetLogEntries(context.TODO(), &pb.GetLogEntriesRequest{Since: 2})
+	ut.Equal(t, err, nil)
+	ut.Equal(t, len(entries.Entries), 1)
+}
+
+func TestDBFailover(t *testing.T) {
+	forEachBackend(t, testFailover)
+}
+
+func testFailover(t *testing.T, typ backend.Type) {
+	e := newTestEnv(t, typ, 3)
+	defer e.clean()
+
+	keys, values := genData("key", "value", 100)
+	tableName, _ := kvzoo.NewTableName("/xxxx/xx")
+	err := loadDataToTable(e.proxy, tableName, keys, values)
+	ut.Equal(t, err, nil)
+
+	//master is down, the first slave is promoted
+	e.servers[0].Stop()
+	err = e.proxy.Failover()
+	ut.Equal(t, err, nil)
+	ut.Equal(t, e.proxy.Master(), e.addrs[1])
+
+	_, newValues := genData("key", "vv", 100)
+	err = updateDataInTable(e.proxy, tableName, keys, newValues)
+	ut.Equal(t, err, nil)
+	data, err := getTableData(e.proxy, tableName)
+	ut.Equal(t, err, nil)
+	assertMapEqualsToSlices(t, data, keys, newValues)
+
+	//request with old epoch is rejected by new master
+	newMaster, err := client.NewClient(e.addrs[1], time.Second)
+	ut.Equal(t, err, nil)
+	defer newMaster.Close()
+	_, err = newMaster.CreateOrGetTable(context.TODO(), &pb.CreateOrGetTableRequest{Name: string(tableName)})
+	ut.Assert(t, err != nil, "")
+
+	//old master comes back, it's fenced and synced with new master
+	e.restartServer(t, typ, 0)
+
+	for i := 0; i < 10; i++ {
+		if err = e.proxy.Resync(); err == nil {
+			break
+		}
+		time.Sleep(time.Second)
+	}
+	ut.Equal(t, err, nil)
+	_, err = e.proxy.Checksum()
+	ut.Assert(t, err == nil, "")
+	e.checkTableHasData(t, tableName, keys, newValues)
+
+	oldMaster, err := client.NewClient(e.addrs[0], time.Second)
+	ut.Equal(t, err, nil)
+	defer oldMaster.Close()
+	epoch, err := oldMaster.GetEpoch(context.TODO(), &pb.GetEpochRequest{})
+	ut.Equal(t, err, nil)
+	ut.Equal(t, epoch.Epoch, uint64(1))
+	ut.Equal(t, epoch.Master, e.addrs[1])
+	for _, status := range e.proxy.SyncStatus() {
+		ut.Equal(t, status.State, client.SyncStateInSync)
+		ut.Equal(t, status.Fenced, false)
+	}
+}
+
+func TestDBFailoverBehindMaster(t *testing.T) {
+	forEachBackend(t, testFailoverBehindMaster)
+}
+
+func testFailoverBehindMaster(t *testing.T, typ backend.Type) {
+	e := newTestEnv(t, typ, 2)
+	defer e.clean()
+
+	keys, values := genData("key", "value", 100)
+	tableName, _ := kvzoo.NewTableName("/xxxx/xx")
+	err := loadDataToTable(e.proxy, tableName, keys, values)
+	ut.Equal(t, err, nil)
+
+	//slave misses the write, since it's stopped
+	e.servers[1].Stop()
+	err = updateDataInTable(e.proxy, tableName, keys[:1], []string{"vv"})
+	ut.Equal(t, err, nil)
+
+	//slave is not promoted, otherwise the write is lost
+	e.restartServer(t, typ, 1)
+	e.servers[0].Stop()
+	for i := 0; i < 10; i++ {
+		if err = e.proxy.Failover(); err != nil && strings.Contains(err.Error(), "falls behind master") {
+			break
+		}
+		time.Sleep(time.Second)
+	}
+	ut.Assert(t, err != nil && strings.Contains(err.Error(), "falls behind master"), "")
+	ut.Equal(t, e.proxy.Master(), e.addrs[0])
+	destroyBackend(t, typ, "s0")
+}
+
+func TestDBAutoFailover(t *testing.T) {
+	forEachBackend(t, testAutoFailover)
+}
+
+func testAutoFailover(t *testing.T, typ backend.Type) {
+	e := newTestEnv(t, typ, 2)
+	defer e.clean()
+
+	//proxy beside slave, it promotes slave when master is down
+	standby, err := client.NewProxy(e.addrs[0], e.addrs[1:])
+	ut.Equal(t, err, nil)
+	defer standby.Close()
+	standby.StartFailover()
+
+	keys, values := genData("key", "value", 100)
+	tableName, _ := kvzoo.NewTableName("/xxxx/xx")
+	err = loadDataToTable(e.proxy, tableName, keys, values)
+	ut.Equal(t, err, nil)
+
+	e.servers[0].Stop()
+	for i := 0; i < 30 && standby.Master() != e.addrs[1]; i++ {
+		time.Sleep(time.Second)
+	}
+	ut.Equal(t, standby.Master(), e.addrs[1])
+
+	//standby proxy keeps serving, and proxy without failover follows
+	//the promoted master
+	_, newValues := genData("key", "vv", 100)
+	err = updateDataInTable(standby, tableName, keys, newValues)
+	ut.Equal(t, err, nil)
+	data, err := getTableData(standby, tableName)
+	ut.Equal(t, err, nil)
+	assertMapEqualsToSlices(t, data, keys, newValues)
+
+	for i := 0; i < 10 && e.proxy.Master() != e.addrs[1]; i++ {
+		time.Sleep(time.Second)
+	}
+	ut.Equal(t, e.proxy.Master(), e.addrs[1])
+	data, err = getTableData(e.proxy, tableName)
+	ut.Equal(t, err, nil)
+	assertMapEqualsToSlices(t, data, keys, newValues)
+	destroyBackend(t, typ, "s0")
+}
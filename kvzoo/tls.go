@@ -0,0 +1,78 @@
+package kvzoo
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+)
+
+//the same certificate can be used by both server and client if it
+//has both server and client auth usage
+type TLSConfig struct {
+	CertFile string
+	KeyFile  string
+	//ca to verify the certificate of peer, if it's set, server
+	//requires client to present certificate signed by it
+	CAFile string
+	//name in server certificate, client uses the host of server
+	//address if it's empty
+	ServerName string
+}
+
+func (c *TLSConfig) ServerConfig() (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("load server certificate failed:%s", err.Error())
+	}
+
+	conf := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if c.CAFile != "" {
+		pool, err := loadCertPool(c.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		conf.ClientCAs = pool
+		conf.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return conf, nil
+}
+
+//without ca file, server certificate is verified by system roots
+func (c *TLSConfig) ClientConfig() (*tls.Config, error) {
+	conf := &tls.Config{
+		ServerName: c.ServerName,
+		MinVersion: tls.VersionTLS12,
+	}
+	if c.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load client certificate failed:%s", err.Error())
+		}
+		conf.Certificates = []tls.Certificate{cert}
+	}
+	if c.CAFile != "" {
+		pool, err := loadCertPool(c.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		conf.RootCAs = pool
+	}
+	return conf, nil
+}
+
+func loadCertPool(caFile string) (*x509.CertPool, error) {
+	data, err := ioutil.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("read ca file failed:%s", err.Error())
+	}
+
+	pool := x509.NewCertPool()
+	if pool.AppendCertsFromPEM(data) == false {
+		return nil, fmt.Errorf("no valid certificate in ca file %s", caFile)
+	}
+	return pool, nil
+}
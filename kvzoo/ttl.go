@@ -0,0 +1,334 @@
+package kvzoo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"path"
+	"sort"
+	"time"
+)
+
+//returned by CompareAndSwap if current value isn't the expected one
+var ErrConflict = errors.New("value has been changed")
+
+var ErrRawTransaction = errors.New("operation isn't supported by raw transaction")
+
+//expire time of keys is saved in a sidecar table, which is a child
+//table of the table, key is same with the table and value is the
+//expire time in unix nano, the time is absolute, so nodes which apply
+//the same operations have the same data, and checksum and snapshot
+//include the ttl
+const ExpireTableName = "__kvzoo_expire"
+
+func (tn TableName) ExpireTable() TableName {
+	return TableName(path.Join(string(tn), ExpireTableName))
+}
+
+func (tn TableName) IsExpireTable() bool {
+	return path.Base(string(tn)) == ExpireTableName
+}
+
+func encodeExpireTime(expireAt time.Time) []byte {
+	data := make([]byte, 8)
+	binary.BigEndian.PutUint64(data, uint64(expireAt.UnixNano()))
+	return data
+}
+
+func decodeExpireTime(data []byte) time.Time {
+	if len(data) != 8 {
+		return time.Time{}
+	}
+	return time.Unix(0, int64(binary.BigEndian.Uint64(data)))
+}
+
+func isExpired(expireAt time.Time, now time.Time) bool {
+	return expireAt.IsZero() == false && now.Before(expireAt) == false
+}
+
+//operations implemented by backend, NewTransaction adds ttl and
+//compare-and-swap on top of them
+type BaseTransaction interface {
+	Commit() error
+	Rollback() error
+
+	Add(string, []byte) error
+	Delete(string) error
+	Update(string, []byte) error
+	Get(string) ([]byte, error)
+	List() (map[string][]byte, error)
+	Scan(ScanOptions) ([]KeyValue, string, error)
+
+	//sidecar table which saves the expire time, it shares the
+	//transaction, nil is returned if it doesn't exist and create
+	//is false
+	ExpireTable(create bool) (BaseTransaction, error)
+}
+
+//expired keys are invisible to read, and are removed by DeleteExpired
+func NewTransaction(tx BaseTransaction) Transaction {
+	return &transaction{
+		BaseTransaction: tx,
+	}
+}
+
+type transaction struct {
+	BaseTransaction
+}
+
+func (tx *transaction) expireTime(key string) (time.Time, error) {
+	et, err := tx.ExpireTable(false)
+	if err != nil || et == nil {
+		return time.Time{}, err
+	}
+
+	data, err := et.Get(key)
+	if err == ErrNotFound {
+		return time.Time{}, nil
+	} else if err != nil {
+		return time.Time{}, err
+	}
+	return decodeExpireTime(data), nil
+}
+
+//zero time removes the ttl
+func (tx *transaction) setExpireTime(key string, expireAt time.Time) error {
+	et, err := tx.ExpireTable(expireAt.IsZero() == false)
+	if err != nil || et == nil {
+		return err
+	}
+
+	if expireAt.IsZero() {
+		return et.Delete(key)
+	}
+
+	data := encodeExpireTime(expireAt)
+	if err := et.Update(key, data); err != ErrNotFound {
+		return err
+	}
+	return et.Add(key, data)
+}
+
+func (tx *transaction) get(key string) ([]byte, error) {
+	value, err := tx.BaseTransaction.Get(key)
+	if err != nil {
+		return nil, err
+	}
+
+	expireAt, err := tx.expireTime(key)
+	if err != nil {
+		return nil, err
+	}
+	if isExpired(expireAt, time.Now()) {
+		return nil, ErrNotFound
+	}
+	return value, nil
+}
+
+//expired key is replaced by the new one, new key doesn't have ttl
+func (tx *transaction) Add(key string, value []byte) error {
+	if _, err := tx.get(key); err == ErrNotFound {
+		if err := tx.Delete(key); err != nil {
+			return err
+		}
+	}
+	return tx.BaseTransaction.Add(key, value)
+}
+
+func (tx *transaction) Delete(key string) error {
+	if err := tx.BaseTransaction.Delete(key); err != nil {
+		return err
+	}
+	return tx.setExpireTime(key, time.Time{})
+}
+
+//ttl of the key is kept, use ExpireAt to change it
+func (tx *transaction) Update(key string, value []byte) error {
+	if _, err := tx.get(key); err != nil {
+		return err
+	}
+	return tx.BaseTransaction.Update(key, value)
+}
+
+func (tx *transaction) Get(key string) ([]byte, error) {
+	return tx.get(key)
+}
+
+//key is expired if its expire time isn't after now
+func (tx *transaction) expiredKeys(now time.Time) (map[string]struct{}, error) {
+	et, err := tx.ExpireTable(false)
+	if err != nil || et == nil {
+		return nil, err
+	}
+
+	values, err := et.List()
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]struct{})
+	for k, data := range values {
+		if isExpired(decodeExpireTime(data), now) {
+			keys[k] = struct{}{}
+		}
+	}
+	return keys, nil
+}
+
+func (tx *transaction) List() (map[string][]byte, error) {
+	values, err := tx.BaseTransaction.List()
+	if err != nil {
+		return nil, err
+	}
+
+	expired, err := tx.expiredKeys(time.Now())
+	if err != nil {
+		return nil, err
+	}
+	for k := range expired {
+		delete(values, k)
+	}
+	return values, nil
+}
+
+//expired keys are skipped, so a page may have less keys than the
+//limit even if cursor isn't empty
+func (tx *transaction) Scan(opts ScanOptions) ([]KeyValue, string, error) {
+	kvs, cursor, err := tx.BaseTransaction.Scan(opts)
+	if err != nil {
+		return nil, "", err
+	}
+
+	now := time.Now()
+	visible := kvs[:0]
+	for _, kv := range kvs {
+		expireAt, err := tx.expireTime(kv.Key)
+		if err != nil {
+			return nil, "", err
+		}
+		if isExpired(expireAt, now) == false {
+			visible = append(visible, kv)
+		}
+	}
+	return visible, cursor, nil
+}
+
+func (tx *transaction) ExpireAt(key string, expireAt time.Time) error {
+	if _, err := tx.get(key); err != nil {
+		return err
+	}
+	return tx.setExpireTime(key, expireAt)
+}
+
+func (tx *transaction) CompareAndSwap(key string, oldValue, newValue []byte) error {
+	value, err := tx.get(key)
+	if err != nil {
+		return err
+	}
+
+	if bytes.Equal(value, oldValue) == false {
+		return ErrConflict
+	}
+	return tx.BaseTransaction.Update(key, newValue)
+}
+
+func (tx *transaction) DeleteExpired() ([]string, error) {
+	expired, err := tx.expiredKeys(time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(expired))
+	for k := range expired {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		if err := tx.Delete(k); err != nil {
+			return nil, err
+		}
+	}
+	return keys, nil
+}
+
+//raw transaction doesn't check or change the ttl, expired keys are
+//visible, it's used to copy table between nodes, the expire table is
+//copied as other tables
+func RawTransaction(tx Transaction) Transaction {
+	if t, ok := tx.(*transaction); ok {
+		return &rawTransaction{
+			BaseTransaction: t.BaseTransaction,
+		}
+	}
+	return tx
+}
+
+type rawTransaction struct {
+	BaseTransaction
+}
+
+func (tx *rawTransaction) ExpireAt(key string, expireAt time.Time) error {
+	return ErrRawTransaction
+}
+
+func (tx *rawTransaction) CompareAndSwap(key string, oldValue, newValue []byte) error {
+	return ErrRawTransaction
+}
+
+func (tx *rawTransaction) DeleteExpired() ([]string, error) {
+	return nil, ErrRawTransaction
+}
+
+//add or update the key no matter whether it has expired, ttl of the
+//key is kept, it's used by node which applies the result decided by
+//another node, since the key may have expired on this node
+func Put(tx Transaction, key string, value []byte) error {
+	raw := RawTransaction(tx)
+	if err := raw.Update(key, value); err != ErrNotFound {
+		return err
+	}
+	return raw.Add(key, value)
+}
+
+//only tables which have the expire table are checked, table without
+//expired key isn't changed
+func DeleteExpiredKeys(db DB) error {
+	names, err := db.TableNames()
+	if err != nil {
+		return err
+	}
+
+	for _, tn := range names {
+		if tn.IsExpireTable() == false {
+			continue
+		}
+
+		parent, err := tn.Parent()
+		if err != nil {
+			continue
+		}
+		table, err := db.CreateOrGetTable(parent)
+		if err != nil {
+			return err
+		}
+
+		tx, err := table.Begin()
+		if err != nil {
+			return err
+		}
+
+		keys, err := tx.DeleteExpired()
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+
+		if len(keys) == 0 {
+			tx.Rollback()
+		} else if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package kvzoo
+
+import (
+	"strings"
+	"sync"
+)
+
+type EventType string
+
+const (
+	EventAdd    EventType = "add"
+	EventUpdate EventType = "update"
+	EventDelete EventType = "delete"
+	//the table or its parent table is deleted
+	EventDeleteTable EventType = "deleteTable"
+)
+
+//watcher has a buffered channel, if the watcher cann't keep up with
+//the changes, it will be stopped
+const WatchEventBufferSize = 1024
+
+type Event struct {
+	Type  EventType
+	Key   string
+	Value []byte
+}
+
+type Watcher interface {
+	//channel is closed when watcher is stopped, or the watcher is too
+	//slow, or db is closed or restored, after that the table should be
+	//reloaded and watched again
+	Events() <-chan Event
+	Stop()
+}
+
+//WatchHub dispatches committed changes to the watchers of the table,
+//backend uses it to implement Watch
+type WatchHub struct {
+	watchers map[TableName]map[*tableWatcher]struct{}
+	lock     sync.Mutex
+}
+
+type tableWatcher struct {
+	hub       *WatchHub
+	tableName TableName
+	ch        chan Event
+	stopped   bool
+}
+
+func NewWatchHub() *WatchHub {
+	return &WatchHub{
+		watchers: make(map[TableName]map[*tableWatcher]struct{}),
+	}
+}
+
+func (h *WatchHub) Watch(tableName TableName) Watcher {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	w := &tableWatcher{
+		hub:       h,
+		tableName: tableName,
+		ch:        make(chan Event, WatchEventBufferSize),
+	}
+	watchers, ok := h.watchers[tableName]
+	if ok == false {
+		watchers = make(map[*tableWatcher]struct{})
+		h.watchers[tableName] = watchers
+	}
+	watchers[w] = struct{}{}
+	return w
+}
+
+//commit and dispatch are done while holding the lock, so watchers
+//get events in the same order as commit
+func (h *WatchHub) Publish(tableName TableName, events []Event, commit func() error) error {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if err := commit(); err != nil {
+		return err
+	}
+
+	if len(events) == 1 && events[0].Type == EventDeleteTable {
+		prefix := string(tableName) + "/"
+		for tn, watchers := range h.watchers {
+			if tn == tableName || strings.HasPrefix(string(tn), prefix) {
+				h.dispatch(watchers, events)
+			}
+		}
+	} else if watchers, ok := h.watchers[tableName]; ok {
+		h.dispatch(watchers, events)
+	}
+	return nil
+}
+
+func (h *WatchHub) dispatch(watchers map[*tableWatcher]struct{}, events []Event) {
+	for w := range watchers {
+		for _, e := range events {
+			select {
+			case w.ch <- e:
+			default:
+				h.stopWatcher(w)
+			}
+			if w.stopped {
+				break
+			}
+		}
+	}
+}
+
+func (h *WatchHub) StopAll() {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	for _, watchers := range h.watchers {
+		for w := range watchers {
+			h.stopWatcher(w)
+		}
+	}
+}
+
+func (h *WatchHub) stopWatcher(w *tableWatcher) {
+	if w.stopped {
+		return
+	}
+
+	w.stopped = true
+	close(w.ch)
+	watchers := h.watchers[w.tableName]
+	delete(watchers, w)
+	if len(watchers) == 0 {
+		delete(h.watchers, w.tableName)
+	}
+}
+
+func (w *tableWatcher) Events() <-chan Event {
+	return w.ch
+}
+
+func (w *tableWatcher) Stop() {
+	w.hub.lock.Lock()
+	defer w.hub.lock.Unlock()
+	w.hub.stopWatcher(w)
+}
@@ -19,21 +19,35 @@ var ClusterKinds = []string{"Node", "node", "Cluster", "cluster"}
 func SendMail(alarm *types.Alarm, table kvzoo.Table) error {
 	threshold, err := getThresholdFromDB(table, types.ThresholdTable)
 	if err != nil {
-		return fmt.Errorf("get threshold %s failed: %s", err.Error())
+		return fmt.Errorf("get threshold failed: %s", err.Error())
 	}
+	return sendMail(threshold, threshold.MailTo, Subject, genMessage(alarm))
+}
+
+//mail to in threshold is also the receiver, nothing is sent if mail
+//from isn't set
+func SendMailTo(table kvzoo.Table, to []string, subject, body string) error {
+	threshold, err := getThresholdFromDB(table, types.ThresholdTable)
+	if err != nil {
+		return fmt.Errorf("get threshold failed: %s", err.Error())
+	}
+	return sendMail(threshold, append(to, threshold.MailTo...), subject, body)
+}
+
+func sendMail(threshold *types.Threshold, to []string, subject, body string) error {
 	if len(threshold.MailFrom.User) == 0 ||
 		len(threshold.MailFrom.Host) == 0 ||
 		threshold.MailFrom.Port == 0 ||
 		len(threshold.MailFrom.Password) == 0 ||
-		len(threshold.MailTo) == 0 {
+		len(to) == 0 {
 		return nil
 	}
 
 	m := gomail.NewMessage()
 	m.SetHeader("From", threshold.MailFrom.User)
-	m.SetHeader("To", threshold.MailTo...)
-	m.SetHeader("Subject", Subject)
-	m.SetBody("text/html", genMessage(alarm))
+	m.SetHeader("To", to...)
+	m.SetHeader("Subject", subject)
+	m.SetBody("text/html", body)
 
 	d := gomail.NewDialer(threshold.MailFrom.Host, threshold.MailFrom.Port, threshold.MailFrom.User, threshold.MailFrom.Password)
 	return d.DialAndSend(m)
@@ -1,16 +1,18 @@
 package auditlog
 
 import (
-	"encoding/json"
 	"fmt"
 	"net/http"
-	"sort"
+	"sync"
 	"time"
 
 	"gorest"
 	resterr "gorest/error"
 	"gorest/resource"
 
+	"cement/log"
+	"cement/uuid"
+	"config"
 	"kvzoo"
 	"pkg/auditlog/storage"
 	"pkg/db"
@@ -18,19 +20,35 @@ import (
 )
 
 const (
-	AuditLogTable    = "auditlog"
-	MaxAuditLogCount = 1000
+	AuditLogTable = "auditlog"
+
+	//request id from client is used if it's not too long, otherwise
+	//a new one is generated, it's returned in response header
+	RequestIDHeader = "X-Request-Id"
+	maxRequestIDLen = 64
+	requestIDKey    = "auditlog_request_id"
+	oldResourceKey  = "auditlog_old_resource"
 
 	OperationTypeCreate = "create"
 	OperationTypeUpdate = "update"
 	OperationTypeDelete = "delete"
+
+	forwarderKind        = "auditlog"
+	forwardCheckInterval = time.Minute
 )
 
+//called when a forwarder fails to send or drops logs
+type AlarmHandler func(kind, name, message string)
+
 type AuditLogger struct {
 	Storage storage.StorageDriver
+	//forward logs to outside, error of them doesn't fail the request
+	Forwarders   []storage.Forwarder
+	lock         sync.Mutex
+	alarmHandler AlarmHandler
 }
 
-func New() (*AuditLogger, error) {
+func New(conf config.AuditLogConf) (*AuditLogger, error) {
 	a := &AuditLogger{}
 
 	tn, _ := kvzoo.TableNameFromSegments(AuditLogTable)
@@ -39,92 +57,243 @@ func New() (*AuditLogger, error) {
 		return nil, fmt.Errorf("create or get db table %s failed %s", tn, err.Error())
 	}
 
-	driver, err := storage.NewDefaultDriver(table, MaxAuditLogCount)
+	maxAge := time.Duration(conf.RetentionDays) * 24 * time.Hour
+	driver, err := storage.NewDefaultDriver(table, conf.MaxCount, maxAge)
 	if err != nil {
 		return a, err
 	}
 
 	a.Storage = driver
+	a.Forwarders, err = newForwarders(conf)
+	if err != nil {
+		return a, err
+	}
+
+	if len(a.Forwarders) > 0 {
+		go a.checkForwarders()
+	}
 	return a, nil
 }
 
-func (a *AuditLogger) List(user string) (types.AuditLogs, error) {
-	logs, err := a.Storage.List()
-	if err != nil {
-		return logs, err
+func newForwarders(conf config.AuditLogConf) ([]storage.Forwarder, error) {
+	var drivers []storage.Forwarder
+	for _, c := range conf.Syslogs {
+		d, err := storage.NewSyslogDriver(c.Network, c.Addr, c.AppName)
+		if err != nil {
+			return nil, err
+		}
+		drivers = append(drivers, d)
 	}
 
-	sort.Sort(sort.Reverse(logs))
-	if user == types.Administrator {
-		return logs, err
+	for _, c := range conf.Webhooks {
+		d, err := storage.NewWebhookDriver(c.URL, c.Token, c.BatchSize, time.Duration(c.FlushIntervalSeconds)*time.Second)
+		if err != nil {
+			return nil, err
+		}
+		drivers = append(drivers, d)
 	}
 
-	result := types.AuditLogs{}
-	for _, log := range logs {
-		if log.User == user {
-			result = append(result, log)
+	for _, c := range conf.Files {
+		d, err := storage.NewFileDriver(c.Path, int64(c.MaxSizeMB)*1024*1024, c.MaxBackups)
+		if err != nil {
+			return nil, err
 		}
+		drivers = append(drivers, d)
+	}
+	return drivers, nil
+}
+
+func (a *AuditLogger) SetAlarmHandler(h AlarmHandler) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.alarmHandler = h
+}
+
+func (a *AuditLogger) checkForwarders() {
+	last := make([]storage.ForwardStats, len(a.Forwarders))
+	for {
+		time.Sleep(forwardCheckInterval)
+		last = a.reportForwarders(last)
 	}
-	return result, nil
 }
 
+//compare counters with last check, alarm is raised if any log failed
+//or dropped since then
+func (a *AuditLogger) reportForwarders(last []storage.ForwardStats) []storage.ForwardStats {
+	a.lock.Lock()
+	handler := a.alarmHandler
+	a.lock.Unlock()
+
+	current := make([]storage.ForwardStats, len(a.Forwarders))
+	for i, f := range a.Forwarders {
+		current[i] = f.Stats()
+		failed := current[i].Failed - last[i].Failed
+		dropped := current[i].Dropped - last[i].Dropped
+		if failed == 0 && dropped == 0 {
+			continue
+		}
+
+		message := fmt.Sprintf("%d audit logs failed to send and %d dropped, %d sent in total", failed, dropped, current[i].Sent)
+		log.Warnf("%s %s", f.Name(), message)
+		if handler != nil {
+			handler(forwarderKind, f.Name(), message)
+		}
+	}
+	return current
+}
+
+//user other than admin can only query his own logs
+func (a *AuditLogger) Query(user string, q *storage.Query) (types.AuditLogs, string, error) {
+	if user != types.Administrator {
+		if q.User != "" && q.User != user {
+			return types.AuditLogs{}, "", nil
+		}
+		q.User = user
+	}
+	return a.Storage.Query(q)
+}
+
+//AuditHandler should be used before other handlers, so the request
+//rejected by them has request id too
 func (a *AuditLogger) AuditHandler() gorest.HandlerFunc {
 	return func(ctx *resource.Context) *resterr.APIError {
-		log := &types.AuditLog{
+		requestID := ctx.Request.Header.Get(RequestIDHeader)
+		if requestID == "" || len(requestID) > maxRequestIDLen {
+			requestID = uuid.MustGen()
+		}
+		ctx.Set(requestIDKey, requestID)
+		ctx.Response.Header().Set(RequestIDHeader, requestID)
+		return nil
+	}
+}
+
+//DiffHandler saves the previous resource of update to generate the
+//diff, it should be used after authorization, otherwise user without
+//permission could call the get handler
+func (a *AuditLogger) DiffHandler() gorest.HandlerFunc {
+	return func(ctx *resource.Context) *resterr.APIError {
+		if getOperation(ctx) == OperationTypeUpdate {
+			if get := ctx.Resource.GetSchema().GetHandler().GetGetHandler(); get != nil {
+				if old, err := get(ctx); err == nil && old != nil {
+					ctx.Set(oldResourceKey, old)
+				}
+			}
+		}
+		return nil
+	}
+}
+
+//log is recorded after the request is handled, so it has the result,
+//since the operation is done, failure of recording cann't fail the
+//request and is only logged
+func (a *AuditLogger) RecordHandler() gorest.AfterHandlerFunc {
+	return func(ctx *resource.Context, status int, apiErr *resterr.APIError) {
+		operation := getOperation(ctx)
+		if operation == "" {
+			return
+		}
+
+		auditLog := &types.AuditLog{
 			User:          getCurrentUser(ctx),
 			SourceAddress: ctx.Request.RemoteAddr,
+			Operation:     operation,
 			ResourceKind:  resource.DefaultKindName(ctx.Resource),
 			ResourcePath:  ctx.Request.URL.Path,
+			AuthSource:    getAuthSource(ctx),
+			ResponseCode:  status,
 		}
-
-		switch ctx.Request.Method {
-		case http.MethodPost:
-			log.Operation = OperationTypeCreate
-		case http.MethodPut:
-			log.Operation = OperationTypeUpdate
-		case http.MethodDelete:
-			log.Operation = OperationTypeDelete
-		default:
-			return nil
+		auditLog.Cluster, auditLog.Namespace = getClusterAndNamespace(ctx.Resource)
+		if requestID, ok := ctx.Get(requestIDKey); ok {
+			auditLog.RequestID = requestID.(string)
+		}
+		if apiErr != nil {
+			auditLog.Error = apiErr.Message
 		}
 
-		var detail interface{} = ctx.Resource
-		if action := ctx.Resource.GetAction(); action != nil {
-			if action.Name == types.ActionLogin {
-				return nil
+		if operation != OperationTypeDelete {
+			var detail interface{} = ctx.Resource
+			if action := ctx.Resource.GetAction(); action != nil {
+				detail = action.Input
+			}
+			detailStr, err := getLogDetail(auditLog.ResourceKind, detail)
+			if err != nil {
+				log.Warnf("marshal %s audit log failed %s", operation, err.Error())
 			}
-			log.Operation = action.Name
-			detail = action.Input
+			auditLog.Detail = detailStr
 		}
 
-		if log.Operation == OperationTypeDelete {
-			log.Detail = ""
-		} else {
-			detailStr, err := getLogDetail(detail)
+		if old, ok := ctx.Get(oldResourceKey); ok && apiErr == nil {
+			diff, err := diffResource(auditLog.ResourceKind, old, ctx.Resource)
 			if err != nil {
-				return resterr.NewAPIError(resterr.ServerError, fmt.Sprintf("marshal %s audit log failed %s", log.Operation))
+				log.Warnf("generate diff of %s failed %s", auditLog.ResourcePath, err.Error())
 			}
-			log.Detail = detailStr
+			auditLog.Diff = diff
 		}
 
-		log.SetCreationTimestamp(time.Now())
-		if err := a.Storage.Add(log); err != nil {
-			return resterr.NewAPIError(resterr.ServerError, fmt.Sprintf("record audit log failed %s", err.Error()))
+		if err := a.Storage.Add(auditLog); err != nil {
+			log.Warnf("record audit log of request %s failed %s", auditLog.RequestID, err.Error())
 		}
-		return nil
+		a.forward(auditLog)
 	}
 }
 
-func getLogDetail(d interface{}) (string, error) {
-	result, err := json.Marshal(d)
-	if err != nil {
-		return "", err
+//empty operation means the request isn't audited, login isn't
+//audited since its input has password
+func getOperation(ctx *resource.Context) string {
+	if action := ctx.Resource.GetAction(); action != nil {
+		if action.Name == types.ActionLogin {
+			return ""
+		}
+		return action.Name
+	}
+
+	switch ctx.Request.Method {
+	case http.MethodPost:
+		return OperationTypeCreate
+	case http.MethodPut, http.MethodPatch:
+		return OperationTypeUpdate
+	case http.MethodDelete:
+		return OperationTypeDelete
+	default:
+		return ""
+	}
+}
+
+func (a *AuditLogger) forward(auditLog *types.AuditLog) {
+	for _, d := range a.Forwarders {
+		if err := d.Add(auditLog); err != nil {
+			log.Warnf("forward audit log failed %s", err.Error())
+		}
+	}
+}
+
+func getClusterAndNamespace(r resource.Resource) (string, string) {
+	var cluster, namespace string
+	for ; r != nil; r = r.GetParent() {
+		switch resource.DefaultKindName(r) {
+		case "cluster":
+			cluster = r.GetID()
+		case "namespace":
+			namespace = r.GetID()
+		}
 	}
-	return string(result), nil
+	return cluster, namespace
 }
 
 func getCurrentUser(ctx *resource.Context) string {
-	currentUser := ctx.Request.Context().Value(types.CurrentUserKey)
+	return getUser(ctx.Request)
+}
+
+func getAuthSource(ctx *resource.Context) string {
+	source := ctx.Request.Context().Value(types.AuthSourceKey)
+	if source == nil {
+		return ""
+	}
+	return source.(string)
+}
+
+func getUser(r *http.Request) string {
+	currentUser := r.Context().Value(types.CurrentUserKey)
 	if currentUser == nil {
 		return ""
 	}
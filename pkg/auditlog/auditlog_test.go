@@ -0,0 +1,165 @@
+package auditlog
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"cement/log"
+	ut "cement/unittest"
+	"gorest"
+	resterr "gorest/error"
+	"gorest/resource"
+	"gorest/resource/schema"
+	"pkg/auditlog/storage"
+	"pkg/types"
+)
+
+var testVersion = resource.APIVersion{
+	Group:   "zcloud.cn",
+	Version: "v1",
+}
+
+//forwarder logs the failures
+func TestMain(m *testing.M) {
+	log.InitLogger(log.Error)
+	os.Exit(m.Run())
+}
+
+type Account struct {
+	resource.ResourceBase `json:",inline"`
+	Name                  string `json:"name"`
+	Password              string `json:"password"`
+}
+
+type accountHandler struct {
+	gets int
+}
+
+func (h *accountHandler) Get(ctx *resource.Context) (resource.Resource, *resterr.APIError) {
+	h.gets++
+	account := &Account{Name: "ben", Password: "old-secret"}
+	account.SetID(ctx.Resource.GetID())
+	return account, nil
+}
+
+func (h *accountHandler) Update(ctx *resource.Context) (resource.Resource, *resterr.APIError) {
+	return ctx.Resource, nil
+}
+
+type logRecorder struct {
+	logs []*types.AuditLog
+}
+
+func (r *logRecorder) Add(log *types.AuditLog) error {
+	r.logs = append(r.logs, log)
+	return nil
+}
+
+func (r *logRecorder) Query(q *storage.Query) (types.AuditLogs, string, error) {
+	return r.logs, "", nil
+}
+
+func TestRedactCredentials(t *testing.T) {
+	for _, input := range []interface{}{
+		&types.ResetPassword{OldPassword: "old-secret", NewPassword: "new-secret"},
+		&types.UserPassword{Password: "old-secret", LDAPPassword: "new-secret", TOTPCode: "123456"},
+		&types.TOTPCode{Code: "123456"},
+		&types.TOTPRecoveryCodes{RecoveryCodes: []string{"old-secret", "new-secret"}},
+		&types.User{Name: "ben", Password: "new-secret"},
+	} {
+		detail, err := getLogDetail("user", input)
+		ut.Assert(t, err == nil, "get log detail should succeed: %v", err)
+		for _, secret := range []string{"old-secret", "new-secret", "123456"} {
+			ut.Assert(t, strings.Contains(detail, secret) == false, "credential %s isn't redacted: %s", secret, detail)
+		}
+		ut.Assert(t, strings.Contains(detail, redactedValue), "credential should be redacted: %s", detail)
+	}
+}
+
+func TestDiffHandlerAfterAuthorization(t *testing.T) {
+	handler := &accountHandler{}
+	schemas := schema.NewSchemaManager()
+	schemas.MustImport(&testVersion, Account{}, handler)
+
+	recorder := &logRecorder{}
+	audit := &AuditLogger{
+		Storage: recorder,
+	}
+	allowed := false
+	server := gorest.NewAPIServer(schemas)
+	server.Use(audit.AuditHandler())
+	server.Use(func(ctx *resource.Context) *resterr.APIError {
+		ctx.Request = ctx.Request.WithContext(context.WithValue(ctx.Request.Context(), types.CurrentUserKey, "ben"))
+		if allowed == false {
+			return resterr.NewAPIError(resterr.PermissionDenied, "permission denied")
+		}
+		return nil
+	})
+	server.Use(audit.DiffHandler())
+	server.UseAfter(audit.RecordHandler())
+
+	update := func() int {
+		req := httptest.NewRequest(http.MethodPut, "/apis/zcloud.cn/v1/accounts/ben", strings.NewReader(`{"name":"ben2","password":"new-secret"}`))
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	ut.Equal(t, update(), http.StatusForbidden)
+	ut.Equal(t, handler.gets, 0)
+	ut.Equal(t, len(recorder.logs), 1)
+	ut.Equal(t, len(recorder.logs[0].Diff), 0)
+
+	allowed = true
+	ut.Equal(t, update(), http.StatusOK)
+	ut.Equal(t, handler.gets, 1)
+	ut.Equal(t, len(recorder.logs), 2)
+	log := recorder.logs[1]
+	ut.Equal(t, log.Diff, []types.AuditLogFieldDiff{
+		{Path: "name", Old: `"ben"`, New: `"ben2"`},
+		{Path: "password", Old: redactedValue, New: redactedValue},
+	})
+	ut.Assert(t, strings.Contains(log.Detail, "new-secret") == false, "password should be redacted: %s", log.Detail)
+}
+
+type statsForwarder struct {
+	logRecorder
+	stats storage.ForwardStats
+}
+
+func (f *statsForwarder) Name() string {
+	return "webhook http://127.0.0.1"
+}
+
+func (f *statsForwarder) Stats() storage.ForwardStats {
+	return f.stats
+}
+
+func TestReportForwarders(t *testing.T) {
+	f := &statsForwarder{}
+	a := &AuditLogger{
+		Forwarders: []storage.Forwarder{f},
+	}
+	var alarms []string
+	a.SetAlarmHandler(func(kind, name, message string) {
+		ut.Equal(t, kind, "auditlog")
+		ut.Equal(t, name, f.Name())
+		alarms = append(alarms, message)
+	})
+
+	f.stats = storage.ForwardStats{Sent: 10}
+	last := a.reportForwarders(make([]storage.ForwardStats, 1))
+	ut.Equal(t, len(alarms), 0)
+
+	f.stats = storage.ForwardStats{Sent: 12, Failed: 2, Dropped: 1}
+	last = a.reportForwarders(last)
+	ut.Equal(t, alarms, []string{"2 audit logs failed to send and 1 dropped, 12 sent in total"})
+
+	//no alarm if counters don't change
+	a.reportForwarders(last)
+	ut.Equal(t, len(alarms), 1)
+}
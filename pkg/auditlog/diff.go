@@ -0,0 +1,148 @@
+package auditlog
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+
+	"pkg/types"
+)
+
+//fields of resource base aren't compared
+var ignoredDiffFields = map[string]struct{}{
+	"id":                {},
+	"type":              {},
+	"links":             {},
+	"creationTimestamp": {},
+	"deletionTimestamp": {},
+}
+
+//nested objects are compared field by field, path of the nested field
+//is joined by dot, field which is only in old resource is removed
+//except the read only field of new resource, since the resource
+//returned by get handler has them but update request hasn't
+func diffResource(kind string, old, new interface{}) ([]types.AuditLogFieldDiff, error) {
+	oldFields, err := toJSONObject(old)
+	if err != nil {
+		return nil, err
+	}
+	newFields, err := toJSONObject(new)
+	if err != nil {
+		return nil, err
+	}
+
+	for field := range ignoredDiffFields {
+		delete(oldFields, field)
+		delete(newFields, field)
+	}
+	for field := range readOnlyFields(new) {
+		if _, ok := newFields[field]; ok == false {
+			delete(oldFields, field)
+		}
+	}
+
+	var diffs []types.AuditLogFieldDiff
+	newRedactor(kind).diffObject("", oldFields, newFields, &diffs)
+	sort.Slice(diffs, func(i, j int) bool {
+		return diffs[i].Path < diffs[j].Path
+	})
+	return diffs, nil
+}
+
+//json name of the fields with rest tag description=readonly
+func readOnlyFields(v interface{}) map[string]struct{} {
+	fields := make(map[string]struct{})
+	typ := reflect.TypeOf(v)
+	if typ == nil {
+		return fields
+	}
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct {
+		return fields
+	}
+
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if strings.Contains(field.Tag.Get("rest"), "description=readonly") == false {
+			continue
+		}
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "" {
+			name = field.Name
+		}
+		fields[name] = struct{}{}
+	}
+	return fields
+}
+
+func toJSONObject(v interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var obj map[string]interface{}
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+func (r redactor) diffObject(prefix string, old, new map[string]interface{}, diffs *[]types.AuditLogFieldDiff) {
+	joinPath := func(field string) string {
+		if prefix == "" {
+			return field
+		}
+		return prefix + "." + field
+	}
+
+	for field, newValue := range new {
+		path := joinPath(field)
+
+		oldValue, ok := old[field]
+		if ok == false {
+			*diffs = append(*diffs, types.AuditLogFieldDiff{
+				Path: path,
+				New:  r.toRedactedJSON(field, newValue),
+			})
+			continue
+		}
+
+		oldObj, oldIsObj := oldValue.(map[string]interface{})
+		newObj, newIsObj := newValue.(map[string]interface{})
+		if oldIsObj && newIsObj && r.isRedactedField(field) == false {
+			r.diffObject(path, oldObj, newObj, diffs)
+		} else if reflect.DeepEqual(oldValue, newValue) == false {
+			*diffs = append(*diffs, types.AuditLogFieldDiff{
+				Path: path,
+				Old:  r.toRedactedJSON(field, oldValue),
+				New:  r.toRedactedJSON(field, newValue),
+			})
+		}
+	}
+
+	for field, oldValue := range old {
+		if _, ok := new[field]; ok == false {
+			*diffs = append(*diffs, types.AuditLogFieldDiff{
+				Path: joinPath(field),
+				Old:  r.toRedactedJSON(field, oldValue),
+			})
+		}
+	}
+}
+
+//only the change of redacted field is recorded
+func (r redactor) toRedactedJSON(field string, v interface{}) string {
+	if r.isRedactedField(field) {
+		return redactedValue
+	}
+	return toJSON(r.redact(v))
+}
+
+func toJSON(v interface{}) string {
+	data, _ := json.Marshal(v)
+	return string(data)
+}
@@ -0,0 +1,107 @@
+package auditlog
+
+import (
+	"testing"
+
+	ut "cement/unittest"
+	"gorest/resource"
+	"pkg/types"
+)
+
+func TestDiffResource(t *testing.T) {
+	old := map[string]interface{}{
+		"id":       "n1",
+		"name":     "n1",
+		"replicas": 1,
+		"status":   "running",
+		"spec": map[string]interface{}{
+			"image": "nginx:1.16",
+			"port":  80,
+		},
+	}
+	new := map[string]interface{}{
+		"id":       "n2",
+		"replicas": 3,
+		"labels":   []string{"web"},
+		"spec": map[string]interface{}{
+			"image": "nginx:1.17",
+			"port":  80,
+		},
+	}
+
+	diffs, err := diffResource("deployment", old, new)
+	ut.Assert(t, err == nil, "diff should succeed: %v", err)
+	ut.Equal(t, diffs, []types.AuditLogFieldDiff{
+		{Path: "labels", New: `["web"]`},
+		{Path: "name", Old: `"n1"`},
+		{Path: "replicas", Old: "1", New: "3"},
+		{Path: "spec.image", Old: `"nginx:1.16"`, New: `"nginx:1.17"`},
+		{Path: "status", Old: `"running"`},
+	})
+
+	diffs, err = diffResource("deployment", old, old)
+	ut.Assert(t, err == nil, "diff should succeed: %v", err)
+	ut.Equal(t, len(diffs), 0)
+}
+
+type labeledResource struct {
+	resource.ResourceBase `json:",inline"`
+	Name                  string            `json:"name"`
+	Labels                map[string]string `json:"labels,omitempty"`
+	Memo                  string            `json:"memo,omitempty"`
+	Status                string            `json:"status,omitempty" rest:"description=readonly"`
+}
+
+func TestDiffDeletedLabel(t *testing.T) {
+	old := &labeledResource{
+		Name:   "web",
+		Labels: map[string]string{"app": "web", "tier": "frontend"},
+		Memo:   "test",
+		Status: "running",
+	}
+	new := &labeledResource{
+		Name:   "web",
+		Labels: map[string]string{"app": "web"},
+	}
+
+	diffs, err := diffResource("labeledresource", old, new)
+	ut.Assert(t, err == nil, "diff should succeed: %v", err)
+	ut.Equal(t, diffs, []types.AuditLogFieldDiff{
+		{Path: "labels.tier", Old: `"frontend"`},
+		{Path: "memo", Old: `"test"`},
+	})
+
+	new.Labels = nil
+	diffs, err = diffResource("labeledresource", old, new)
+	ut.Assert(t, err == nil, "diff should succeed: %v", err)
+	ut.Equal(t, diffs, []types.AuditLogFieldDiff{
+		{Path: "labels", Old: `{"app":"web","tier":"frontend"}`},
+		{Path: "memo", Old: `"test"`},
+	})
+}
+
+func TestRedactSecret(t *testing.T) {
+	old := &types.Secret{
+		Name: "db",
+		Data: []types.SecretData{{Key: "user", Value: "old-user"}, {Key: "pass", Value: "old-pass"}},
+	}
+	new := &types.Secret{
+		Name: "db",
+		Data: []types.SecretData{{Key: "user", Value: "new-user"}, {Key: "pass", Value: "old-pass"}},
+	}
+
+	detail, err := getLogDetail("secret", new)
+	ut.Assert(t, err == nil, "get log detail should succeed: %v", err)
+	ut.Equal(t, detail, `{"creationTimestamp":null,"data":[{"key":"user","value":"******"},{"key":"pass","value":"******"}],"deletionTimestamp":null,"name":"db"}`)
+
+	diffs, err := diffResource("secret", old, new)
+	ut.Assert(t, err == nil, "diff should succeed: %v", err)
+	ut.Equal(t, diffs, []types.AuditLogFieldDiff{
+		{Path: "data", Old: `[{"key":"user","value":"******"},{"key":"pass","value":"******"}]`, New: `[{"key":"user","value":"******"},{"key":"pass","value":"******"}]`},
+	})
+
+	//value of other kinds isn't redacted
+	detail, err = getLogDetail("configmap", map[string]interface{}{"value": "v1"})
+	ut.Assert(t, err == nil, "get log detail should succeed: %v", err)
+	ut.Equal(t, detail, `{"value":"v1"}`)
+}
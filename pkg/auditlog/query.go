@@ -0,0 +1,181 @@
+package auditlog
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"cement/log"
+	"pkg/auditlog/storage"
+	"pkg/types"
+)
+
+const (
+	AuditLogExportPath = "/apis/export.zcloud.cn/v1/auditlogs"
+	//next marker of list is returned in the response header
+	NextMarkerHeader = "X-Next-Marker"
+
+	ExportFormatCSV       = "csv"
+	ExportFormatJSONLines = "jsonl"
+
+	MaxQueryLimit   = 1000
+	exportBatchSize = 500
+)
+
+var csvHeader = []string{"id", "creationTimestamp", "user", "sourceAddress", "operation", "resourceKind", "resourcePath", "cluster", "namespace", "detail", "requestId", "authSource", "responseCode", "error"}
+
+//time could be RFC3339 or date, order is asc or desc, default is
+//desc which returns the newest log first
+func ParseQuery(values url.Values) (*storage.Query, error) {
+	q := &storage.Query{
+		User:         values.Get("user"),
+		Operation:    values.Get("operation"),
+		ResourceKind: values.Get("resourceKind"),
+		Cluster:      values.Get("cluster"),
+		Namespace:    values.Get("namespace"),
+		Marker:       values.Get("marker"),
+	}
+
+	var err error
+	if q.From, err = parseTime(values.Get("from")); err != nil {
+		return nil, err
+	}
+	if q.To, err = parseTime(values.Get("to")); err != nil {
+		return nil, err
+	}
+
+	if limit := values.Get("limit"); limit != "" {
+		if q.Limit, err = strconv.Atoi(limit); err != nil || q.Limit <= 0 {
+			return nil, fmt.Errorf("invalid limit %s", limit)
+		}
+	}
+
+	switch order := values.Get("order"); order {
+	case "asc":
+		q.Ascending = true
+	case "", "desc":
+	default:
+		return nil, fmt.Errorf("invalid order %s, it should be asc or desc", order)
+	}
+	return q, nil
+}
+
+func parseTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time %s, it should be RFC3339 or date like 2006-01-02", s)
+}
+
+//logs are queried in batches, so the whole audit log isn't loaded
+//into memory
+func (a *AuditLogger) Export(w io.Writer, user, format string, q *storage.Query) error {
+	var write func(*types.AuditLog) error
+	var flush func() error
+	switch format {
+	case ExportFormatCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(csvHeader); err != nil {
+			return err
+		}
+		write = func(log *types.AuditLog) error {
+			return cw.Write([]string{
+				log.ID,
+				log.GetCreationTimestamp().Format(time.RFC3339),
+				log.User,
+				log.SourceAddress,
+				log.Operation,
+				log.ResourceKind,
+				log.ResourcePath,
+				log.Cluster,
+				log.Namespace,
+				log.Detail,
+				log.RequestID,
+				log.AuthSource,
+				strconv.Itoa(log.ResponseCode),
+				log.Error,
+			})
+		}
+		flush = func() error {
+			cw.Flush()
+			return cw.Error()
+		}
+	case ExportFormatJSONLines:
+		encoder := json.NewEncoder(w)
+		write = func(log *types.AuditLog) error {
+			return encoder.Encode(log)
+		}
+		flush = func() error {
+			return nil
+		}
+	default:
+		return fmt.Errorf("unknown export format %s, it should be csv or jsonl", format)
+	}
+
+	q.Limit = exportBatchSize
+	for {
+		logs, marker, err := a.Query(user, q)
+		if err != nil {
+			return err
+		}
+
+		for _, log := range logs {
+			if err := write(log); err != nil {
+				return err
+			}
+		}
+
+		if marker == "" {
+			return flush()
+		}
+		q.Marker = marker
+	}
+}
+
+func (a *AuditLogger) RegisterHandler(router gin.IRoutes) error {
+	router.GET(AuditLogExportPath, func(c *gin.Context) {
+		user := getUser(c.Request)
+		if user == "" {
+			c.String(http.StatusUnauthorized, "user isn't authenticated")
+			return
+		}
+
+		q, err := ParseQuery(c.Request.URL.Query())
+		if err != nil {
+			c.String(http.StatusBadRequest, err.Error())
+			return
+		}
+
+		format := c.DefaultQuery("format", ExportFormatCSV)
+		switch format {
+		case ExportFormatCSV:
+			c.Header("Content-Type", "text/csv")
+		case ExportFormatJSONLines:
+			c.Header("Content-Type", "application/x-ndjson")
+		default:
+			c.String(http.StatusBadRequest, fmt.Sprintf("unknown export format %s, it should be csv or jsonl", format))
+			return
+		}
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=auditlogs.%s", format))
+
+		//header is sent, error can only be logged
+		if err := a.Export(c.Writer, user, format, q); err != nil {
+			log.Warnf("export audit log failed %s", err.Error())
+		}
+	})
+	return nil
+}
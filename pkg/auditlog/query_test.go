@@ -0,0 +1,53 @@
+package auditlog
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+	"time"
+
+	ut "cement/unittest"
+	"pkg/auditlog/storage"
+	"pkg/types"
+)
+
+func TestExportCSV(t *testing.T) {
+	log := &types.AuditLog{
+		User:          "ben",
+		SourceAddress: "10.0.0.1",
+		Operation:     OperationTypeUpdate,
+		ResourceKind:  "deployment",
+		ResourcePath:  "/apis/zcloud.cn/v1/clusters/local/namespaces/default/deployments/web",
+		Cluster:       "local",
+		Namespace:     "default",
+		Detail:        `{"name":"web","replicas":2}`,
+		RequestID:     "req-1",
+		AuthSource:    "token",
+		ResponseCode:  422,
+		Error:         "invalid replicas, it should be positive",
+	}
+	log.SetID("1")
+	log.SetCreationTimestamp(time.Now())
+	a := &AuditLogger{
+		Storage: &logRecorder{logs: []*types.AuditLog{log}},
+	}
+
+	var buf bytes.Buffer
+	err := a.Export(&buf, types.Administrator, ExportFormatCSV, &storage.Query{})
+	ut.Assert(t, err == nil, "export should succeed: %v", err)
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	ut.Assert(t, err == nil, "export should be valid csv: %v", err)
+	ut.Equal(t, len(records), 2)
+	ut.Equal(t, records[0], csvHeader)
+	row := make(map[string]string)
+	for i, name := range records[0] {
+		row[name] = records[1][i]
+	}
+	ut.Equal(t, row["user"], "ben")
+	ut.Equal(t, row["detail"], log.Detail)
+	ut.Equal(t, row["requestId"], "req-1")
+	ut.Equal(t, row["authSource"], "token")
+	ut.Equal(t, row["responseCode"], "422")
+	ut.Equal(t, row["error"], log.Error)
+}
@@ -0,0 +1,89 @@
+package auditlog
+
+import (
+	"encoding/json"
+)
+
+const redactedValue = "******"
+
+//values of the fields are hidden in audit log, since logs are
+//forwarded to outside, field is matched by json name in any level,
+//credentials include passwords, tokens, keys and totp codes
+var redactedFields = map[string]struct{}{
+	"password":         {},
+	"ldapPassword":     {},
+	"oldPassword":      {},
+	"newPassword":      {},
+	"adminPassword":    {},
+	"registryPassword": {},
+	"sshKey":           {},
+	"token":            {},
+	"secret":           {},
+	"qrCode":           {},
+	"totpCode":         {},
+	"code":             {},
+	"recoveryCodes":    {},
+}
+
+//values which are secret for the resource kind, they are hidden too,
+//key of secret data is kept so it's known which data is changed
+var kindRedactedFields = map[string][]string{
+	"secret": {"value"},
+}
+
+//redacted fields of a resource kind
+type redactor map[string]struct{}
+
+func newRedactor(kind string) redactor {
+	r := make(redactor, len(redactedFields))
+	for field := range redactedFields {
+		r[field] = struct{}{}
+	}
+	for _, field := range kindRedactedFields[kind] {
+		r[field] = struct{}{}
+	}
+	return r
+}
+
+func (r redactor) isRedactedField(field string) bool {
+	_, ok := r[field]
+	return ok
+}
+
+func getLogDetail(kind string, d interface{}) (string, error) {
+	data, err := json.Marshal(d)
+	if err != nil {
+		return "", err
+	}
+
+	var obj interface{}
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return "", err
+	}
+	result, err := json.Marshal(newRedactor(kind).redact(obj))
+	if err != nil {
+		return "", err
+	}
+	return string(result), nil
+}
+
+//empty value is kept, so it's known the field isn't set
+func (r redactor) redact(v interface{}) interface{} {
+	switch obj := v.(type) {
+	case map[string]interface{}:
+		for field, value := range obj {
+			if r.isRedactedField(field) {
+				if value != nil && value != "" {
+					obj[field] = redactedValue
+				}
+			} else {
+				obj[field] = r.redact(value)
+			}
+		}
+	case []interface{}:
+		for i, value := range obj {
+			obj[i] = r.redact(value)
+		}
+	}
+	return v
+}
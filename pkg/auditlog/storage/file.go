@@ -0,0 +1,124 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	"pkg/types"
+)
+
+const (
+	DefaultFileMaxSize    = 100 * 1024 * 1024
+	DefaultFileMaxBackups = 5
+)
+
+//write log as json line into the file, when the file exceeds max
+//size, it's renamed to path.1, and path.1 to path.2 and so on, the
+//oldest backup is removed
+type FileDriver struct {
+	forwardCounter
+	path       string
+	maxSize    int64
+	maxBackups int
+	file       *os.File
+	size       int64
+	lock       sync.Mutex
+}
+
+func NewFileDriver(path string, maxSize int64, maxBackups int) (Forwarder, error) {
+	if maxSize <= 0 {
+		maxSize = DefaultFileMaxSize
+	}
+	if maxBackups <= 0 {
+		maxBackups = DefaultFileMaxBackups
+	}
+
+	d := &FileDriver{
+		path:       path,
+		maxSize:    maxSize,
+		maxBackups: maxBackups,
+	}
+	if err := d.open(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (d *FileDriver) open() error {
+	f, err := os.OpenFile(d.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return fmt.Errorf("open audit log file %s failed: %s", d.path, err.Error())
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	d.file = f
+	d.size = info.Size()
+	return nil
+}
+
+func (d *FileDriver) Name() string {
+	return "file " + d.path
+}
+
+func (d *FileDriver) Add(a *types.AuditLog) error {
+	line, err := json.Marshal(a)
+	if err == nil {
+		err = d.write(append(line, '\n'))
+	}
+	d.done(1, err)
+	return err
+}
+
+//log is written in api request, so failed write isn't retried with
+//backoff, the file is reopened and written again at once, partially
+//written line isn't written again
+func (d *FileDriver) write(line []byte) error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if d.size > 0 && d.size+int64(len(line)) > d.maxSize {
+		if err := d.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := d.file.Write(line)
+	d.size += int64(n)
+	if err == nil || n > 0 {
+		return err
+	}
+
+	d.file.Close()
+	if err := d.open(); err != nil {
+		return err
+	}
+	n, err = d.file.Write(line)
+	d.size += int64(n)
+	return err
+}
+
+func (d *FileDriver) rotate() error {
+	d.file.Close()
+	os.Remove(backupPath(d.path, d.maxBackups))
+	for i := d.maxBackups - 1; i > 0; i-- {
+		os.Rename(backupPath(d.path, i), backupPath(d.path, i+1))
+	}
+	err := os.Rename(d.path, backupPath(d.path, 1))
+	if openErr := d.open(); openErr != nil {
+		return openErr
+	}
+	if err != nil {
+		return fmt.Errorf("rotate audit log file %s failed: %s", d.path, err.Error())
+	}
+	return nil
+}
+
+func backupPath(path string, i int) string {
+	return fmt.Sprintf("%s.%d", path, i)
+}
@@ -0,0 +1,72 @@
+package storage
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+const (
+	forwardBufferSize = 1024
+	forwardTimeout    = 5 * time.Second
+	forwardRetries    = 3
+)
+
+//interval before the first retry, it's doubled after each failure
+var forwardRetryInterval = time.Second
+
+//driver which sends logs to outside of gaocloud, name identifies the
+//destination in alarm and log
+type Forwarder interface {
+	Driver
+	Name() string
+	Stats() ForwardStats
+}
+
+//failed logs can't be sent after retries, dropped logs are discarded
+//since the buffer is full, counters only increase
+type ForwardStats struct {
+	Sent    uint64
+	Failed  uint64
+	Dropped uint64
+}
+
+//it should be the first field of forwarder, so the counters are 64 bit
+//aligned for atomic operations
+type forwardCounter struct {
+	sent    uint64
+	failed  uint64
+	dropped uint64
+}
+
+func (c *forwardCounter) Stats() ForwardStats {
+	return ForwardStats{
+		Sent:    atomic.LoadUint64(&c.sent),
+		Failed:  atomic.LoadUint64(&c.failed),
+		Dropped: atomic.LoadUint64(&c.dropped),
+	}
+}
+
+func (c *forwardCounter) done(count int, err error) {
+	if err == nil {
+		atomic.AddUint64(&c.sent, uint64(count))
+	} else {
+		atomic.AddUint64(&c.failed, uint64(count))
+	}
+}
+
+func (c *forwardCounter) drop() {
+	atomic.AddUint64(&c.dropped, 1)
+}
+
+//send is retried with exponential backoff, error of the last try is
+//returned
+func retryWithBackoff(send func() error) error {
+	interval := forwardRetryInterval
+	err := send()
+	for i := 0; i < forwardRetries && err != nil; i++ {
+		time.Sleep(interval)
+		interval *= 2
+		err = send()
+	}
+	return err
+}
@@ -0,0 +1,242 @@
+package storage
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"cement/log"
+	ut "cement/unittest"
+	"pkg/types"
+)
+
+//failed logs are logged
+func TestMain(m *testing.M) {
+	log.InitLogger(log.Error)
+	os.Exit(m.Run())
+}
+
+func newLog(id int) *types.AuditLog {
+	log := &types.AuditLog{
+		UID:       uint64(id),
+		User:      "admin",
+		Operation: "delete",
+	}
+	log.SetID(uintToStr(log.UID))
+	log.SetCreationTimestamp(time.Now())
+	return log
+}
+
+func checkSyslogMessage(t *testing.T, msg string, id string) {
+	ut.Assert(t, strings.HasPrefix(msg, "<133>1 "), "message should have priority and version: %s", msg)
+	fields := strings.SplitN(msg, " ", 8)
+	ut.Equal(t, len(fields), 8)
+	_, err := time.Parse(time.RFC3339, fields[1])
+	ut.Assert(t, err == nil, "timestamp should be RFC3339: %s", fields[1])
+	ut.Equal(t, fields[3], "test")
+	ut.Equal(t, fields[5], syslogMsgID)
+	ut.Equal(t, fields[6], "-")
+
+	var log types.AuditLog
+	err = json.Unmarshal([]byte(fields[7]), &log)
+	ut.Assert(t, err == nil, "message should be json: %s", fields[7])
+	ut.Equal(t, log.ID, id)
+	ut.Equal(t, log.Operation, "delete")
+}
+
+func TestSyslogDriver(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	ut.Assert(t, err == nil, "listen udp should succeed: %v", err)
+	defer conn.Close()
+
+	d, err := NewSyslogDriver("udp", conn.LocalAddr().String(), "test")
+	ut.Assert(t, err == nil, "create syslog driver should succeed: %v", err)
+	ut.Assert(t, d.Add(newLog(1)) == nil, "add log should succeed")
+
+	buf := make([]byte, 4096)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	ut.Assert(t, err == nil, "read syslog message should succeed: %v", err)
+	checkSyslogMessage(t, string(buf[:n]), "1")
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	ut.Assert(t, err == nil, "listen tcp should succeed: %v", err)
+	defer ln.Close()
+
+	d, err = NewSyslogDriver("tcp", ln.Addr().String(), "test")
+	ut.Assert(t, err == nil, "create syslog driver should succeed: %v", err)
+	ut.Assert(t, d.Add(newLog(2)) == nil, "add log should succeed")
+	ut.Assert(t, d.Add(newLog(3)) == nil, "add log should succeed")
+
+	c, err := ln.Accept()
+	ut.Assert(t, err == nil, "accept should succeed: %v", err)
+	defer c.Close()
+	c.SetReadDeadline(time.Now().Add(5 * time.Second))
+	r := bufio.NewReader(c)
+	for _, id := range []string{"2", "3"} {
+		length, err := r.ReadString(' ')
+		ut.Assert(t, err == nil, "read message length should succeed: %v", err)
+		n, err := strconv.Atoi(strings.TrimSpace(length))
+		ut.Assert(t, err == nil, "message length should be integer: %s", length)
+		msg := make([]byte, n)
+		_, err = io.ReadFull(r, msg)
+		ut.Assert(t, err == nil, "read message should succeed: %v", err)
+		checkSyslogMessage(t, string(msg), id)
+	}
+
+	_, err = NewSyslogDriver("unix", "/dev/log", "")
+	ut.Assert(t, err != nil, "unix network isn't supported")
+}
+
+func TestWebhookDriver(t *testing.T) {
+	batches := make(chan types.AuditLogs, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var logs types.AuditLogs
+		json.NewDecoder(r.Body).Decode(&logs)
+		batches <- logs
+	}))
+	defer server.Close()
+
+	d, err := NewWebhookDriver(server.URL, "secret", 3, 100*time.Millisecond)
+	ut.Assert(t, err == nil, "create webhook driver should succeed: %v", err)
+	for i := 1; i <= 7; i++ {
+		ut.Assert(t, d.Add(newLog(i)) == nil, "add log should succeed")
+	}
+
+	var sizes []int
+	var ids []string
+	for len(ids) < 7 {
+		select {
+		case logs := <-batches:
+			sizes = append(sizes, len(logs))
+			for _, log := range logs {
+				ids = append(ids, log.ID)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("webhook should receive all the logs, got %v", ids)
+		}
+	}
+	ut.Equal(t, sizes, []int{3, 3, 1})
+	ut.Equal(t, ids, []string{"1", "2", "3", "4", "5", "6", "7"})
+
+	_, err = NewWebhookDriver("", "", 0, 0)
+	ut.Assert(t, err != nil, "webhook url should be specified")
+}
+
+func TestWebhookRetry(t *testing.T) {
+	forwardRetryInterval = 10 * time.Millisecond
+	defer func() {
+		forwardRetryInterval = time.Second
+	}()
+
+	var posts int32
+	failures := int32(2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&posts, 1) <= atomic.LoadInt32(&failures) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	d, err := NewWebhookDriver(server.URL, "", 2, time.Hour)
+	ut.Assert(t, err == nil, "create webhook driver should succeed: %v", err)
+	d.Add(newLog(1))
+	d.Add(newLog(2))
+	waitStats := func(stats ForwardStats) {
+		for i := 0; i < 100 && d.Stats() != stats; i++ {
+			time.Sleep(10 * time.Millisecond)
+		}
+		ut.Equal(t, d.Stats(), stats)
+	}
+	//batch is sent after two retries
+	waitStats(ForwardStats{Sent: 2})
+	ut.Equal(t, atomic.LoadInt32(&posts), int32(3))
+
+	//batch fails after all the retries
+	atomic.StoreInt32(&posts, 0)
+	atomic.StoreInt32(&failures, forwardRetries+1)
+	d.Add(newLog(3))
+	d.Add(newLog(4))
+	waitStats(ForwardStats{Sent: 2, Failed: 2})
+	ut.Equal(t, atomic.LoadInt32(&posts), int32(forwardRetries+1))
+}
+
+func TestForwarderDropLog(t *testing.T) {
+	d := &WebhookDriver{
+		url:  "http://127.0.0.1",
+		logs: make(chan *types.AuditLog, 1),
+	}
+	ut.Assert(t, d.Add(newLog(1)) == nil, "add log should succeed")
+	ut.Assert(t, d.Add(newLog(2)) != nil, "log should be dropped if buffer is full")
+	ut.Equal(t, d.Stats(), ForwardStats{Dropped: 1})
+
+	s := &SyslogDriver{
+		network: "udp",
+		addr:    "127.0.0.1:514",
+		logs:    make(chan *types.AuditLog),
+	}
+	ut.Assert(t, s.Add(newLog(1)) != nil, "log should be dropped if buffer is full")
+	ut.Equal(t, s.Stats(), ForwardStats{Dropped: 1})
+}
+
+func TestFileDriver(t *testing.T) {
+	dir, err := ioutil.TempDir("", "auditlog")
+	ut.Assert(t, err == nil, "create temp dir should succeed: %v", err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "audit.log")
+	line, _ := json.Marshal(newLog(1))
+	//each file has two logs
+	d, err := NewFileDriver(path, int64(len(line)+1)*2, 2)
+	ut.Assert(t, err == nil, "create file driver should succeed: %v", err)
+	for i := 1; i <= 7; i++ {
+		ut.Assert(t, d.Add(newLog(i)) == nil, "add log should succeed")
+	}
+
+	readIDs := func(path string) []string {
+		data, err := ioutil.ReadFile(path)
+		ut.Assert(t, err == nil, "read %s should succeed: %v", path, err)
+		var ids []string
+		for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+			var log types.AuditLog
+			ut.Assert(t, json.Unmarshal([]byte(line), &log) == nil, "line should be json: %s", line)
+			ids = append(ids, log.ID)
+		}
+		return ids
+	}
+	ut.Equal(t, readIDs(path), []string{"7"})
+	ut.Equal(t, readIDs(path+".1"), []string{"5", "6"})
+	ut.Equal(t, readIDs(path+".2"), []string{"3", "4"})
+	_, err = os.Stat(path + ".3")
+	ut.Assert(t, os.IsNotExist(err), "only two backups are kept")
+
+	//driver appends to the existing file
+	d, err = NewFileDriver(path, int64(len(line)+1)*2, 2)
+	ut.Assert(t, err == nil, "create file driver should succeed: %v", err)
+	ut.Assert(t, d.Add(newLog(8)) == nil, "add log should succeed")
+	ut.Equal(t, readIDs(path), []string{"7", "8"})
+
+	//file is reopened if write fails
+	path = filepath.Join(dir, "reopen.log")
+	d, err = NewFileDriver(path, 0, 0)
+	ut.Assert(t, err == nil, "create file driver should succeed: %v", err)
+	d.(*FileDriver).file.Close()
+	ut.Assert(t, d.Add(newLog(1)) == nil, "add log should succeed")
+	ut.Equal(t, readIDs(path), []string{"1"})
+	ut.Equal(t, d.Stats(), ForwardStats{Sent: 1})
+}
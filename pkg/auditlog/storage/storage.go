@@ -3,136 +3,322 @@ package storage
 import (
 	"encoding/json"
 	"fmt"
-	"sort"
 	"strconv"
 	"sync"
+	"time"
 
+	"cement/log"
 	"pkg/types"
 
 	"kvzoo"
 )
 
-type StorageDriver interface {
+const (
+	scanBatchSize = 500
+	pruneInterval = time.Hour
+)
+
+//every audit log is added to all the drivers
+type Driver interface {
 	Add(a *types.AuditLog) error
-	List() (types.AuditLogs, error)
 }
 
+//driver which saves logs and can be queried, other drivers only
+//forward logs to outside of gaocloud
+type StorageDriver interface {
+	Driver
+	//logs are returned from the newest one unless query is ascending,
+	//marker is the id of last log if there may be more logs
+	Query(q *Query) (types.AuditLogs, string, error)
+}
+
+//empty field matches any value, time range is [From, To)
+type Query struct {
+	User         string
+	Operation    string
+	ResourceKind string
+	Cluster      string
+	Namespace    string
+	From         time.Time
+	To           time.Time
+	//zero means no limit
+	Limit int
+	//continue after the log with the id
+	Marker    string
+	Ascending bool
+}
+
+func (q *Query) Match(a *types.AuditLog) bool {
+	created := a.GetCreationTimestamp()
+	return (q.User == "" || q.User == a.User) &&
+		(q.Operation == "" || q.Operation == a.Operation) &&
+		(q.ResourceKind == "" || q.ResourceKind == a.ResourceKind) &&
+		(q.Cluster == "" || q.Cluster == a.Cluster) &&
+		(q.Namespace == "" || q.Namespace == a.Namespace) &&
+		(q.From.IsZero() || created.Before(q.From) == false) &&
+		(q.To.IsZero() || created.Before(q.To))
+}
+
+//logs are saved in the order of creation time, so scan stops at
+//the first log beyond the time range
+func (q *Query) beyond(a *types.AuditLog) bool {
+	created := a.GetCreationTimestamp()
+	if q.Ascending {
+		return q.To.IsZero() == false && created.Before(q.To) == false
+	}
+	return q.From.IsZero() == false && created.Before(q.From)
+}
+
+//key is the zero padded uid, so keys are sorted by creation time
 type DefaultDriver struct {
 	maxRecordCount int
+	maxAge         time.Duration
 	table          kvzoo.Table
-	firstID        uint64
+	recordCount    int
 	currentID      uint64
 	lock           sync.Mutex
 }
 
-func NewDefaultDriver(table kvzoo.Table, maxRecordCount int) (StorageDriver, error) {
+//zero max count or max age means no limit
+func NewDefaultDriver(table kvzoo.Table, maxRecordCount int, maxAge time.Duration) (StorageDriver, error) {
 	d := &DefaultDriver{
 		maxRecordCount: maxRecordCount,
+		maxAge:         maxAge,
 		table:          table,
 	}
 
 	if err := d.initDB(); err != nil {
 		return d, err
 	}
+
+	if maxAge > 0 {
+		go d.pruneLoop()
+	}
 	return d, nil
 }
 
+//logs saved by old version use uid without padding as key, they
+//are moved to the new key
 func (d *DefaultDriver) initDB() error {
-	logs, err := listFromDB(d.table)
+	tx, err := d.table.Begin()
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
 
-	logCount := len(logs)
-	if logCount == 0 {
-		return nil
-	}
-
-	sort.Sort(logs)
-
-	d.firstID = logs[0].UID
-	d.currentID = logs[logCount-1].UID
-	return nil
-}
-
-func listFromDB(table kvzoo.Table) (types.AuditLogs, error) {
-	tx, err := table.Begin()
-	if err != nil {
-		return nil, err
-	}
-
-	defer tx.Commit()
 	values, err := tx.List()
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	var logs types.AuditLogs
-	for _, value := range values {
+	for key, value := range values {
 		var log types.AuditLog
 		if err := json.Unmarshal(value, &log); err != nil {
-			return nil, err
+			return fmt.Errorf("unmarshal auditlog %s failed: %s", key, err.Error())
+		}
+
+		if log.UID > d.currentID {
+			d.currentID = log.UID
+		}
+
+		if newKey := logKey(log.UID); key != newKey {
+			if err := tx.Delete(key); err != nil {
+				return err
+			}
+			if err := tx.Add(newKey, value); err != nil {
+				return err
+			}
 		}
-		logs = append(logs, &log)
 	}
-	return logs, nil
+	d.recordCount = len(values)
+
+	if err := d.prune(tx, time.Now(), true); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func logKey(uid uint64) string {
+	return fmt.Sprintf("%020d", uid)
 }
 
 func (d *DefaultDriver) Add(a *types.AuditLog) error {
 	d.lock.Lock()
 	defer d.lock.Unlock()
 
-	if d.currentID-d.firstID > uint64(d.maxRecordCount-2) {
-		if err := deleteFromDB(d.table, uintToStr(d.firstID)); err != nil {
-			return err
-		}
-		d.firstID += 1
-	}
+	//creation time is set under the lock, so it has the same order
+	//as uid
+	now := time.Now()
+	a.SetCreationTimestamp(now)
 	a.UID = d.currentID + 1
-	a.SetID(uintToStr(d.currentID + 1))
-	if err := addToDB(d.table, a); err != nil {
+	a.SetID(uintToStr(a.UID))
+	value, err := json.Marshal(a)
+	if err != nil {
+		return fmt.Errorf("marshal auditlog %s failed: %s", a.ID, err.Error())
+	}
+
+	tx, err := d.table.Begin()
+	if err != nil {
+		return fmt.Errorf("begin transaction failed: %s", err.Error())
+	}
+	defer tx.Rollback()
+
+	if err := tx.Add(logKey(a.UID), value); err != nil {
+		return err
+	}
+
+	count := d.recordCount
+	d.recordCount += 1
+	if err := d.prune(tx, now, false); err != nil {
+		d.recordCount = count
 		return err
 	}
-	d.currentID += 1
+
+	if err := tx.Commit(); err != nil {
+		d.recordCount = count
+		return err
+	}
+	d.currentID = a.UID
 	return nil
 }
 
-func addToDB(table kvzoo.Table, log *types.AuditLog) error {
-	value, err := json.Marshal(log)
-	if err != nil {
-		return fmt.Errorf("marshal auditlog %s failed: %s", log.ID, err.Error())
+//delete logs from the oldest one until count is in the limit, if
+//checkAge is true, logs older than max age are deleted too
+func (d *DefaultDriver) prune(tx kvzoo.Transaction, now time.Time, checkAge bool) error {
+	checkAge = checkAge && d.maxAge > 0
+	overflow := 0
+	if d.maxRecordCount > 0 {
+		overflow = d.recordCount - d.maxRecordCount
+	}
+	if overflow <= 0 && checkAge == false {
+		return nil
 	}
 
-	tx, err := table.Begin()
-	if err != nil {
-		return fmt.Errorf("begin transaction failed: %s", err.Error())
+	opts := kvzoo.ScanOptions{Limit: scanBatchSize}
+	if checkAge == false && overflow < scanBatchSize {
+		opts.Limit = overflow
 	}
 
-	defer tx.Rollback()
-	if err := tx.Add(log.ID, value); err != nil {
-		return err
+	for {
+		kvs, cursor, err := tx.Scan(opts)
+		if err != nil {
+			return err
+		}
+
+		for _, kv := range kvs {
+			if d.maxRecordCount == 0 || d.recordCount <= d.maxRecordCount {
+				if checkAge == false {
+					return nil
+				}
+
+				var log types.AuditLog
+				if err := json.Unmarshal(kv.Value, &log); err != nil {
+					return fmt.Errorf("unmarshal auditlog %s failed: %s", kv.Key, err.Error())
+				}
+				if now.Sub(log.GetCreationTimestamp()) <= d.maxAge {
+					return nil
+				}
+			}
+
+			if err := tx.Delete(kv.Key); err != nil {
+				return err
+			}
+			d.recordCount -= 1
+		}
+
+		if cursor == "" {
+			return nil
+		}
+		opts.Cursor = cursor
 	}
-	return tx.Commit()
 }
 
-func deleteFromDB(table kvzoo.Table, id string) error {
-	tx, err := table.Begin()
+func (d *DefaultDriver) pruneLoop() {
+	for {
+		time.Sleep(pruneInterval)
+		if err := d.pruneExpired(); err != nil {
+			log.Warnf("delete expired audit logs failed: %s", err.Error())
+		}
+	}
+}
+
+func (d *DefaultDriver) pruneExpired() error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	tx, err := d.table.Begin()
 	if err != nil {
 		return err
 	}
-
 	defer tx.Rollback()
-	if err := tx.Delete(id); err != nil {
+
+	count := d.recordCount
+	if err := d.prune(tx, time.Now(), true); err != nil {
+		d.recordCount = count
 		return err
 	}
 
-	return tx.Commit()
+	if d.recordCount == count {
+		return nil
+	}
+	if err := tx.Commit(); err != nil {
+		d.recordCount = count
+		return err
+	}
+	return nil
 }
 
 func uintToStr(uid uint64) string {
 	return strconv.FormatUint(uid, 10)
 }
 
-func (d *DefaultDriver) List() (types.AuditLogs, error) {
-	return listFromDB(d.table)
+func (d *DefaultDriver) Query(q *Query) (types.AuditLogs, string, error) {
+	opts := kvzoo.ScanOptions{
+		Limit:   scanBatchSize,
+		Reverse: q.Ascending == false,
+	}
+	if q.Marker != "" {
+		uid, err := strconv.ParseUint(q.Marker, 10, 64)
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid marker %s", q.Marker)
+		}
+		opts.Cursor = logKey(uid)
+	}
+
+	tx, err := d.table.Begin()
+	if err != nil {
+		return nil, "", err
+	}
+	defer tx.Rollback()
+
+	logs := types.AuditLogs{}
+	for {
+		kvs, cursor, err := tx.Scan(opts)
+		if err != nil {
+			return nil, "", err
+		}
+
+		for _, kv := range kvs {
+			var log types.AuditLog
+			if err := json.Unmarshal(kv.Value, &log); err != nil {
+				return nil, "", fmt.Errorf("unmarshal auditlog %s failed: %s", kv.Key, err.Error())
+			}
+
+			if q.beyond(&log) {
+				return logs, "", nil
+			}
+
+			if q.Match(&log) {
+				logs = append(logs, &log)
+				if q.Limit > 0 && len(logs) == q.Limit {
+					return logs, log.ID, nil
+				}
+			}
+		}
+
+		if cursor == "" {
+			return logs, "", nil
+		}
+		opts.Cursor = cursor
+	}
 }
@@ -0,0 +1,138 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	ut "cement/unittest"
+	"kvzoo"
+	"kvzoo/backend/memory"
+	"pkg/types"
+)
+
+func newTable(t *testing.T) kvzoo.Table {
+	db, err := memory.New()
+	ut.Assert(t, err == nil, "create db should succeed: %v", err)
+	tn, _ := kvzoo.TableNameFromSegments("auditlog")
+	table, err := db.CreateOrGetTable(tn)
+	ut.Assert(t, err == nil, "create table should succeed: %v", err)
+	return table
+}
+
+func logIDs(logs types.AuditLogs) []string {
+	var ids []string
+	for _, log := range logs {
+		ids = append(ids, log.ID)
+	}
+	return ids
+}
+
+func TestQuery(t *testing.T) {
+	d, err := NewDefaultDriver(newTable(t), 0, 0)
+	ut.Assert(t, err == nil, "create driver should succeed: %v", err)
+
+	for i := 0; i < 12; i++ {
+		err := d.Add(&types.AuditLog{
+			User:         fmt.Sprintf("user%d", i%2),
+			Operation:    "create",
+			ResourceKind: "deployment",
+			Cluster:      fmt.Sprintf("cluster%d", i%3),
+			Namespace:    "default",
+		})
+		ut.Assert(t, err == nil, "add log should succeed: %v", err)
+	}
+
+	logs, marker, err := d.Query(&Query{})
+	ut.Assert(t, err == nil, "query should succeed: %v", err)
+	ut.Equal(t, len(logs), 12)
+	ut.Equal(t, logs[0].ID, "12")
+	ut.Equal(t, logs[11].ID, "1")
+	ut.Equal(t, marker, "")
+
+	q := &Query{User: "user0", Cluster: "cluster0", Limit: 1}
+	var ids []string
+	for {
+		logs, marker, err := d.Query(q)
+		ut.Assert(t, err == nil, "query should succeed: %v", err)
+		ids = append(ids, logIDs(logs)...)
+		if marker == "" {
+			break
+		}
+		q.Marker = marker
+	}
+	ut.Equal(t, ids, []string{"7", "1"})
+
+	logs, marker, err = d.Query(&Query{Limit: 5, Marker: "3", Ascending: true})
+	ut.Assert(t, err == nil, "query should succeed: %v", err)
+	ut.Equal(t, logIDs(logs), []string{"4", "5", "6", "7", "8"})
+	ut.Equal(t, marker, "8")
+
+	_, _, err = d.Query(&Query{Marker: "abc"})
+	ut.Assert(t, err != nil, "query with invalid marker should fail")
+}
+
+func TestMaxCount(t *testing.T) {
+	table := newTable(t)
+	d, err := NewDefaultDriver(table, 5, 0)
+	ut.Assert(t, err == nil, "create driver should succeed: %v", err)
+
+	for i := 0; i < 8; i++ {
+		err := d.Add(&types.AuditLog{User: "admin"})
+		ut.Assert(t, err == nil, "add log should succeed: %v", err)
+	}
+	logs, _, err := d.Query(&Query{Ascending: true})
+	ut.Assert(t, err == nil, "query should succeed: %v", err)
+	ut.Equal(t, logIDs(logs), []string{"4", "5", "6", "7", "8"})
+
+	d, err = NewDefaultDriver(table, 3, 0)
+	ut.Assert(t, err == nil, "create driver should succeed: %v", err)
+	err = d.Add(&types.AuditLog{User: "admin"})
+	ut.Assert(t, err == nil, "add log should succeed: %v", err)
+	logs, _, err = d.Query(&Query{Ascending: true})
+	ut.Assert(t, err == nil, "query should succeed: %v", err)
+	ut.Equal(t, logIDs(logs), []string{"7", "8", "9"})
+}
+
+//logs saved with the key of old version are migrated and pruned
+func TestTimeRangeAndRetention(t *testing.T) {
+	table := newTable(t)
+	tx, err := table.Begin()
+	ut.Assert(t, err == nil, "begin transaction should succeed: %v", err)
+	now := time.Now()
+	for i := 1; i <= 12; i++ {
+		log := &types.AuditLog{UID: uint64(i), User: "admin"}
+		log.SetID(uintToStr(log.UID))
+		log.SetCreationTimestamp(now.Add(time.Duration(i-13)*24*time.Hour + 12*time.Hour))
+		value, _ := json.Marshal(log)
+		ut.Assert(t, tx.Add(log.ID, value) == nil, "add log should succeed")
+	}
+	ut.Assert(t, tx.Commit() == nil, "commit should succeed")
+
+	d, err := NewDefaultDriver(table, 0, 7*24*time.Hour)
+	ut.Assert(t, err == nil, "create driver should succeed: %v", err)
+	logs, _, err := d.Query(&Query{})
+	ut.Assert(t, err == nil, "query should succeed: %v", err)
+	ut.Equal(t, logIDs(logs), []string{"12", "11", "10", "9", "8", "7", "6"})
+
+	err = d.Add(&types.AuditLog{User: "admin"})
+	ut.Assert(t, err == nil, "add log should succeed: %v", err)
+
+	q := &Query{
+		From: now.Add(-4*24*time.Hour - time.Minute),
+		To:   now.Add(-2 * 24 * time.Hour),
+	}
+	logs, _, err = d.Query(q)
+	ut.Assert(t, err == nil, "query should succeed: %v", err)
+	ut.Equal(t, logIDs(logs), []string{"10", "9"})
+
+	q.Ascending = true
+	logs, _, err = d.Query(q)
+	ut.Assert(t, err == nil, "query should succeed: %v", err)
+	ut.Equal(t, logIDs(logs), []string{"9", "10"})
+
+	logs, _, err = d.Query(&Query{From: now.Add(-time.Minute)})
+	ut.Assert(t, err == nil, "query should succeed: %v", err)
+	ut.Equal(t, logIDs(logs), []string{"13"})
+}
@@ -0,0 +1,122 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"cement/log"
+	"pkg/types"
+)
+
+const (
+	//facility local0 and severity notice
+	syslogPriority = 16*8 + 5
+	syslogMsgID    = "auditlog"
+	DefaultAppName = "gaocloud"
+)
+
+//send log to remote syslog server in RFC 5424 format, message is the
+//json of the log, tcp message is framed by octet counting (RFC 6587)
+type SyslogDriver struct {
+	forwardCounter
+	network  string
+	addr     string
+	appName  string
+	hostname string
+	conn     net.Conn
+	logs     chan *types.AuditLog
+}
+
+//network is udp or tcp
+func NewSyslogDriver(network, addr, appName string) (Forwarder, error) {
+	if network != "udp" && network != "tcp" {
+		return nil, fmt.Errorf("unsupported syslog network %s", network)
+	}
+
+	if appName == "" {
+		appName = DefaultAppName
+	}
+	hostname, _ := os.Hostname()
+	if hostname == "" {
+		hostname = "-"
+	}
+
+	d := &SyslogDriver{
+		network:  network,
+		addr:     addr,
+		appName:  appName,
+		hostname: hostname,
+		logs:     make(chan *types.AuditLog, forwardBufferSize),
+	}
+	go d.run()
+	return d, nil
+}
+
+func (d *SyslogDriver) Name() string {
+	return fmt.Sprintf("syslog %s://%s", d.network, d.addr)
+}
+
+//log is sent in background, so slow syslog server doesn't block api
+//request, if the buffer is full, log is dropped
+func (d *SyslogDriver) Add(a *types.AuditLog) error {
+	select {
+	case d.logs <- a:
+		return nil
+	default:
+		d.drop()
+		return fmt.Errorf("syslog %s is too slow, audit log %s is dropped", d.addr, a.ID)
+	}
+}
+
+func (d *SyslogDriver) run() {
+	for a := range d.logs {
+		msg, err := d.format(a)
+		if err == nil {
+			err = retryWithBackoff(func() error {
+				return d.send(msg)
+			})
+		}
+		d.done(1, err)
+		if err != nil {
+			log.Warnf("send audit log %s to syslog %s failed: %s", a.ID, d.addr, err.Error())
+		}
+	}
+}
+
+func (d *SyslogDriver) format(a *types.AuditLog) ([]byte, error) {
+	detail, err := json.Marshal(a)
+	if err != nil {
+		return nil, err
+	}
+
+	header := fmt.Sprintf("<%d>1 %s %s %s %d %s - ", syslogPriority,
+		a.GetCreationTimestamp().Format(time.RFC3339), d.hostname, d.appName, os.Getpid(), syslogMsgID)
+	msg := append([]byte(header), detail...)
+	if d.network == "tcp" {
+		msg = append([]byte(fmt.Sprintf("%d ", len(msg))), msg...)
+	}
+	return msg, nil
+}
+
+//reconnect once if the connection is broken
+func (d *SyslogDriver) send(msg []byte) error {
+	var err error
+	for i := 0; i < 2; i++ {
+		if d.conn == nil {
+			if d.conn, err = net.DialTimeout(d.network, d.addr, forwardTimeout); err != nil {
+				return err
+			}
+		}
+
+		d.conn.SetWriteDeadline(time.Now().Add(forwardTimeout))
+		if _, err = d.conn.Write(msg); err == nil {
+			return nil
+		}
+		d.conn.Close()
+		d.conn = nil
+	}
+	return err
+}
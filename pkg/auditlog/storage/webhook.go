@@ -0,0 +1,136 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"cement/log"
+	"pkg/types"
+)
+
+const (
+	DefaultWebhookBatchSize     = 100
+	DefaultWebhookFlushInterval = 5 * time.Second
+)
+
+//post logs to the url in batches, body is the json array of logs
+type WebhookDriver struct {
+	forwardCounter
+	url           string
+	token         string
+	batchSize     int
+	flushInterval time.Duration
+	client        *http.Client
+	logs          chan *types.AuditLog
+}
+
+//if token isn't empty, it's sent as bearer token, a batch is sent
+//when it's full or flush interval passed
+func NewWebhookDriver(url, token string, batchSize int, flushInterval time.Duration) (Forwarder, error) {
+	if url == "" {
+		return nil, fmt.Errorf("webhook url is empty")
+	}
+
+	if batchSize <= 0 {
+		batchSize = DefaultWebhookBatchSize
+	}
+	if flushInterval <= 0 {
+		flushInterval = DefaultWebhookFlushInterval
+	}
+
+	d := &WebhookDriver{
+		url:           url,
+		token:         token,
+		batchSize:     batchSize,
+		flushInterval: flushInterval,
+		client: &http.Client{
+			Timeout: forwardTimeout,
+		},
+		logs: make(chan *types.AuditLog, forwardBufferSize),
+	}
+	go d.run()
+	return d, nil
+}
+
+func (d *WebhookDriver) Name() string {
+	return "webhook " + d.url
+}
+
+//like syslog driver, log is dropped if the buffer is full
+func (d *WebhookDriver) Add(a *types.AuditLog) error {
+	select {
+	case d.logs <- a:
+		return nil
+	default:
+		d.drop()
+		return fmt.Errorf("webhook %s is too slow, audit log %s is dropped", d.url, a.ID)
+	}
+}
+
+func (d *WebhookDriver) run() {
+	ticker := time.NewTicker(d.flushInterval)
+	defer ticker.Stop()
+
+	batch := make(types.AuditLogs, 0, d.batchSize)
+	for {
+		select {
+		case a := <-d.logs:
+			batch = append(batch, a)
+			if len(batch) < d.batchSize {
+				continue
+			}
+		case <-ticker.C:
+			if len(batch) == 0 {
+				continue
+			}
+		}
+
+		d.flush(batch)
+		batch = make(types.AuditLogs, 0, d.batchSize)
+	}
+}
+
+//new logs are buffered in channel while the batch is retried
+func (d *WebhookDriver) flush(batch types.AuditLogs) {
+	if len(batch) == 0 {
+		return
+	}
+
+	err := retryWithBackoff(func() error {
+		return d.post(batch)
+	})
+	d.done(len(batch), err)
+	if err != nil {
+		log.Warnf("post %d audit logs to webhook %s failed: %s", len(batch), d.url, err.Error())
+	}
+}
+
+func (d *WebhookDriver) post(batch types.AuditLogs) error {
+	body, err := json.Marshal(batch)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, d.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if d.token != "" {
+		req.Header.Set("Authorization", "Bearer "+d.token)
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("server returns %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,267 @@
+package apitoken
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gorest/resource"
+	"kvzoo"
+	"pkg/db"
+	"pkg/types"
+)
+
+const (
+	//token with the prefix is api token, otherwise it's jwt token
+	TokenPrefix = "gct_"
+	tokenLen    = 32
+)
+
+var (
+	APITokenTableName = "api_token"
+)
+
+type Token struct {
+	User                string           `json:"user"`
+	Name                string           `json:"name"`
+	ReadOnly            bool             `json:"readOnly"`
+	Cluster             string           `json:"cluster"`
+	CreationTimestamp   resource.ISOTime `json:"creationTimestamp"`
+	ExpirationTimestamp resource.ISOTime `json:"expirationTimestamp"`
+}
+
+//only sha256 of the token is saved, since token is random bytes,
+//salt isn't needed, tokens are indexed by the hash
+type Manager struct {
+	lock   sync.RWMutex
+	tokens map[string]*Token
+	db     kvzoo.Table
+}
+
+func New() (*Manager, error) {
+	tn, _ := kvzoo.TableNameFromSegments(APITokenTableName)
+	table, err := db.GetGlobalDB().CreateOrGetTable(tn)
+	if err != nil {
+		return nil, err
+	}
+	return NewManager(table)
+}
+
+func NewManager(table kvzoo.Table) (*Manager, error) {
+	tx, err := table.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	tokensInDB, err := tx.List()
+	if err != nil {
+		return nil, err
+	}
+
+	tokens := make(map[string]*Token)
+	for hash, tokenInDB := range tokensInDB {
+		var token Token
+		if err := json.Unmarshal(tokenInDB, &token); err != nil {
+			return nil, err
+		}
+		tokens[hash] = &token
+	}
+
+	return &Manager{
+		tokens: tokens,
+		db:     table,
+	}, nil
+}
+
+func IsAPIToken(token string) bool {
+	return strings.HasPrefix(token, TokenPrefix)
+}
+
+//the returned api token has the generated token, the request isn't
+//changed, since it's recorded in audit log
+func (m *Manager) Create(userName string, apiToken *types.APIToken) (*types.APIToken, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if _, token := m.get(userName, apiToken.Name); token != nil {
+		return nil, fmt.Errorf("api token %s already exists", apiToken.Name)
+	}
+
+	secret, err := genToken()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	token := &Token{
+		User:              userName,
+		Name:              apiToken.Name,
+		ReadOnly:          apiToken.ReadOnly,
+		Cluster:           apiToken.Cluster,
+		CreationTimestamp: resource.ISOTime(now),
+	}
+	if apiToken.ExpireDays > 0 {
+		token.ExpirationTimestamp = resource.ISOTime(now.AddDate(0, 0, apiToken.ExpireDays))
+	}
+
+	hash := hashToken(secret)
+	if err := m.addToDB(hash, token); err != nil {
+		return nil, fmt.Errorf("save api token %s failed:%s", apiToken.Name, err.Error())
+	}
+	m.tokens[hash] = token
+
+	created := toAPIToken(token)
+	created.Token = secret
+	return created, nil
+}
+
+func (m *Manager) Get(userName, name string) *types.APIToken {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	if _, token := m.get(userName, name); token != nil {
+		return toAPIToken(token)
+	}
+	return nil
+}
+
+func (m *Manager) get(userName, name string) (string, *Token) {
+	for hash, token := range m.tokens {
+		if token.User == userName && token.Name == name {
+			return hash, token
+		}
+	}
+	return "", nil
+}
+
+func (m *Manager) List(userName string) []*types.APIToken {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	var apiTokens []*types.APIToken
+	for _, token := range m.tokens {
+		if token.User == userName {
+			apiTokens = append(apiTokens, toAPIToken(token))
+		}
+	}
+	sort.Slice(apiTokens, func(i, j int) bool {
+		return apiTokens[i].Name < apiTokens[j].Name
+	})
+	return apiTokens
+}
+
+func (m *Manager) Delete(userName, name string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	hash, token := m.get(userName, name)
+	if token == nil {
+		return fmt.Errorf("api token %s doesn't exist", name)
+	}
+
+	if err := m.deleteFromDB(hash); err != nil {
+		return fmt.Errorf("delete api token %s failed:%s", name, err.Error())
+	}
+	delete(m.tokens, hash)
+	return nil
+}
+
+//called when the user is deleted
+func (m *Manager) DeleteUserTokens(userName string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for hash, token := range m.tokens {
+		if token.User == userName {
+			if err := m.deleteFromDB(hash); err != nil {
+				return fmt.Errorf("delete api token %s failed:%s", token.Name, err.Error())
+			}
+			delete(m.tokens, hash)
+		}
+	}
+	return nil
+}
+
+//return the user and the scope of the token
+func (m *Manager) Authenticate(secret string) (string, *types.APITokenScope, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	token, ok := m.tokens[hashToken(secret)]
+	if ok == false {
+		return "", nil, fmt.Errorf("api token is invalid")
+	}
+
+	expiration := time.Time(token.ExpirationTimestamp)
+	if expiration.IsZero() == false && time.Now().After(expiration) {
+		return "", nil, fmt.Errorf("api token %s is expired", token.Name)
+	}
+
+	return token.User, &types.APITokenScope{
+		ReadOnly: token.ReadOnly,
+		Cluster:  token.Cluster,
+	}, nil
+}
+
+func (m *Manager) addToDB(hash string, token *Token) error {
+	value, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+
+	tx, err := m.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := tx.Add(hash, value); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (m *Manager) deleteFromDB(hash string) error {
+	tx, err := m.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := tx.Delete(hash); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func toAPIToken(token *Token) *types.APIToken {
+	apiToken := &types.APIToken{
+		Name:                token.Name,
+		ExpirationTimestamp: token.ExpirationTimestamp,
+		ReadOnly:            token.ReadOnly,
+		Cluster:             token.Cluster,
+	}
+	apiToken.SetID(token.Name)
+	apiToken.SetCreationTimestamp(time.Time(token.CreationTimestamp))
+	return apiToken
+}
+
+func genToken() (string, error) {
+	b := make([]byte, tokenLen)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate api token failed:%s", err.Error())
+	}
+	return TokenPrefix + hex.EncodeToString(b), nil
+}
+
+func hashToken(secret string) string {
+	h := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(h[:])
+}
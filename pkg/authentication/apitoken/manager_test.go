@@ -0,0 +1,91 @@
+package apitoken
+
+import (
+	"testing"
+	"time"
+
+	ut "cement/unittest"
+	"gorest/resource"
+	"kvzoo"
+	"kvzoo/backend/memory"
+	"pkg/types"
+)
+
+func TestAPIToken(t *testing.T) {
+	db, err := memory.New()
+	ut.Assert(t, err == nil, "create db should succeed: %v", err)
+	tn, _ := kvzoo.TableNameFromSegments(APITokenTableName)
+	table, err := db.CreateOrGetTable(tn)
+	ut.Assert(t, err == nil, "create table should succeed: %v", err)
+
+	m, err := NewManager(table)
+	ut.Assert(t, err == nil, "create manager should succeed: %v", err)
+
+	request := &types.APIToken{Name: "ci", ReadOnly: true, Cluster: "local", ExpireDays: 30}
+	ci, err := m.Create("ben", request)
+	ut.Assert(t, err == nil, "create token should succeed: %v", err)
+	ut.Assert(t, IsAPIToken(ci.Token), "token should have prefix")
+	ut.Equal(t, request.Token, "")
+	ut.Equal(t, ci.GetID(), "ci")
+	ut.Assert(t, time.Time(ci.ExpirationTimestamp).After(time.Now().AddDate(0, 0, 29)), "")
+	_, err = m.Create("ben", &types.APIToken{Name: "ci"})
+	ut.Assert(t, err != nil, "duplicate token name should fail")
+
+	deploy, err := m.Create("alice", &types.APIToken{Name: "ci"})
+	ut.Assert(t, err == nil, "same name for other user should succeed: %v", err)
+	ut.Assert(t, deploy.Token != ci.Token, "")
+
+	user, scope, err := m.Authenticate(ci.Token)
+	ut.Assert(t, err == nil, "authenticate should succeed: %v", err)
+	ut.Equal(t, user, "ben")
+	ut.Equal(t, *scope, types.APITokenScope{ReadOnly: true, Cluster: "local"})
+
+	user, scope, err = m.Authenticate(deploy.Token)
+	ut.Assert(t, err == nil, "authenticate should succeed: %v", err)
+	ut.Equal(t, user, "alice")
+	ut.Equal(t, *scope, types.APITokenScope{})
+
+	_, _, err = m.Authenticate(TokenPrefix + "invalid")
+	ut.Assert(t, err != nil, "invalid token should fail")
+
+	//token isn't returned after creation
+	tokens := m.List("ben")
+	ut.Equal(t, len(tokens), 1)
+	ut.Equal(t, tokens[0].Token, "")
+	ut.Equal(t, m.Get("ben", "ci").Token, "")
+	ut.Assert(t, m.Get("ben", "deploy") == nil, "")
+
+	//only hash is saved
+	tx, _ := table.Begin()
+	values, _ := tx.List()
+	tx.Rollback()
+	ut.Equal(t, len(values), 2)
+	for hash, value := range values {
+		ut.Assert(t, hash != ci.Token && hash != deploy.Token, "token shouldn't be saved")
+		ut.Assert(t, string(value) != "", "")
+	}
+
+	//tokens are loaded from db
+	m, err = NewManager(table)
+	ut.Assert(t, err == nil, "load manager should succeed: %v", err)
+	user, _, err = m.Authenticate(ci.Token)
+	ut.Assert(t, err == nil, "authenticate should succeed: %v", err)
+	ut.Equal(t, user, "ben")
+
+	//expired
+	for _, token := range m.tokens {
+		if token.User == "ben" {
+			token.ExpirationTimestamp = resource.ISOTime(time.Now().Add(-time.Second))
+		}
+	}
+	_, _, err = m.Authenticate(ci.Token)
+	ut.Assert(t, err != nil, "expired token should fail")
+
+	ut.Assert(t, m.Delete("ben", "ci") == nil, "revoke token should succeed")
+	ut.Assert(t, m.Delete("ben", "ci") != nil, "revoke unknown token should fail")
+	ut.Equal(t, len(m.List("ben")), 0)
+
+	ut.Assert(t, m.DeleteUserTokens("alice") == nil, "")
+	_, _, err = m.Authenticate(deploy.Token)
+	ut.Assert(t, err != nil, "token of deleted user should fail")
+}
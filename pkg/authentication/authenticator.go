@@ -1,49 +1,153 @@
 package authentication
 
 import (
+	"fmt"
 	"net/http"
+	"strings"
 
+	"config"
 	resterr "gorest/error"
+	"pkg/authentication/apitoken"
 	"pkg/authentication/cas"
 	"pkg/authentication/jwt"
+	"pkg/authentication/ldap"
+	"pkg/authentication/oidc"
 	"pkg/types"
 )
 
 type Authenticator struct {
-	JwtAuth *jwt.Authenticator
-	CasAuth *cas.Authenticator
+	JwtAuth   *jwt.Authenticator
+	CasAuth   *cas.Authenticator
+	LDAPAuth  *ldap.Authenticator
+	OIDCAuth  *oidc.Authenticator
+	APITokens *apitoken.Manager
+
+	groupHandler GroupHandler
 }
 
-func New(casServer string) (*Authenticator, error) {
-	jwtAuth, err := jwt.NewAuthenticator()
+//called when ldap or oidc user logins, groups are the gaocloud groups
+//which the user belongs to
+type GroupHandler func(userName string, groups []string) error
+
+func New(conf *config.GaoCloudConf) (*Authenticator, error) {
+	jwtAuth, err := jwt.NewAuthenticator(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	apiTokens, err := apitoken.New()
 	if err != nil {
 		return nil, err
 	}
 
 	auth := &Authenticator{
-		JwtAuth: jwtAuth,
+		JwtAuth:   jwtAuth,
+		APITokens: apiTokens,
 	}
 
-	if casServer != "" {
-		casAuth, err := cas.NewAuthenticator(casServer)
+	if conf.Server.CasAddr != "" {
+		casAuth, err := cas.NewAuthenticator(conf.Server.CasAddr)
 		if err != nil {
 			return nil, err
 		}
 		auth.CasAuth = casAuth
+		jwtAuth.SetExternalRevoker(casAuth.RevokeUser)
+	}
+
+	if conf.LDAP.Addr != "" {
+		ldapAuth, err := ldap.NewAuthenticator(conf.LDAP)
+		if err != nil {
+			return nil, err
+		}
+		auth.LDAPAuth = ldapAuth
+		jwtAuth.SetExternalVerifier(auth.verifyLDAPUser)
+	}
+
+	if conf.OIDC.Issuer != "" {
+		oidcAuth, err := oidc.NewAuthenticator(conf.OIDC)
+		if err != nil {
+			return nil, err
+		}
+		oidcAuth.SetLocalUserChecker(jwtAuth.HasUser)
+		auth.OIDCAuth = oidcAuth
 	}
 	return auth, nil
 }
 
+func (a *Authenticator) SetGroupHandler(h GroupHandler) {
+	a.groupHandler = h
+}
+
+func (a *Authenticator) setGroups(userName string, groups []string) error {
+	if a.groupHandler != nil {
+		if err := a.groupHandler(userName, groups); err != nil {
+			return fmt.Errorf("update groups of user %s failed:%s", userName, err.Error())
+		}
+	}
+	return nil
+}
+
+//ldap user gets jwt token like local user after login
+func (a *Authenticator) verifyLDAPUser(userName, password string) error {
+	groups, err := a.LDAPAuth.Authenticate(userName, password)
+	if err != nil {
+		return err
+	}
+	return a.setGroups(userName, groups)
+}
+
+//groups are updated before the session is saved, so user can't
+//login if the groups can't be updated, session is registered by jwt
+//and expires with the id token
+func (a *Authenticator) loginOIDCUser(w http.ResponseWriter, r *http.Request) error {
+	user, groups, expireAt, err := a.OIDCAuth.Login(w, r)
+	if err != nil {
+		return err
+	}
+
+	if err := a.setGroups(user, groups); err != nil {
+		return err
+	}
+	return a.JwtAuth.AddExternalSession(w, r, user, types.AuthSourceOIDC, expireAt)
+}
+
 func (a *Authenticator) Authenticate(w http.ResponseWriter, req *http.Request) (string, *resterr.APIError) {
-	user, err := a.JwtAuth.Authenticate(w, req)
+	user, _, err := a.AuthenticateSource(w, req)
+	return user, err
+}
+
+//source is one of apitoken, token, session, oidc and cas
+func (a *Authenticator) AuthenticateSource(w http.ResponseWriter, req *http.Request) (string, string, *resterr.APIError) {
+	user, source, _, err := a.authenticate(w, req)
+	return user, source, err
+}
+
+//scope is only returned when the request is authenticated by api
+//token, api token is checked first, since it's also in the bearer
+//header like jwt token
+func (a *Authenticator) authenticate(w http.ResponseWriter, req *http.Request) (string, string, *types.APITokenScope, *resterr.APIError) {
+	if token := getBearerToken(req); apitoken.IsAPIToken(token) {
+		user, scope, err := a.APITokens.Authenticate(token)
+		if err != nil {
+			return "", "", nil, resterr.NewAPIError(resterr.Unauthorized, err.Error())
+		}
+		return user, types.AuthSourceAPIToken, scope, nil
+	}
+
+	user, source, err := a.authenticateSession(w, req)
+	return user, source, nil, err
+}
+
+func (a *Authenticator) authenticateSession(w http.ResponseWriter, req *http.Request) (string, string, *resterr.APIError) {
+	user, source, err := a.JwtAuth.AuthenticateSource(w, req)
 	if err != nil {
-		return "", err
+		return "", "", err
 	} else if user != "" {
-		return user, nil
+		return user, source, nil
 	}
 
 	if a.CasAuth == nil {
-		return "", nil
+		return "", "", nil
 	} else {
 		user, err := a.CasAuth.Authenticate(w, req)
 		if err == nil && user != "" {
@@ -53,6 +157,14 @@ func (a *Authenticator) Authenticate(w http.ResponseWriter, req *http.Request) (
 				a.JwtAuth.AddUser(newUser)
 			}
 		}
-		return user, err
+		return user, types.AuthSourceCAS, err
+	}
+}
+
+func getBearerToken(req *http.Request) string {
+	splitToken := strings.Split(req.Header.Get("Authorization"), "Bearer ")
+	if len(splitToken) != 2 {
+		return ""
 	}
+	return splitToken[1]
 }
@@ -40,6 +40,11 @@ func (a *Authenticator) Logout(w http.ResponseWriter, r *http.Request) {
 	a.client.RedirectToLogout(w, r, "")
 }
 
+//user should login cas again after the sessions are revoked
+func (a *Authenticator) RevokeUser(user string) {
+	a.client.RemoveUserTickets(user)
+}
+
 func (a *Authenticator) SaveTicket(w http.ResponseWriter, r *http.Request) error {
 	return a.client.SaveTicket(w, r)
 }
@@ -248,3 +248,8 @@ func (c *Client) RemoveTicket(w http.ResponseWriter, r *http.Request) {
 	}
 	c.sessions.ClearSession(w, r)
 }
+
+//session whose ticket is removed is cleared in next request
+func (c *Client) RemoveUserTickets(user string) {
+	c.tickets.DeleteUser(user)
+}
@@ -40,6 +40,16 @@ func (s *MemoryStore) Delete(id string) error {
 	return nil
 }
 
+func (s *MemoryStore) DeleteUser(user string) {
+	s.mu.Lock()
+	for id, t := range s.store {
+		if t.User == user {
+			delete(s.store, id)
+		}
+	}
+	s.mu.Unlock()
+}
+
 func (s *MemoryStore) Clear() error {
 	s.mu.Lock()
 	s.store = make(map[string]*AuthenticationResponse)
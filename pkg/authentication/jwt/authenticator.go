@@ -4,11 +4,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"strings"
 	"sync"
 	"time"
 
+	"cement/log"
+	"config"
 	"gorest"
 	resterr "gorest/error"
 	"kvzoo"
@@ -22,23 +25,56 @@ const (
 
 var (
 	SessionCookieName  = "_jwt_session"
-	tokenSecret        = []byte("hello single cloud")
 	tokenValidDuration = 24 * 3600 * time.Second
 )
 
-type Authenticator struct {
-	repo *TokenRepo
+//verify the password of the user who isn't saved locally, like
+//ldap user
+type PasswordVerifier func(userName, password string) error
+
+//revoke the sessions which aren't registered, like cas sessions
+type SessionRevoker func(userName string)
 
-	lock     sync.Mutex
-	users    map[string]string
-	sessions *session.SessionMgr
-	db       kvzoo.Table
+type Authenticator struct {
+	repo     *TokenRepo
+	registry *SessionRegistry
+	limiter  *LoginLimiter
+	totp     *TOTPManager
+	//admin without totp can only enroll totp
+	requireAdminTOTP bool
+
+	lock sync.Mutex
+	//user name to bcrypt hash of the password
+	users            map[string]string
+	sessions         *session.SessionMgr
+	db               kvzoo.Table
+	externalVerifier PasswordVerifier
+	externalRevoker  SessionRevoker
 }
 
-func NewAuthenticator() (*Authenticator, error) {
+func NewAuthenticator(conf *config.GaoCloudConf) (*Authenticator, error) {
+	keys, err := loadKeyRing(time.Duration(conf.JWT.KeyRotationDays) * 24 * time.Hour)
+	if err != nil {
+		return nil, err
+	}
+
+	registry, err := loadSessionRegistry()
+	if err != nil {
+		return nil, err
+	}
+
+	totp, err := loadTOTPManager()
+	if err != nil {
+		return nil, err
+	}
+
 	auth := &Authenticator{
-		repo:     NewTokenRepo(tokenSecret, tokenValidDuration),
-		sessions: session.New(SessionCookieName),
+		repo:             NewTokenRepo(keys),
+		registry:         registry,
+		limiter:          NewLoginLimiter(conf.Login),
+		totp:             totp,
+		requireAdminTOTP: conf.Login.RequireAdminTOTP,
+		sessions:         session.New(SessionCookieName),
 	}
 
 	if err := auth.loadUsers(); err != nil {
@@ -57,21 +93,38 @@ func NewAuthenticator() (*Authenticator, error) {
 	return auth, nil
 }
 
-func (a *Authenticator) Authenticate(_ http.ResponseWriter, req *http.Request) (string, *resterr.APIError) {
+func (a *Authenticator) Authenticate(w http.ResponseWriter, req *http.Request) (string, *resterr.APIError) {
+	user, _, err := a.AuthenticateSource(w, req)
+	return user, err
+}
+
+//source is session if token is saved in session, otherwise token
+//is in request header, session created for the user of identity
+//provider keeps the source of the provider
+func (a *Authenticator) AuthenticateSource(_ http.ResponseWriter, req *http.Request) (string, string, *resterr.APIError) {
+	source := types.AuthSourceSession
 	token, _ := a.sessions.GetSession(req)
 	if token == "" {
 		token = getFromHeader(req)
 		if token == "" {
-			return "", nil
+			return "", "", nil
 		}
+		source = types.AuthSourceToken
 	}
 
-	user, err := a.repo.ParseToken(token)
+	user, sessionID, err := a.repo.parseToken(token)
 	if err != nil {
-		return "", resterr.NewAPIError(resterr.ServerError, err.Error())
-	} else {
-		return user, nil
+		return "", "", resterr.NewAPIError(resterr.ServerError, err.Error())
+	}
+
+	s, ok := a.registry.get(user, sessionID)
+	if ok == false {
+		return "", "", resterr.NewAPIError(resterr.Unauthorized, ErrRevokedToken.Error())
 	}
+	if source == types.AuthSourceSession && s.Source != "" {
+		source = s.Source
+	}
+	return user, source, nil
 }
 
 func (a *Authenticator) AddUser(user *types.User) error {
@@ -81,13 +134,17 @@ func (a *Authenticator) AddUser(user *types.User) error {
 	name := user.GetID()
 	if _, ok := a.users[name]; ok {
 		return fmt.Errorf("user %s already exists", name)
-	} else {
-		if err := a.addUser(user); err != nil {
-			return err
-		}
-		a.users[name] = user.Password
-		return nil
 	}
+
+	hash, err := hashPassword(user.Password)
+	if err != nil {
+		return err
+	}
+	if err := a.addUser(name, hash); err != nil {
+		return err
+	}
+	a.users[name] = hash
+	return nil
 }
 
 func (a *Authenticator) HasUser(userName string) bool {
@@ -110,55 +167,229 @@ func (a *Authenticator) DeleteUser(userName string) error {
 			return err
 		}
 		delete(a.users, userName)
-		return nil
+		if err := a.totp.Disable(userName); err != nil {
+			return err
+		}
+		return a.revokeUser(userName)
 	} else {
 		return fmt.Errorf("user %s doesn't exist", userName)
 	}
 }
 
+//user of identity provider like ldap and oidc isn't saved locally,
+//only its sessions and totp are removed
+func (a *Authenticator) DeleteExternalUser(userName string) error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	if _, ok := a.users[userName]; ok {
+		return fmt.Errorf("user %s is local user", userName)
+	}
+	if err := a.totp.Disable(userName); err != nil {
+		return err
+	}
+	return a.revokeUser(userName)
+}
+
 func (a *Authenticator) ResetPassword(userName string, old, new string, force bool) error {
 	a.lock.Lock()
 	defer a.lock.Unlock()
 
-	old_, ok := a.users[userName]
+	oldHash, ok := a.users[userName]
 	if ok == false {
 		return fmt.Errorf("user %s doesn't exist", userName)
 	}
 
-	if !force && old_ != old {
-		return fmt.Errorf("password isn't correct")
+	if !force {
+		if match, _ := checkPassword(oldHash, old); match == false {
+			return fmt.Errorf("password isn't correct")
+		}
 	}
 
 	if new == "" {
 		return fmt.Errorf("new password is empty")
 	}
 
-	if err := a.updateUser(userName, new); err != nil {
+	hash, err := hashPassword(new)
+	if err != nil {
 		return err
 	}
-	a.users[userName] = new
-	return nil
+	if err := a.updateUser(userName, hash); err != nil {
+		return err
+	}
+	a.users[userName] = hash
+	return a.revokeUser(userName)
 }
 
-func (a *Authenticator) CreateToken(userName, password string) (string, error) {
+func (a *Authenticator) SetExternalVerifier(verifier PasswordVerifier) {
 	a.lock.Lock()
 	defer a.lock.Unlock()
+	a.externalVerifier = verifier
+}
+
+func (a *Authenticator) SetExternalRevoker(revoker SessionRevoker) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.externalRevoker = revoker
+}
+
+//client ip is used to limit the failed logins, it could be empty,
+//if the user has enabled totp, challenge is returned instead of token,
+//failures aren't cleared until the totp code is verified
+func (a *Authenticator) CreateToken(userName, password, clientIP string) (*types.LoginInfo, error) {
+	return a.createToken(userName, clientIP, func() error {
+		return a.verifyPassword(userName, password)
+	})
+}
+
+//user who isn't saved locally like ldap user logins with the plain
+//password, since it's verified by the external verifier
+func (a *Authenticator) CreateExternalToken(userName, password, clientIP string) (*types.LoginInfo, error) {
+	return a.createToken(userName, clientIP, func() error {
+		return a.verifyExternalPassword(userName, password)
+	})
+}
+
+func (a *Authenticator) createToken(userName, clientIP string, verify func() error) (*types.LoginInfo, error) {
+	if err := a.limiter.check(userName, clientIP); err != nil {
+		return nil, err
+	}
+
+	if err := verify(); err != nil {
+		a.limiter.fail(userName, clientIP)
+		return nil, err
+	}
+
+	if a.IsTOTPEnabled(userName) {
+		return &types.LoginInfo{
+			TOTPRequired: true,
+			Challenge:    a.totp.newChallenge(userName),
+		}, nil
+	}
+	return a.issueToken(userName)
+}
+
+//second step of the login for user with totp, code is totp code or
+//recovery code
+func (a *Authenticator) CreateTokenWithTOTP(userName, challenge, code, clientIP string) (*types.LoginInfo, error) {
+	if err := a.limiter.check(userName, clientIP); err != nil {
+		return nil, err
+	}
+
+	if err := a.totp.verifyChallenge(userName, challenge, code); err != nil {
+		a.limiter.fail(userName, clientIP)
+		return nil, err
+	}
+	return a.issueToken(userName)
+}
+
+func (a *Authenticator) issueToken(userName string) (*types.LoginInfo, error) {
+	a.limiter.succeed(userName)
+	return a.newToken(userName, "", tokenValidDuration)
+}
+
+//user authenticated by identity provider like oidc gets a registered
+//session like local user, so it could be listed and revoked, session
+//doesn't live longer than the token of the provider
+func (a *Authenticator) AddExternalSession(w http.ResponseWriter, r *http.Request, userName, source string, expireAt time.Time) error {
+	validDuration := time.Until(expireAt)
+	if validDuration <= 0 {
+		return fmt.Errorf("token of user %s is expired", userName)
+	} else if validDuration > tokenValidDuration {
+		validDuration = tokenValidDuration
+	}
+
+	info, err := a.newToken(userName, source, validDuration)
+	if err != nil {
+		return err
+	}
+	a.sessions.AddSession(w, r, info.Token)
+	return nil
+}
+
+func (a *Authenticator) newToken(userName, source string, validDuration time.Duration) (*types.LoginInfo, error) {
+	s, err := a.registry.add(userName, source, validDuration)
+	if err != nil {
+		return nil, fmt.Errorf("register session failed:%s", err.Error())
+	}
+
+	token, err := a.repo.createToken(userName, s.ID, s.ExpirationTimestamp)
+	if err != nil {
+		return nil, err
+	}
+	return &types.LoginInfo{Token: token}, nil
+}
+
+//password of local user is the sha1 hex of the plain password, it's
+//checked without lock since it's slow
+func (a *Authenticator) verifyPassword(userName, password string) error {
+	a.lock.Lock()
+	hash, ok := a.users[userName]
+	verifier := a.externalVerifier
+	a.lock.Unlock()
 
-	password_, ok := a.users[userName]
 	if ok == false {
-		return "", fmt.Errorf("user %s doesn't exist", userName)
+		if verifier != nil {
+			return fmt.Errorf("user %s isn't local user, it should login with ldap password", userName)
+		}
+		return fmt.Errorf("user %s doesn't exist", userName)
+	}
+
+	match, needRehash := checkPassword(hash, password)
+	if match == false {
+		return fmt.Errorf("password isn't correct")
+	}
+	if needRehash {
+		if err := a.rehashPassword(userName, hash, password); err != nil {
+			log.Warnf("replace old password hash of user %s failed:%s", userName, err.Error())
+		}
+	}
+	return nil
+}
+
+//local user can't login by the external verifier, otherwise the
+//local user could be taken over by the external user with the same
+//name, verifier is called without lock since it's slow
+func (a *Authenticator) verifyExternalPassword(userName, password string) error {
+	a.lock.Lock()
+	_, ok := a.users[userName]
+	verifier := a.externalVerifier
+	a.lock.Unlock()
+
+	if ok {
+		return fmt.Errorf("user %s is local user, it should login with password", userName)
+	} else if verifier == nil {
+		return fmt.Errorf("user %s doesn't exist", userName)
 	}
-	if password != password_ {
-		return "", fmt.Errorf("password isn't correct")
+	return verifier(userName, password)
+}
+
+func (a *Authenticator) rehashPassword(userName, oldHash, password string) error {
+	hash, err := hashPassword(password)
+	if err != nil {
+		return err
 	}
 
-	return a.repo.CreateToken(userName)
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if current, ok := a.users[userName]; ok == false || current != oldHash {
+		return nil
+	}
+
+	if err := a.updateUser(userName, hash); err != nil {
+		return err
+	}
+	a.users[userName] = hash
+	return nil
 }
 
 func (a *Authenticator) Login(w http.ResponseWriter, r *http.Request) {
 	var params struct {
-		Name     string `json:"name"`
-		Password string `json:"password"`
+		Name         string `json:"name"`
+		Password     string `json:"password"`
+		LDAPPassword string `json:"ldapPassword"`
+		Challenge    string `json:"challenge"`
+		TOTPCode     string `json:"totpCode"`
 	}
 
 	reqBody, err := ioutil.ReadAll(r.Body)
@@ -175,24 +406,125 @@ func (a *Authenticator) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	token, err := a.CreateToken(params.Name, params.Password)
+	var info *types.LoginInfo
+	if params.Challenge != "" {
+		info, err = a.CreateTokenWithTOTP(params.Name, params.Challenge, params.TOTPCode, ClientIP(r))
+	} else if params.LDAPPassword != "" {
+		info, err = a.CreateExternalToken(params.Name, params.LDAPPassword, ClientIP(r))
+	} else {
+		info, err = a.CreateToken(params.Name, params.Password, ClientIP(r))
+	}
 	if err != nil {
 		apiErr := resterr.NewAPIError(resterr.InvalidFormat, err.Error())
 		gorest.WriteResponse(w, apiErr.Status, apiErr)
 		return
 	}
 
-	a.sessions.AddSession(w, r, token)
+	//session is saved after totp code is verified
+	if info.TOTPRequired {
+		gorest.WriteResponse(w, http.StatusOK, info)
+		return
+	}
+	a.sessions.AddSession(w, r, info.Token)
 }
 
+//token in session is revoked, so it can't be used even if it's
+//leaked
 func (a *Authenticator) Logout(w http.ResponseWriter, r *http.Request) {
 	currentUser_ := r.Context().Value(types.CurrentUserKey)
 	if currentUser_ == nil {
 		return
 	}
+
+	if token, _ := a.sessions.GetSession(r); token != "" {
+		if user, sessionID, err := a.repo.parseToken(token); err == nil {
+			if err := a.registry.Revoke(user, sessionID); err != nil {
+				log.Warnf("revoke session of user %s failed:%s", user, err.Error())
+			}
+		}
+	}
 	a.sessions.ClearSession(w, r)
 }
 
+func (a *Authenticator) SetLockoutHandler(h LockoutHandler) {
+	a.limiter.SetLockoutHandler(h)
+}
+
+func (a *Authenticator) LockedUntil(userName string) time.Time {
+	return a.limiter.LockedUntil(userName)
+}
+
+func (a *Authenticator) Unlock(userName string) {
+	a.limiter.Unlock(userName)
+}
+
+//only local user could enable totp, ldap user should use the second
+//factor of ldap
+func (a *Authenticator) EnableTOTP(userName string) (*types.TOTPProvision, error) {
+	if a.HasUser(userName) == false {
+		return nil, fmt.Errorf("user %s isn't local user", userName)
+	}
+	return a.totp.Enable(userName)
+}
+
+func (a *Authenticator) ConfirmTOTP(userName, code string) ([]string, error) {
+	return a.totp.Confirm(userName, code)
+}
+
+//code isn't checked if force is true or totp isn't confirmed
+func (a *Authenticator) DisableTOTP(userName, code string, force bool) error {
+	if !force && a.IsTOTPEnabled(userName) {
+		if err := a.totp.Verify(userName, code); err != nil {
+			return err
+		}
+	}
+	return a.totp.Disable(userName)
+}
+
+func (a *Authenticator) IsTOTPEnabled(userName string) bool {
+	return a.totp.IsEnabled(userName)
+}
+
+//admin should enable totp before other operations if it's required
+func (a *Authenticator) TOTPEnrollmentRequired(userName string) bool {
+	return a.requireAdminTOTP && userName == types.Administrator && a.IsTOTPEnabled(userName) == false
+}
+
+func (a *Authenticator) ListSessions(userName string) []*types.Session {
+	return a.registry.List(userName)
+}
+
+func (a *Authenticator) RevokeSession(userName, sessionID string) error {
+	return a.registry.Revoke(userName, sessionID)
+}
+
+func (a *Authenticator) RevokeUserSessions(userName string) error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	return a.revokeUser(userName)
+}
+
+//registered sessions and the sessions of external revoker are all
+//revoked, caller should hold the lock
+func (a *Authenticator) revokeUser(userName string) error {
+	if err := a.registry.RevokeUser(userName); err != nil {
+		return err
+	}
+	if a.externalRevoker != nil {
+		a.externalRevoker(userName)
+	}
+	return nil
+}
+
+//forwarded header isn't used, since it could be forged by client
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
 func getFromHeader(req *http.Request) string {
 	reqToken := req.Header.Get("Authorization")
 	if reqToken == "" {
@@ -0,0 +1,64 @@
+package jwt
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	ut "cement/unittest"
+	"kvzoo/backend/bolt"
+)
+
+//web login with the body which is sent by the client
+func webLogin(auth *Authenticator, body string) int {
+	w := httptest.NewRecorder()
+	auth.Login(w, httptest.NewRequest(http.MethodPost, "/web/login", strings.NewReader(body)))
+	return w.Code
+}
+
+func TestLDAPLogin(t *testing.T) {
+	dbPath := "user.db"
+	ut.WithTempFile(t, dbPath, func(t *testing.T, f *os.File) {
+		db, err := bolt.New(f.Name())
+		ut.Assert(t, err == nil, "create db should succeed:%v", err)
+		auth, err := newAuthenticator(db)
+		ut.Assert(t, err == nil, "load user should succeed:%v", err)
+
+		var verified []string
+		auth.SetExternalVerifier(func(userName, password string) error {
+			verified = append(verified, userName+":"+password)
+			if userName == "ldap_user" && password == "secret" {
+				return nil
+			}
+			return fmt.Errorf("password isn't correct")
+		})
+
+		sum := sha1.Sum([]byte("secret"))
+		hashed := hex.EncodeToString(sum[:])
+		ut.Equal(t, webLogin(auth, `{"name":"ldap_user","ldapPassword":"secret"}`), http.StatusOK)
+		ut.Equal(t, verified, []string{"ldap_user:secret"})
+
+		//hashed password can't bind ldap, so it isn't sent to verifier
+		ut.Assert(t, webLogin(auth, `{"name":"ldap_user","password":"`+hashed+`"}`) != http.StatusOK, "ldap user login with password should fail")
+		ut.Equal(t, len(verified), 1)
+		//skip the delay after failure
+		auth.Unlock("ldap_user")
+
+		info, err := auth.CreateExternalToken("ldap_user", "secret", "")
+		ut.Assert(t, err == nil, "ldap user login should succeed:%v", err)
+		ut.Assert(t, info.Token != "", "")
+		_, err = auth.CreateExternalToken("ldap_user", "wrong", "")
+		ut.Assert(t, err != nil, "wrong ldap password should fail")
+		auth.Unlock("ldap_user")
+
+		//local user isn't verified by ldap
+		_, err = auth.CreateExternalToken("admin", "zcloud", "")
+		ut.Assert(t, err != nil, "local user login with ldap password should fail")
+		ut.Equal(t, len(verified), 3)
+	})
+}
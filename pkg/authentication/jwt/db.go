@@ -1,8 +1,9 @@
 package jwt
 
 import (
+	"time"
+
 	"kvzoo"
-	"pkg/types"
 	"pkg/db"
 )
 
@@ -10,6 +11,33 @@ var (
 	JwtAuthenticatorTableName = "jwt_authenticator"
 )
 
+func loadKeyRing(rotationPeriod time.Duration) (*KeyRing, error) {
+	tn, _ := kvzoo.TableNameFromSegments(JwtSigningKeyTableName)
+	table, err := db.GetGlobalDB().CreateOrGetTable(tn)
+	if err != nil {
+		return nil, err
+	}
+	return NewKeyRing(table, rotationPeriod, tokenValidDuration)
+}
+
+func loadSessionRegistry() (*SessionRegistry, error) {
+	tn, _ := kvzoo.TableNameFromSegments(JwtSessionTableName)
+	table, err := db.GetGlobalDB().CreateOrGetTable(tn)
+	if err != nil {
+		return nil, err
+	}
+	return NewSessionRegistry(table)
+}
+
+func loadTOTPManager() (*TOTPManager, error) {
+	tn, _ := kvzoo.TableNameFromSegments(JwtTOTPTableName)
+	table, err := db.GetGlobalDB().CreateOrGetTable(tn)
+	if err != nil {
+		return nil, err
+	}
+	return NewTOTPManager(table)
+}
+
 func (a *Authenticator) loadUsers() error {
 	tn, _ := kvzoo.TableNameFromSegments(JwtAuthenticatorTableName)
 	table, err := db.GetGlobalDB().CreateOrGetTable(tn)
@@ -38,14 +66,14 @@ func (a *Authenticator) loadUsers() error {
 	return nil
 }
 
-func (a *Authenticator) addUser(user *types.User) error {
+func (a *Authenticator) addUser(userName string, password string) error {
 	tx, err := a.db.Begin()
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
-	if err := tx.Add(user.GetID(), []byte(user.Password)); err != nil {
+	if err := tx.Add(userName, []byte(password)); err != nil {
 		return err
 	}
 	return tx.Commit()
@@ -1,19 +1,55 @@
 package jwt
 
 import (
+	"net/http/httptest"
 	"os"
 	"testing"
+	"time"
 
 	"kvzoo"
 	ut "cement/unittest"
+	"config"
 	"kvzoo/backend/bolt"
 	"pkg/authentication/session"
 	"pkg/types"
 )
 
 func newAuthenticator(db kvzoo.DB) (*Authenticator, error) {
+	tn, _ := kvzoo.TableNameFromSegments(JwtSigningKeyTableName)
+	table, err := db.CreateOrGetTable(tn)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := NewKeyRing(table, 0, time.Hour)
+	if err != nil {
+		return nil, err
+	}
+
+	tn, _ = kvzoo.TableNameFromSegments(JwtSessionTableName)
+	table, err = db.CreateOrGetTable(tn)
+	if err != nil {
+		return nil, err
+	}
+	registry, err := NewSessionRegistry(table)
+	if err != nil {
+		return nil, err
+	}
+
+	tn, _ = kvzoo.TableNameFromSegments(JwtTOTPTableName)
+	table, err = db.CreateOrGetTable(tn)
+	if err != nil {
+		return nil, err
+	}
+	totp, err := NewTOTPManager(table)
+	if err != nil {
+		return nil, err
+	}
+
 	auth := &Authenticator{
-		repo:     NewTokenRepo(tokenSecret, tokenValidDuration),
+		repo:     NewTokenRepo(keys),
+		registry: registry,
+		limiter:  NewLoginLimiter(config.CreateDefaultConfig().Login),
+		totp:     totp,
 		sessions: session.New(SessionCookieName),
 	}
 
@@ -93,6 +129,184 @@ func TestUserDB(t *testing.T) {
 		ut.Assert(t, err == nil, "load user should succeed")
 		ut.Assert(t, auth.HasUser(types.Administrator), "")
 		ut.Assert(t, auth.HasUser(newUser.Name) == false, "")
-		ut.Equal(t, auth.users[types.Administrator], "123")
+		match, _ := checkPassword(auth.users[types.Administrator], "123")
+		ut.Assert(t, match, "admin password should be reset")
+		ut.Assert(t, auth.users[types.Administrator] != "123", "password should be hashed")
+	})
+}
+
+func TestPasswordMigration(t *testing.T) {
+	dbPath := "user.db"
+	ut.WithTempFile(t, dbPath, func(t *testing.T, f *os.File) {
+		db, err := bolt.New(f.Name())
+		ut.Assert(t, err == nil, "create db should succeed:%v", err)
+		auth, err := newAuthenticator(db)
+		ut.Assert(t, err == nil, "load user should succeed:%v", err)
+
+		//password saved by old version
+		ut.Assert(t, auth.addUser("ben", "123") == nil, "")
+		auth, err = newAuthenticator(db)
+		ut.Assert(t, err == nil, "load user should succeed:%v", err)
+		ut.Equal(t, auth.users["ben"], "123")
+
+		_, err = auth.CreateToken("ben", "456", "")
+		ut.Assert(t, err != nil, "wrong password should fail")
+		ut.Equal(t, auth.users["ben"], "123")
+		//skip the delay after failure
+		auth.Unlock("ben")
+
+		_, err = auth.CreateToken("ben", "123", "")
+		ut.Assert(t, err == nil, "login with old password should succeed:%v", err)
+		ut.Assert(t, auth.users["ben"] != "123", "password should be rehashed after login")
+
+		auth, err = newAuthenticator(db)
+		ut.Assert(t, err == nil, "load user should succeed:%v", err)
+		match, needRehash := checkPassword(auth.users["ben"], "123")
+		ut.Assert(t, match && needRehash == false, "rehashed password should be saved")
+		_, err = auth.CreateToken("ben", "123", "")
+		ut.Assert(t, err == nil, "login should succeed:%v", err)
+
+		//user without password can't login
+		casUser := &types.User{Name: "cas_user"}
+		casUser.SetID(casUser.Name)
+		ut.Assert(t, auth.AddUser(casUser) == nil, "")
+		_, err = auth.CreateToken("cas_user", "", "")
+		ut.Assert(t, err != nil, "login with empty password should fail")
+	})
+}
+
+func createToken(t *testing.T, auth *Authenticator, userName, password string) string {
+	info, err := auth.CreateToken(userName, password, "")
+	ut.Assert(t, err == nil, "login should succeed:%v", err)
+	return info.Token
+}
+
+func TestSessionRevocation(t *testing.T) {
+	dbPath := "user.db"
+	ut.WithTempFile(t, dbPath, func(t *testing.T, f *os.File) {
+		db, err := bolt.New(f.Name())
+		ut.Assert(t, err == nil, "create db should succeed:%v", err)
+		auth, err := newAuthenticator(db)
+		ut.Assert(t, err == nil, "load user should succeed:%v", err)
+
+		newUser := &types.User{
+			Name:     "ben",
+			Password: "123",
+		}
+		newUser.SetID(newUser.Name)
+		ut.Assert(t, auth.AddUser(newUser) == nil, "")
+
+		authenticate := func(token string) string {
+			req := httptest.NewRequest("GET", "/apis/zcloud.cn/v1/clusters", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			user, _ := auth.Authenticate(nil, req)
+			return user
+		}
+
+		token1 := createToken(t, auth, "ben", "123")
+		token2 := createToken(t, auth, "ben", "123")
+		ut.Equal(t, authenticate(token1), "ben")
+		ut.Equal(t, authenticate(token2), "ben")
+		sessions := auth.ListSessions("ben")
+		ut.Equal(t, len(sessions), 2)
+
+		//token isn't registered
+		unregistered, _ := auth.repo.createToken("ben", "", time.Now().Add(time.Hour))
+		ut.Equal(t, authenticate(unregistered), "")
+
+		//sessions are loaded from db
+		auth, err = newAuthenticator(db)
+		ut.Assert(t, err == nil, "load user should succeed:%v", err)
+		ut.Equal(t, len(auth.ListSessions("ben")), 2)
+		ut.Equal(t, authenticate(token1), "ben")
+
+		_, sessionID, _ := auth.repo.parseToken(token1)
+		ut.Assert(t, auth.RevokeSession("admin", sessionID) != nil, "revoke session of other user should fail")
+		ut.Assert(t, auth.RevokeSession("ben", sessionID) == nil, "revoke session should succeed")
+		ut.Equal(t, authenticate(token1), "")
+		ut.Equal(t, authenticate(token2), "ben")
+		ut.Equal(t, len(auth.ListSessions("ben")), 1)
+
+		auth, err = newAuthenticator(db)
+		ut.Assert(t, err == nil, "load user should succeed:%v", err)
+		ut.Equal(t, authenticate(token1), "")
+
+		//reset password revokes all the sessions
+		token3 := createToken(t, auth, "ben", "123")
+		ut.Assert(t, auth.ResetPassword("ben", "123", "456", false) == nil, "")
+		ut.Equal(t, authenticate(token2), "")
+		ut.Equal(t, authenticate(token3), "")
+		ut.Equal(t, len(auth.ListSessions("ben")), 0)
+
+		//sessions which aren't registered like cas sessions are
+		//revoked by external revoker
+		var revoked []string
+		auth.SetExternalRevoker(func(userName string) {
+			revoked = append(revoked, userName)
+		})
+		ut.Assert(t, auth.RevokeUserSessions("ben") == nil, "")
+		ut.Assert(t, auth.ResetPassword("ben", "", "456", true) == nil, "")
+
+		token4 := createToken(t, auth, "ben", "456")
+		ut.Equal(t, authenticate(token4), "ben")
+		ut.Assert(t, auth.DeleteUser("ben") == nil, "")
+		ut.Equal(t, authenticate(token4), "")
+		ut.Equal(t, revoked, []string{"ben", "ben", "ben"})
+	})
+}
+
+func TestExternalSession(t *testing.T) {
+	dbPath := "user.db"
+	ut.WithTempFile(t, dbPath, func(t *testing.T, f *os.File) {
+		db, err := bolt.New(f.Name())
+		ut.Assert(t, err == nil, "create db should succeed:%v", err)
+		auth, err := newAuthenticator(db)
+		ut.Assert(t, err == nil, "load user should succeed:%v", err)
+
+		login := func(expireAt time.Time) (*httptest.ResponseRecorder, error) {
+			w := httptest.NewRecorder()
+			err := auth.AddExternalSession(w, httptest.NewRequest("GET", "/web/oidc/callback", nil), "ben@example.com", types.AuthSourceOIDC, expireAt)
+			return w, err
+		}
+		authenticate := func(w *httptest.ResponseRecorder) (string, string) {
+			req := httptest.NewRequest("GET", "/apis/zcloud.cn/v1/clusters", nil)
+			for _, c := range w.Result().Cookies() {
+				req.AddCookie(c)
+			}
+			user, source, _ := auth.AuthenticateSource(nil, req)
+			return user, source
+		}
+
+		_, err = login(time.Now().Add(-time.Second))
+		ut.Assert(t, err != nil, "expired id token should fail")
+
+		w, err := login(time.Now().Add(time.Hour))
+		ut.Assert(t, err == nil, "add session should succeed:%v", err)
+		user, source := authenticate(w)
+		ut.Equal(t, user, "ben@example.com")
+		ut.Equal(t, source, types.AuthSourceOIDC)
+		sessions := auth.ListSessions("ben@example.com")
+		ut.Equal(t, len(sessions), 1)
+		ut.Assert(t, time.Time(sessions[0].ExpirationTimestamp).Before(time.Now().Add(time.Hour+time.Second)), "session shouldn't live longer than id token")
+
+		//session is capped by the valid duration of jwt token
+		_, err = login(time.Now().Add(30 * 24 * time.Hour))
+		ut.Assert(t, err == nil, "add session should succeed:%v", err)
+		for _, s := range auth.ListSessions("ben@example.com") {
+			ut.Assert(t, time.Time(s.ExpirationTimestamp).Before(time.Now().Add(tokenValidDuration+time.Second)), "session shouldn't live longer than jwt token")
+		}
+
+		ut.Assert(t, auth.RevokeUserSessions("ben@example.com") == nil, "")
+		user, _ = authenticate(w)
+		ut.Equal(t, user, "")
+
+		//external user isn't saved, deleting it revokes its sessions
+		ut.Assert(t, auth.DeleteUser("ben@example.com") != nil, "external user isn't local user")
+		w, _ = login(time.Now().Add(time.Hour))
+		ut.Assert(t, auth.DeleteExternalUser("admin") != nil, "local user isn't external user")
+		ut.Assert(t, auth.DeleteExternalUser("ben@example.com") == nil, "delete external user should succeed")
+		user, _ = authenticate(w)
+		ut.Equal(t, user, "")
+		ut.Equal(t, len(auth.ListSessions("ben@example.com")), 0)
 	})
 }
@@ -0,0 +1,161 @@
+package jwt
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"cement/uuid"
+	"kvzoo"
+)
+
+const (
+	signingKeyLen = 32
+)
+
+var (
+	JwtSigningKeyTableName = "jwt_signing_key"
+)
+
+type signingKey struct {
+	ID                string    `json:"id"`
+	Secret            []byte    `json:"secret"`
+	CreationTimestamp time.Time `json:"creationTimestamp"`
+}
+
+//token is signed by the newest key, old keys are kept to verify the
+//tokens signed before rotation until these tokens are expired
+type KeyRing struct {
+	lock           sync.Mutex
+	keys           []*signingKey
+	rotationPeriod time.Duration
+	keepPeriod     time.Duration
+	db             kvzoo.Table
+}
+
+//key is generated if there is no key in db
+func NewKeyRing(table kvzoo.Table, rotationPeriod, keepPeriod time.Duration) (*KeyRing, error) {
+	keys, err := loadKeys(table)
+	if err != nil {
+		return nil, err
+	}
+
+	k := &KeyRing{
+		keys:           keys,
+		rotationPeriod: rotationPeriod,
+		keepPeriod:     keepPeriod,
+		db:             table,
+	}
+	if len(keys) == 0 {
+		if err := k.rotate(); err != nil {
+			return nil, err
+		}
+	}
+	return k, nil
+}
+
+func loadKeys(table kvzoo.Table) ([]*signingKey, error) {
+	tx, err := table.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	keysInDB, err := tx.List()
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []*signingKey
+	for _, keyInDB := range keysInDB {
+		var key signingKey
+		if err := json.Unmarshal(keyInDB, &key); err != nil {
+			return nil, err
+		}
+		keys = append(keys, &key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].CreationTimestamp.Before(keys[j].CreationTimestamp)
+	})
+	return keys, nil
+}
+
+//rotate the key if it's too old
+func (k *KeyRing) currentKey() (*signingKey, error) {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	current := k.keys[len(k.keys)-1]
+	if k.rotationPeriod > 0 && time.Since(current.CreationTimestamp) > k.rotationPeriod {
+		if err := k.rotate(); err != nil {
+			return nil, err
+		}
+		current = k.keys[len(k.keys)-1]
+	}
+	return current, nil
+}
+
+func (k *KeyRing) getKey(id string) *signingKey {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	for _, key := range k.keys {
+		if key.ID == id {
+			return key
+		}
+	}
+	return nil
+}
+
+func (k *KeyRing) Rotate() error {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+	return k.rotate()
+}
+
+//key replaced before keep period is removed, since tokens signed by
+//it are all expired
+func (k *KeyRing) rotate() error {
+	secret := make([]byte, signingKeyLen)
+	if _, err := rand.Read(secret); err != nil {
+		return fmt.Errorf("generate jwt signing key failed:%s", err.Error())
+	}
+
+	key := &signingKey{
+		ID:                uuid.MustGen(),
+		Secret:            secret,
+		CreationTimestamp: time.Now(),
+	}
+
+	tx, err := k.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	value, _ := json.Marshal(key)
+	if err := tx.Add(key.ID, value); err != nil {
+		return err
+	}
+
+	keys := append(k.keys, key)
+	var validKeys []*signingKey
+	for i, old := range keys {
+		if i+1 < len(keys) && time.Since(keys[i+1].CreationTimestamp) > k.keepPeriod {
+			if err := tx.Delete(old.ID); err != nil {
+				return err
+			}
+		} else {
+			validKeys = append(validKeys, old)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	k.keys = validKeys
+	return nil
+}
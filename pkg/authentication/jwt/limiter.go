@@ -0,0 +1,153 @@
+package jwt
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"config"
+)
+
+const (
+	LockoutKindUser     = "user"
+	LockoutKindClientIP = "clientIP"
+	minLoginDelay       = time.Second
+	maxLoginDelay       = 30 * time.Second
+)
+
+//called when a user or a client ip is locked
+type LockoutHandler func(kind, name, message string)
+
+type failureRecord struct {
+	failures    int
+	lastFailure time.Time
+	lockedUntil time.Time
+}
+
+//each failed login of the user or from the client ip doubles the
+//delay before next login, user or client ip is locked after too many
+//failures, records are kept in memory and forgotten after lockout
+//duration without failure
+type LoginLimiter struct {
+	lock            sync.Mutex
+	maxUserFailures int
+	maxIPFailures   int
+	lockoutDuration time.Duration
+	users           map[string]*failureRecord
+	clientIPs       map[string]*failureRecord
+	lockoutHandler  LockoutHandler
+}
+
+func NewLoginLimiter(conf config.LoginConf) *LoginLimiter {
+	return &LoginLimiter{
+		maxUserFailures: conf.MaxUserFailures,
+		maxIPFailures:   conf.MaxIPFailures,
+		lockoutDuration: time.Duration(conf.LockoutMinutes) * time.Minute,
+		users:           make(map[string]*failureRecord),
+		clientIPs:       make(map[string]*failureRecord),
+	}
+}
+
+func (l *LoginLimiter) SetLockoutHandler(h LockoutHandler) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.lockoutHandler = h
+}
+
+//password isn't checked if login should be refused, so it doesn't
+//count as a failure
+func (l *LoginLimiter) check(userName, clientIP string) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	now := time.Now()
+	if r, ok := l.users[userName]; ok && now.Before(r.lockedUntil) {
+		return fmt.Errorf("too many failed logins of user %s, retry after %s", userName, r.lockedUntil.Format(time.RFC3339))
+	}
+	if r, ok := l.clientIPs[clientIP]; ok && now.Before(r.lockedUntil) {
+		return fmt.Errorf("too many failed logins from %s, retry after %s", clientIP, r.lockedUntil.Format(time.RFC3339))
+	}
+	return nil
+}
+
+func (l *LoginLimiter) fail(userName, clientIP string) {
+	l.lock.Lock()
+	now := time.Now()
+	l.forgetFailures(now)
+
+	var lockouts [][2]string
+	if l.recordFailure(l.users, userName, l.maxUserFailures, now) {
+		lockouts = append(lockouts, [2]string{LockoutKindUser, userName})
+	}
+	if clientIP != "" && l.recordFailure(l.clientIPs, clientIP, l.maxIPFailures, now) {
+		lockouts = append(lockouts, [2]string{LockoutKindClientIP, clientIP})
+	}
+	handler := l.lockoutHandler
+	l.lock.Unlock()
+
+	if handler != nil {
+		for _, lockout := range lockouts {
+			handler(lockout[0], lockout[1], fmt.Sprintf("%s %s is locked for %v because of too many failed logins", lockout[0], lockout[1], l.lockoutDuration))
+		}
+	}
+}
+
+//return true if the target is locked by the failure
+func (l *LoginLimiter) recordFailure(records map[string]*failureRecord, name string, maxFailures int, now time.Time) bool {
+	r, ok := records[name]
+	if ok == false {
+		r = &failureRecord{}
+		records[name] = r
+	}
+	r.failures += 1
+	r.lastFailure = now
+
+	if maxFailures > 0 && r.failures >= maxFailures {
+		r.lockedUntil = now.Add(l.lockoutDuration)
+		return true
+	}
+
+	delay := maxLoginDelay
+	if r.failures <= 6 {
+		delay = minLoginDelay << uint(r.failures-1)
+	}
+	r.lockedUntil = now.Add(delay)
+	return false
+}
+
+//failures from the client ip aren't cleared, otherwise attacker could
+//login with his own account to reset them
+func (l *LoginLimiter) succeed(userName string) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	delete(l.users, userName)
+}
+
+func (l *LoginLimiter) forgetFailures(now time.Time) {
+	for _, records := range []map[string]*failureRecord{l.users, l.clientIPs} {
+		for name, r := range records {
+			if now.After(r.lockedUntil) && now.Sub(r.lastFailure) > l.lockoutDuration {
+				delete(records, name)
+			}
+		}
+	}
+}
+
+//zero time means the user isn't locked, delay after failure isn't
+//regarded as lock
+func (l *LoginLimiter) LockedUntil(userName string) time.Time {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	r, ok := l.users[userName]
+	if ok && l.maxUserFailures > 0 && r.failures >= l.maxUserFailures && time.Now().Before(r.lockedUntil) {
+		return r.lockedUntil
+	}
+	return time.Time{}
+}
+
+func (l *LoginLimiter) Unlock(userName string) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	delete(l.users, userName)
+}
@@ -0,0 +1,63 @@
+package jwt
+
+import (
+	"testing"
+	"time"
+
+	ut "cement/unittest"
+	"config"
+)
+
+func TestLoginLimiter(t *testing.T) {
+	l := NewLoginLimiter(config.LoginConf{
+		MaxUserFailures: 3,
+		MaxIPFailures:   5,
+		LockoutMinutes:  15,
+	})
+	var lockouts []string
+	l.SetLockoutHandler(func(kind, name, message string) {
+		lockouts = append(lockouts, kind+":"+name)
+	})
+
+	//delay is doubled after each failure
+	l.fail("ben", "10.0.0.1")
+	ut.Assert(t, l.check("ben", "10.0.0.2") != nil, "login should be delayed after failure")
+	ut.Assert(t, l.check("alice", "10.0.0.1") != nil, "login from same ip should be delayed")
+	ut.Assert(t, l.check("alice", "10.0.0.2") == nil, "")
+	delay := time.Until(l.users["ben"].lockedUntil)
+	ut.Assert(t, delay > 0 && delay <= minLoginDelay, "first delay should be 1s, but get %v", delay)
+	l.fail("ben", "10.0.0.1")
+	delay = time.Until(l.users["ben"].lockedUntil)
+	ut.Assert(t, delay > minLoginDelay && delay <= 2*minLoginDelay, "second delay should be 2s, but get %v", delay)
+	ut.Assert(t, l.LockedUntil("ben").IsZero(), "delay isn't lock")
+
+	l.fail("ben", "10.0.0.1")
+	ut.Equal(t, lockouts, []string{"user:ben"})
+	until := l.LockedUntil("ben")
+	ut.Assert(t, time.Until(until) > 14*time.Minute, "user should be locked for 15m")
+
+	l.Unlock("ben")
+	ut.Assert(t, l.LockedUntil("ben").IsZero(), "")
+	ut.Assert(t, l.check("ben", "10.0.0.2") == nil, "unlocked user should login")
+
+	//ip is locked by failures of different users
+	l.fail("alice", "10.0.0.1")
+	l.fail("carol", "10.0.0.1")
+	ut.Equal(t, lockouts, []string{"user:ben", "clientIP:10.0.0.1"})
+	ut.Assert(t, l.check("dave", "10.0.0.1") != nil, "locked ip should be refused")
+
+	//success doesn't clear failures of ip
+	l.succeed("alice")
+	ut.Assert(t, l.check("alice", "10.0.0.1") != nil, "")
+	ut.Assert(t, l.check("alice", "10.0.0.3") == nil, "")
+
+	//failures are forgotten after lockout duration
+	for _, r := range l.clientIPs {
+		r.lastFailure = r.lastFailure.Add(-time.Hour)
+		r.lockedUntil = r.lockedUntil.Add(-time.Hour)
+	}
+	l.fail("erin", "10.0.0.4")
+	ut.Assert(t, l.check("alice", "10.0.0.1") == nil, "")
+	_, ok := l.clientIPs["10.0.0.1"]
+	ut.Assert(t, ok == false, "expired record should be removed")
+}
@@ -0,0 +1,36 @@
+package jwt
+
+import (
+	"crypto/subtle"
+	"fmt"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+//password from client is sha1 of the plain password, it's hashed
+//again with bcrypt before saved, user without password like cas
+//user is saved with empty hash
+func hashPassword(password string) (string, error) {
+	if password == "" {
+		return "", nil
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("hash password failed:%s", err.Error())
+	}
+	return string(hash), nil
+}
+
+//the second return value is true if the hash is saved by old version
+//which is the password itself, it should be replaced by bcrypt hash
+func checkPassword(hash, password string) (bool, bool) {
+	if hash == "" || password == "" {
+		return false, false
+	}
+
+	if _, err := bcrypt.Cost([]byte(hash)); err == nil {
+		return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil, false
+	}
+	return subtle.ConstantTimeCompare([]byte(hash), []byte(password)) == 1, true
+}
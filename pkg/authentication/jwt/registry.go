@@ -0,0 +1,194 @@
+package jwt
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"cement/uuid"
+	"gorest/resource"
+	"kvzoo"
+	"pkg/types"
+)
+
+var (
+	JwtSessionTableName = "jwt_session"
+)
+
+type tokenSession struct {
+	ID                  string    `json:"id"`
+	User                string    `json:"user"`
+	CreationTimestamp   time.Time `json:"creationTimestamp"`
+	ExpirationTimestamp time.Time `json:"expirationTimestamp"`
+	//identity provider like oidc, empty for password login
+	Source string `json:"source,omitempty"`
+}
+
+//every token created by login is registered, token is valid only if
+//its session is in the registry, session expires with the token, so
+//it's saved with ttl in db
+type SessionRegistry struct {
+	lock     sync.Mutex
+	sessions map[string]*tokenSession
+	db       kvzoo.Table
+}
+
+func NewSessionRegistry(table kvzoo.Table) (*SessionRegistry, error) {
+	tx, err := table.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	sessionsInDB, err := tx.List()
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make(map[string]*tokenSession)
+	for id, sessionInDB := range sessionsInDB {
+		var s tokenSession
+		if err := json.Unmarshal(sessionInDB, &s); err != nil {
+			return nil, err
+		}
+		sessions[id] = &s
+	}
+
+	return &SessionRegistry{
+		sessions: sessions,
+		db:       table,
+	}, nil
+}
+
+//expired sessions are removed when new session is added
+func (r *SessionRegistry) add(userName, source string, validDuration time.Duration) (*tokenSession, error) {
+	now := time.Now()
+	s := &tokenSession{
+		ID:                  uuid.MustGen(),
+		User:                userName,
+		CreationTimestamp:   now,
+		ExpirationTimestamp: now.Add(validDuration),
+		Source:              source,
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	value, _ := json.Marshal(s)
+	if err := tx.Add(s.ID, value); err != nil {
+		return nil, err
+	}
+	if err := tx.ExpireAt(s.ID, s.ExpirationTimestamp); err != nil {
+		return nil, err
+	}
+	if _, err := tx.DeleteExpired(); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	for id, old := range r.sessions {
+		if now.After(old.ExpirationTimestamp) {
+			delete(r.sessions, id)
+		}
+	}
+	r.sessions[s.ID] = s
+	return s, nil
+}
+
+//only valid session is returned
+func (r *SessionRegistry) get(userName, id string) (*tokenSession, bool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	s, ok := r.sessions[id]
+	if ok == false || s.User != userName || time.Now().After(s.ExpirationTimestamp) {
+		return nil, false
+	}
+	return s, true
+}
+
+func (r *SessionRegistry) List(userName string) []*types.Session {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	now := time.Now()
+	var sessions []*types.Session
+	for _, s := range r.sessions {
+		if s.User == userName && now.Before(s.ExpirationTimestamp) {
+			sessions = append(sessions, toSession(s))
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return time.Time(sessions[i].CreationTimestamp).Before(time.Time(sessions[j].CreationTimestamp))
+	})
+	return sessions
+}
+
+func (r *SessionRegistry) Revoke(userName, id string) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	s, ok := r.sessions[id]
+	if ok == false || s.User != userName {
+		return fmt.Errorf("session %s doesn't exist", id)
+	}
+	return r.revoke([]string{id})
+}
+
+func (r *SessionRegistry) RevokeUser(userName string) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	var ids []string
+	for id, s := range r.sessions {
+		if s.User == userName {
+			ids = append(ids, id)
+		}
+	}
+	return r.revoke(ids)
+}
+
+func (r *SessionRegistry) revoke(ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, id := range ids {
+		if err := tx.Delete(id); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("revoke sessions failed:%s", err.Error())
+	}
+
+	for _, id := range ids {
+		delete(r.sessions, id)
+	}
+	return nil
+}
+
+func toSession(s *tokenSession) *types.Session {
+	ts := &types.Session{
+		ExpirationTimestamp: resource.ISOTime(s.ExpirationTimestamp),
+	}
+	ts.SetID(s.ID)
+	ts.SetCreationTimestamp(s.CreationTimestamp)
+	return ts
+}
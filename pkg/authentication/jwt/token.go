@@ -10,75 +10,97 @@ import (
 var (
 	ErrInvalidToken = errors.New("token isn't valid")
 	ErrExpiredToken = errors.New("token is expired")
+	ErrRevokedToken = errors.New("token is revoked")
 )
 
 const (
 	UserKey   = "user"
 	ExpireKey = "expireAt"
+	KeyIDKey  = "kid"
+	//jwt id
+	SessionIDKey = "jti"
 )
 
+//token is only created for registered session, the session decides
+//the expiration time
 type TokenRepo struct {
-	secret        []byte
-	validDuration time.Duration
+	keys *KeyRing
 }
 
-func NewTokenRepo(secret []byte, validDuration time.Duration) *TokenRepo {
+func NewTokenRepo(keys *KeyRing) *TokenRepo {
 	return &TokenRepo{
-		secret:        secret,
-		validDuration: validDuration,
+		keys: keys,
 	}
 }
 
-func (r *TokenRepo) CreateToken(user string) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+//id of the signing key is in token header, session id is used to
+//revoke the token
+func (r *TokenRepo) createToken(user, sessionID string, expireAt time.Time) (string, error) {
+	key, err := r.keys.currentKey()
+	if err != nil {
+		return "", err
+	}
+
+	claims := jwt.MapClaims{
 		UserKey:   user,
-		ExpireKey: time.Now().Add(r.validDuration).Unix(),
-	})
+		ExpireKey: expireAt.Unix(),
+	}
+	if sessionID != "" {
+		claims[SessionIDKey] = sessionID
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	token.Header[KeyIDKey] = key.ID
 
-	return token.SignedString(r.secret)
+	return token.SignedString(key.Secret)
 }
 
 func (r *TokenRepo) ParseToken(tokenRaw string) (string, error) {
+	user, _, err := r.parseToken(tokenRaw)
+	return user, err
+}
+
+//return user and session id
+func (r *TokenRepo) parseToken(tokenRaw string) (string, string, error) {
 	token, err := jwt.Parse(tokenRaw, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return "", ErrInvalidToken
 		}
-		return r.secret, nil
+
+		keyID, _ := token.Header[KeyIDKey].(string)
+		key := r.keys.getKey(keyID)
+		if key == nil {
+			return "", ErrInvalidToken
+		}
+		return key.Secret, nil
 	})
 
 	if err != nil || token.Valid == false {
-		return "", ErrInvalidToken
+		return "", "", ErrInvalidToken
 	}
 
 	claims, ok := token.Claims.(jwt.MapClaims)
 	if ok == false {
-		return "", ErrInvalidToken
+		return "", "", ErrInvalidToken
 	}
 
 	if expire_, ok := claims[ExpireKey]; ok == false {
-		return "", ErrInvalidToken
+		return "", "", ErrInvalidToken
 	} else if expire, ok := expire_.(float64); ok == false {
-		return "", ErrInvalidToken
+		return "", "", ErrInvalidToken
 	} else {
 		expireTime := time.Unix(int64(expire), 0)
 		if time.Now().After(expireTime) {
-			return "", ErrExpiredToken
+			return "", "", ErrExpiredToken
 		}
 	}
 
+	sessionID, _ := claims[SessionIDKey].(string)
+
 	if user_, ok := claims[UserKey]; ok == false {
-		return "", ErrInvalidToken
+		return "", "", ErrInvalidToken
 	} else if user, ok := user_.(string); ok == false || user == "" {
-		return "", ErrInvalidToken
-	} else {
-		return user, nil
-	}
-}
-
-func (r *TokenRepo) RenewToken(tokenRaw string) (string, error) {
-	if user, err := r.ParseToken(tokenRaw); err != nil {
-		return "", err
+		return "", "", ErrInvalidToken
 	} else {
-		return r.CreateToken(user)
+		return user, sessionID, nil
 	}
 }
@@ -4,30 +4,93 @@ import (
 	"testing"
 	"time"
 
+	"github.com/dgrijalva/jwt-go"
+
 	ut "cement/unittest"
+	"kvzoo"
+	"kvzoo/backend/memory"
 )
 
+func newKeyRing(t *testing.T, rotationPeriod, keepPeriod time.Duration) (*KeyRing, kvzoo.Table) {
+	db, err := memory.New()
+	ut.Assert(t, err == nil, "create db should succeed: %v", err)
+	tn, _ := kvzoo.TableNameFromSegments(JwtSigningKeyTableName)
+	table, err := db.CreateOrGetTable(tn)
+	ut.Assert(t, err == nil, "create table should succeed: %v", err)
+	keys, err := NewKeyRing(table, rotationPeriod, keepPeriod)
+	ut.Assert(t, err == nil, "create key ring should succeed: %v", err)
+	return keys, table
+}
+
 func TestTokenCreationAndValidation(t *testing.T) {
-	repo := NewTokenRepo([]byte("gogod boy"), 10*time.Second)
-	token, err := repo.CreateToken("ben")
+	keys, _ := newKeyRing(t, 0, 10*time.Second)
+	repo := NewTokenRepo(keys)
+	token, err := repo.createToken("ben", "s1", time.Now().Add(10*time.Second))
 	ut.Assert(t, err == nil, "create token shouldn't failed, but get:%v", err)
 
 	user, err := repo.ParseToken(token)
 	ut.Assert(t, err == nil, "token is valid, but get:%v", err)
 	ut.Equal(t, user, "ben")
+	_, sessionID, _ := repo.parseToken(token)
+	ut.Equal(t, sessionID, "s1")
 
 	_, err = repo.ParseToken(token + "xxx")
 	ut.Assert(t, err == ErrInvalidToken, "token is invalid, but get nothing")
 
-	repo = NewTokenRepo([]byte("gogod boy"), 2*time.Second)
-	token, err = repo.CreateToken("ben")
-	<-time.After(time.Second)
-	extend, err := repo.RenewToken(token)
-	ut.Assert(t, err == nil, "renewed token should succeed, but get:%v", err)
-	<-time.After(time.Second)
+	token, err = repo.createToken("ben", "s2", time.Now().Add(time.Second))
+	ut.Assert(t, err == nil, "create token shouldn't failed, but get:%v", err)
+	<-time.After(2 * time.Second)
 	_, err = repo.ParseToken(token)
 	ut.Assert(t, err == ErrExpiredToken, "token is expired, but get:%v", err)
-	user, err = repo.ParseToken(extend)
-	ut.Assert(t, err == nil, "renewed token is valid, but get:%v", err)
+}
+
+func TestKeyRotation(t *testing.T) {
+	keys, table := newKeyRing(t, time.Hour, time.Hour)
+	repo := NewTokenRepo(keys)
+	token, err := repo.createToken("ben", "s1", time.Now().Add(time.Hour))
+	ut.Assert(t, err == nil, "create token should succeed: %v", err)
+
+	//token signed by hardcoded secret of old version
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		UserKey:   "admin",
+		ExpireKey: time.Now().Add(time.Hour).Unix(),
+	})
+	forgedToken, _ := forged.SignedString([]byte("hello single cloud"))
+	_, err = repo.ParseToken(forgedToken)
+	ut.Assert(t, err == ErrInvalidToken, "token without key id should be invalid, but get:%v", err)
+	forged.Header[KeyIDKey] = keys.keys[0].ID
+	forgedToken, _ = forged.SignedString([]byte("hello single cloud"))
+	_, err = repo.ParseToken(forgedToken)
+	ut.Assert(t, err == ErrInvalidToken, "token signed by other key should be invalid, but get:%v", err)
+
+	//token signed by old key is valid after rotation
+	ut.Assert(t, keys.Rotate() == nil, "rotate key should succeed")
+	newToken, _ := repo.createToken("ben", "s2", time.Now().Add(time.Hour))
+	parsedNew, _, _ := new(jwt.Parser).ParseUnverified(newToken, jwt.MapClaims{})
+	parsedOld, _, _ := new(jwt.Parser).ParseUnverified(token, jwt.MapClaims{})
+	ut.Assert(t, parsedNew.Header[KeyIDKey] != parsedOld.Header[KeyIDKey], "new token should be signed by new key")
+	user, err := repo.ParseToken(token)
+	ut.Assert(t, err == nil, "token signed by old key should be valid, but get:%v", err)
 	ut.Equal(t, user, "ben")
+
+	//keys are loaded from db
+	keys, err = NewKeyRing(table, time.Hour, time.Hour)
+	ut.Assert(t, err == nil, "load key ring should succeed: %v", err)
+	ut.Equal(t, len(keys.keys), 2)
+	repo = NewTokenRepo(keys)
+	_, err = repo.ParseToken(token)
+	ut.Assert(t, err == nil, "token should be valid after restart, but get:%v", err)
+
+	//old keys are removed after keep period, current key is rotated
+	//after rotation period
+	for _, key := range keys.keys {
+		key.CreationTimestamp = key.CreationTimestamp.Add(-2 * time.Hour)
+	}
+	_, err = repo.createToken("ben", "s3", time.Now().Add(time.Hour))
+	ut.Assert(t, err == nil, "create token should succeed: %v", err)
+	ut.Equal(t, len(keys.keys), 2)
+	_, err = repo.ParseToken(token)
+	ut.Assert(t, err == ErrInvalidToken, "token signed by removed key should be invalid, but get:%v", err)
+	_, err = repo.ParseToken(newToken)
+	ut.Assert(t, err == nil, "token signed by last key should be valid, but get:%v", err)
 }
@@ -0,0 +1,350 @@
+package jwt
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"image/png"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/hotp"
+	"github.com/pquerna/otp/totp"
+
+	"cement/uuid"
+	"kvzoo"
+	"pkg/types"
+)
+
+const (
+	TOTPIssuer        = "GaoCloud"
+	totpPeriod        = 30
+	totpSkew          = 1
+	qrCodeSize        = 200
+	recoveryCodeCount = 10
+	challengeDuration = 5 * time.Minute
+	maxChallengeTries = 5
+)
+
+var (
+	JwtTOTPTableName = "jwt_totp"
+)
+
+//secret is saved when totp is enabled, totp is used for login after
+//the first code is confirmed, recovery code is saved as sha256, and
+//it's removed after used, code of used time step can't be reused
+type totpRecord struct {
+	Secret        string   `json:"secret"`
+	Confirmed     bool     `json:"confirmed"`
+	RecoveryCodes []string `json:"recoveryCodes"`
+	LastCounter   uint64   `json:"lastCounter"`
+}
+
+//login of user with totp returns a challenge instead of token after
+//password is checked, token is returned when the challenge and the
+//code are verified
+type loginChallenge struct {
+	user     string
+	expireAt time.Time
+	tries    int
+}
+
+type TOTPManager struct {
+	lock       sync.Mutex
+	records    map[string]*totpRecord
+	challenges map[string]*loginChallenge
+	db         kvzoo.Table
+}
+
+func NewTOTPManager(table kvzoo.Table) (*TOTPManager, error) {
+	tx, err := table.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	recordsInDB, err := tx.List()
+	if err != nil {
+		return nil, err
+	}
+
+	records := make(map[string]*totpRecord)
+	for user, recordInDB := range recordsInDB {
+		var record totpRecord
+		if err := json.Unmarshal(recordInDB, &record); err != nil {
+			return nil, err
+		}
+		records[user] = &record
+	}
+
+	return &TOTPManager{
+		records:    records,
+		challenges: make(map[string]*loginChallenge),
+		db:         table,
+	}, nil
+}
+
+func (m *TOTPManager) IsEnabled(userName string) bool {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	record, ok := m.records[userName]
+	return ok && record.Confirmed
+}
+
+//new secret replaces the unconfirmed one, enabled totp should be
+//disabled first
+func (m *TOTPManager) Enable(userName string) (*types.TOTPProvision, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if record, ok := m.records[userName]; ok && record.Confirmed {
+		return nil, fmt.Errorf("totp of user %s is already enabled", userName)
+	}
+
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      TOTPIssuer,
+		AccountName: userName,
+		Period:      totpPeriod,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("generate totp key failed:%s", err.Error())
+	}
+
+	qrCode, err := genQRCode(key)
+	if err != nil {
+		return nil, err
+	}
+
+	record := &totpRecord{Secret: key.Secret()}
+	if err := m.save(userName, record); err != nil {
+		return nil, err
+	}
+	m.records[userName] = record
+
+	return &types.TOTPProvision{
+		Secret: key.Secret(),
+		URL:    key.URL(),
+		QRCode: qrCode,
+	}, nil
+}
+
+//totp is enabled after the first code is verified, recovery codes
+//are only returned here
+func (m *TOTPManager) Confirm(userName, code string) ([]string, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	record, ok := m.records[userName]
+	if ok == false {
+		return nil, fmt.Errorf("totp of user %s isn't enabled", userName)
+	} else if record.Confirmed {
+		return nil, fmt.Errorf("totp of user %s is already confirmed", userName)
+	}
+
+	counter, ok := validateCode(record, code)
+	if ok == false {
+		return nil, fmt.Errorf("totp code isn't correct")
+	}
+
+	codes, hashes, err := genRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	confirmed := *record
+	confirmed.Confirmed = true
+	confirmed.LastCounter = counter
+	confirmed.RecoveryCodes = hashes
+	if err := m.save(userName, &confirmed); err != nil {
+		return nil, err
+	}
+	m.records[userName] = &confirmed
+	return codes, nil
+}
+
+func (m *TOTPManager) Disable(userName string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if _, ok := m.records[userName]; ok == false {
+		return nil
+	}
+
+	tx, err := m.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := tx.Delete(userName); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	delete(m.records, userName)
+	return nil
+}
+
+//code is totp code or recovery code
+func (m *TOTPManager) Verify(userName, code string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.verify(userName, code)
+}
+
+func (m *TOTPManager) verify(userName, code string) error {
+	record, ok := m.records[userName]
+	if ok == false || record.Confirmed == false {
+		return fmt.Errorf("totp of user %s isn't enabled", userName)
+	}
+
+	verified := *record
+	if counter, ok := validateCode(record, code); ok {
+		verified.LastCounter = counter
+	} else if index := findRecoveryCode(record, code); index != -1 {
+		verified.RecoveryCodes = append(append([]string{}, record.RecoveryCodes[:index]...), record.RecoveryCodes[index+1:]...)
+	} else {
+		return fmt.Errorf("totp code isn't correct")
+	}
+
+	if err := m.save(userName, &verified); err != nil {
+		return err
+	}
+	m.records[userName] = &verified
+	return nil
+}
+
+func (m *TOTPManager) newChallenge(userName string) string {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	now := time.Now()
+	for id, c := range m.challenges {
+		if now.After(c.expireAt) {
+			delete(m.challenges, id)
+		}
+	}
+
+	id := uuid.MustGen()
+	m.challenges[id] = &loginChallenge{
+		user:     userName,
+		expireAt: now.Add(challengeDuration),
+	}
+	return id
+}
+
+//challenge is removed after it's verified or it's tried too many times
+func (m *TOTPManager) verifyChallenge(userName, id, code string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	c, ok := m.challenges[id]
+	if ok == false || c.user != userName || time.Now().After(c.expireAt) {
+		return fmt.Errorf("login challenge is invalid or expired")
+	}
+
+	if err := m.verify(userName, code); err != nil {
+		c.tries += 1
+		if c.tries >= maxChallengeTries {
+			delete(m.challenges, id)
+		}
+		return err
+	}
+	delete(m.challenges, id)
+	return nil
+}
+
+func (m *TOTPManager) save(userName string, record *totpRecord) error {
+	value, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	tx, err := m.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, ok := m.records[userName]; ok {
+		err = tx.Update(userName, value)
+	} else {
+		err = tx.Add(userName, value)
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+//return the time step of the code, code of the time step which isn't
+//after the last used one is rejected
+func validateCode(record *totpRecord, code string) (uint64, bool) {
+	current := uint64(time.Now().Unix()) / totpPeriod
+	for counter := current - totpSkew; counter <= current+totpSkew; counter++ {
+		if counter <= record.LastCounter {
+			continue
+		}
+
+		ok, _ := hotp.ValidateCustom(code, counter, record.Secret, hotp.ValidateOpts{
+			Digits:    otp.DigitsSix,
+			Algorithm: otp.AlgorithmSHA1,
+		})
+		if ok {
+			return counter, true
+		}
+	}
+	return 0, false
+}
+
+func findRecoveryCode(record *totpRecord, code string) int {
+	hash := hashRecoveryCode(code)
+	for i, h := range record.RecoveryCodes {
+		if h == hash {
+			return i
+		}
+	}
+	return -1
+}
+
+func genRecoveryCodes() ([]string, []string, error) {
+	var codes, hashes []string
+	for i := 0; i < recoveryCodeCount; i++ {
+		b := make([]byte, 10)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, fmt.Errorf("generate recovery code failed:%s", err.Error())
+		}
+		code := strings.ToLower(base32.StdEncoding.EncodeToString(b))
+		code = code[:8] + "-" + code[8:]
+		codes = append(codes, code)
+		hashes = append(hashes, hashRecoveryCode(code))
+	}
+	return codes, hashes, nil
+}
+
+func hashRecoveryCode(code string) string {
+	h := sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(code))))
+	return hex.EncodeToString(h[:])
+}
+
+//png image in data url
+func genQRCode(key *otp.Key) (string, error) {
+	img, err := key.Image(qrCodeSize, qrCodeSize)
+	if err != nil {
+		return "", fmt.Errorf("generate qr code failed:%s", err.Error())
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return "", fmt.Errorf("encode qr code failed:%s", err.Error())
+	}
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
@@ -0,0 +1,93 @@
+package jwt
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pquerna/otp/totp"
+
+	ut "cement/unittest"
+	"kvzoo/backend/bolt"
+	"pkg/types"
+)
+
+func TestTOTPLogin(t *testing.T) {
+	dbPath := "user.db"
+	ut.WithTempFile(t, dbPath, func(t *testing.T, f *os.File) {
+		db, err := bolt.New(f.Name())
+		ut.Assert(t, err == nil, "create db should succeed:%v", err)
+		auth, err := newAuthenticator(db)
+		ut.Assert(t, err == nil, "load user should succeed:%v", err)
+
+		newUser := &types.User{
+			Name:     "ben",
+			Password: "123",
+		}
+		newUser.SetID(newUser.Name)
+		ut.Assert(t, auth.AddUser(newUser) == nil, "")
+
+		_, err = auth.EnableTOTP("ldap_user")
+		ut.Assert(t, err != nil, "only local user could enable totp")
+
+		provision, err := auth.EnableTOTP("ben")
+		ut.Assert(t, err == nil, "enable totp should succeed:%v", err)
+		ut.Assert(t, strings.HasPrefix(provision.URL, "otpauth://totp/"), "")
+		ut.Assert(t, strings.HasPrefix(provision.QRCode, "data:image/png;base64,"), "")
+
+		//totp isn't used before it's confirmed
+		ut.Assert(t, createToken(t, auth, "ben", "123") != "", "")
+		_, err = auth.ConfirmTOTP("ben", "000000")
+		ut.Assert(t, err != nil, "confirm with wrong code should fail")
+		now := time.Now()
+		code, _ := totp.GenerateCode(provision.Secret, now)
+		recoveryCodes, err := auth.ConfirmTOTP("ben", code)
+		ut.Assert(t, err == nil, "confirm totp should succeed:%v", err)
+		ut.Equal(t, len(recoveryCodes), recoveryCodeCount)
+		_, err = auth.EnableTOTP("ben")
+		ut.Assert(t, err != nil, "enabled totp should be disabled first")
+
+		info, err := auth.CreateToken("ben", "123", "")
+		ut.Assert(t, err == nil, "login should succeed:%v", err)
+		ut.Assert(t, info.TOTPRequired && info.Token == "" && info.Challenge != "", "totp should be required")
+
+		_, err = auth.CreateTokenWithTOTP("ben", info.Challenge, code, "")
+		ut.Assert(t, err != nil, "used code should be rejected")
+		auth.Unlock("ben")
+		_, err = auth.CreateTokenWithTOTP("admin", info.Challenge, code, "")
+		ut.Assert(t, err != nil, "challenge of other user should fail")
+		auth.Unlock("admin")
+
+		code, _ = totp.GenerateCode(provision.Secret, now.Add(totpPeriod*time.Second))
+		info, err = auth.CreateTokenWithTOTP("ben", info.Challenge, code, "")
+		ut.Assert(t, err == nil, "login with totp should succeed:%v", err)
+		ut.Assert(t, info.Token != "", "")
+		_, err = auth.CreateTokenWithTOTP("ben", info.Challenge, code, "")
+		ut.Assert(t, err != nil, "challenge should be used only once")
+		auth.Unlock("ben")
+
+		//recovery code could be used once
+		info, _ = auth.CreateToken("ben", "123", "")
+		info, err = auth.CreateTokenWithTOTP("ben", info.Challenge, strings.ToUpper(recoveryCodes[0]), "")
+		ut.Assert(t, err == nil, "login with recovery code should succeed:%v", err)
+		info, _ = auth.CreateToken("ben", "123", "")
+		_, err = auth.CreateTokenWithTOTP("ben", info.Challenge, recoveryCodes[0], "")
+		ut.Assert(t, err != nil, "used recovery code should fail")
+		auth.Unlock("ben")
+
+		//totp is loaded from db
+		auth, err = newAuthenticator(db)
+		ut.Assert(t, err == nil, "load user should succeed:%v", err)
+		ut.Assert(t, auth.IsTOTPEnabled("ben"), "")
+
+		auth.requireAdminTOTP = true
+		ut.Assert(t, auth.TOTPEnrollmentRequired("admin"), "")
+		ut.Assert(t, auth.TOTPEnrollmentRequired("ben") == false, "")
+
+		ut.Assert(t, auth.DisableTOTP("ben", "000000", false) != nil, "disable with wrong code should fail")
+		ut.Assert(t, auth.DisableTOTP("ben", recoveryCodes[1], false) == nil, "")
+		ut.Assert(t, auth.IsTOTPEnabled("ben") == false, "")
+		ut.Assert(t, createToken(t, auth, "ben", "123") != "", "")
+	})
+}
@@ -0,0 +1,138 @@
+package ldap
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	goldap "github.com/go-ldap/ldap/v3"
+
+	"config"
+)
+
+const (
+	ldapTimeout = 10 * time.Second
+)
+
+//verify the password by binding as the user, the user is searched
+//under base dn with user filter, groups of the user are read from
+//group attribute
+type Authenticator struct {
+	conf         config.LDAPConf
+	tlsConf      *tls.Config
+	groupMapping map[string]string
+}
+
+func NewAuthenticator(conf config.LDAPConf) (*Authenticator, error) {
+	u, err := url.Parse(conf.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ldap addr %s:%s", conf.Addr, err.Error())
+	}
+	if u.Scheme != "ldap" && u.Scheme != "ldaps" {
+		return nil, fmt.Errorf("ldap addr %s should start with ldap:// or ldaps://", conf.Addr)
+	}
+
+	//dn is case insensitive
+	groupMapping := make(map[string]string)
+	for dn, group := range conf.GroupMapping {
+		groupMapping[strings.ToLower(dn)] = group
+	}
+
+	return &Authenticator{
+		conf: conf,
+		tlsConf: &tls.Config{
+			ServerName:         u.Hostname(),
+			InsecureSkipVerify: conf.InsecureSkipVerify,
+		},
+		groupMapping: groupMapping,
+	}, nil
+}
+
+//return the gaocloud groups of the user if the password is correct
+func (a *Authenticator) Authenticate(userName, password string) ([]string, error) {
+	//bind with empty password is unauthenticated bind, which always
+	//succeeds
+	if userName == "" || password == "" {
+		return nil, fmt.Errorf("user name or password is empty")
+	}
+
+	conn, err := a.connect()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if a.conf.BindDN != "" {
+		if err := conn.Bind(a.conf.BindDN, a.conf.BindPassword); err != nil {
+			return nil, fmt.Errorf("bind ldap with %s failed:%s", a.conf.BindDN, err.Error())
+		}
+	}
+
+	req := goldap.NewSearchRequest(a.conf.BaseDN, goldap.ScopeWholeSubtree, goldap.NeverDerefAliases,
+		0, int(ldapTimeout.Seconds()), false,
+		fmt.Sprintf(a.conf.UserFilter, goldap.EscapeFilter(userName)),
+		[]string{a.conf.GroupAttribute}, nil)
+	result, err := conn.Search(req)
+	if err != nil {
+		return nil, fmt.Errorf("search ldap user %s failed:%s", userName, err.Error())
+	}
+	if len(result.Entries) == 0 {
+		return nil, fmt.Errorf("user %s doesn't exist", userName)
+	} else if len(result.Entries) > 1 {
+		return nil, fmt.Errorf("user %s matches %d ldap entries", userName, len(result.Entries))
+	}
+
+	entry := result.Entries[0]
+	if err := conn.Bind(entry.DN, password); err != nil {
+		return nil, fmt.Errorf("password isn't correct")
+	}
+
+	return a.getGroups(entry.GetAttributeValues(a.conf.GroupAttribute)), nil
+}
+
+func (a *Authenticator) connect() (*goldap.Conn, error) {
+	conn, err := goldap.DialURL(a.conf.Addr, goldap.DialWithTLSConfig(a.tlsConf))
+	if err != nil {
+		return nil, fmt.Errorf("connect ldap %s failed:%s", a.conf.Addr, err.Error())
+	}
+	conn.SetTimeout(ldapTimeout)
+
+	if a.conf.StartTLS {
+		if err := conn.StartTLS(a.tlsConf); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("start tls with ldap %s failed:%s", a.conf.Addr, err.Error())
+		}
+	}
+	return conn, nil
+}
+
+//group which isn't a dn like posixGroup name is used directly
+func (a *Authenticator) getGroups(ldapGroups []string) []string {
+	var groups []string
+	for _, ldapGroup := range ldapGroups {
+		if group, ok := a.groupMapping[strings.ToLower(ldapGroup)]; ok {
+			groups = append(groups, group)
+		} else if cn := getCN(ldapGroup); cn != "" {
+			groups = append(groups, cn)
+		}
+	}
+	sort.Strings(groups)
+	return groups
+}
+
+func getCN(group string) string {
+	dn, err := goldap.ParseDN(group)
+	if err != nil || len(dn.RDNs) == 0 {
+		return group
+	}
+
+	for _, attr := range dn.RDNs[0].Attributes {
+		if strings.EqualFold(attr.Type, "cn") {
+			return attr.Value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,199 @@
+package ldap
+
+import (
+	"fmt"
+	"net"
+	"testing"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	goldap "github.com/go-ldap/ldap/v3"
+
+	ut "cement/unittest"
+	"config"
+)
+
+type fakeEntry struct {
+	dn       string
+	uid      string
+	password string
+	groups   []string
+}
+
+//in process ldap server which only supports simple bind and search
+//by uid
+type fakeServer struct {
+	ln           net.Listener
+	bindDN       string
+	bindPassword string
+	entries      []fakeEntry
+}
+
+func newFakeServer(t *testing.T, entries []fakeEntry) *fakeServer {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	ut.Assert(t, err == nil, "listen should succeed: %v", err)
+	s := &fakeServer{
+		ln:           ln,
+		bindDN:       "cn=admin,dc=example,dc=com",
+		bindPassword: "admin",
+		entries:      entries,
+	}
+	go s.run()
+	return s
+}
+
+func (s *fakeServer) addr() string {
+	return "ldap://" + s.ln.Addr().String()
+}
+
+func (s *fakeServer) run() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		go s.serve(conn)
+	}
+}
+
+func (s *fakeServer) serve(conn net.Conn) {
+	defer conn.Close()
+	bound := false
+	for {
+		packet, err := ber.ReadPacket(conn)
+		if err != nil || len(packet.Children) < 2 {
+			return
+		}
+
+		id := packet.Children[0].Value.(int64)
+		op := packet.Children[1]
+		switch op.Tag {
+		case goldap.ApplicationBindRequest:
+			dn := op.Children[1].Value.(string)
+			password := op.Children[2].Data.String()
+			bound = s.checkPassword(dn, password)
+			code := goldap.LDAPResultSuccess
+			if bound == false {
+				code = goldap.LDAPResultInvalidCredentials
+			}
+			conn.Write(newResponse(id, goldap.ApplicationBindResponse, code).Bytes())
+		case goldap.ApplicationSearchRequest:
+			if bound == false {
+				conn.Write(newResponse(id, goldap.ApplicationSearchResultDone, goldap.LDAPResultInsufficientAccessRights).Bytes())
+				continue
+			}
+			filter, _ := goldap.DecompileFilter(op.Children[6])
+			for _, entry := range s.entries {
+				if filter == fmt.Sprintf("(uid=%s)", goldap.EscapeFilter(entry.uid)) {
+					conn.Write(newSearchEntry(id, entry).Bytes())
+				}
+			}
+			conn.Write(newResponse(id, goldap.ApplicationSearchResultDone, goldap.LDAPResultSuccess).Bytes())
+		default:
+			return
+		}
+	}
+}
+
+func (s *fakeServer) checkPassword(dn, password string) bool {
+	if dn == s.bindDN {
+		return password == s.bindPassword
+	}
+	for _, entry := range s.entries {
+		if entry.dn == dn {
+			return password == entry.password
+		}
+	}
+	return false
+}
+
+func newMessage(id int64, op *ber.Packet) *ber.Packet {
+	packet := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "")
+	packet.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, id, ""))
+	packet.AppendChild(op)
+	return packet
+}
+
+func newResponse(id int64, tag ber.Tag, code int) *ber.Packet {
+	op := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, "")
+	op.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, int64(code), ""))
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", ""))
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", ""))
+	return newMessage(id, op)
+}
+
+func newSearchEntry(id int64, entry fakeEntry) *ber.Packet {
+	op := ber.Encode(ber.ClassApplication, ber.TypeConstructed, goldap.ApplicationSearchResultEntry, nil, "")
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, entry.dn, ""))
+	attrs := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "")
+	attr := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "")
+	attr.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "memberOf", ""))
+	values := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "")
+	for _, group := range entry.groups {
+		values.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, group, ""))
+	}
+	attr.AppendChild(values)
+	attrs.AppendChild(attr)
+	op.AppendChild(attrs)
+	return newMessage(id, op)
+}
+
+func TestAuthenticate(t *testing.T) {
+	s := newFakeServer(t, []fakeEntry{
+		fakeEntry{
+			dn:       "uid=ben,ou=people,dc=example,dc=com",
+			uid:      "ben",
+			password: "ben123",
+			groups: []string{
+				"cn=dev,ou=groups,dc=example,dc=com",
+				"CN=Ops,OU=Groups,DC=example,DC=com",
+				"cn=qa,ou=groups,dc=example,dc=com",
+			},
+		},
+		fakeEntry{
+			dn:       "uid=dup,ou=people,dc=example,dc=com",
+			uid:      "dup",
+			password: "dup",
+		},
+		fakeEntry{
+			dn:       "uid=dup,ou=others,dc=example,dc=com",
+			uid:      "dup",
+			password: "dup",
+		},
+	})
+	defer s.ln.Close()
+
+	auth, err := NewAuthenticator(config.LDAPConf{
+		Addr:           s.addr(),
+		BindDN:         s.bindDN,
+		BindPassword:   s.bindPassword,
+		BaseDN:         "dc=example,dc=com",
+		UserFilter:     "(uid=%s)",
+		GroupAttribute: "memberOf",
+		GroupMapping: map[string]string{
+			"cn=ops,ou=groups,dc=example,dc=com": "operator",
+		},
+	})
+	ut.Assert(t, err == nil, "create authenticator should succeed: %v", err)
+
+	groups, err := auth.Authenticate("ben", "ben123")
+	ut.Assert(t, err == nil, "authenticate should succeed: %v", err)
+	ut.Equal(t, groups, []string{"dev", "operator", "qa"})
+
+	_, err = auth.Authenticate("ben", "wrong")
+	ut.Assert(t, err != nil, "wrong password should fail")
+	_, err = auth.Authenticate("ben", "")
+	ut.Assert(t, err != nil, "empty password should fail")
+	_, err = auth.Authenticate("nobody", "ben123")
+	ut.Assert(t, err != nil, "unknown user should fail")
+	_, err = auth.Authenticate("dup", "dup")
+	ut.Assert(t, err != nil, "user matches multiple entries should fail")
+	_, err = auth.Authenticate("ben)(uid=*", "ben123")
+	ut.Assert(t, err != nil, "user name should be escaped")
+
+	auth.conf.BindPassword = "wrong"
+	_, err = auth.Authenticate("ben", "ben123")
+	ut.Assert(t, err != nil, "wrong bind password should fail")
+
+	_, err = NewAuthenticator(config.LDAPConf{Addr: "http://127.0.0.1"})
+	ut.Assert(t, err != nil, "only ldap and ldaps are supported")
+}
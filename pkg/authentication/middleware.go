@@ -14,10 +14,11 @@ import (
 )
 
 const (
-	WebLogoutPath      = "/web/logout"
-	WebLoginPath       = "/web/login"
-	WebRolePath        = "/web/role"
-	WebCASRedirectPath = "/web/casredirect"
+	WebLogoutPath       = "/web/logout"
+	WebLoginPath        = "/web/login"
+	WebRolePath         = "/web/role"
+	WebCASRedirectPath  = "/web/casredirect"
+	WebOIDCCallbackPath = "/web/oidc/callback"
 )
 
 func indexPath(r *http.Request, index string) string {
@@ -49,8 +50,11 @@ func (a *Authenticator) RegisterHandler(router gin.IRoutes) error {
 		}
 
 		if user == "" {
-			user, _ = a.JwtAuth.Authenticate(c.Writer, c.Request)
-			if user != "" {
+			var source string
+			user, source, _ = a.JwtAuth.AuthenticateSource(c.Writer, c.Request)
+			if source == types.AuthSourceOIDC {
+				authBy = "OIDC"
+			} else if user != "" {
 				authBy = "JWT"
 			}
 		}
@@ -79,6 +83,21 @@ func (a *Authenticator) RegisterHandler(router gin.IRoutes) error {
 		http.Redirect(c.Writer, c.Request, indexPath(c.Request, "/index"), http.StatusFound)
 	})
 
+	router.GET(WebOIDCCallbackPath, func(c *gin.Context) {
+		if a.OIDCAuth != nil {
+			if err := a.loginOIDCUser(c.Writer, c.Request); err != nil {
+				body, _ := json.Marshal(map[string]string{
+					"err": err.Error(),
+				})
+				c.Writer.Header().Set("Content-Type", "application/json")
+				c.Writer.WriteHeader(http.StatusUnauthorized)
+				c.Writer.Write(body)
+				return
+			}
+		}
+		http.Redirect(c.Writer, c.Request, indexPath(c.Request, "/index"), http.StatusFound)
+	})
+
 	router.POST(WebLoginPath, func(c *gin.Context) {
 		a.JwtAuth.Login(c.Writer, c.Request)
 	})
@@ -101,6 +120,7 @@ func (a *Authenticator) MiddlewareFunc() gin.HandlerFunc {
 		"/apis/ws.zcloud.cn",
 		WebRolePath,
 		WebCASRedirectPath,
+		WebOIDCCallbackPath,
 	}
 
 	var jumpExceptionalPaths = []string{
@@ -117,7 +137,7 @@ func (a *Authenticator) MiddlewareFunc() gin.HandlerFunc {
 			}
 		}
 
-		userName, err := a.Authenticate(c.Writer, c.Request)
+		userName, source, scope, err := a.authenticate(c.Writer, c.Request)
 		if err != nil {
 			log.Errorf("auth failed:%v", err)
 			return
@@ -125,6 +145,10 @@ func (a *Authenticator) MiddlewareFunc() gin.HandlerFunc {
 
 		if userName != "" {
 			ctx := context.WithValue(c.Request.Context(), types.CurrentUserKey, userName)
+			ctx = context.WithValue(ctx, types.AuthSourceKey, source)
+			if scope != nil {
+				ctx = context.WithValue(ctx, types.APITokenScopeKey, scope)
+			}
 			c.Request = c.Request.WithContext(ctx)
 		} else {
 			doRedirect := true
@@ -142,6 +166,9 @@ func (a *Authenticator) MiddlewareFunc() gin.HandlerFunc {
 			if a.CasAuth != nil {
 				log.Debugf("redirect path %v to cas", path)
 				a.CasAuth.RedirectToLogin(c.Writer, c.Request, WebCASRedirectPath)
+			} else if a.OIDCAuth != nil {
+				log.Debugf("redirect path %v to oidc", path)
+				a.OIDCAuth.RedirectToLogin(c.Writer, c.Request)
 			} else {
 				log.Debugf("redirect path %v to /login", path)
 				http.Redirect(c.Writer, c.Request, indexPath(c.Request, "/login"), http.StatusFound)
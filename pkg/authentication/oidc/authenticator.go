@@ -0,0 +1,223 @@
+package oidc
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+
+	"cement/uuid"
+	"config"
+	"pkg/types"
+)
+
+const (
+	oidcTimeout        = 10 * time.Second
+	stateValidDuration = 10 * time.Minute
+)
+
+var (
+	StateCookieName = "_oidc_state"
+)
+
+//login with authorization code flow, id token is verified with the
+//keys of the issuer, user name and groups are got from the claims of
+//the id token, session is saved by the caller after login
+type Authenticator struct {
+	conf     config.OIDCConf
+	oauth    oauth2.Config
+	verifier *oidc.IDTokenVerifier
+	//sign the state cookie, it's regenerated after restart, login
+	//in progress should start again
+	stateKey     []byte
+	groupMapping map[string]string
+	isLocalUser  func(string) bool
+}
+
+//issuer is discovered, so it should be accessible
+func NewAuthenticator(conf config.OIDCConf) (*Authenticator, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), oidcTimeout)
+	defer cancel()
+	provider, err := oidc.NewProvider(ctx, conf.Issuer)
+	if err != nil {
+		return nil, fmt.Errorf("discover oidc issuer %s failed:%s", conf.Issuer, err.Error())
+	}
+
+	stateKey := make([]byte, 32)
+	if _, err := rand.Read(stateKey); err != nil {
+		return nil, fmt.Errorf("generate oidc state key failed:%s", err.Error())
+	}
+
+	scopes := conf.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{oidc.ScopeOpenID, "profile", "email"}
+	}
+
+	return &Authenticator{
+		conf: conf,
+		oauth: oauth2.Config{
+			ClientID:     conf.ClientID,
+			ClientSecret: conf.ClientSecret,
+			Endpoint:     provider.Endpoint(),
+			RedirectURL:  conf.RedirectURL,
+			Scopes:       scopes,
+		},
+		verifier: provider.Verifier(&oidc.Config{
+			ClientID: conf.ClientID,
+		}),
+		stateKey:     stateKey,
+		groupMapping: conf.GroupMapping,
+	}, nil
+}
+
+//user of identity provider with the same name as local user is
+//rejected, otherwise it could take over the local user
+func (a *Authenticator) SetLocalUserChecker(isLocalUser func(string) bool) {
+	a.isLocalUser = isLocalUser
+}
+
+//state and nonce are saved in a signed cookie which expires soon,
+//nothing is kept in memory before the callback
+func (a *Authenticator) RedirectToLogin(w http.ResponseWriter, r *http.Request) {
+	state := uuid.MustGen()
+	nonce := uuid.MustGen()
+	http.SetCookie(w, &http.Cookie{
+		Name:     StateCookieName,
+		Value:    a.signState(state, nonce, time.Now().Add(stateValidDuration)),
+		Path:     "/",
+		MaxAge:   int(stateValidDuration.Seconds()),
+		HttpOnly: true,
+	})
+	http.Redirect(w, r, a.oauth.AuthCodeURL(state, oidc.Nonce(nonce)), http.StatusFound)
+}
+
+//cookie value is state.nonce.expiration.signature
+func (a *Authenticator) signState(state, nonce string, expireAt time.Time) string {
+	value := state + "." + nonce + "." + strconv.FormatInt(expireAt.Unix(), 10)
+	return value + "." + a.stateSignature(value)
+}
+
+func (a *Authenticator) stateSignature(value string) string {
+	mac := hmac.New(sha256.New, a.stateKey)
+	mac.Write([]byte(value))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+//return the state and nonce in cookie
+func (a *Authenticator) verifyState(r *http.Request) (string, string, error) {
+	c, err := r.Cookie(StateCookieName)
+	if err != nil {
+		return "", "", fmt.Errorf("login state is missing")
+	}
+
+	fields := strings.Split(c.Value, ".")
+	if len(fields) != 4 {
+		return "", "", fmt.Errorf("login state is invalid")
+	}
+	value := strings.Join(fields[:3], ".")
+	if hmac.Equal([]byte(fields[3]), []byte(a.stateSignature(value))) == false {
+		return "", "", fmt.Errorf("login state is invalid")
+	}
+	expireAt, err := strconv.ParseInt(fields[2], 10, 64)
+	if err != nil || time.Now().After(time.Unix(expireAt, 0)) {
+		return "", "", fmt.Errorf("login state is expired")
+	}
+	return fields[0], fields[1], nil
+}
+
+//handle the redirect from the identity provider, return the user name,
+//gaocloud groups and the expiration of the id token, session isn't
+//saved
+func (a *Authenticator) Login(w http.ResponseWriter, r *http.Request) (string, []string, time.Time, error) {
+	state, nonce, err := a.verifyState(r)
+	http.SetCookie(w, &http.Cookie{
+		Name:   StateCookieName,
+		Path:   "/",
+		MaxAge: -1,
+	})
+	if err != nil {
+		return "", nil, time.Time{}, err
+	}
+
+	q := r.URL.Query()
+	if q.Get("state") != state {
+		return "", nil, time.Time{}, fmt.Errorf("login state doesn't match")
+	}
+	if e := q.Get("error"); e != "" {
+		return "", nil, time.Time{}, fmt.Errorf("identity provider returns error %s:%s", e, q.Get("error_description"))
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), oidcTimeout)
+	defer cancel()
+	token, err := a.oauth.Exchange(ctx, q.Get("code"))
+	if err != nil {
+		return "", nil, time.Time{}, fmt.Errorf("exchange code failed:%s", err.Error())
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if ok == false {
+		return "", nil, time.Time{}, fmt.Errorf("no id token in token response")
+	}
+	idToken, err := a.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return "", nil, time.Time{}, fmt.Errorf("verify id token failed:%s", err.Error())
+	}
+	if idToken.Nonce != nonce {
+		return "", nil, time.Time{}, fmt.Errorf("id token nonce doesn't match")
+	}
+
+	var claims map[string]interface{}
+	if err := idToken.Claims(&claims); err != nil {
+		return "", nil, time.Time{}, fmt.Errorf("parse id token claims failed:%s", err.Error())
+	}
+	user, groups, err := a.getUserAndGroups(claims)
+	if err != nil {
+		return "", nil, time.Time{}, err
+	}
+	return user, groups, idToken.Expiry, nil
+}
+
+func (a *Authenticator) getUserAndGroups(claims map[string]interface{}) (string, []string, error) {
+	user, _ := claims[a.conf.UserClaim].(string)
+	if user == "" {
+		return "", nil, fmt.Errorf("id token has no claim %s", a.conf.UserClaim)
+	}
+
+	//unverified email could be set to anyone's by the user, email
+	//without email_verified claim is treated as unverified
+	if a.conf.UserClaim == "email" {
+		if verified, ok := claims["email_verified"].(bool); ok == false || verified == false {
+			return "", nil, fmt.Errorf("email %s isn't verified", user)
+		}
+	}
+
+	if user == types.Administrator || (a.isLocalUser != nil && a.isLocalUser(user)) {
+		return "", nil, fmt.Errorf("user %s conflicts with local user", user)
+	}
+
+	var groups []string
+	values, _ := claims[a.conf.GroupsClaim].([]interface{})
+	for _, v := range values {
+		oidcGroup, ok := v.(string)
+		if ok == false {
+			continue
+		}
+		if group, ok := a.groupMapping[oidcGroup]; ok {
+			groups = append(groups, group)
+		} else {
+			groups = append(groups, oidcGroup)
+		}
+	}
+	sort.Strings(groups)
+	return user, groups, nil
+}
@@ -0,0 +1,217 @@
+package oidc
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	ut "cement/unittest"
+	"config"
+)
+
+//identity provider which returns id token with the claims, nonce of
+//the token is the one in authorization request unless it's in claims
+type fakeProvider struct {
+	server     *httptest.Server
+	key        *rsa.PrivateKey
+	signingKey *rsa.PrivateKey
+	claims     map[string]interface{}
+	nonce      string
+}
+
+func newFakeProvider(t *testing.T) *fakeProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	ut.Assert(t, err == nil, "generate key should succeed: %v", err)
+	p := &fakeProvider{
+		key:        key,
+		signingKey: key,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"issuer":                                p.server.URL,
+			"authorization_endpoint":                p.server.URL + "/auth",
+			"token_endpoint":                        p.server.URL + "/token",
+			"jwks_uri":                              p.server.URL + "/keys",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{
+				map[string]string{
+					"kty": "RSA",
+					"kid": "test",
+					"alg": "RS256",
+					"use": "sig",
+					"n":   base64.RawURLEncoding.EncodeToString(p.key.N.Bytes()),
+					"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(p.key.E)).Bytes()),
+				},
+			},
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("code") != "good_code" {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "access",
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+			"id_token":     p.idToken(t),
+		})
+	})
+	p.server = httptest.NewServer(mux)
+	return p
+}
+
+func (p *fakeProvider) idToken(t *testing.T) string {
+	claims := map[string]interface{}{
+		"iss":   p.server.URL,
+		"aud":   "gaocloud",
+		"sub":   "1001",
+		"iat":   time.Now().Unix(),
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"nonce": p.nonce,
+	}
+	for k, v := range p.claims {
+		claims[k] = v
+	}
+
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": "test", "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, p.signingKey, crypto.SHA256, digest[:])
+	ut.Assert(t, err == nil, "sign id token should succeed: %v", err)
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+//redirect to the provider, then call back with the code and the
+//state in redirect url
+func login(t *testing.T, a *Authenticator, p *fakeProvider, code string) (string, []string, error) {
+	user, groups, _, err := loginWithExpiry(t, a, p, code)
+	return user, groups, err
+}
+
+func loginWithExpiry(t *testing.T, a *Authenticator, p *fakeProvider, code string) (string, []string, time.Time, error) {
+	w := httptest.NewRecorder()
+	a.RedirectToLogin(w, httptest.NewRequest("GET", "/index", nil))
+	ut.Equal(t, w.Code, http.StatusFound)
+	location, err := url.Parse(w.Header().Get("Location"))
+	ut.Assert(t, err == nil, "redirect location should be url: %v", err)
+	ut.Equal(t, location.Path, "/auth")
+	ut.Equal(t, location.Query().Get("client_id"), "gaocloud")
+	p.nonce = location.Query().Get("nonce")
+
+	q := url.Values{}
+	q.Set("state", location.Query().Get("state"))
+	q.Set("code", code)
+	r := httptest.NewRequest("GET", "/web/oidc/callback?"+q.Encode(), nil)
+	for _, c := range w.Result().Cookies() {
+		r.AddCookie(c)
+	}
+	return a.Login(httptest.NewRecorder(), r)
+}
+
+func TestLogin(t *testing.T) {
+	p := newFakeProvider(t)
+	defer p.server.Close()
+
+	a, err := NewAuthenticator(config.OIDCConf{
+		Issuer:      p.server.URL,
+		ClientID:    "gaocloud",
+		RedirectURL: "https://gaocloud.example.com/web/oidc/callback",
+		UserClaim:   "email",
+		GroupsClaim: "groups",
+		GroupMapping: map[string]string{
+			"platform-team": "ops",
+		},
+	})
+	ut.Assert(t, err == nil, "create authenticator should succeed: %v", err)
+
+	p.claims = map[string]interface{}{
+		"email":          "ben@example.com",
+		"email_verified": true,
+		"groups":         []string{"platform-team", "dev"},
+	}
+	user, groups, err := login(t, a, p, "good_code")
+	ut.Assert(t, err == nil, "login should succeed: %v", err)
+	ut.Equal(t, user, "ben@example.com")
+	ut.Equal(t, groups, []string{"dev", "ops"})
+
+	_, _, err = login(t, a, p, "bad_code")
+	ut.Assert(t, err != nil, "invalid code should fail")
+
+	p.claims["email_verified"] = false
+	_, _, err = login(t, a, p, "good_code")
+	ut.Assert(t, err != nil, "unverified email should fail")
+	delete(p.claims, "email_verified")
+	_, _, err = login(t, a, p, "good_code")
+	ut.Assert(t, err != nil, "email without email_verified should fail")
+	p.claims["email_verified"] = true
+
+	//local user can't be taken over
+	a.SetLocalUserChecker(func(user string) bool {
+		return user == "local@example.com"
+	})
+	for _, name := range []string{"local@example.com", "admin"} {
+		p.claims["email"] = name
+		_, _, err = login(t, a, p, "good_code")
+		ut.Assert(t, err != nil, "user %s conflicts with local user should fail", name)
+	}
+	p.claims["email"] = "ben@example.com"
+	user, _, err = login(t, a, p, "good_code")
+	ut.Assert(t, err == nil, "login should succeed: %v", err)
+	ut.Equal(t, user, "ben@example.com")
+
+	p.claims["nonce"] = "replayed"
+	_, _, err = login(t, a, p, "good_code")
+	ut.Assert(t, err != nil, "nonce mismatch should fail")
+	delete(p.claims, "nonce")
+
+	p.signingKey, _ = rsa.GenerateKey(rand.Reader, 2048)
+	_, _, err = login(t, a, p, "good_code")
+	ut.Assert(t, err != nil, "id token signed by unknown key should fail")
+	p.signingKey = p.key
+
+	//session expires with the id token
+	_, _, expireAt, err := loginWithExpiry(t, a, p, "good_code")
+	ut.Assert(t, err == nil, "login should succeed: %v", err)
+	ut.Assert(t, time.Until(expireAt) > 50*time.Minute && time.Until(expireAt) <= time.Hour, "expiry should be the one of id token: %v", expireAt)
+
+	callback := func(cookie string) error {
+		q := url.Values{}
+		q.Set("state", "forged")
+		q.Set("code", "good_code")
+		r := httptest.NewRequest("GET", "/web/oidc/callback?"+q.Encode(), nil)
+		if cookie != "" {
+			r.AddCookie(&http.Cookie{Name: StateCookieName, Value: cookie})
+		}
+		_, _, _, err := a.Login(httptest.NewRecorder(), r)
+		return err
+	}
+	ut.Assert(t, callback("") != nil, "callback without state should fail")
+
+	//state cookie is signed and expires
+	valid := a.signState("forged", p.nonce, time.Now().Add(time.Minute))
+	ut.Assert(t, callback(valid) == nil, "callback with signed state should succeed")
+	ut.Assert(t, callback(a.signState("forged", p.nonce, time.Now().Add(-time.Second))) != nil, "expired state should fail")
+	ut.Assert(t, callback("forged."+p.nonce+".9999999999."+strings.Split(valid, ".")[3]) != nil, "tampered state should fail")
+	other, _ := NewAuthenticator(config.OIDCConf{Issuer: p.server.URL, ClientID: "gaocloud", UserClaim: "email"})
+	ut.Assert(t, callback(other.signState("forged", p.nonce, time.Now().Add(time.Minute))) != nil, "state signed by other key should fail")
+}
@@ -7,6 +7,7 @@ import (
 
 	resttypes "gorest/resource"
 	"kvzoo"
+	"pkg/db"
 	"pkg/types"
 )
 
@@ -33,20 +34,34 @@ type User struct {
 	Projects          []types.Project   `json:"projects,omitempty"`
 	CreationTimestamp resttypes.ISOTime `json:"creationTimestamp,omitempty"`
 	DeletionTimestamp resttypes.ISOTime `json:"deletionTimestamp,omitempty"`
+	//groups from external authentication like ldap
+	ExternalGroups []string `json:"externalGroups,omitempty"`
 }
 
 type Authorizer struct {
-	users map[string]*User
-	lock  sync.RWMutex
-	db    kvzoo.Table
+	users   map[string]*User
+	groups  map[string]*Group
+	lock    sync.RWMutex
+	db      kvzoo.Table
+	groupDB kvzoo.Table
 }
 
 func New() (*Authorizer, error) {
+	return NewWithDB(db.GetGlobalDB())
+}
+
+//users and groups are loaded from and saved to the db
+func NewWithDB(kvdb kvzoo.DB) (*Authorizer, error) {
 	auth := &Authorizer{
-		users: make(map[string]*User),
+		users:  make(map[string]*User),
+		groups: make(map[string]*Group),
+	}
+
+	if err := auth.loadUsers(kvdb); err != nil {
+		return nil, err
 	}
 
-	if err := auth.loadUsers(); err != nil {
+	if err := auth.loadGroups(kvdb); err != nil {
 		return nil, err
 	}
 
@@ -59,21 +74,33 @@ func New() (*Authorizer, error) {
 	return auth, nil
 }
 
+//any role of the project can access the namespace, namespace is empty
+//means any namespace in the cluster
 func (a *Authorizer) Authorize(userName, cluster, namespace string) bool {
+	return a.AuthorizeOperation(userName, cluster, namespace, "", VerbGet)
+}
+
+//namespace is empty for the resource which belongs to cluster, only
+//cluster admin can change them
+func (a *Authorizer) AuthorizeOperation(userName, cluster, namespace, kind, verb string) bool {
 	if userName == types.Administrator {
 		return true
 	}
 
 	a.lock.RLock()
-	user, ok := a.users[userName]
+	projects := a.getProjects(userName)
 	a.lock.RUnlock()
-	if ok == false {
-		return false
-	}
 
-	for _, project := range user.Projects {
-		if (project.Cluster == AllClusters || project.Cluster == cluster) &&
-			(namespace == "" || project.Namespace == AllNamespaces || project.Namespace == namespace) {
+	for _, project := range projects {
+		if projectHasNamespace(project, cluster, namespace) == false {
+			continue
+		}
+
+		if namespace == "" {
+			if verb == VerbGet || project.Role == types.RoleClusterAdmin {
+				return true
+			}
+		} else if roleAllows(project.Role, kind, verb) {
 			return true
 		}
 	}
@@ -81,6 +108,30 @@ func (a *Authorizer) Authorize(userName, cluster, namespace string) bool {
 	return false
 }
 
+//projects of the user and the groups which the user belongs to
+func (a *Authorizer) getProjects(userName string) []types.Project {
+	var projects []types.Project
+	if user, ok := a.users[userName]; ok {
+		projects = append(projects, user.Projects...)
+	}
+
+	for _, name := range a.getGroupNames(userName) {
+		projects = append(projects, a.groups[name].Projects...)
+	}
+	return projects
+}
+
+func projectHasNamespace(project types.Project, cluster, namespace string) bool {
+	if project.Cluster != AllClusters && project.Cluster != cluster {
+		return false
+	}
+
+	return namespace == "" ||
+		project.Namespace == AllNamespaces ||
+		project.Namespace == namespace ||
+		project.Role == types.RoleClusterAdmin
+}
+
 func (a *Authorizer) AddUser(user *types.User) error {
 	a.lock.Lock()
 	defer a.lock.Unlock()
@@ -115,6 +166,7 @@ func (a *Authorizer) GetUser(userName string) *types.User {
 		user := &types.User{
 			Name:     userName,
 			Projects: user_.Projects,
+			Groups:   a.getGroupNames(userName),
 		}
 		user.SetID(userName)
 		user.SetCreationTimestamp(time.Time(user_.CreationTimestamp))
@@ -133,6 +185,7 @@ func (a *Authorizer) ListUser() []*types.User {
 		user := &types.User{
 			Name:     name,
 			Projects: user_.Projects,
+			Groups:   a.getGroupNames(name),
 		}
 		user.SetID(name)
 		user.SetCreationTimestamp(time.Time(user_.CreationTimestamp))
@@ -172,3 +225,26 @@ func (a *Authorizer) UpdateUser(user *types.User) error {
 		return nil
 	}
 }
+
+//external groups are replaced at each login, user is created if it
+//doesn't exist, so the groups are kept after restart
+func (a *Authorizer) SetExternalGroups(userName string, groups []string) error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	user_, ok := a.users[userName]
+	if ok == false {
+		user_ = &User{
+			CreationTimestamp: resttypes.ISOTime(time.Now()),
+			ExternalGroups:    groups,
+		}
+		if err := a.addUser(userName, user_); err != nil {
+			return err
+		}
+		a.users[userName] = user_
+		return nil
+	}
+
+	user_.ExternalGroups = groups
+	return a.updateUser(userName, user_)
+}
@@ -0,0 +1,81 @@
+package authorization
+
+import (
+	"testing"
+
+	ut "cement/unittest"
+	"pkg/types"
+)
+
+func TestAuthorizeOperation(t *testing.T) {
+	auth := &Authorizer{
+		users: map[string]*User{
+			"viewer": &User{
+				Projects: []types.Project{
+					types.Project{Cluster: "local", Namespace: "default", Role: types.RoleViewer},
+				},
+			},
+			"dev": &User{
+				Projects: []types.Project{
+					types.Project{Cluster: "local", Namespace: "default", Role: types.RoleDeveloper},
+					types.Project{Cluster: "local", Namespace: "test", Role: types.RoleViewer},
+				},
+			},
+			"nsadmin": &User{
+				Projects: []types.Project{
+					types.Project{Cluster: "local", Namespace: "default"},
+				},
+			},
+			"clusteradmin": &User{
+				Projects: []types.Project{
+					types.Project{Cluster: "local", Namespace: "default", Role: types.RoleClusterAdmin},
+				},
+			},
+		},
+	}
+
+	cases := []struct {
+		user      string
+		namespace string
+		kind      string
+		verb      string
+		allowed   bool
+	}{
+		{"viewer", "default", "deployment", VerbGet, true},
+		{"viewer", "default", "deployment", VerbDelete, false},
+		{"viewer", "default", "deployment", "restart", false},
+		{"viewer", "default", "deployment", ActionVerb(types.ActionGetHistory), true},
+		{"viewer", "default", "namespace", ActionVerb(types.ActionSearchPod), true},
+		{"viewer", "default", "deployment", ActionVerb(types.ActionRollback), false},
+		{"viewer", "test", "deployment", VerbGet, false},
+		{"viewer", "", "node", VerbGet, true},
+		{"viewer", "", "node", VerbUpdate, false},
+
+		{"dev", "default", "deployment", VerbDelete, true},
+		{"dev", "default", "resourcequota", VerbGet, true},
+		{"dev", "default", "resourcequota", VerbCreate, false},
+		{"dev", "test", "deployment", VerbCreate, false},
+		{"dev", "test", "deployment", VerbGet, true},
+
+		{"nsadmin", "default", "limitrange", VerbCreate, true},
+		{"nsadmin", "test", "deployment", VerbGet, false},
+		{"nsadmin", "default", "namespace", ActionVerb(types.ActionSearchPod), true},
+		{"nsadmin", "default", "deployment", ActionVerb(types.ActionGetHistory), true},
+		{"nsadmin", "", "namespace", VerbCreate, false},
+
+		{"clusteradmin", "test", "deployment", VerbDelete, true},
+		{"clusteradmin", "", "namespace", VerbCreate, true},
+		{"clusteradmin", "", "storage", VerbDelete, true},
+
+		{"unknown", "default", "deployment", VerbGet, false},
+		{types.Administrator, "", "node", VerbDelete, true},
+	}
+
+	for _, c := range cases {
+		ut.Equal(t, auth.AuthorizeOperation(c.user, "local", c.namespace, c.kind, c.verb), c.allowed)
+	}
+
+	ut.Assert(t, auth.AuthorizeOperation("clusteradmin", "remote", "default", "deployment", VerbGet) == false, "")
+	ut.Assert(t, auth.Authorize("viewer", "local", "default"), "")
+	ut.Assert(t, auth.Authorize("clusteradmin", "local", "test"), "")
+}
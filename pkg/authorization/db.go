@@ -4,16 +4,16 @@ import (
 	"encoding/json"
 
 	"kvzoo"
-	"pkg/db"
 )
 
 var (
 	AuthorizerTableName = "authorizer"
+	GroupTableName      = "group"
 )
 
-func (a *Authorizer) loadUsers() error {
+func (a *Authorizer) loadUsers(kvdb kvzoo.DB) error {
 	tn, _ := kvzoo.TableNameFromSegments(AuthorizerTableName)
-	table, err := db.GetGlobalDB().CreateOrGetTable(tn)
+	table, err := kvdb.CreateOrGetTable(tn)
 	if err != nil {
 		return err
 	}
@@ -43,13 +43,68 @@ func (a *Authorizer) loadUsers() error {
 }
 
 func (a *Authorizer) addUser(name string, user *User) error {
-	tx, err := a.db.Begin()
+	return addToTable(a.db, name, user)
+}
+
+func (a *Authorizer) deleteUser(userName string) error {
+	return deleteFromTable(a.db, userName)
+}
+
+func (a *Authorizer) updateUser(name string, user *User) error {
+	return updateInTable(a.db, name, user)
+}
+
+func (a *Authorizer) loadGroups(kvdb kvzoo.DB) error {
+	tn, _ := kvzoo.TableNameFromSegments(GroupTableName)
+	table, err := kvdb.CreateOrGetTable(tn)
+	if err != nil {
+		return err
+	}
+
+	tx, err := table.Begin()
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
-	data, err := json.Marshal(user)
+	groupsInDB, err := tx.List()
+	if err != nil {
+		return err
+	}
+
+	groups := make(map[string]*Group)
+	for name, groupInDB := range groupsInDB {
+		var group Group
+		if err := json.Unmarshal(groupInDB, &group); err != nil {
+			return err
+		}
+		groups[name] = &group
+	}
+	a.groups = groups
+	a.groupDB = table
+	return nil
+}
+
+func (a *Authorizer) addGroup(name string, group *Group) error {
+	return addToTable(a.groupDB, name, group)
+}
+
+func (a *Authorizer) deleteGroup(name string) error {
+	return deleteFromTable(a.groupDB, name)
+}
+
+func (a *Authorizer) updateGroup(name string, group *Group) error {
+	return updateInTable(a.groupDB, name, group)
+}
+
+func addToTable(table kvzoo.Table, name string, v interface{}) error {
+	tx, err := table.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	data, err := json.Marshal(v)
 	if err != nil {
 		return err
 	}
@@ -60,27 +115,27 @@ func (a *Authorizer) addUser(name string, user *User) error {
 	return tx.Commit()
 }
 
-func (a *Authorizer) deleteUser(userName string) error {
-	tx, err := a.db.Begin()
+func deleteFromTable(table kvzoo.Table, name string) error {
+	tx, err := table.Begin()
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
-	if err := tx.Delete(userName); err != nil {
+	if err := tx.Delete(name); err != nil {
 		return err
 	}
 	return tx.Commit()
 }
 
-func (a *Authorizer) updateUser(name string, user *User) error {
-	tx, err := a.db.Begin()
+func updateInTable(table kvzoo.Table, name string, v interface{}) error {
+	tx, err := table.Begin()
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
-	data, err := json.Marshal(user)
+	data, err := json.Marshal(v)
 	if err != nil {
 		return err
 	}
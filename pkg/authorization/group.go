@@ -0,0 +1,125 @@
+package authorization
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"cement/slice"
+	resttypes "gorest/resource"
+	"pkg/types"
+)
+
+type Group struct {
+	Users             []string          `json:"users,omitempty"`
+	Projects          []types.Project   `json:"projects,omitempty"`
+	CreationTimestamp resttypes.ISOTime `json:"creationTimestamp,omitempty"`
+}
+
+func (a *Authorizer) AddGroup(group *types.Group) error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	name := group.GetID()
+	if _, ok := a.groups[name]; ok {
+		return fmt.Errorf("group %s already exists", name)
+	}
+
+	group_ := &Group{
+		Users:             group.Users,
+		Projects:          group.Projects,
+		CreationTimestamp: resttypes.ISOTime(group.GetCreationTimestamp()),
+	}
+	if err := a.addGroup(name, group_); err != nil {
+		return err
+	}
+	a.groups[name] = group_
+	return nil
+}
+
+func (a *Authorizer) GetGroup(name string) *types.Group {
+	a.lock.RLock()
+	defer a.lock.RUnlock()
+	if group_, ok := a.groups[name]; ok {
+		return toGroup(name, group_)
+	} else {
+		return nil
+	}
+}
+
+func (a *Authorizer) ListGroup() []*types.Group {
+	a.lock.RLock()
+	defer a.lock.RUnlock()
+	groups := make([]*types.Group, 0, len(a.groups))
+	for name, group_ := range a.groups {
+		groups = append(groups, toGroup(name, group_))
+	}
+	return groups
+}
+
+func toGroup(name string, group_ *Group) *types.Group {
+	group := &types.Group{
+		Name:     name,
+		Users:    group_.Users,
+		Projects: group_.Projects,
+	}
+	group.SetID(name)
+	group.SetCreationTimestamp(time.Time(group_.CreationTimestamp))
+	return group
+}
+
+func (a *Authorizer) DeleteGroup(name string) error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	if _, ok := a.groups[name]; ok == false {
+		return fmt.Errorf("group %s doesn't exist", name)
+	}
+
+	if err := a.deleteGroup(name); err != nil {
+		return err
+	}
+	delete(a.groups, name)
+	return nil
+}
+
+func (a *Authorizer) UpdateGroup(group *types.Group) error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	name := group.GetID()
+	group_, ok := a.groups[name]
+	if ok == false {
+		return fmt.Errorf("group %s doesn't exist", name)
+	}
+
+	newGroup := &Group{
+		Users:             group.Users,
+		Projects:          group.Projects,
+		CreationTimestamp: group_.CreationTimestamp,
+	}
+	if err := a.updateGroup(name, newGroup); err != nil {
+		return err
+	}
+	a.groups[name] = newGroup
+	return nil
+}
+
+//groups which have the user or are external groups of the user,
+//external group which doesn't exist is ignored
+func (a *Authorizer) getGroupNames(userName string) []string {
+	var externalGroups []string
+	if user, ok := a.users[userName]; ok {
+		externalGroups = user.ExternalGroups
+	}
+
+	var names []string
+	for name, group := range a.groups {
+		if slice.SliceIndex(group.Users, userName) != -1 ||
+			slice.SliceIndex(externalGroups, name) != -1 {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,90 @@
+package authorization
+
+import (
+	"testing"
+	"time"
+
+	ut "cement/unittest"
+	"kvzoo"
+	"kvzoo/backend/memory"
+	"pkg/types"
+)
+
+func newTable(t *testing.T, name string) kvzoo.Table {
+	db, err := memory.New()
+	ut.Assert(t, err == nil, "create db should succeed: %v", err)
+	tn, _ := kvzoo.TableNameFromSegments(name)
+	table, err := db.CreateOrGetTable(tn)
+	ut.Assert(t, err == nil, "create table should succeed: %v", err)
+	return table
+}
+
+func TestGroup(t *testing.T) {
+	auth := &Authorizer{
+		users: map[string]*User{
+			"ben": &User{},
+		},
+		groups:  make(map[string]*Group),
+		groupDB: newTable(t, GroupTableName),
+	}
+	ut.Assert(t, auth.Authorize("ben", "local", "default") == false, "")
+
+	group := &types.Group{
+		Name:  "dev",
+		Users: []string{"ben", "cas_user"},
+		Projects: []types.Project{
+			types.Project{Cluster: "local", Namespace: "default", Role: types.RoleDeveloper},
+		},
+	}
+	group.SetID(group.Name)
+	group.SetCreationTimestamp(time.Now())
+	ut.Assert(t, auth.AddGroup(group) == nil, "add group should succeed")
+	ut.Assert(t, auth.AddGroup(group) != nil, "add duplicate group should fail")
+
+	ut.Assert(t, auth.Authorize("ben", "local", "default"), "")
+	ut.Assert(t, auth.AuthorizeOperation("ben", "local", "default", "deployment", VerbCreate), "")
+	ut.Assert(t, auth.AuthorizeOperation("ben", "local", "default", "resourcequota", VerbCreate) == false, "")
+	//user who doesn't login yet
+	ut.Assert(t, auth.Authorize("cas_user", "local", "default"), "")
+	ut.Equal(t, auth.GetUser("ben").Groups, []string{"dev"})
+
+	group.Users = []string{"cas_user"}
+	ut.Assert(t, auth.UpdateGroup(group) == nil, "update group should succeed")
+	ut.Assert(t, auth.Authorize("ben", "local", "default") == false, "")
+	ut.Equal(t, len(auth.GetUser("ben").Groups), 0)
+
+	ut.Assert(t, auth.DeleteGroup("dev") == nil, "delete group should succeed")
+	ut.Assert(t, auth.Authorize("cas_user", "local", "default") == false, "")
+	ut.Assert(t, auth.DeleteGroup("dev") != nil, "delete unknown group should fail")
+	ut.Equal(t, len(auth.ListGroup()), 0)
+}
+
+func TestExternalGroups(t *testing.T) {
+	auth := &Authorizer{
+		users:   make(map[string]*User),
+		groups:  make(map[string]*Group),
+		db:      newTable(t, AuthorizerTableName),
+		groupDB: newTable(t, GroupTableName),
+	}
+
+	for _, name := range []string{"dev", "ops"} {
+		group := &types.Group{
+			Name: name,
+			Projects: []types.Project{
+				types.Project{Cluster: "local", Namespace: name},
+			},
+		}
+		group.SetID(name)
+		ut.Assert(t, auth.AddGroup(group) == nil, "add group should succeed")
+	}
+
+	ut.Assert(t, auth.SetExternalGroups("ldap_user", []string{"dev", "unknown"}) == nil, "set groups should succeed")
+	ut.Assert(t, auth.HasUser("ldap_user"), "user should be created")
+	ut.Assert(t, auth.Authorize("ldap_user", "local", "dev"), "")
+	ut.Assert(t, auth.Authorize("ldap_user", "local", "ops") == false, "")
+	ut.Equal(t, auth.GetUser("ldap_user").Groups, []string{"dev"})
+
+	ut.Assert(t, auth.SetExternalGroups("ldap_user", []string{"ops"}) == nil, "set groups should succeed")
+	ut.Assert(t, auth.Authorize("ldap_user", "local", "dev") == false, "")
+	ut.Assert(t, auth.Authorize("ldap_user", "local", "ops"), "")
+}
@@ -0,0 +1,50 @@
+package authorization
+
+import (
+	"cement/slice"
+	"pkg/types"
+)
+
+//verb of the request, action uses its name as verb
+const (
+	VerbGet    = "get"
+	VerbCreate = "create"
+	VerbUpdate = "update"
+	VerbDelete = "delete"
+)
+
+//actions which only read the resource, they are authorized as get
+var readOnlyActions = []string{
+	types.ActionGetHistory,
+	types.ActionSearchPod,
+}
+
+//resources which are the settings of namespace, developer can only
+//get them
+var namespaceSettingKinds = []string{
+	"namespace",
+	"resourcequota",
+	"limitrange",
+}
+
+func roleAllows(role, kind, verb string) bool {
+	switch role {
+	case types.RoleViewer:
+		return verb == VerbGet
+	case types.RoleDeveloper:
+		return verb == VerbGet || slice.SliceIndex(namespaceSettingKinds, kind) == -1
+	case types.RoleNamespaceAdmin, types.RoleClusterAdmin, "":
+		return true
+	default:
+		return false
+	}
+}
+
+//verb of the action, read only action is get, others use the action
+//name
+func ActionVerb(action string) string {
+	if slice.SliceIndex(readOnlyActions, action) != -1 {
+		return VerbGet
+	}
+	return action
+}
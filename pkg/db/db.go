@@ -2,11 +2,14 @@ package db
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"path"
+	"time"
 
 	"cement/log"
 	"kvzoo"
+	"kvzoo/backend"
 	"kvzoo/client"
 	"kvzoo/server"
 
@@ -15,6 +18,7 @@ import (
 
 const (
 	DBFileName     = "gaocloud.db"
+	SqliteFileName = "gaocloud.sqlite3"
 	DBVersionTable = "version"
 	DBVersion      = "v1.0"
 )
@@ -24,14 +28,63 @@ type Version struct {
 }
 
 var globalDB kvzoo.DB
+var dbProxy *client.Proxy
 
 func GetGlobalDB() kvzoo.DB {
     return globalDB
 }
 
+func GetSyncStatus() []client.SlaveSyncStatus {
+	if dbProxy == nil {
+		return nil
+	}
+	return dbProxy.SyncStatus()
+}
+
+var ErrDBStopped = errors.New("db is stopped before it's promoted to master")
+
+//proxy beside master and slave both start failover, so master can
+//be switched even if the whole host of master is down, node which is
+//demoted by failover waits until it's promoted again, there is only
+//one node running as master at the same time
 func RunAsMaster(conf *config.GaoCloudConf, stopCh chan struct{}) error {
+	self := fmt.Sprintf(":%d", conf.DB.Port)
+	if conf.DB.AdvertiseDBAddr != "" {
+		self = conf.DB.AdvertiseDBAddr
+	}
+
+	var slaves []string
+	if conf.DB.SlaveDBAddr != "" {
+		slaves = append(slaves, conf.DB.SlaveDBAddr)
+	}
+	return runDB(conf, self, slaves, self, stopCh)
+}
+
+//return after slave is promoted to master, so control plane can be
+//started on it, slave without master address is never promoted
+func RunAsSlave(conf *config.GaoCloudConf, stopCh chan struct{}) error {
+	if conf.DB.MasterDBAddr != "" {
+		return runDB(conf, conf.DB.MasterDBAddr, []string{conf.DB.AdvertiseDBAddr}, conf.DB.AdvertiseDBAddr, stopCh)
+	}
+
+	db, err := server.NewWithBackend(fmt.Sprintf(":%d", conf.DB.Port), backendConfig(conf), serverOptions(conf)...)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		<-stopCh
+		db.Stop()
+	}()
+	if err := db.Start(); err != nil {
+		return err
+	}
+	return ErrDBStopped
+}
+
+func runDB(conf *config.GaoCloudConf, masterAddr string, slaves []string, self string, stopCh chan struct{}) error {
 	dbServerAddr := fmt.Sprintf(":%d", conf.DB.Port)
-	db, err := server.NewWithBoltDB(dbServerAddr, path.Join(conf.DB.Path, DBFileName))
+	db, err := server.NewWithBackend(dbServerAddr, backendConfig(conf), serverOptions(conf)...)
 	if err != nil {
 		return err
 	}
@@ -43,12 +96,7 @@ func RunAsMaster(conf *config.GaoCloudConf, stopCh chan struct{}) error {
 	}()
 	<-dbStarted
 
-	var slaves []string
-	if conf.DB.SlaveDBAddr != "" {
-		slaves = append(slaves, conf.DB.SlaveDBAddr)
-	}
-
-	globalDB, err = client.New(dbServerAddr, slaves)
+	proxy, err := client.NewProxy(masterAddr, slaves, clientOptions(conf)...)
 	if err != nil {
 		db.Stop()
 		return err
@@ -56,22 +104,60 @@ func RunAsMaster(conf *config.GaoCloudConf, stopCh chan struct{}) error {
 
 	go func() {
 		<-stopCh
+		proxy.Close()
 		db.Stop()
 	}()
 
+	if len(slaves) > 0 {
+		proxy.StartFailover()
+	}
+
+	if waitForPromotion(proxy, self, stopCh) == false {
+		return ErrDBStopped
+	}
+
+	dbProxy = proxy
+	globalDB = proxy
+	dbProxy.StartDeleteExpired()
+
 	if err := checkDBVersion(globalDB); err != nil {
 		return err
 	}
 
-	if conf.DB.SlaveDBAddr != "" {
+	//slave which is down is resynced once it comes back
+	if len(slaves) > 0 {
 		if _, err := globalDB.Checksum(); err != nil {
-			return err
+			log.Warnf("slave db isn't consistent with master:%s, start to resync", err.Error())
+			if err := dbProxy.Resync(); err != nil {
+				log.Warnf("resync slave db failed:%s", err.Error())
+			}
 		}
 	}
 
 	return nil
 }
 
+func waitForPromotion(proxy *client.Proxy, self string, stopCh chan struct{}) bool {
+	if proxy.Master() == self {
+		return true
+	}
+
+	log.Infof("db master is %s, wait until %s is promoted", proxy.Master(), self)
+	ticker := time.NewTicker(client.HealthCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stopCh:
+			return false
+		case <-ticker.C:
+			if proxy.Master() == self {
+				log.Infof("%s is promoted to db master", self)
+				return true
+			}
+		}
+	}
+}
+
 func checkDBVersion(db kvzoo.DB) error {
 	tn, _ := kvzoo.TableNameFromSegments(DBVersionTable)
 	table, err := db.CreateOrGetTable(tn)
@@ -114,13 +200,46 @@ func checkDBVersion(db kvzoo.DB) error {
 	return nil
 }
 
-func RunAsSlave(conf *config.GaoCloudConf) {
-	dbServerAddr := fmt.Sprintf(":%d", conf.DB.Port)
-	db, err := server.NewWithBoltDB(dbServerAddr, path.Join(conf.DB.Path, DBFileName))
-	if err != nil {
-		log.Fatalf("start slave failed:%s", err.Error())
-		return
+func backendConfig(conf *config.GaoCloudConf) backend.Config {
+	switch conf.DB.Backend {
+	case config.MemoryBackend:
+		return backend.Config{Type: backend.Memory}
+	case config.Sqlite3Backend:
+		return backend.Config{Type: backend.Sqlite3, Path: path.Join(conf.DB.Path, SqliteFileName)}
+	case config.PostgresqlBackend:
+		return backend.Config{Type: backend.Postgresql, DSN: conf.DB.DSN}
+	default:
+		return backend.Config{Type: backend.Bolt, Path: path.Join(conf.DB.Path, DBFileName)}
 	}
+}
+
+func tlsConfig(conf *config.GaoCloudConf) kvzoo.TLSConfig {
+	return kvzoo.TLSConfig{
+		CertFile:   conf.DB.TlsCertFile,
+		KeyFile:    conf.DB.TlsKeyFile,
+		CAFile:     conf.DB.TlsCaFile,
+		ServerName: conf.DB.TlsServerName,
+	}
+}
 
-	db.Start()
+func serverOptions(conf *config.GaoCloudConf) []server.Option {
+	var opts []server.Option
+	if conf.DB.TLSEnabled() {
+		opts = append(opts, server.WithTLS(tlsConfig(conf)))
+	}
+	if conf.DB.Token != "" {
+		opts = append(opts, server.WithToken(conf.DB.Token))
+	}
+	return opts
+}
+
+func clientOptions(conf *config.GaoCloudConf) []client.Option {
+	var opts []client.Option
+	if conf.DB.TLSEnabled() {
+		opts = append(opts, client.WithTLS(tlsConfig(conf)))
+	}
+	if conf.DB.Token != "" {
+		opts = append(opts, client.WithToken(conf.DB.Token))
+	}
+	return opts
 }
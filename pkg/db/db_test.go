@@ -0,0 +1,134 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"cement/log"
+	ut "cement/unittest"
+	"kvzoo"
+	"kvzoo/client"
+	pb "kvzoo/proto"
+
+	"config"
+)
+
+//proxy logs the failures
+func TestMain(m *testing.M) {
+	log.InitLogger(log.Error)
+	os.Exit(m.Run())
+}
+
+func newDBConf(role config.DBRole, port, peerPort int) *config.GaoCloudConf {
+	conf := &config.GaoCloudConf{
+		DB: config.DBConf{
+			Port:            port,
+			Role:            role,
+			AdvertiseDBAddr: fmt.Sprintf("127.0.0.1:%d", port),
+			Backend:         config.MemoryBackend,
+		},
+	}
+	if role == config.Master {
+		conf.DB.SlaveDBAddr = fmt.Sprintf("127.0.0.1:%d", peerPort)
+	} else {
+		conf.DB.MasterDBAddr = fmt.Sprintf("127.0.0.1:%d", peerPort)
+	}
+	return conf
+}
+
+func putValues(db kvzoo.DB, tn kvzoo.TableName, values map[string]string) error {
+	table, err := db.CreateOrGetTable(tn)
+	if err != nil {
+		return err
+	}
+
+	tx, err := table.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for k, v := range values {
+		if err := kvzoo.Put(tx, k, []byte(v)); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func getValues(db kvzoo.DB, tn kvzoo.TableName) (map[string]string, error) {
+	table, err := db.CreateOrGetTable(tn)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := table.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	values, err := tx.List()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]string, len(values))
+	for k, v := range values {
+		result[k] = string(v)
+	}
+	return result, nil
+}
+
+func TestFailover(t *testing.T) {
+	masterConf := newDBConf(config.Master, 7900, 7901)
+	slaveConf := newDBConf(config.Slave, 7901, 7900)
+
+	slaveStopCh := make(chan struct{})
+	defer close(slaveStopCh)
+	promoted := make(chan error)
+	go func() {
+		promoted <- RunAsSlave(slaveConf, slaveStopCh)
+	}()
+
+	//master writes to slave only after slave is up
+	slave, err := client.NewClient(slaveConf.DB.AdvertiseDBAddr, time.Second)
+	ut.Equal(t, err, nil)
+	defer slave.Close()
+	for i := 0; i < 10; i++ {
+		if _, err = slave.GetEpoch(context.TODO(), &pb.GetEpochRequest{}); err == nil {
+			break
+		}
+		time.Sleep(time.Second)
+	}
+	ut.Equal(t, err, nil)
+
+	masterStopCh := make(chan struct{})
+	err = RunAsMaster(masterConf, masterStopCh)
+	ut.Equal(t, err, nil)
+
+	tn, _ := kvzoo.TableNameFromSegments("failover")
+	err = putValues(GetGlobalDB(), tn, map[string]string{"k1": "v1", "k2": "v2"})
+	ut.Equal(t, err, nil)
+	for _, status := range GetSyncStatus() {
+		ut.Equal(t, status.State, client.SyncStateInSync)
+	}
+
+	//the whole master is down, include its proxy
+	close(masterStopCh)
+	select {
+	case err = <-promoted:
+	case <-time.After(time.Duration(client.MaxMasterFailures+3) * client.HealthCheckInterval):
+		err = fmt.Errorf("slave isn't promoted")
+	}
+	ut.Equal(t, err, nil)
+
+	err = putValues(GetGlobalDB(), tn, map[string]string{"k2": "vv", "k3": "v3"})
+	ut.Equal(t, err, nil)
+	values, err := getValues(GetGlobalDB(), tn)
+	ut.Equal(t, err, nil)
+	ut.Equal(t, values, map[string]string{"k1": "v1", "k2": "vv", "k3": "v3"})
+}
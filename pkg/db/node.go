@@ -0,0 +1,51 @@
+package db
+
+import (
+	"fmt"
+
+	"kvzoo/client"
+
+	"config"
+)
+
+//master and slave address in configure file, only master knows both
+func NodeAddrs(conf *config.GaoCloudConf) (string, []string, error) {
+	if conf.DB.Role != config.Master {
+		return "", nil, fmt.Errorf("db role is %s, db address should be specified", conf.DB.Role)
+	}
+
+	var slaves []string
+	if conf.DB.SlaveDBAddr != "" {
+		slaves = append(slaves, conf.DB.SlaveDBAddr)
+	}
+	return fmt.Sprintf(":%d", conf.DB.Port), slaves, nil
+}
+
+//connect to the db specified by addr, if addr is empty, connect
+//to master and slave in configure file
+func NewProxy(conf *config.GaoCloudConf, addr string) (*client.Proxy, error) {
+	if addr != "" {
+		return client.NewProxy(addr, nil, clientOptions(conf)...)
+	}
+
+	master, slaves, err := NodeAddrs(conf)
+	if err != nil {
+		return nil, err
+	}
+	return client.NewProxy(master, slaves, clientOptions(conf)...)
+}
+
+//client of single node, it uses the epoch of the node, so it can
+//modify the node after master is switched
+func NewNodeClient(conf *config.GaoCloudConf, addr string) (*client.Client, error) {
+	c, err := client.NewClient(addr, client.ConnectTimeout, clientOptions(conf)...)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.UseNodeEpoch(); err != nil {
+		c.Close()
+		return nil, fmt.Errorf("get epoch of %s failed:%s", addr, err.Error())
+	}
+	return c, nil
+}
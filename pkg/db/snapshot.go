@@ -0,0 +1,86 @@
+package db
+
+import (
+	"fmt"
+	"os"
+
+	"kvzoo/client"
+
+	"config"
+)
+
+//snapshot is written to a temporary file first, so the old snapshot
+//file won't be damaged if snapshot failed, if addr is empty, snapshot
+//is taken from master in configure file
+func TakeSnapshot(conf *config.GaoCloudConf, addr, filePath string) error {
+	if addr == "" {
+		master, _, err := NodeAddrs(conf)
+		if err != nil {
+			return err
+		}
+		addr = master
+	}
+
+	c, err := NewNodeClient(conf, addr)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	tmpPath := filePath + ".tmp"
+	f, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+
+	if err := client.SnapshotNode(c, f); err != nil {
+		f.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("take snapshot failed:%s", err.Error())
+	}
+
+	if err := f.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return os.Rename(tmpPath, filePath)
+}
+
+//restore snapshot to db, if addr is empty, snapshot is restored to
+//master and then slave in configure file, so they have the same data
+func RestoreSnapshot(conf *config.GaoCloudConf, addr, filePath string) error {
+	addrs := []string{addr}
+	if addr == "" {
+		master, slaves, err := NodeAddrs(conf)
+		if err != nil {
+			return err
+		}
+		addrs = append([]string{master}, slaves...)
+	}
+
+	for _, addr := range addrs {
+		if err := restoreSnapshot(conf, addr, filePath); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func restoreSnapshot(conf *config.GaoCloudConf, addr, filePath string) error {
+	c, err := NewNodeClient(conf, addr)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	f, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := client.RestoreNode(c, f); err != nil {
+		return fmt.Errorf("restore snapshot failed:%s", err.Error())
+	}
+	return nil
+}
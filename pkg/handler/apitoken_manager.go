@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"fmt"
+
+	resterr "gorest/error"
+	restresource "gorest/resource"
+	"pkg/authentication/apitoken"
+	"pkg/types"
+)
+
+type APITokenManager struct {
+	apiTokens *apitoken.Manager
+}
+
+func newAPITokenManager(apiTokens *apitoken.Manager) *APITokenManager {
+	return &APITokenManager{
+		apiTokens: apiTokens,
+	}
+}
+
+//only user himself could create token, since token is shown only once
+func (m *APITokenManager) Create(ctx *restresource.Context) (restresource.Resource, *resterr.APIError) {
+	userName := ctx.Resource.GetParent().GetID()
+	if getCurrentUser(ctx) != userName {
+		return nil, resterr.NewAPIError(resterr.PermissionDenied, "only user himself could create api token")
+	}
+
+	token := ctx.Resource.(*types.APIToken)
+	if token.ExpireDays < 0 {
+		return nil, resterr.NewAPIError(resterr.InvalidFormat, "expire days should not be negative")
+	}
+
+	created, err := m.apiTokens.Create(userName, token)
+	if err != nil {
+		return nil, resterr.NewAPIError(resterr.DuplicateResource, err.Error())
+	}
+	//token is returned in a new resource, so it isn't recorded in audit
+	//log with the request, parent is needed to generate links
+	created.SetParent(token.GetParent())
+	return created, nil
+}
+
+func (m *APITokenManager) Get(ctx *restresource.Context) (restresource.Resource, *resterr.APIError) {
+	userName := ctx.Resource.GetParent().GetID()
+	if isAdminOrSelf(getCurrentUser(ctx), userName) == false {
+		return nil, nil
+	}
+
+	target := ctx.Resource.GetID()
+	if token := m.apiTokens.Get(userName, target); token != nil {
+		return token, nil
+	}
+	return nil, resterr.NewAPIError(resterr.NotFound, fmt.Sprintf("no found api token %s", target))
+}
+
+func (m *APITokenManager) List(ctx *restresource.Context) (interface{}, *resterr.APIError) {
+	userName := ctx.Resource.GetParent().GetID()
+	if isAdminOrSelf(getCurrentUser(ctx), userName) == false {
+		return nil, nil
+	}
+	return m.apiTokens.List(userName), nil
+}
+
+//admin could revoke token of any user
+func (m *APITokenManager) Delete(ctx *restresource.Context) *resterr.APIError {
+	userName := ctx.Resource.GetParent().GetID()
+	if isAdminOrSelf(getCurrentUser(ctx), userName) == false {
+		return resterr.NewAPIError(resterr.PermissionDenied, "only user himself or admin could revoke api token")
+	}
+
+	if err := m.apiTokens.Delete(userName, ctx.Resource.GetID()); err != nil {
+		return resterr.NewAPIError(resterr.NotFound, err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	ut "cement/unittest"
+	"gorest"
+	resterr "gorest/error"
+	restresource "gorest/resource"
+	"gorest/resource/schema"
+	"kvzoo"
+	"kvzoo/backend/memory"
+	"pkg/auditlog"
+	"pkg/auditlog/storage"
+	"pkg/authentication/apitoken"
+	"pkg/types"
+)
+
+type dumbUserManager struct{}
+
+func (m *dumbUserManager) Get(ctx *restresource.Context) (restresource.Resource, *resterr.APIError) {
+	return nil, nil
+}
+
+type auditLogRecorder struct {
+	logs []*types.AuditLog
+}
+
+func (r *auditLogRecorder) Add(log *types.AuditLog) error {
+	r.logs = append(r.logs, log)
+	return nil
+}
+
+func (r *auditLogRecorder) Query(q *storage.Query) (types.AuditLogs, string, error) {
+	return nil, "", nil
+}
+
+func TestCreateAPITokenAuditLog(t *testing.T) {
+	db, err := memory.New()
+	ut.Assert(t, err == nil, "create db should succeed: %v", err)
+	tn, _ := kvzoo.TableNameFromSegments(apitoken.APITokenTableName)
+	table, err := db.CreateOrGetTable(tn)
+	ut.Assert(t, err == nil, "create table should succeed: %v", err)
+	tokens, err := apitoken.NewManager(table)
+	ut.Assert(t, err == nil, "create api token manager should succeed: %v", err)
+
+	schemas := schema.NewSchemaManager()
+	schemas.MustImport(&Version, types.User{}, &dumbUserManager{})
+	schemas.MustImport(&Version, types.APIToken{}, newAPITokenManager(tokens))
+
+	recorder := &auditLogRecorder{}
+	audit := &auditlog.AuditLogger{
+		Storage: recorder,
+	}
+	server := gorest.NewAPIServer(schemas)
+	server.Use(audit.AuditHandler())
+	server.Use(func(ctx *restresource.Context) *resterr.APIError {
+		ctx.Request = ctx.Request.WithContext(context.WithValue(ctx.Request.Context(), types.CurrentUserKey, "ben"))
+		return nil
+	})
+	server.UseAfter(audit.RecordHandler())
+
+	req := httptest.NewRequest(http.MethodPost, "/apis/zcloud.cn/v1/users/ben/apitokens", strings.NewReader(`{"name":"ci"}`))
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, req)
+	ut.Equal(t, w.Code, http.StatusCreated)
+
+	var created types.APIToken
+	ut.Assert(t, json.Unmarshal(w.Body.Bytes(), &created) == nil, "response should be api token")
+	ut.Assert(t, apitoken.IsAPIToken(created.Token), "token should be returned: %s", created.Token)
+	ut.Equal(t, string(created.GetLinks()[restresource.SelfLink]), "/apis/zcloud.cn/v1/users/ben/apitokens/ci")
+
+	ut.Equal(t, len(recorder.logs), 1)
+	log := recorder.logs[0]
+	ut.Equal(t, log.Operation, auditlog.OperationTypeCreate)
+	ut.Equal(t, log.User, "ben")
+	ut.Assert(t, strings.Contains(log.Detail, `"name":"ci"`), "audit log should have the request: %s", log.Detail)
+	ut.Assert(t, strings.Contains(log.Detail, apitoken.TokenPrefix) == false, "audit log shouldn't have the token: %s", log.Detail)
+}
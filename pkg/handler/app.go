@@ -90,17 +90,25 @@ func (a *App) registerRestHandler(router gin.IRoutes) error {
 	schemas.MustImport(&Version, types.WorkFlow{}, newWorkFlowManager(a.clusterManager))
 	schemas.MustImport(&Version, types.WorkFlowTask{}, newWorkFlowTaskManager(a.clusterManager))
 
-	auditLogger, err := auditlog.New()
+	auditLogger, err := auditlog.New(a.conf.AuditLog)
 	if err != nil {
 		return err
 	}
+	auditLogger.SetAlarmHandler(func(kind, name, message string) {
+		alarm.New().Kind(kind).Name(name).Reason("AuditLogForwardFailed").Message(message).Publish()
+	})
 	schemas.MustImport(&Version, types.AuditLog{}, newAuditLogManager(auditLogger))
+	if err := auditLogger.RegisterHandler(router); err != nil {
+		return err
+	}
 
-	userQuotaManager, err := newUserQuotaManager(a.clusterManager)
+	userQuotaManager, err := newUserQuotaManager(a.clusterManager, a.conf.UserQuota)
 	if err != nil {
 		return err
 	}
 	schemas.MustImport(&Version, types.UserQuota{}, userQuotaManager)
+	schemas.MustImport(&Version, types.UserQuotaPolicy{}, newUserQuotaPolicyManager(userQuotaManager.policies))
+	schemas.MustImport(&Version, types.UserQuotaTemplate{}, newUserQuotaTemplateManager(userQuotaManager.templates))
 	appManager := newApplicationManager(a.clusterManager, a.conf.Chart.Path)
 	schemas.MustImport(&Version, types.Application{}, appManager)
 	schemas.MustImport(&Version, types.Monitor{}, newMonitorManager(a.clusterManager, a.conf.Chart.Path))
@@ -118,13 +126,20 @@ func (a *App) registerRestHandler(router gin.IRoutes) error {
 	schemas.MustImport(&Version, types.Threshold{}, thresholdManager)
 
 	schemas.MustImport(&Version, types.Storage{}, newStorageManager(a.clusterManager))
+	schemas.MustImport(&Version, types.DBSyncStatus{}, newDBSyncStatusManager())
 
-	userManager := newUserManager(a.clusterManager.authenticator.JwtAuth, a.clusterManager.authorizer)
+	apiTokens := a.clusterManager.authenticator.APITokens
+	userManager := newUserManager(a.clusterManager.authenticator.JwtAuth, a.clusterManager.authorizer, apiTokens)
 	schemas.MustImport(&Version, types.User{}, userManager)
+	schemas.MustImport(&Version, types.Session{}, newSessionManager(a.clusterManager.authenticator.JwtAuth))
+	schemas.MustImport(&Version, types.APIToken{}, newAPITokenManager(apiTokens))
+	schemas.MustImport(&Version, types.Group{}, newGroupManager(a.clusterManager.authorizer))
 	schemas.MustImport(&Version, types.HorizontalPodAutoscaler{}, newHorizontalPodAutoscalerManager(a.clusterManager))
 	server := gorest.NewAPIServer(schemas)
-	server.Use(a.clusterManager.authorizationHandler(a.conf.Server.EnableDebug))
 	server.Use(auditLogger.AuditHandler())
+	server.Use(a.clusterManager.authorizationHandler(a.conf.Server.EnableDebug))
+	server.Use(auditLogger.DiffHandler())
+	server.UseAfter(auditLogger.RecordHandler())
 
 	adaptor.RegisterHandler(router, server, schemas.GenerateResourceRoute())
 	return nil
@@ -19,10 +19,26 @@ func newAuditLogManager(audit *auditlog.AuditLogger) *AuditLogManager {
 	}
 }
 
+//marker to get next page is returned in response header, logs are
+//queried and paginated by storage, so gorest doesn't apply the query
 func (a *AuditLogManager) List(ctx *resource.Context) (interface{}, *resterr.APIError) {
-	logs, err := a.audit.List(getCurrentUser(ctx))
+	ctx.SkipListQuery()
+	q, err := auditlog.ParseQuery(ctx.Request.URL.Query())
+	if err != nil {
+		return nil, resterr.NewAPIError(resterr.InvalidOption, err.Error())
+	}
+
+	if q.Limit == 0 || q.Limit > auditlog.MaxQueryLimit {
+		q.Limit = auditlog.MaxQueryLimit
+	}
+
+	logs, marker, err := a.audit.Query(getCurrentUser(ctx), q)
 	if err != nil {
 		return nil, resterr.NewAPIError(resterr.ServerError, fmt.Sprintf("list audit log failed %s", err.Error()))
 	}
+
+	if marker != "" {
+		ctx.Response.Header().Set(auditlog.NextMarkerHeader, marker)
+	}
 	return logs, nil
 }
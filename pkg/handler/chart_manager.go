@@ -69,7 +69,9 @@ func newChartManager(chartDir, repoUrl string) *ChartManager {
 	return &ChartManager{chartDir: chartDir}
 }
 
+//filters select the chart dir, they aren't applied to the charts
 func (m *ChartManager) List(ctx *resource.Context) (interface{}, *resterror.APIError) {
+	ctx.SkipListFilter()
 	charts, err := getCharts(m.chartDir, ctx.GetFilters(), false)
 	if err != nil {
 		return nil, resterror.NewAPIError(resterror.ServerError, fmt.Sprintf("list charts info failed:%s", err.Error()))
@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+
+	resterr "gorest/error"
+	restresource "gorest/resource"
+	"pkg/authorization"
+	"pkg/types"
+)
+
+//namespace itself is authorized in the namespace except create, update
+//and delete which change the cluster, so the user can get the namespace
+//and call its actions with the role of the namespace
+func authorizeClusterResource(authorizer *authorization.Authorizer, user string, r restresource.Resource, ancestors []restresource.Resource, verb string, enableDebug bool) *resterr.APIError {
+	cluster, ok := ancestors[0].(*types.Cluster)
+	if ok == false {
+		return nil
+	}
+
+	kind := restresource.DefaultKindName(r)
+	var namespace string
+	if len(ancestors) == 1 {
+		if _, ok := r.(*types.Namespace); ok && r.GetID() != "" && isNamespaceLevelVerb(verb) {
+			namespace = r.GetID()
+		}
+	} else if _, ok := ancestors[1].(*types.Namespace); ok {
+		namespace = ancestors[1].GetID()
+	} else {
+		return nil
+	}
+
+	if namespace == "" {
+		if authorizer.AuthorizeOperation(user, cluster.GetID(), "", kind, verb) == false {
+			return resterr.NewAPIError(resterr.PermissionDenied, fmt.Sprintf("user %s has no sufficient permission to %s %s in cluster %s", user, verb, kind, cluster.GetID()))
+		}
+		return nil
+	}
+
+	if !IsNamespaceVisiable(namespace, enableDebug) {
+		return resterr.NewAPIError(resterr.PermissionDenied, fmt.Sprintf("user %s has no sufficient permission to work on namespace %s", user, namespace))
+	}
+
+	if authorizer.AuthorizeOperation(user, cluster.GetID(), namespace, kind, verb) == false {
+		return resterr.NewAPIError(resterr.PermissionDenied, fmt.Sprintf("user %s has no sufficient permission to %s %s in cluster %s namespace %s", user, verb, kind, cluster.GetID(), namespace))
+	}
+	return nil
+}
+
+func isNamespaceLevelVerb(verb string) bool {
+	return verb != authorization.VerbCreate && verb != authorization.VerbUpdate && verb != authorization.VerbDelete
+}
+
+func getVerb(ctx *restresource.Context) string {
+	if action := ctx.Resource.GetAction(); action != nil {
+		return authorization.ActionVerb(action.Name)
+	}
+
+	switch ctx.Request.Method {
+	case http.MethodPost:
+		return authorization.VerbCreate
+	case http.MethodPut, http.MethodPatch:
+		return authorization.VerbUpdate
+	case http.MethodDelete:
+		return authorization.VerbDelete
+	default:
+		return authorization.VerbGet
+	}
+}
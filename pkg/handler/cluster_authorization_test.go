@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	ut "cement/unittest"
+	restresource "gorest/resource"
+	"kvzoo/backend/memory"
+	"pkg/authorization"
+	"pkg/types"
+)
+
+func newTestAuthorizer(t *testing.T) *authorization.Authorizer {
+	db, err := memory.New()
+	ut.Assert(t, err == nil, "create db should succeed: %v", err)
+	auth, err := authorization.NewWithDB(db)
+	ut.Assert(t, err == nil, "create authorizer should succeed: %v", err)
+
+	for name, role := range map[string]string{
+		"viewer":  types.RoleViewer,
+		"nsadmin": types.RoleNamespaceAdmin,
+	} {
+		user := &types.User{
+			Projects: []types.Project{
+				types.Project{Cluster: "local", Namespace: "default", Role: role},
+			},
+		}
+		user.SetID(name)
+		ut.Assert(t, auth.AddUser(user) == nil, "add user should succeed")
+	}
+	return auth
+}
+
+func authorizeRequest(auth *authorization.Authorizer, user, method string, r restresource.Resource) bool {
+	ctx := &restresource.Context{
+		Resource: r,
+		Request:  httptest.NewRequest(method, "/", nil),
+	}
+	verb := getVerb(ctx)
+	return authorizeClusterResource(auth, user, r, restresource.GetAncestors(r), verb, false) == nil
+}
+
+func newTestNamespace(name string) *types.Namespace {
+	cluster := &types.Cluster{}
+	cluster.SetID("local")
+	namespace := &types.Namespace{}
+	namespace.SetID(name)
+	namespace.SetParent(cluster)
+	return namespace
+}
+
+func TestAuthorizeNamespaceAction(t *testing.T) {
+	auth := newTestAuthorizer(t)
+
+	for _, user := range []string{"viewer", "nsadmin"} {
+		namespace := newTestNamespace("default")
+		namespace.SetAction(&restresource.Action{Name: types.ActionSearchPod})
+		ut.Assert(t, authorizeRequest(auth, user, http.MethodPost, namespace), "%s should search pod in its namespace", user)
+
+		namespace = newTestNamespace("default")
+		ut.Assert(t, authorizeRequest(auth, user, http.MethodGet, namespace), "%s should get its namespace", user)
+
+		namespace = newTestNamespace("test")
+		namespace.SetAction(&restresource.Action{Name: types.ActionSearchPod})
+		ut.Assert(t, authorizeRequest(auth, user, http.MethodPost, namespace) == false, "%s shouldn't search pod in other namespace", user)
+
+		namespace = newTestNamespace("default")
+		ut.Assert(t, authorizeRequest(auth, user, http.MethodDelete, namespace) == false, "%s shouldn't delete namespace", user)
+	}
+}
+
+func TestAuthorizeDeploymentAction(t *testing.T) {
+	auth := newTestAuthorizer(t)
+
+	newDeployment := func(action string) *types.Deployment {
+		deployment := &types.Deployment{}
+		deployment.SetID("web")
+		deployment.SetParent(newTestNamespace("default"))
+		deployment.SetAction(&restresource.Action{Name: action})
+		return deployment
+	}
+
+	for _, user := range []string{"viewer", "nsadmin"} {
+		ut.Assert(t, authorizeRequest(auth, user, http.MethodPost, newDeployment(types.ActionGetHistory)), "%s should get deployment history", user)
+	}
+	ut.Assert(t, authorizeRequest(auth, "nsadmin", http.MethodPost, newDeployment(types.ActionRollback)), "namespace admin should rollback deployment")
+	ut.Assert(t, authorizeRequest(auth, "viewer", http.MethodPost, newDeployment(types.ActionRollback)) == false, "viewer shouldn't rollback deployment")
+}
@@ -137,10 +137,15 @@ func getClusterInfo(zkeCluster *zke.Cluster, sc *types.Cluster) *types.Cluster {
 func (m *ClusterManager) List(ctx *restresource.Context) (interface{}, *resterr.APIError) {
 	requestFlags := ctx.Request.URL.Query()
 	user := getCurrentUser(ctx)
+	scope, _ := ctx.Request.Context().Value(types.APITokenScopeKey).(*types.APITokenScope)
 	var readyClusters []*types.Cluster
 	var allClusters []*types.Cluster
 
 	for _, c := range m.zkeManager.List() {
+		if scope != nil && scope.Cluster != "" && scope.Cluster != c.Name {
+			continue
+		}
+
 		if m.authorizer.Authorize(user, c.Name, "") {
 			sc := c.ToScCluster()
 			allClusters = append(allClusters, sc)
@@ -201,26 +206,78 @@ func (m *ClusterManager) authorizationHandler(enableDebug bool) gorest.HandlerFu
 			m.authorizer.AddUser(newUser)
 		}
 
+		verb := getVerb(ctx)
+		if err := m.checkTOTPEnrollment(ctx, user, verb); err != nil {
+			return err
+		}
+
 		ancestors := restresource.GetAncestors(ctx.Resource)
-		if len(ancestors) < 2 {
+		if err := checkAPITokenScope(ctx, ancestors, verb); err != nil {
+			return err
+		}
+
+		if len(ancestors) == 0 {
 			return nil
 		}
 
-		if _, ok := ancestors[0].(*types.Cluster); ok {
-			if _, ok := ancestors[1].(*types.Namespace); ok {
-				cluster := ancestors[0].GetID()
-				namespace := ancestors[1].GetID()
-				if !IsNamespaceVisiable(namespace, enableDebug) {
-					return resterr.NewAPIError(resterr.PermissionDenied, fmt.Sprintf("user %s has no sufficient permission to work on namespace %s", user, namespace))
-				}
-
-				if m.authorizer.Authorize(user, cluster, namespace) == false {
-					return resterr.NewAPIError(resterr.PermissionDenied, fmt.Sprintf("user %s has no sufficient permission to work on cluster %s namespace %s", user, cluster, namespace))
-				}
-			}
+		return authorizeClusterResource(m.authorizer, user, ctx.Resource, ancestors, verb, enableDebug)
+	}
+}
+
+//admin which is required to use totp can only get itself and enroll
+//totp before totp is enabled
+func (m *ClusterManager) checkTOTPEnrollment(ctx *restresource.Context, user, verb string) *resterr.APIError {
+	if m.authenticator.JwtAuth.TOTPEnrollmentRequired(user) == false {
+		return nil
+	}
+
+	if _, ok := ctx.Resource.(*types.User); ok && ctx.Resource.GetID() == user {
+		switch verb {
+		case authorization.VerbGet, types.ActionEnableTOTP, types.ActionConfirmTOTP:
+			return nil
 		}
+	}
+	return resterr.NewAPIError(resterr.PermissionDenied, fmt.Sprintf("user %s should enable totp first", user))
+}
+
+//api token can only do what its scope allows in addition to the
+//permission of its user, token can't manage tokens, otherwise a
+//scoped token could create an unscoped one
+func checkAPITokenScope(ctx *restresource.Context, ancestors []restresource.Resource, verb string) *resterr.APIError {
+	scope, ok := ctx.Request.Context().Value(types.APITokenScopeKey).(*types.APITokenScope)
+	if ok == false {
+		return nil
+	}
+
+	if _, ok := ctx.Resource.(*types.APIToken); ok && verb != authorization.VerbGet {
+		return resterr.NewAPIError(resterr.PermissionDenied, "api token can't be managed by api token")
+	}
+
+	if scope.ReadOnly && verb != authorization.VerbGet {
+		return resterr.NewAPIError(resterr.PermissionDenied, fmt.Sprintf("api token is read only, can't %s", verb))
+	}
+
+	if scope.Cluster == "" {
 		return nil
 	}
+
+	var cluster string
+	if _, ok := ctx.Resource.(*types.Cluster); ok {
+		//list clusters is allowed, other clusters are filtered out
+		//in list
+		if ctx.Resource.GetID() == "" && verb == authorization.VerbGet {
+			return nil
+		}
+		cluster = ctx.Resource.GetID()
+	} else if len(ancestors) > 0 {
+		if _, ok := ancestors[0].(*types.Cluster); ok {
+			cluster = ancestors[0].GetID()
+		}
+	}
+	if cluster != scope.Cluster {
+		return resterr.NewAPIError(resterr.PermissionDenied, fmt.Sprintf("api token is only for cluster %s", scope.Cluster))
+	}
+	return nil
 }
 
 type StorageNodeListener struct {
@@ -0,0 +1,38 @@
+package handler
+
+import (
+	"pkg/db"
+	"pkg/types"
+
+	resterr "gorest/error"
+	"gorest/resource"
+)
+
+type DBSyncStatusManager struct{}
+
+func newDBSyncStatusManager() *DBSyncStatusManager {
+	return &DBSyncStatusManager{}
+}
+
+func (m *DBSyncStatusManager) List(ctx *resource.Context) (interface{}, *resterr.APIError) {
+	if isAdmin(getCurrentUser(ctx)) == false {
+		return nil, resterr.NewAPIError(resterr.PermissionDenied, "only admin can get db sync status")
+	}
+
+	var statuses []*types.DBSyncStatus
+	for _, s := range db.GetSyncStatus() {
+		status := &types.DBSyncStatus{
+			State:          string(s.State),
+			StaleTables:    s.StaleTables,
+			SyncedTables:   s.SyncedTables,
+			LastError:      s.LastError,
+			LastSyncTime:   resource.ISOTime(s.LastSyncTime),
+			LogIndex:       s.LogIndex,
+			MasterLogIndex: s.MasterLogIndex,
+			Fenced:         s.Fenced,
+		}
+		status.SetID(s.Addr)
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
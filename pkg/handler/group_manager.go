@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"fmt"
+	"time"
+
+	"cement/slice"
+	resterr "gorest/error"
+	restresource "gorest/resource"
+	"pkg/authorization"
+	"pkg/types"
+)
+
+type GroupManager struct {
+	authorizer *authorization.Authorizer
+}
+
+func newGroupManager(authorizer *authorization.Authorizer) *GroupManager {
+	return &GroupManager{
+		authorizer: authorizer,
+	}
+}
+
+func (m *GroupManager) Create(ctx *restresource.Context) (restresource.Resource, *resterr.APIError) {
+	if isAdmin(getCurrentUser(ctx)) == false {
+		return nil, resterr.NewAPIError(resterr.PermissionDenied, "only admin can create group")
+	}
+
+	group := ctx.Resource.(*types.Group)
+	group.SetID(group.Name)
+	group.SetCreationTimestamp(time.Now())
+	if err := m.authorizer.AddGroup(group); err != nil {
+		return nil, resterr.NewAPIError(resterr.DuplicateResource, "duplicate group name")
+	}
+	return group, nil
+}
+
+//user can only get the group he belongs to
+func (m *GroupManager) Get(ctx *restresource.Context) (restresource.Resource, *resterr.APIError) {
+	currentUser := getCurrentUser(ctx)
+	target := ctx.Resource.GetID()
+	group := m.authorizer.GetGroup(target)
+	if group == nil {
+		return nil, resterr.NewAPIError(resterr.NotFound, fmt.Sprintf("no found group %s", target))
+	}
+
+	if isAdmin(currentUser) == false && slice.SliceIndex(group.Users, currentUser) == -1 {
+		return nil, nil
+	}
+	return group, nil
+}
+
+func (m *GroupManager) Delete(ctx *restresource.Context) *resterr.APIError {
+	if isAdmin(getCurrentUser(ctx)) == false {
+		return resterr.NewAPIError(resterr.PermissionDenied, "only admin can delete group")
+	}
+
+	if err := m.authorizer.DeleteGroup(ctx.Resource.GetID()); err != nil {
+		return resterr.NewAPIError(resterr.NotFound, err.Error())
+	}
+	return nil
+}
+
+func (m *GroupManager) Update(ctx *restresource.Context) (restresource.Resource, *resterr.APIError) {
+	if isAdmin(getCurrentUser(ctx)) == false {
+		return nil, resterr.NewAPIError(resterr.PermissionDenied, "only admin could update group")
+	}
+
+	group := ctx.Resource.(*types.Group)
+	if err := m.authorizer.UpdateGroup(group); err != nil {
+		return nil, resterr.NewAPIError(resterr.NotFound, err.Error())
+	}
+	return group, nil
+}
+
+func (m *GroupManager) List(ctx *restresource.Context) (interface{}, *resterr.APIError) {
+	currentUser := getCurrentUser(ctx)
+	groups := m.authorizer.ListGroup()
+	if isAdmin(currentUser) {
+		return groups, nil
+	}
+
+	var groupsOfUser []*types.Group
+	for _, group := range groups {
+		if slice.SliceIndex(group.Users, currentUser) != -1 {
+			groupsOfUser = append(groupsOfUser, group)
+		}
+	}
+	return groupsOfUser, nil
+}
@@ -50,10 +50,6 @@ func newNamespaceManager(clusters *ClusterManager, enableDebug bool) (*Namespace
 }
 
 func (m *NamespaceManager) Create(ctx *resource.Context) (resource.Resource, *resterror.APIError) {
-	if isAdmin(getCurrentUser(ctx)) == false {
-		return nil, resterror.NewAPIError(resterror.PermissionDenied, "only admin can create namespace")
-	}
-
 	cluster := m.clusters.GetClusterForSubResource(ctx.Resource)
 	if cluster == nil {
 		return nil, resterror.NewAPIError(resterror.NotFound, "cluster doesn't exist")
@@ -133,10 +129,6 @@ func (m *NamespaceManager) Delete(ctx *resource.Context) *resterror.APIError {
 		return resterror.NewAPIError(resterror.PermissionDenied, "system namespace can only be deleted at debug mod")
 	}
 
-	if isAdmin(getCurrentUser(ctx)) == false {
-		return resterror.NewAPIError(resterror.PermissionDenied, "only admin can delete namespace")
-	}
-
 	cluster := m.clusters.GetClusterForSubResource(ctx.Resource)
 	if cluster == nil {
 		return resterror.NewAPIError(resterror.NotFound, "cluster doesn't exist")
@@ -218,10 +218,6 @@ func getRoleFromLabels(labels map[string]string) []types.NodeRole {
 }
 
 func (m *NodeManager) Action(ctx *restresource.Context) (interface{}, *resterr.APIError) {
-	if isAdmin(getCurrentUser(ctx)) == false {
-		return nil, resterr.NewAPIError(resterr.PermissionDenied, "only admin can call node action api")
-	}
-
 	cluster := m.clusters.GetClusterForSubResource(ctx.Resource)
 	if cluster == nil {
 		return nil, resterr.NewAPIError(resterr.NotFound, "cluster doesn't exist")
@@ -0,0 +1,55 @@
+package handler
+
+import (
+	"fmt"
+
+	resterr "gorest/error"
+	restresource "gorest/resource"
+	"pkg/authentication/jwt"
+)
+
+type SessionManager struct {
+	authenticator *jwt.Authenticator
+}
+
+func newSessionManager(authenticator *jwt.Authenticator) *SessionManager {
+	return &SessionManager{
+		authenticator: authenticator,
+	}
+}
+
+func (m *SessionManager) List(ctx *restresource.Context) (interface{}, *resterr.APIError) {
+	userName := ctx.Resource.GetParent().GetID()
+	if isAdminOrSelf(getCurrentUser(ctx), userName) == false {
+		return nil, nil
+	}
+	return m.authenticator.ListSessions(userName), nil
+}
+
+func (m *SessionManager) Get(ctx *restresource.Context) (restresource.Resource, *resterr.APIError) {
+	userName := ctx.Resource.GetParent().GetID()
+	if isAdminOrSelf(getCurrentUser(ctx), userName) == false {
+		return nil, nil
+	}
+
+	target := ctx.Resource.GetID()
+	for _, session := range m.authenticator.ListSessions(userName) {
+		if session.GetID() == target {
+			return session, nil
+		}
+	}
+	return nil, resterr.NewAPIError(resterr.NotFound, fmt.Sprintf("no found session %s", target))
+}
+
+//token of the session is rejected after it's revoked
+func (m *SessionManager) Delete(ctx *restresource.Context) *resterr.APIError {
+	userName := ctx.Resource.GetParent().GetID()
+	if isAdminOrSelf(getCurrentUser(ctx), userName) == false {
+		return resterr.NewAPIError(resterr.PermissionDenied, "only user himself or admin could revoke session")
+	}
+
+	if err := m.authenticator.RevokeSession(userName, ctx.Resource.GetID()); err != nil {
+		return resterr.NewAPIError(resterr.NotFound, err.Error())
+	}
+	return nil
+}
@@ -120,9 +120,6 @@ func (m *StorageManager) getStorage(cluster *zke.Cluster, name string) (*types.S
 }
 
 func (m *StorageManager) Delete(ctx *resource.Context) *resterr.APIError {
-	if isAdmin(getCurrentUser(ctx)) == false {
-		return resterr.NewAPIError(resterr.PermissionDenied, "only admin can delete nfs")
-	}
 	cluster := m.clusters.GetClusterForSubResource(ctx.Resource)
 	if cluster == nil {
 		return resterr.NewAPIError(resterr.NotFound, "storage doesn't exist")
@@ -153,9 +150,6 @@ func (m *StorageManager) deleteStorage(cluster *zke.Cluster, name string) error
 }
 
 func (m *StorageManager) Create(ctx *resource.Context) (resource.Resource, *resterr.APIError) {
-	if isAdmin(getCurrentUser(ctx)) == false {
-		return nil, resterr.NewAPIError(resterr.PermissionDenied, "only admin can create storage")
-	}
 	cluster := m.clusters.GetClusterForSubResource(ctx.Resource)
 	if cluster == nil {
 		return nil, resterr.NewAPIError(resterr.NotFound, "cluster doesn't exist")
@@ -201,9 +195,6 @@ func (m *StorageManager) checkStorageExist(cli client.Client, name string) (bool
 }
 
 func (m *StorageManager) Update(ctx *resource.Context) (resource.Resource, *resterr.APIError) {
-	if isAdmin(getCurrentUser(ctx)) == false {
-		return nil, resterr.NewAPIError(resterr.PermissionDenied, "only admin can update storage")
-	}
 	cluster := m.clusters.GetClusterForSubResource(ctx.Resource)
 	if cluster == nil {
 		return nil, resterr.NewAPIError(resterr.NotFound, "cluster doesn't exist")
@@ -7,6 +7,7 @@ import (
 	"cement/log"
 	resterr "gorest/error"
 	restresource "gorest/resource"
+	"pkg/authentication/apitoken"
 	"pkg/authentication/jwt"
 	"pkg/authorization"
 	"pkg/types"
@@ -15,12 +16,14 @@ import (
 type UserManager struct {
 	authorizer    *authorization.Authorizer
 	authenticator *jwt.Authenticator
+	apiTokens     *apitoken.Manager
 }
 
-func newUserManager(authenticator *jwt.Authenticator, authorizer *authorization.Authorizer) *UserManager {
+func newUserManager(authenticator *jwt.Authenticator, authorizer *authorization.Authorizer, apiTokens *apitoken.Manager) *UserManager {
 	return &UserManager{
 		authenticator: authenticator,
 		authorizer:    authorizer,
+		apiTokens:     apiTokens,
 	}
 }
 
@@ -53,6 +56,10 @@ func (m *UserManager) Get(ctx *restresource.Context) (restresource.Resource, *re
 	}
 
 	if user := m.authorizer.GetUser(target); user != nil {
+		if isAdmin(currentUser) {
+			m.setLockStatus(user)
+		}
+		user.TOTPEnabled = m.authenticator.IsTOTPEnabled(target)
 		return user, nil
 	} else {
 		return nil, resterr.NewAPIError(resterr.NotFound, fmt.Sprintf("no found user %s", target))
@@ -65,12 +72,24 @@ func (m *UserManager) Delete(ctx *restresource.Context) *resterr.APIError {
 	}
 
 	userName := ctx.Resource.GetID()
-	if err := m.authenticator.DeleteUser(userName); err != nil {
-		return resterr.NewAPIError(resterr.NotFound, err.Error())
+	if m.authorizer.GetUser(userName) == nil {
+		return resterr.NewAPIError(resterr.NotFound, fmt.Sprintf("no found user %s", userName))
+	}
+
+	//ldap and oidc users are only saved in authorizer
+	if m.authenticator.HasUser(userName) {
+		if err := m.authenticator.DeleteUser(userName); err != nil {
+			return resterr.NewAPIError(resterr.NotFound, err.Error())
+		}
+	} else if err := m.authenticator.DeleteExternalUser(userName); err != nil {
+		return resterr.NewAPIError(resterr.ServerError, err.Error())
 	}
 	if err := m.authorizer.DeleteUser(userName); err != nil {
 		return resterr.NewAPIError(resterr.NotFound, err.Error())
 	}
+	if err := m.apiTokens.DeleteUserTokens(userName); err != nil {
+		return resterr.NewAPIError(resterr.ServerError, err.Error())
+	}
 	return nil
 }
 
@@ -99,9 +118,14 @@ func (m *UserManager) List(ctx *restresource.Context) (interface{}, *resterr.API
 	var users []*types.User
 	if isAdmin(currentUser) {
 		users = m.authorizer.ListUser()
+		for _, user := range users {
+			m.setLockStatus(user)
+			user.TOTPEnabled = m.authenticator.IsTOTPEnabled(user.GetID())
+		}
 	} else {
 		user := m.authorizer.GetUser(currentUser)
 		if user != nil {
+			user.TOTPEnabled = m.authenticator.IsTOTPEnabled(currentUser)
 			users = []*types.User{user}
 		} else {
 			log.Errorf("user %s is deleted during request", currentUser)
@@ -117,6 +141,16 @@ func (m *UserManager) Action(ctx *restresource.Context) (interface{}, *resterr.A
 		return m.login(ctx)
 	case types.ActionResetPassword:
 		return nil, m.resetPassword(ctx)
+	case types.ActionRevokeSessions:
+		return nil, m.revokeSessions(ctx)
+	case types.ActionUnlock:
+		return nil, m.unlock(ctx)
+	case types.ActionEnableTOTP:
+		return m.enableTOTP(ctx)
+	case types.ActionConfirmTOTP:
+		return m.confirmTOTP(ctx)
+	case types.ActionDisableTOTP:
+		return nil, m.disableTOTP(ctx)
 	default:
 		return nil, nil
 	}
@@ -129,16 +163,28 @@ func (m *UserManager) login(ctx *restresource.Context) (interface{}, *resterr.AP
 		return nil, resterr.NewAPIError(resterr.InvalidFormat, "login param not valid")
 	}
 
-	if up.Password == "" {
-		return nil, resterr.NewAPIError(resterr.NotNullable, "empty password")
+	userName := ctx.Resource.GetID()
+	clientIP := jwt.ClientIP(ctx.Request)
+	var info *types.LoginInfo
+	var err error
+	if up.Challenge != "" {
+		if up.TOTPCode == "" {
+			return nil, resterr.NewAPIError(resterr.NotNullable, "empty totp code")
+		}
+		info, err = m.authenticator.CreateTokenWithTOTP(userName, up.Challenge, up.TOTPCode, clientIP)
+	} else if up.LDAPPassword != "" {
+		info, err = m.authenticator.CreateExternalToken(userName, up.LDAPPassword, clientIP)
+	} else {
+		if up.Password == "" {
+			return nil, resterr.NewAPIError(resterr.NotNullable, "empty password")
+		}
+		info, err = m.authenticator.CreateToken(userName, up.Password, clientIP)
 	}
 
-	userName := ctx.Resource.GetID()
-	token, err := m.authenticator.CreateToken(userName, up.Password)
 	if err != nil {
 		return nil, resterr.NewAPIError(resterr.InvalidBodyContent, err.Error())
 	} else {
-		return types.LoginInfo{token}, nil
+		return *info, nil
 	}
 }
 
@@ -160,6 +206,87 @@ func (m *UserManager) resetPassword(ctx *restresource.Context) *resterr.APIError
 	return nil
 }
 
+func (m *UserManager) revokeSessions(ctx *restresource.Context) *resterr.APIError {
+	userName := ctx.Resource.GetID()
+	if isAdminOrSelf(getCurrentUser(ctx), userName) == false {
+		return resterr.NewAPIError(resterr.PermissionDenied, "only user himself or admin could revoke sessions")
+	}
+
+	if err := m.authenticator.RevokeUserSessions(userName); err != nil {
+		return resterr.NewAPIError(resterr.ServerError, err.Error())
+	}
+	return nil
+}
+
+func (m *UserManager) unlock(ctx *restresource.Context) *resterr.APIError {
+	if isAdmin(getCurrentUser(ctx)) == false {
+		return resterr.NewAPIError(resterr.PermissionDenied, "only admin could unlock user")
+	}
+
+	m.authenticator.Unlock(ctx.Resource.GetID())
+	return nil
+}
+
+func (m *UserManager) enableTOTP(ctx *restresource.Context) (interface{}, *resterr.APIError) {
+	userName := ctx.Resource.GetID()
+	if getCurrentUser(ctx) != userName {
+		return nil, resterr.NewAPIError(resterr.PermissionDenied, "only user himself could enable totp")
+	}
+
+	provision, err := m.authenticator.EnableTOTP(userName)
+	if err != nil {
+		return nil, resterr.NewAPIError(resterr.InvalidBodyContent, err.Error())
+	}
+	return provision, nil
+}
+
+func (m *UserManager) confirmTOTP(ctx *restresource.Context) (interface{}, *resterr.APIError) {
+	action := ctx.Resource.GetAction()
+	param, ok := action.Input.(*types.TOTPCode)
+	if ok == false {
+		return nil, resterr.NewAPIError(resterr.InvalidFormat, "totp code param not valid")
+	}
+
+	userName := ctx.Resource.GetID()
+	if getCurrentUser(ctx) != userName {
+		return nil, resterr.NewAPIError(resterr.PermissionDenied, "only user himself could confirm totp")
+	}
+
+	codes, err := m.authenticator.ConfirmTOTP(userName, param.Code)
+	if err != nil {
+		return nil, resterr.NewAPIError(resterr.InvalidBodyContent, err.Error())
+	}
+	return &types.TOTPRecoveryCodes{RecoveryCodes: codes}, nil
+}
+
+//admin could disable totp of other user without code, for example
+//the user lost the device and recovery codes
+func (m *UserManager) disableTOTP(ctx *restresource.Context) *resterr.APIError {
+	action := ctx.Resource.GetAction()
+	param, ok := action.Input.(*types.TOTPCode)
+	if ok == false {
+		return resterr.NewAPIError(resterr.InvalidFormat, "totp code param not valid")
+	}
+
+	currentUser := getCurrentUser(ctx)
+	userName := ctx.Resource.GetID()
+	if isAdminOrSelf(currentUser, userName) == false {
+		return resterr.NewAPIError(resterr.PermissionDenied, "only user himself or admin could disable totp")
+	}
+
+	if err := m.authenticator.DisableTOTP(userName, param.Code, currentUser != userName); err != nil {
+		return resterr.NewAPIError(resterr.PermissionDenied, err.Error())
+	}
+	return nil
+}
+
+func (m *UserManager) setLockStatus(user *types.User) {
+	if until := m.authenticator.LockedUntil(user.GetID()); until.IsZero() == false {
+		user.Locked = true
+		user.LockedUntil = restresource.ISOTime(until)
+	}
+}
+
 func getCurrentUser(ctx *restresource.Context) string {
 	currentUser := ctx.Request.Context().Value(types.CurrentUserKey)
 	if currentUser == nil {
@@ -171,3 +298,7 @@ func getCurrentUser(ctx *restresource.Context) string {
 func isAdmin(user string) bool {
 	return user == types.Administrator
 }
+
+func isAdminOrSelf(currentUser, userName string) bool {
+	return isAdmin(currentUser) || currentUser == userName
+}
@@ -6,34 +6,79 @@ import (
 	"fmt"
 	"regexp"
 	"sort"
+	"sync"
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	apiresource "k8s.io/apimachinery/pkg/api/resource"
 
+	"cement/log"
+	"config"
 	"gok8s/client"
 	resterror "gorest/error"
 	"gorest/resource"
 	"kvzoo"
+	"pkg/alarm"
 	"pkg/db"
 	"pkg/types"
+	"pkg/userquota"
 )
 
-const UserQuotaTable = "userquota"
+const (
+	UserQuotaTable = "userquota"
+	//how often expired quotas are checked
+	userQuotaReclaimInterval = time.Minute
+)
 
 type UserQuotaManager struct {
-	clusters *ClusterManager
-	db       kvzoo.Table
+	clusters  *ClusterManager
+	db        kvzoo.Table
+	policies  *userquota.Policies
+	templates *userquota.Templates
+	notifier  *userquota.Notifier
+	//approvals are serialized, so the quotas used to match policy
+	//don't change before the quota is approved
+	approveLock sync.Mutex
 }
 
-func newUserQuotaManager(clusters *ClusterManager) (*UserQuotaManager, error) {
-	tn, _ := kvzoo.TableNameFromSegments(UserQuotaTable)
-	table, err := db.GetGlobalDB().CreateOrGetTable(tn)
+func newUserQuotaManager(clusters *ClusterManager, conf config.UserQuotaConf) (*UserQuotaManager, error) {
+	tables := make(map[string]kvzoo.Table)
+	for _, name := range []string{UserQuotaTable, userquota.UserQuotaPolicyTable, userquota.UserQuotaTemplateTable, types.ThresholdTable} {
+		tn, _ := kvzoo.TableNameFromSegments(name)
+		table, err := db.GetGlobalDB().CreateOrGetTable(tn)
+		if err != nil {
+			return nil, fmt.Errorf("new userquota manager failed: %s", err.Error())
+		}
+		tables[name] = table
+	}
+
+	policies, err := userquota.NewPolicies(tables[userquota.UserQuotaPolicyTable])
 	if err != nil {
-		return nil, fmt.Errorf("new userquota manager failed: %s", err.Error())
+		return nil, fmt.Errorf("load userquota policies failed: %s", err.Error())
 	}
-	return &UserQuotaManager{clusters: clusters, db: table}, nil
+
+	templates, err := userquota.NewTemplates(tables[userquota.UserQuotaTemplateTable])
+	if err != nil {
+		return nil, fmt.Errorf("load userquota templates failed: %s", err.Error())
+	}
+
+	if err := migrateNamespaceCreated(tables[UserQuotaTable]); err != nil {
+		return nil, fmt.Errorf("migrate userquotas failed: %s", err.Error())
+	}
+
+	thresholdTable := tables[types.ThresholdTable]
+	m := &UserQuotaManager{
+		clusters:  clusters,
+		db:        tables[UserQuotaTable],
+		policies:  policies,
+		templates: templates,
+		notifier: userquota.NewNotifier(conf, func(to []string, subject, body string) error {
+			return alarm.SendMailTo(thresholdTable, to, subject, body)
+		}),
+	}
+	go m.reclaimExpiredQuotas()
+	return m, nil
 }
 
 func (m *UserQuotaManager) Create(ctx *resource.Context) (resource.Resource, *resterror.APIError) {
@@ -43,6 +88,10 @@ func (m *UserQuotaManager) Create(ctx *resource.Context) (resource.Resource, *re
 	}
 
 	userQuota := ctx.Resource.(*types.UserQuota)
+	if err := m.templates.Apply(userQuota); err != nil {
+		return nil, resterror.NewAPIError(types.InvalidClusterConfig, fmt.Sprintf("params is invalid: %s", err.Error()))
+	}
+
 	if err := checkUserQuotaParamsValid(userQuota); err != nil {
 		return nil, resterror.NewAPIError(types.InvalidClusterConfig, fmt.Sprintf("params is invalid: %s", err.Error()))
 	}
@@ -71,30 +120,22 @@ func (m *UserQuotaManager) Create(ctx *resource.Context) (resource.Resource, *re
 			fmt.Sprintf("commit userquota table failed: %s", err.Error()))
 	}
 
+	m.notifier.Notify(userQuota)
+	if approved := m.autoApprove(userQuota); approved != nil {
+		return approved, nil
+	}
 	return userQuota, nil
 }
 
 func (m *UserQuotaManager) List(ctx *resource.Context) (interface{}, *resterror.APIError) {
 	userName := getCurrentUser(ctx)
-	tx, err := m.db.Begin()
-	if err != nil {
-		return nil, resterror.NewAPIError(resterror.ServerError, fmt.Sprintf("list user %s quotas failed %s", userName, err.Error()))
-	}
-
-	defer tx.Commit()
-	values, err := tx.List()
+	quotas, err := m.listUserQuotas()
 	if err != nil {
 		return nil, resterror.NewAPIError(resterror.ServerError, fmt.Sprintf("list user %s quotas failed %s", userName, err.Error()))
 	}
 
 	var userQuotas types.UserQuotas
-	for _, value := range values {
-		quota, err := storageResourceValueToSCUserQuota(value)
-		if err != nil {
-			return nil, resterror.NewAPIError(resterror.ServerError,
-				fmt.Sprintf("list user %s quotas failed %s", userName, err.Error()))
-		}
-
+	for _, quota := range quotas {
 		if isAdmin(userName) == false && quota.UserName != userName {
 			continue
 		}
@@ -106,6 +147,29 @@ func (m *UserQuotaManager) List(ctx *resource.Context) (interface{}, *resterror.
 	return userQuotas, nil
 }
 
+func (m *UserQuotaManager) listUserQuotas() ([]*types.UserQuota, error) {
+	tx, err := m.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Commit()
+	values, err := tx.List()
+	if err != nil {
+		return nil, err
+	}
+
+	var quotas []*types.UserQuota
+	for _, value := range values {
+		quota, err := storageResourceValueToSCUserQuota(value)
+		if err != nil {
+			return nil, err
+		}
+		quotas = append(quotas, quota)
+	}
+	return quotas, nil
+}
+
 func (m *UserQuotaManager) Get(ctx *resource.Context) (resource.Resource, *resterror.APIError) {
 	userName := getCurrentUser(ctx)
 	userQuota := ctx.Resource.(*types.UserQuota)
@@ -135,6 +199,10 @@ func (m *UserQuotaManager) Update(ctx *resource.Context) (resource.Resource, *re
 	}
 
 	userQuota := ctx.Resource.(*types.UserQuota)
+	if err := m.templates.Apply(userQuota); err != nil {
+		return nil, resterror.NewAPIError(types.InvalidClusterConfig, fmt.Sprintf("params is invalid: %s", err.Error()))
+	}
+
 	if err := checkUserQuotaParamsValid(userQuota); err != nil {
 		return nil, resterror.NewAPIError(types.InvalidClusterConfig, fmt.Sprintf("params is invalid: %s", err.Error()))
 	}
@@ -168,6 +236,7 @@ func (m *UserQuotaManager) Update(ctx *resource.Context) (resource.Resource, *re
 	}
 
 	setUserQuota(userQuota, userName, types.TypeUserQuotaUpdate, quota.GetCreationTimestamp())
+	userQuota.NamespaceCreated = quota.NamespaceCreated
 	value, err := json.Marshal(userQuota)
 	if err != nil {
 		return nil, resterror.NewAPIError(types.ConnectClusterFailed,
@@ -184,6 +253,10 @@ func (m *UserQuotaManager) Update(ctx *resource.Context) (resource.Resource, *re
 			fmt.Sprintf("commit user_resource_quota table failed: %s", err.Error()))
 	}
 
+	m.notifier.Notify(userQuota)
+	if approved := m.autoApprove(userQuota); approved != nil {
+		return approved, nil
+	}
 	return userQuota, nil
 }
 
@@ -217,7 +290,7 @@ func (m *UserQuotaManager) Delete(ctx *resource.Context) *resterror.APIError {
 			fmt.Sprintf("can`t delete user quota which status is processing"))
 	}
 
-	if quota.ClusterName != "" {
+	if quota.ClusterName != "" && quota.NamespaceCreated {
 		cluster := m.clusters.GetClusterByName(quota.ClusterName)
 		if cluster == nil {
 			return resterror.NewAPIError(resterror.NotFound, "cluster doesn't exist")
@@ -238,20 +311,24 @@ func (m *UserQuotaManager) Delete(ctx *resource.Context) *resterror.APIError {
 			fmt.Sprintf("delete user quota failed: %v", err.Error()))
 	}
 
-	if quota.ClusterName != "" {
-		authorizer := m.clusters.GetAuthorizer()
-		user := authorizer.GetUser(quota.UserName)
-		if user != nil {
-			for i, project := range user.Projects {
-				if project.Cluster == quota.ClusterName && project.Namespace == quota.Namespace {
-					user.Projects = append(user.Projects[:i], user.Projects[i+1:]...)
-					break
-				}
+	if quota.ClusterName != "" && quota.NamespaceCreated {
+		m.removeUserProject(quota)
+	}
+	return nil
+}
+
+func (m *UserQuotaManager) removeUserProject(quota *types.UserQuota) {
+	authorizer := m.clusters.GetAuthorizer()
+	user := authorizer.GetUser(quota.UserName)
+	if user != nil {
+		for i, project := range user.Projects {
+			if project.Cluster == quota.ClusterName && project.Namespace == quota.Namespace {
+				user.Projects = append(user.Projects[:i], user.Projects[i+1:]...)
+				break
 			}
-			authorizer.UpdateUser(user)
 		}
+		authorizer.UpdateUser(user)
 	}
-	return nil
 }
 
 func (m *UserQuotaManager) Action(ctx *resource.Context) (interface{}, *resterror.APIError) {
@@ -275,27 +352,61 @@ func (m *UserQuotaManager) approval(ctx *resource.Context) *resterror.APIError {
 		return resterror.NewAPIError(resterror.InvalidFormat, "approval param is not valid")
 	}
 
-	cluster := m.clusters.GetClusterByName(clusterInfo.ClusterName)
+	m.approveLock.Lock()
+	defer m.approveLock.Unlock()
+	_, err := m.approve(ctx.Resource.(*types.UserQuota).GetID(), clusterInfo.ClusterName, getCurrentUser(ctx), false)
+	return err
+}
+
+//quota is approved by the first matched policy, it's left to admin
+//if no policy matches or the approval fails, quota of existing
+//namespace is never approved by policy, since the namespace may
+//belong to others
+func (m *UserQuotaManager) autoApprove(quota *types.UserQuota) *types.UserQuota {
+	m.approveLock.Lock()
+	defer m.approveLock.Unlock()
+
+	quotas, err := m.listUserQuotas()
+	if err != nil {
+		log.Warnf("list user quotas failed: %s", err.Error())
+		return nil
+	}
+
+	policy := m.policies.Match(quota, quotas)
+	if policy == nil {
+		return nil
+	}
+
+	approved, apiErr := m.approve(quota.GetID(), quota.ClusterName, userquota.PolicyApproverPrefix+policy.Name, true)
+	if apiErr != nil {
+		log.Warnf("approve user quota %s by policy %s failed: %s", quota.GetID(), policy.Name, apiErr.Error())
+		return nil
+	}
+	return approved
+}
+
+//namespace must be created by the approval if onlyNewNamespace is true
+func (m *UserQuotaManager) approve(userQuotaID, clusterName, approvedBy string, onlyNewNamespace bool) (*types.UserQuota, *resterror.APIError) {
+	cluster := m.clusters.GetClusterByName(clusterName)
 	if cluster == nil {
-		return resterror.NewAPIError(resterror.NotFound, fmt.Sprintf("cluster %s doesn't exist", clusterInfo.ClusterName))
+		return nil, resterror.NewAPIError(resterror.NotFound, fmt.Sprintf("cluster %s doesn't exist", clusterName))
 	}
 
-	userQuotaID := ctx.Resource.(*types.UserQuota).GetID()
 	tx, err := m.db.Begin()
 	if err != nil {
-		return resterror.NewAPIError(resterror.ServerError,
+		return nil, resterror.NewAPIError(resterror.ServerError,
 			fmt.Sprintf("approval user quota %s failed %s", userQuotaID, err.Error()))
 	}
 
 	defer tx.Rollback()
 	quota, err := getUserQuotaFromDB(tx, userQuotaID)
 	if err != nil {
-		return resterror.NewAPIError(resterror.ServerError,
+		return nil, resterror.NewAPIError(resterror.ServerError,
 			fmt.Sprintf("approval user quota %s failed %s", userQuotaID, err.Error()))
 	}
 
 	if quota.Status != types.StatusUserQuotaProcessing {
-		return resterror.NewAPIError(resterror.ServerError,
+		return nil, resterror.NewAPIError(resterror.ServerError,
 			fmt.Sprintf("approval user quota %s failed: only approval request that status is processing", userQuotaID))
 	}
 
@@ -308,32 +419,44 @@ func (m *UserQuotaManager) approval(ctx *resource.Context) *resterror.APIError {
 	}
 
 	exists := hasNamespace(cluster.GetKubeClient(), quota.Namespace)
+	if exists && onlyNewNamespace {
+		return nil, resterror.NewAPIError(resterror.PermissionDenied,
+			fmt.Sprintf("namespace %s already exists, it should be approved by admin", quota.Namespace))
+	}
+
 	if exists == false {
 		if err := createNamespace(cluster.GetKubeClient(), quota.Namespace); err != nil {
-			return resterror.NewAPIError(types.ConnectClusterFailed,
+			return nil, resterror.NewAPIError(types.ConnectClusterFailed,
 				fmt.Sprintf("create user %s namespace %s failed %s",
 					quota.UserName, quota.Namespace, err.Error()))
 		}
 
 		if err := createResourceQuota(cluster.GetKubeClient(), quota.Namespace, resourceQuota); err != nil {
 			deleteNamespace(cluster.GetKubeClient(), quota.Namespace)
-			return resterror.NewAPIError(resterror.ServerError,
+			return nil, resterror.NewAPIError(resterror.ServerError,
 				fmt.Sprintf("create user %s resourcequota with namespace %s failed %s",
 					quota.UserName, quota.Namespace, err.Error()))
 		}
 	} else {
 		oldK8sResourceQuota, err = updateResourceQuota(cluster.GetKubeClient(), quota.Namespace, resourceQuota.Limits)
 		if err != nil {
-			return resterror.NewAPIError(types.ConnectClusterFailed,
+			return nil, resterror.NewAPIError(types.ConnectClusterFailed,
 				fmt.Sprintf("update user %s resourcequota with namespace %s failed %s",
 					quota.UserName, quota.Namespace, err.Error()))
 		}
 	}
 
-	setUserQuotaByAdmin(quota, clusterInfo.ClusterName, "", types.StatusUserQuotaApproval)
+	setUserQuotaByAdmin(quota, clusterName, "", types.StatusUserQuotaApproval)
+	quota.ApprovedBy = approvedBy
+	if exists == false {
+		quota.NamespaceCreated = true
+	}
+	if quota.ExpireDays != 0 {
+		quota.ExpirationTimestamp = resource.ISOTime(time.Time(quota.ResponseTimestamp).Add(time.Duration(quota.ExpireDays) * 24 * time.Hour))
+	}
 	value, err := json.Marshal(quota)
 	if err != nil {
-		return resterror.NewAPIError(types.ConnectClusterFailed,
+		return nil, resterror.NewAPIError(types.ConnectClusterFailed,
 			fmt.Sprintf("marshal user quota to storage value failed: %s", err.Error()))
 	}
 
@@ -347,14 +470,14 @@ func (m *UserQuotaManager) approval(ctx *resource.Context) *resterror.APIError {
 
 	if err := tx.Update(userQuotaID, value); err != nil {
 		rollbackResource()
-		return resterror.NewAPIError(resterror.ServerError,
+		return nil, resterror.NewAPIError(resterror.ServerError,
 			fmt.Sprintf("approval user %s quota with namespace %s failed %s",
 				quota.UserName, quota.Namespace, err.Error()))
 	}
 
 	if err := tx.Commit(); err != nil {
 		rollbackResource()
-		return resterror.NewAPIError(resterror.ServerError,
+		return nil, resterror.NewAPIError(resterror.ServerError,
 			fmt.Sprintf("approval user %s quota with namespace %s failed %s",
 				quota.UserName, quota.Namespace, err.Error()))
 	}
@@ -364,14 +487,15 @@ func (m *UserQuotaManager) approval(ctx *resource.Context) *resterror.APIError {
 		user := authorizer.GetUser(quota.UserName)
 		if user != nil {
 			user.Projects = append(user.Projects, types.Project{
-				Cluster:   clusterInfo.ClusterName,
+				Cluster:   clusterName,
 				Namespace: quota.Namespace,
 			})
 			authorizer.UpdateUser(user)
 		}
 	}
 
-	return nil
+	m.notifier.Notify(quota)
+	return quota, nil
 }
 
 func (m *UserQuotaManager) reject(ctx *resource.Context) *resterror.APIError {
@@ -418,6 +542,84 @@ func (m *UserQuotaManager) reject(ctx *resource.Context) *resterror.APIError {
 			fmt.Sprintf("reject user %s quota with namespace %s failed %s",
 				quota.UserName, quota.Namespace, err.Error()))
 	}
+
+	m.notifier.Notify(quota)
+	return nil
+}
+
+//namespace of expired quota is deleted if it's created by the quota,
+//quota is kept with status expired, so user could see it and request
+//again by update
+func (m *UserQuotaManager) reclaimExpiredQuotas() {
+	for {
+		time.Sleep(userQuotaReclaimInterval)
+		quotas, err := m.listUserQuotas()
+		if err != nil {
+			log.Warnf("list user quotas failed: %s", err.Error())
+			continue
+		}
+
+		now := time.Now()
+		for _, quota := range quotas {
+			if userquota.IsExpired(quota, now) {
+				if err := m.reclaim(quota.GetID(), now); err != nil {
+					log.Warnf("reclaim expired user quota %s failed: %s", quota.GetID(), err.Error())
+				}
+			}
+		}
+	}
+}
+
+//quota is checked again in transaction, since it may be updated after
+//list
+func (m *UserQuotaManager) reclaim(userQuotaID string, now time.Time) error {
+	tx, err := m.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+	quota, err := getUserQuotaFromDB(tx, userQuotaID)
+	if err != nil {
+		return err
+	}
+
+	if userquota.IsExpired(quota, now) == false {
+		return nil
+	}
+
+	if quota.NamespaceCreated {
+		cluster := m.clusters.GetClusterByName(quota.ClusterName)
+		if cluster == nil {
+			return fmt.Errorf("cluster %s doesn't exist", quota.ClusterName)
+		}
+
+		if err := deleteNamespace(cluster.GetKubeClient(), quota.Namespace); err != nil && apierrors.IsNotFound(err) == false {
+			return fmt.Errorf("delete namespace failed %s", err.Error())
+		}
+	}
+
+	//the namespace doesn't belong to the quota any more
+	removeProject := quota.NamespaceCreated
+	quota.NamespaceCreated = false
+	quota.Status = types.StatusUserQuotaExpired
+	value, err := json.Marshal(quota)
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Update(userQuotaID, value); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	if removeProject {
+		m.removeUserProject(quota)
+	}
+	m.notifier.Notify(quota)
 	return nil
 }
 
@@ -428,6 +630,9 @@ func setUserQuota(userQuota *types.UserQuota, userName, requestType string, crea
 	userQuota.Status = types.StatusUserQuotaProcessing
 	userQuota.UserName = userName
 	userQuota.RequestType = requestType
+	userQuota.ApprovedBy = ""
+	userQuota.ExpirationTimestamp = resource.ISOTime{}
+	userQuota.NamespaceCreated = false
 }
 
 func setUserQuotaByAdmin(userQuota *types.UserQuota, clusterName, reason, status string) {
@@ -472,6 +677,53 @@ func storageResourceValueToSCUserQuota(value []byte) (*types.UserQuota, error) {
 	return &userQuota, nil
 }
 
+//quota saved before namespaceCreated is added doesn't have the field,
+//its namespace was deleted with it if it had cluster, migrated quota
+//keeps the old behavior
+func migrateNamespaceCreated(table kvzoo.Table) error {
+	tx, err := table.Begin()
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+	values, err := tx.List()
+	if err != nil {
+		return err
+	}
+
+	migrated := 0
+	for id, value := range values {
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(value, &fields); err != nil {
+			return fmt.Errorf("unmarshal userquota %s failed: %s", id, err.Error())
+		}
+		if _, ok := fields["namespaceCreated"]; ok {
+			continue
+		}
+
+		quota, err := storageResourceValueToSCUserQuota(value)
+		if err != nil {
+			return err
+		}
+		quota.NamespaceCreated = quota.ClusterName != "" && quota.Status != types.StatusUserQuotaExpired
+		value, err := json.Marshal(quota)
+		if err != nil {
+			return err
+		}
+		if err := tx.Update(id, value); err != nil {
+			return err
+		}
+		migrated += 1
+	}
+
+	if migrated == 0 {
+		return nil
+	}
+	log.Infof("migrate namespaceCreated of %d userquotas", migrated)
+	return tx.Commit()
+}
+
 func getUserQuotaFromDB(tx kvzoo.Transaction, id string) (*types.UserQuota, error) {
 	value, err := tx.Get(id)
 	if err != nil {
@@ -500,5 +752,9 @@ func checkUserQuotaParamsValid(quota *types.UserQuota) error {
 		return fmt.Errorf("storage %s is invalid: %s", quota.Storage, err.Error())
 	}
 
+	if quota.ExpireDays < 0 {
+		return fmt.Errorf("expire days %d is invalid: cann't be negative", quota.ExpireDays)
+	}
+
 	return nil
 }
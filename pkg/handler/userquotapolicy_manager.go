@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"fmt"
+	"time"
+
+	resterr "gorest/error"
+	restresource "gorest/resource"
+	"pkg/types"
+	"pkg/userquota"
+)
+
+type UserQuotaPolicyManager struct {
+	policies *userquota.Policies
+}
+
+func newUserQuotaPolicyManager(policies *userquota.Policies) *UserQuotaPolicyManager {
+	return &UserQuotaPolicyManager{
+		policies: policies,
+	}
+}
+
+func (m *UserQuotaPolicyManager) Create(ctx *restresource.Context) (restresource.Resource, *resterr.APIError) {
+	if isAdmin(getCurrentUser(ctx)) == false {
+		return nil, resterr.NewAPIError(resterr.PermissionDenied, "only admin can create user quota policy")
+	}
+
+	policy := ctx.Resource.(*types.UserQuotaPolicy)
+	policy.SetID(policy.Name)
+	policy.SetCreationTimestamp(time.Now())
+	if err := m.policies.Add(policy); err != nil {
+		return nil, resterr.NewAPIError(resterr.InvalidBodyContent, err.Error())
+	}
+	return policy, nil
+}
+
+//user could get the policies to know which request is approved
+//automatically
+func (m *UserQuotaPolicyManager) Get(ctx *restresource.Context) (restresource.Resource, *resterr.APIError) {
+	target := ctx.Resource.GetID()
+	if policy := m.policies.Get(target); policy != nil {
+		return policy, nil
+	}
+	return nil, resterr.NewAPIError(resterr.NotFound, fmt.Sprintf("no found user quota policy %s", target))
+}
+
+func (m *UserQuotaPolicyManager) List(ctx *restresource.Context) (interface{}, *resterr.APIError) {
+	return m.policies.List(), nil
+}
+
+func (m *UserQuotaPolicyManager) Update(ctx *restresource.Context) (restresource.Resource, *resterr.APIError) {
+	if isAdmin(getCurrentUser(ctx)) == false {
+		return nil, resterr.NewAPIError(resterr.PermissionDenied, "only admin could update user quota policy")
+	}
+
+	policy := ctx.Resource.(*types.UserQuotaPolicy)
+	if err := m.policies.Update(policy); err != nil {
+		return nil, resterr.NewAPIError(resterr.InvalidBodyContent, err.Error())
+	}
+	return policy, nil
+}
+
+func (m *UserQuotaPolicyManager) Delete(ctx *restresource.Context) *resterr.APIError {
+	if isAdmin(getCurrentUser(ctx)) == false {
+		return resterr.NewAPIError(resterr.PermissionDenied, "only admin can delete user quota policy")
+	}
+
+	if err := m.policies.Delete(ctx.Resource.GetID()); err != nil {
+		return resterr.NewAPIError(resterr.NotFound, err.Error())
+	}
+	return nil
+}
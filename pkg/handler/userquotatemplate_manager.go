@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"fmt"
+	"time"
+
+	resterr "gorest/error"
+	restresource "gorest/resource"
+	"pkg/types"
+	"pkg/userquota"
+)
+
+type UserQuotaTemplateManager struct {
+	templates *userquota.Templates
+}
+
+func newUserQuotaTemplateManager(templates *userquota.Templates) *UserQuotaTemplateManager {
+	return &UserQuotaTemplateManager{
+		templates: templates,
+	}
+}
+
+func (m *UserQuotaTemplateManager) Create(ctx *restresource.Context) (restresource.Resource, *resterr.APIError) {
+	if isAdmin(getCurrentUser(ctx)) == false {
+		return nil, resterr.NewAPIError(resterr.PermissionDenied, "only admin can create user quota template")
+	}
+
+	template := ctx.Resource.(*types.UserQuotaTemplate)
+	template.SetID(template.Name)
+	template.SetCreationTimestamp(time.Now())
+	if err := m.templates.Add(template); err != nil {
+		return nil, resterr.NewAPIError(resterr.InvalidBodyContent, err.Error())
+	}
+	return template, nil
+}
+
+//user could get the templates to request quota with them
+func (m *UserQuotaTemplateManager) Get(ctx *restresource.Context) (restresource.Resource, *resterr.APIError) {
+	target := ctx.Resource.GetID()
+	if template := m.templates.Get(target); template != nil {
+		return template, nil
+	}
+	return nil, resterr.NewAPIError(resterr.NotFound, fmt.Sprintf("no found user quota template %s", target))
+}
+
+func (m *UserQuotaTemplateManager) List(ctx *restresource.Context) (interface{}, *resterr.APIError) {
+	return m.templates.List(), nil
+}
+
+func (m *UserQuotaTemplateManager) Update(ctx *restresource.Context) (restresource.Resource, *resterr.APIError) {
+	if isAdmin(getCurrentUser(ctx)) == false {
+		return nil, resterr.NewAPIError(resterr.PermissionDenied, "only admin could update user quota template")
+	}
+
+	template := ctx.Resource.(*types.UserQuotaTemplate)
+	if err := m.templates.Update(template); err != nil {
+		return nil, resterr.NewAPIError(resterr.InvalidBodyContent, err.Error())
+	}
+	return template, nil
+}
+
+func (m *UserQuotaTemplateManager) Delete(ctx *restresource.Context) *resterr.APIError {
+	if isAdmin(getCurrentUser(ctx)) == false {
+		return resterr.NewAPIError(resterr.PermissionDenied, "only admin can delete user quota template")
+	}
+
+	if err := m.templates.Delete(ctx.Resource.GetID()); err != nil {
+		return resterr.NewAPIError(resterr.NotFound, err.Error())
+	}
+	return nil
+}
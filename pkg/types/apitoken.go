@@ -0,0 +1,32 @@
+package types
+
+import (
+	"gorest/resource"
+)
+
+const (
+	//scope of the api token which authenticates the request
+	APITokenScopeKey string = "_zcloud_api_token_scope"
+)
+
+//token is only returned when it's created, since only hash of the
+//token is saved, token without expiration never expires
+type APIToken struct {
+	resource.ResourceBase `json:",inline"`
+	Name                  string           `json:"name" rest:"required=true,isDomain=true,description=immutable"`
+	Token                 string           `json:"token,omitempty" rest:"description=readonly"`
+	ExpireDays            int              `json:"expireDays,omitempty" rest:"description=immutable"`
+	ExpirationTimestamp   resource.ISOTime `json:"expirationTimestamp,omitempty" rest:"description=readonly"`
+	ReadOnly              bool             `json:"readOnly,omitempty" rest:"description=immutable"`
+	Cluster               string           `json:"cluster,omitempty" rest:"isDomain=true,description=immutable"`
+}
+
+func (t APIToken) GetParents() []resource.ResourceKind {
+	return []resource.ResourceKind{User{}}
+}
+
+//empty cluster means all the clusters
+type APITokenScope struct {
+	ReadOnly bool
+	Cluster  string
+}
@@ -14,7 +14,23 @@ type AuditLog struct {
 	Operation             string `json:"operation"`
 	ResourceKind          string `json:"resourceKind"`
 	ResourcePath          string `json:"resourcePath"`
+	Cluster               string `json:"cluster,omitempty"`
+	Namespace             string `json:"namespace,omitempty"`
 	Detail                string `json:"detail"`
+	RequestID             string `json:"requestId"`
+	//token, session or cas
+	AuthSource   string              `json:"authSource,omitempty"`
+	ResponseCode int                 `json:"responseCode"`
+	Error        string              `json:"error,omitempty"`
+	Diff         []AuditLogFieldDiff `json:"diff,omitempty"`
+}
+
+//old and new are json value of the field, empty old means the field
+//is added
+type AuditLogFieldDiff struct {
+	Path string `json:"path"`
+	Old  string `json:"old,omitempty"`
+	New  string `json:"new,omitempty"`
 }
 
 type AuditLogs []*AuditLog
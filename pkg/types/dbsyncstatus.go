@@ -0,0 +1,19 @@
+package types
+
+import (
+	"gorest/resource"
+)
+
+//sync status of db slave reported by the proxy of master, id is the
+//address of slave, state is insync, stale or syncing
+type DBSyncStatus struct {
+	resource.ResourceBase `json:",inline"`
+	State                 string           `json:"state"`
+	StaleTables           []string         `json:"staleTables,omitempty"`
+	SyncedTables          []string         `json:"syncedTables,omitempty"`
+	LastError             string           `json:"lastError,omitempty"`
+	LastSyncTime          resource.ISOTime `json:"lastSyncTime,omitempty"`
+	LogIndex              uint64           `json:"logIndex"`
+	MasterLogIndex        uint64           `json:"masterLogIndex"`
+	Fenced                bool             `json:"fenced,omitempty"`
+}
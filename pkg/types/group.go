@@ -0,0 +1,15 @@
+package types
+
+import (
+	"gorest/resource"
+)
+
+//users in the group have the projects of the group, user doesn't need
+//to exist when it's added to the group, so cas user can get the
+//projects at the first login
+type Group struct {
+	resource.ResourceBase `json:",inline"`
+	Name                  string    `json:"name" rest:"required=true,isDomain=true,description=immutable"`
+	Users                 []string  `json:"users"`
+	Projects              []Project `json:"projects"`
+}
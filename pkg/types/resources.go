@@ -34,11 +34,16 @@ func Resources() []resource.ResourceKind {
 		OuterService{},
 		KubeConfig{},
 		UserQuota{},
+		UserQuotaPolicy{},
+		UserQuotaTemplate{},
 		Application{},
 		Monitor{},
 		Registry{},
 		EFK{},
 		User{},
+		Group{},
+		APIToken{},
+		Session{},
 		HorizontalPodAutoscaler{},
 		FluentBitConfig{},
 		SvcMeshWorkload{},
@@ -46,6 +51,7 @@ func Resources() []resource.ResourceKind {
 		Threshold{},
 		Metric{},
 		AuditLog{},
+		DBSyncStatus{},
 		Storage{},
 		WorkFlow{},
 		WorkFlowTask{},
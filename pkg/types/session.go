@@ -0,0 +1,16 @@
+package types
+
+import (
+	"gorest/resource"
+)
+
+//token issued by login, it could be revoked before it's expired,
+//id of the session is the jwt id of the token
+type Session struct {
+	resource.ResourceBase `json:",inline"`
+	ExpirationTimestamp   resource.ISOTime `json:"expirationTimestamp,omitempty" rest:"description=readonly"`
+}
+
+func (s Session) GetParents() []resource.ResourceKind {
+	return []resource.ResourceKind{User{}}
+}
@@ -9,10 +9,48 @@ const (
 	CurrentUserKey      string = "_zlcoud_current_user"
 	ActionLogin         string = "login"
 	ActionResetPassword string = "resetPassword"
+	//revoke all the sessions of the user
+	ActionRevokeSessions string = "revokeSessions"
+	//unlock the user locked by too many failed logins
+	ActionUnlock string = "unlock"
+	//enroll totp, it's enabled after the first code is confirmed
+	ActionEnableTOTP  string = "enableTOTP"
+	ActionConfirmTOTP string = "confirmTOTP"
+	ActionDisableTOTP string = "disableTOTP"
+	//how the current user is authenticated
+	AuthSourceKey string = "_zcloud_auth_source"
 )
 
+const (
+	//only get resources
+	RoleViewer = "viewer"
+	//manage resources in namespace, except the namespace settings
+	//like resource quota and limit range
+	RoleDeveloper = "developer"
+	//manage all resources in namespace
+	RoleNamespaceAdmin = "namespaceAdmin"
+	//manage all namespaces and resources of the cluster like node
+	//and storage, no matter which namespace the project has
+	RoleClusterAdmin = "clusterAdmin"
+)
+
+const (
+	AuthSourceToken    = "token"
+	AuthSourceSession  = "session"
+	AuthSourceCAS      = "cas"
+	AuthSourceOIDC     = "oidc"
+	AuthSourceAPIToken = "apitoken"
+)
+
+//challenge and totp code are used in the second step of login
+//when user has enabled totp, password isn't needed in that step,
+//password is the sha1 hex of the plain password of local user, ldap
+//user uses ldap password which is the plain password
 type UserPassword struct {
-	Password string `json:"password"`
+	Password     string `json:"password"`
+	LDAPPassword string `json:"ldapPassword,omitempty"`
+	Challenge    string `json:"challenge,omitempty"`
+	TOTPCode     string `json:"totpCode,omitempty"`
 }
 
 type ResetPassword struct {
@@ -25,15 +63,46 @@ type User struct {
 	Name                  string    `json:"name" rest:"required=true,isDomain=true,description=immutable"`
 	Password              string    `json:"password,omitempty" rest:"required=true"`
 	Projects              []Project `json:"projects"`
+	Groups                []string  `json:"groups,omitempty" rest:"description=readonly"`
+	//only visible to admin
+	Locked      bool             `json:"locked,omitempty" rest:"description=readonly"`
+	LockedUntil resource.ISOTime `json:"lockedUntil,omitempty" rest:"description=readonly"`
+	TOTPEnabled bool             `json:"totpEnabled,omitempty" rest:"description=readonly"`
 }
 
+//role of the user in the project, empty role is namespaceAdmin
+//which is same as the project without role in old version
 type Project struct {
 	Cluster   string `json:"cluster" rest:"isDomain=true"`
 	Namespace string `json:"namespace" rest:"isDomain=true"`
+	Role      string `json:"role,omitempty" rest:"options=viewer|developer|namespaceAdmin|clusterAdmin"`
 }
 
+//token is empty if totp is required, the challenge should be sent
+//back with totp code
 type LoginInfo struct {
-	Token string `json:"token"`
+	Token        string `json:"token"`
+	TOTPRequired bool   `json:"totpRequired,omitempty"`
+	Challenge    string `json:"challenge,omitempty"`
+}
+
+//secret and qr code are for authenticator app, qr code is png image
+//in data url
+type TOTPProvision struct {
+	Secret string `json:"secret"`
+	URL    string `json:"url"`
+	QRCode string `json:"qrCode"`
+}
+
+//code is totp code or recovery code
+type TOTPCode struct {
+	Code string `json:"code"`
+}
+
+//recovery codes are only returned once, each of them could be used
+//once instead of totp code
+type TOTPRecoveryCodes struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
 }
 
 var UserActions = []resource.Action{
@@ -46,6 +115,25 @@ var UserActions = []resource.Action{
 		Name:  ActionResetPassword,
 		Input: &ResetPassword{},
 	},
+	resource.Action{
+		Name: ActionRevokeSessions,
+	},
+	resource.Action{
+		Name: ActionUnlock,
+	},
+	resource.Action{
+		Name:   ActionEnableTOTP,
+		Output: &TOTPProvision{},
+	},
+	resource.Action{
+		Name:   ActionConfirmTOTP,
+		Input:  &TOTPCode{},
+		Output: &TOTPRecoveryCodes{},
+	},
+	resource.Action{
+		Name:  ActionDisableTOTP,
+		Input: &TOTPCode{},
+	},
 }
 
 func (u User) GetActions() []resource.Action {
@@ -13,6 +13,8 @@ const (
 	StatusUserQuotaProcessing = "processing"
 	StatusUserQuotaApproval   = "approval"
 	StatusUserQuotaRejection  = "rejection"
+	//namespace of approved quota is deleted after expiration
+	StatusUserQuotaExpired = "expired"
 
 	ActionApproval  = "approval"
 	ActionRejection = "reject"
@@ -42,6 +44,20 @@ type UserQuota struct {
 	Telephone             string           `json:"telephone,omitempty"`
 	RejectionReason       string           `json:"rejectionReason,omitempty"`
 	ResponseTimestamp     resource.ISOTime `json:"responseTimestamp,omitempty" rest:"description=readonly"`
+	//cpu, memory, storage and expire days which aren't specified are
+	//set by the template
+	Template string `json:"template,omitempty"`
+	//quota expires after the days since approval, zero means never
+	ExpireDays          int              `json:"expireDays,omitempty"`
+	ExpirationTimestamp resource.ISOTime `json:"expirationTimestamp,omitempty" rest:"description=readonly"`
+	//state changes are sent to the email
+	Email string `json:"email,omitempty"`
+	//admin or the auto approval policy
+	ApprovedBy string `json:"approvedBy,omitempty" rest:"description=readonly"`
+	//namespace is created by the approval, only such namespace is
+	//deleted with the quota, it's always saved, so quota saved before
+	//it's added can be found and migrated
+	NamespaceCreated bool `json:"namespaceCreated" rest:"description=readonly"`
 }
 
 var UserQuotaActions = []resource.Action{
@@ -0,0 +1,32 @@
+package types
+
+import (
+	"gorest/resource"
+)
+
+//quota request of the cluster is approved automatically if the total
+//approved quota of the user in the cluster doesn't exceed the limits
+type UserQuotaPolicy struct {
+	resource.ResourceBase `json:",inline"`
+	Name                  string `json:"name" rest:"required=true,isDomain=true,description=immutable"`
+	ClusterName           string `json:"clusterName" rest:"required=true"`
+	//only the namespaces with the prefix are approved, empty means
+	//all the namespaces
+	NamespacePrefix string `json:"namespacePrefix,omitempty"`
+	//request of the resource without limit isn't approved
+	MaxCPU     string `json:"maxCPU,omitempty"`
+	MaxMemory  string `json:"maxMemory,omitempty"`
+	MaxStorage string `json:"maxStorage,omitempty"`
+	//zero means quota without expiration could be approved
+	MaxExpireDays int `json:"maxExpireDays,omitempty"`
+}
+
+type UserQuotaTemplate struct {
+	resource.ResourceBase `json:",inline"`
+	Name                  string `json:"name" rest:"required=true,isDomain=true,description=immutable"`
+	CPU                   string `json:"cpu"`
+	Memory                string `json:"memory"`
+	Storage               string `json:"storage"`
+	ExpireDays            int    `json:"expireDays,omitempty"`
+	Description           string `json:"description,omitempty"`
+}
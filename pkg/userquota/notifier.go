@@ -0,0 +1,119 @@
+package userquota
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"cement/log"
+	"config"
+	"gorest/resource"
+	"pkg/types"
+)
+
+const (
+	webhookTimeout = 10 * time.Second
+	mailSubject    = "GaoCloud User Quota"
+)
+
+//send mail to the receivers, the sender could add other receivers
+//like admin
+type MailSender func(to []string, subject, body string) error
+
+type Event struct {
+	Time      resource.ISOTime `json:"time"`
+	Message   string           `json:"message"`
+	UserQuota *types.UserQuota `json:"userQuota"`
+}
+
+//notify the state change of user quota to the webhooks and the email
+//of the quota, notification is sent in background, failure is only
+//logged
+type Notifier struct {
+	webhooks []config.UserQuotaWebhookConf
+	sendMail MailSender
+	client   *http.Client
+}
+
+func NewNotifier(conf config.UserQuotaConf, sendMail MailSender) *Notifier {
+	return &Notifier{
+		webhooks: conf.Webhooks,
+		sendMail: sendMail,
+		client:   &http.Client{Timeout: webhookTimeout},
+	}
+}
+
+func (n *Notifier) Notify(quota *types.UserQuota) {
+	q := *quota
+	e := &Event{
+		Time:      resource.ISOTime(time.Now()),
+		Message:   genMessage(&q),
+		UserQuota: &q,
+	}
+	go n.notify(e)
+}
+
+func (n *Notifier) notify(e *Event) {
+	for _, hook := range n.webhooks {
+		if err := n.post(hook, e); err != nil {
+			log.Warnf("post user quota %s event to webhook %s failed:%s", e.UserQuota.Name, hook.URL, err.Error())
+		}
+	}
+
+	if n.sendMail == nil {
+		return
+	}
+
+	var to []string
+	if e.UserQuota.Email != "" {
+		to = append(to, e.UserQuota.Email)
+	}
+	if err := n.sendMail(to, mailSubject, e.Message); err != nil {
+		log.Warnf("send user quota %s mail failed:%s", e.UserQuota.Name, err.Error())
+	}
+}
+
+func (n *Notifier) post(hook config.UserQuotaWebhookConf, e *Event) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if hook.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+hook.Token)
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("server returns %s", resp.Status)
+	}
+	return nil
+}
+
+func genMessage(quota *types.UserQuota) string {
+	msg := fmt.Sprintf("user quota of namespace %s requested by %s is %s", quota.Namespace, quota.UserName, quota.Status)
+	switch quota.Status {
+	case types.StatusUserQuotaApproval:
+		msg += fmt.Sprintf(" by %s in cluster %s", quota.ApprovedBy, quota.ClusterName)
+		if quota.ExpireDays != 0 {
+			msg += fmt.Sprintf(", it expires at %s", time.Time(quota.ExpirationTimestamp).Format(time.RFC3339))
+		}
+	case types.StatusUserQuotaRejection:
+		msg += fmt.Sprintf(", reason: %s", quota.RejectionReason)
+	case types.StatusUserQuotaExpired:
+		msg += fmt.Sprintf(", namespace in cluster %s is deleted", quota.ClusterName)
+	}
+	return msg
+}
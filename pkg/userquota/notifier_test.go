@@ -0,0 +1,55 @@
+package userquota
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	ut "cement/unittest"
+	"config"
+	"pkg/types"
+)
+
+func TestNotify(t *testing.T) {
+	events := make(chan Event, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ut.Equal(t, r.Header.Get("Authorization"), "Bearer secret")
+		var e Event
+		json.NewDecoder(r.Body).Decode(&e)
+		events <- e
+	}))
+	defer server.Close()
+
+	mails := make(chan []string, 1)
+	n := NewNotifier(config.UserQuotaConf{
+		Webhooks: []config.UserQuotaWebhookConf{
+			config.UserQuotaWebhookConf{URL: server.URL, Token: "secret"},
+		},
+	}, func(to []string, subject, body string) error {
+		mails <- append(to, body)
+		return nil
+	})
+
+	quota := newQuota("ben", "local", "dev-a", "1", "", "", types.StatusUserQuotaRejection)
+	quota.Email = "ben@example.com"
+	quota.RejectionReason = "no resource"
+	n.Notify(quota)
+
+	select {
+	case e := <-events:
+		ut.Equal(t, e.UserQuota.Status, types.StatusUserQuotaRejection)
+		ut.Assert(t, strings.Contains(e.Message, "no resource"), "message should have the reason: %s", e.Message)
+	case <-time.After(5 * time.Second):
+		t.Fatal("webhook should be called")
+	}
+
+	select {
+	case mail := <-mails:
+		ut.Equal(t, mail[0], "ben@example.com")
+	case <-time.After(5 * time.Second):
+		t.Fatal("mail should be sent")
+	}
+}
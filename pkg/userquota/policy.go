@@ -0,0 +1,256 @@
+package userquota
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	apiresource "k8s.io/apimachinery/pkg/api/resource"
+
+	"kvzoo"
+	"pkg/types"
+)
+
+const (
+	UserQuotaPolicyTable = "userquota_policy"
+	//approvedBy of the quota approved by policy
+	PolicyApproverPrefix = "policy:"
+)
+
+//policies are few and checked for each request, so they are cached
+type Policies struct {
+	lock     sync.RWMutex
+	policies map[string]*types.UserQuotaPolicy
+	db       kvzoo.Table
+}
+
+func NewPolicies(table kvzoo.Table) (*Policies, error) {
+	tx, err := table.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	values, err := tx.List()
+	if err != nil {
+		return nil, err
+	}
+
+	policies := make(map[string]*types.UserQuotaPolicy)
+	for name, value := range values {
+		var policy types.UserQuotaPolicy
+		if err := json.Unmarshal(value, &policy); err != nil {
+			return nil, fmt.Errorf("unmarshal user quota policy %s failed:%s", name, err.Error())
+		}
+		policies[name] = &policy
+	}
+
+	return &Policies{
+		policies: policies,
+		db:       table,
+	}, nil
+}
+
+func (p *Policies) Add(policy *types.UserQuotaPolicy) error {
+	if err := validatePolicy(policy); err != nil {
+		return err
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if _, ok := p.policies[policy.Name]; ok {
+		return fmt.Errorf("user quota policy %s already exists", policy.Name)
+	}
+	if err := saveToDB(p.db, policy.Name, policy, false); err != nil {
+		return err
+	}
+	p.policies[policy.Name] = policy
+	return nil
+}
+
+func (p *Policies) Update(policy *types.UserQuotaPolicy) error {
+	if err := validatePolicy(policy); err != nil {
+		return err
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	old, ok := p.policies[policy.Name]
+	if ok == false {
+		return fmt.Errorf("user quota policy %s doesn't exist", policy.Name)
+	}
+	policy.SetCreationTimestamp(old.GetCreationTimestamp())
+	if err := saveToDB(p.db, policy.Name, policy, true); err != nil {
+		return err
+	}
+	p.policies[policy.Name] = policy
+	return nil
+}
+
+func (p *Policies) Delete(name string) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if _, ok := p.policies[name]; ok == false {
+		return fmt.Errorf("user quota policy %s doesn't exist", name)
+	}
+	if err := deleteFromDB(p.db, name); err != nil {
+		return err
+	}
+	delete(p.policies, name)
+	return nil
+}
+
+func (p *Policies) Get(name string) *types.UserQuotaPolicy {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	return p.policies[name]
+}
+
+func (p *Policies) List() []*types.UserQuotaPolicy {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	var policies []*types.UserQuotaPolicy
+	for _, policy := range p.policies {
+		policies = append(policies, policy)
+	}
+	sort.Slice(policies, func(i, j int) bool {
+		return policies[i].Name < policies[j].Name
+	})
+	return policies
+}
+
+//return the first policy by name which approves the quota, quotas are
+//all the quotas in db, the approved ones of the user in the cluster
+//are added to the request, the quota itself is replaced by the request
+func (p *Policies) Match(quota *types.UserQuota, quotas []*types.UserQuota) *types.UserQuotaPolicy {
+	if quota.ClusterName == "" {
+		return nil
+	}
+
+	cpus := []string{quota.CPU}
+	memories := []string{quota.Memory}
+	storages := []string{quota.Storage}
+	for _, q := range quotas {
+		if q.UserName == quota.UserName && q.ClusterName == quota.ClusterName &&
+			q.Status == types.StatusUserQuotaApproval && q.GetID() != quota.GetID() {
+			cpus = append(cpus, q.CPU)
+			memories = append(memories, q.Memory)
+			storages = append(storages, q.Storage)
+		}
+	}
+
+	cpu, err := sumQuantity(cpus)
+	if err != nil {
+		return nil
+	}
+	memory, err := sumQuantity(memories)
+	if err != nil {
+		return nil
+	}
+	storage, err := sumQuantity(storages)
+	if err != nil {
+		return nil
+	}
+
+	for _, policy := range p.List() {
+		if policy.ClusterName != quota.ClusterName ||
+			strings.HasPrefix(quota.Namespace, policy.NamespacePrefix) == false {
+			continue
+		}
+
+		if policy.MaxExpireDays != 0 && (quota.ExpireDays == 0 || quota.ExpireDays > policy.MaxExpireDays) {
+			continue
+		}
+
+		if withinLimit(cpu, policy.MaxCPU) && withinLimit(memory, policy.MaxMemory) && withinLimit(storage, policy.MaxStorage) {
+			return policy
+		}
+	}
+	return nil
+}
+
+//empty quantity is zero
+func sumQuantity(quantities []string) (apiresource.Quantity, error) {
+	var sum apiresource.Quantity
+	for _, quantity := range quantities {
+		if quantity == "" {
+			continue
+		}
+		q, err := apiresource.ParseQuantity(quantity)
+		if err != nil {
+			return sum, err
+		}
+		sum.Add(q)
+	}
+	return sum, nil
+}
+
+//empty limit only allows zero
+func withinLimit(q apiresource.Quantity, limit string) bool {
+	if limit == "" {
+		return q.IsZero()
+	}
+	l, err := apiresource.ParseQuantity(limit)
+	return err == nil && q.Cmp(l) <= 0
+}
+
+func validatePolicy(policy *types.UserQuotaPolicy) error {
+	for _, limit := range []string{policy.MaxCPU, policy.MaxMemory, policy.MaxStorage} {
+		if limit == "" {
+			continue
+		}
+		if _, err := apiresource.ParseQuantity(limit); err != nil {
+			return fmt.Errorf("limit %s is invalid:%s", limit, err.Error())
+		}
+	}
+
+	if policy.MaxExpireDays < 0 {
+		return fmt.Errorf("max expire days cann't be negative")
+	}
+	return nil
+}
+
+func saveToDB(table kvzoo.Table, key string, v interface{}, update bool) error {
+	value, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	tx, err := table.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if update {
+		err = tx.Update(key, value)
+	} else {
+		err = tx.Add(key, value)
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func deleteFromDB(table kvzoo.Table, key string) error {
+	tx, err := table.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := tx.Delete(key); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+//only approved quota with expire days expires
+func IsExpired(quota *types.UserQuota, now time.Time) bool {
+	return quota.Status == types.StatusUserQuotaApproval && quota.ExpireDays != 0 &&
+		now.After(time.Time(quota.ExpirationTimestamp))
+}
@@ -0,0 +1,112 @@
+package userquota
+
+import (
+	"testing"
+	"time"
+
+	ut "cement/unittest"
+	"gorest/resource"
+	"kvzoo"
+	"kvzoo/backend/memory"
+	"pkg/types"
+)
+
+func newTable(t *testing.T, name string) kvzoo.Table {
+	db, err := memory.New()
+	ut.Assert(t, err == nil, "create db should succeed: %v", err)
+	tn, _ := kvzoo.TableNameFromSegments(name)
+	table, err := db.CreateOrGetTable(tn)
+	ut.Assert(t, err == nil, "create table should succeed: %v", err)
+	return table
+}
+
+func newQuota(user, cluster, namespace, cpu, memory, storage, status string) *types.UserQuota {
+	quota := &types.UserQuota{
+		Name:        namespace,
+		ClusterName: cluster,
+		Namespace:   namespace,
+		UserName:    user,
+		CPU:         cpu,
+		Memory:      memory,
+		Storage:     storage,
+		Status:      status,
+	}
+	quota.SetID(namespace)
+	return quota
+}
+
+func TestPolicyMatch(t *testing.T) {
+	table := newTable(t, UserQuotaPolicyTable)
+	policies, err := NewPolicies(table)
+	ut.Assert(t, err == nil, "load policies should succeed: %v", err)
+
+	ut.Assert(t, policies.Add(&types.UserQuotaPolicy{Name: "bad", ClusterName: "local", MaxCPU: "x"}) != nil,
+		"invalid limit should fail")
+	ut.Assert(t, policies.Add(&types.UserQuotaPolicy{
+		Name:            "small",
+		ClusterName:     "local",
+		NamespacePrefix: "dev-",
+		MaxCPU:          "4",
+		MaxMemory:       "8Gi",
+		MaxStorage:      "100Gi",
+		MaxExpireDays:   30,
+	}) == nil, "")
+	ut.Assert(t, policies.Add(&types.UserQuotaPolicy{Name: "small", ClusterName: "local"}) != nil,
+		"duplicate policy should fail")
+
+	policies, err = NewPolicies(table)
+	ut.Assert(t, err == nil, "load policies should succeed: %v", err)
+	ut.Equal(t, len(policies.List()), 1)
+
+	approved := []*types.UserQuota{
+		newQuota("ben", "local", "dev-a", "2", "4Gi", "50Gi", types.StatusUserQuotaApproval),
+		newQuota("ben", "local", "dev-b", "1", "", "", types.StatusUserQuotaProcessing),
+		newQuota("ben", "remote", "dev-c", "4", "", "", types.StatusUserQuotaApproval),
+		newQuota("lucy", "local", "dev-d", "4", "", "", types.StatusUserQuotaApproval),
+	}
+
+	quota := newQuota("ben", "local", "dev-e", "2", "4Gi", "50Gi", types.StatusUserQuotaProcessing)
+	quota.ExpireDays = 30
+	policy := policies.Match(quota, approved)
+	ut.Assert(t, policy != nil && policy.Name == "small", "quota within limits should be approved")
+
+	quota.ExpireDays = 0
+	ut.Assert(t, policies.Match(quota, approved) == nil, "quota without expiration should not be approved")
+	quota.ExpireDays = 60
+	ut.Assert(t, policies.Match(quota, approved) == nil, "quota exceeds max expire days should not be approved")
+	quota.ExpireDays = 7
+
+	quota.CPU = "2500m"
+	ut.Assert(t, policies.Match(quota, approved) == nil, "total cpu exceeds limit should not be approved")
+	//update of approved quota replaces it
+	update := newQuota("ben", "local", "dev-a", "4", "", "", "")
+	update.ExpireDays = 7
+	ut.Assert(t, policies.Match(update, approved) != nil, "")
+
+	quota.CPU = "2"
+	quota.Namespace = "prod"
+	ut.Assert(t, policies.Match(quota, approved) == nil, "namespace without prefix should not be approved")
+	quota.Namespace = "dev-e"
+	quota.ClusterName = ""
+	ut.Assert(t, policies.Match(quota, approved) == nil, "quota without cluster should not be approved")
+
+	//resource without limit isn't approved
+	ut.Assert(t, policies.Update(&types.UserQuotaPolicy{Name: "small", ClusterName: "local", MaxCPU: "4"}) == nil, "")
+	ut.Assert(t, policies.Match(newQuota("ben", "local", "dev-e", "1", "", "", ""), nil) != nil, "")
+	ut.Assert(t, policies.Match(newQuota("ben", "local", "dev-e", "1", "1Gi", "", ""), nil) == nil, "")
+
+	ut.Assert(t, policies.Delete("small") == nil, "")
+	ut.Assert(t, policies.Delete("small") != nil, "")
+	ut.Equal(t, len(policies.List()), 0)
+}
+
+func TestIsExpired(t *testing.T) {
+	now := time.Now()
+	quota := newQuota("ben", "local", "dev-a", "1", "", "", types.StatusUserQuotaApproval)
+	ut.Assert(t, IsExpired(quota, now) == false, "quota without expire days never expires")
+	quota.ExpireDays = 1
+	quota.ExpirationTimestamp = resource.ISOTime(now.Add(-time.Second))
+	ut.Assert(t, IsExpired(quota, now), "")
+	quota.Status = types.StatusUserQuotaExpired
+	ut.Assert(t, IsExpired(quota, now) == false, "expired quota is reclaimed once")
+}
@@ -0,0 +1,157 @@
+package userquota
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+
+	apiresource "k8s.io/apimachinery/pkg/api/resource"
+
+	"kvzoo"
+	"pkg/types"
+)
+
+const (
+	UserQuotaTemplateTable = "userquota_template"
+)
+
+type Templates struct {
+	lock      sync.RWMutex
+	templates map[string]*types.UserQuotaTemplate
+	db        kvzoo.Table
+}
+
+func NewTemplates(table kvzoo.Table) (*Templates, error) {
+	tx, err := table.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	values, err := tx.List()
+	if err != nil {
+		return nil, err
+	}
+
+	templates := make(map[string]*types.UserQuotaTemplate)
+	for name, value := range values {
+		var template types.UserQuotaTemplate
+		if err := json.Unmarshal(value, &template); err != nil {
+			return nil, fmt.Errorf("unmarshal user quota template %s failed:%s", name, err.Error())
+		}
+		templates[name] = &template
+	}
+
+	return &Templates{
+		templates: templates,
+		db:        table,
+	}, nil
+}
+
+func (t *Templates) Add(template *types.UserQuotaTemplate) error {
+	if err := validateTemplate(template); err != nil {
+		return err
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if _, ok := t.templates[template.Name]; ok {
+		return fmt.Errorf("user quota template %s already exists", template.Name)
+	}
+	if err := saveToDB(t.db, template.Name, template, false); err != nil {
+		return err
+	}
+	t.templates[template.Name] = template
+	return nil
+}
+
+func (t *Templates) Update(template *types.UserQuotaTemplate) error {
+	if err := validateTemplate(template); err != nil {
+		return err
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	old, ok := t.templates[template.Name]
+	if ok == false {
+		return fmt.Errorf("user quota template %s doesn't exist", template.Name)
+	}
+	template.SetCreationTimestamp(old.GetCreationTimestamp())
+	if err := saveToDB(t.db, template.Name, template, true); err != nil {
+		return err
+	}
+	t.templates[template.Name] = template
+	return nil
+}
+
+func (t *Templates) Delete(name string) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if _, ok := t.templates[name]; ok == false {
+		return fmt.Errorf("user quota template %s doesn't exist", name)
+	}
+	if err := deleteFromDB(t.db, name); err != nil {
+		return err
+	}
+	delete(t.templates, name)
+	return nil
+}
+
+func (t *Templates) Get(name string) *types.UserQuotaTemplate {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+	return t.templates[name]
+}
+
+func (t *Templates) List() []*types.UserQuotaTemplate {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+	var templates []*types.UserQuotaTemplate
+	for _, template := range t.templates {
+		templates = append(templates, template)
+	}
+	sort.Slice(templates, func(i, j int) bool {
+		return templates[i].Name < templates[j].Name
+	})
+	return templates
+}
+
+//fields specified in the quota aren't overwritten
+func (t *Templates) Apply(quota *types.UserQuota) error {
+	if quota.Template == "" {
+		return nil
+	}
+
+	template := t.Get(quota.Template)
+	if template == nil {
+		return fmt.Errorf("user quota template %s doesn't exist", quota.Template)
+	}
+
+	if quota.CPU == "" {
+		quota.CPU = template.CPU
+	}
+	if quota.Memory == "" {
+		quota.Memory = template.Memory
+	}
+	if quota.Storage == "" {
+		quota.Storage = template.Storage
+	}
+	if quota.ExpireDays == 0 {
+		quota.ExpireDays = template.ExpireDays
+	}
+	return nil
+}
+
+func validateTemplate(template *types.UserQuotaTemplate) error {
+	for _, quantity := range []string{template.CPU, template.Memory, template.Storage} {
+		if _, err := apiresource.ParseQuantity(quantity); err != nil {
+			return fmt.Errorf("quantity %s is invalid:%s", quantity, err.Error())
+		}
+	}
+
+	if template.ExpireDays < 0 {
+		return fmt.Errorf("expire days cann't be negative")
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+package userquota
+
+import (
+	"testing"
+
+	ut "cement/unittest"
+	"pkg/types"
+)
+
+func TestTemplateApply(t *testing.T) {
+	templates, err := NewTemplates(newTable(t, UserQuotaTemplateTable))
+	ut.Assert(t, err == nil, "load templates should succeed: %v", err)
+
+	ut.Assert(t, templates.Add(&types.UserQuotaTemplate{Name: "bad", CPU: "1"}) != nil, "empty quantity should fail")
+	ut.Assert(t, templates.Add(&types.UserQuotaTemplate{
+		Name:       "small",
+		CPU:        "2",
+		Memory:     "4Gi",
+		Storage:    "20Gi",
+		ExpireDays: 30,
+	}) == nil, "")
+
+	quota := &types.UserQuota{Template: "small", Storage: "50Gi"}
+	ut.Assert(t, templates.Apply(quota) == nil, "")
+	ut.Equal(t, quota.CPU, "2")
+	ut.Equal(t, quota.Memory, "4Gi")
+	ut.Equal(t, quota.Storage, "50Gi")
+	ut.Equal(t, quota.ExpireDays, 30)
+
+	ut.Assert(t, templates.Apply(&types.UserQuota{Template: "large"}) != nil, "unknown template should fail")
+}